@@ -0,0 +1,37 @@
+package apikey
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+)
+
+const (
+	// keyPrefix identifies the API keys generated by Portainer
+	keyPrefix = "ptr_"
+	// displayedPrefixLength is the number of characters of a key that are kept to identify it
+	displayedPrefixLength = 8
+	keyLength             = 32
+)
+
+// GenerateKey generates a new random API key. It returns the raw key, which is only
+// displayed to its owner, the prefix used to identify the key and the digest to store.
+func GenerateKey() (rawKey, prefix, digest string, err error) {
+	randomBytes := make([]byte, keyLength)
+	_, err = rand.Read(randomBytes)
+	if err != nil {
+		return "", "", "", err
+	}
+
+	rawKey = keyPrefix + base64.RawURLEncoding.EncodeToString(randomBytes)
+
+	return rawKey, rawKey[:displayedPrefixLength], Digest(rawKey), nil
+}
+
+// Digest returns the SHA-256 digest of a raw API key, hex encoded.
+func Digest(rawKey string) string {
+	hash := sha256.Sum256([]byte(strings.TrimSpace(rawKey)))
+	return hex.EncodeToString(hash[:])
+}
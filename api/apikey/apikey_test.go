@@ -0,0 +1,23 @@
+package apikey
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGenerateKey(t *testing.T) {
+	rawKey, prefix, digest, err := GenerateKey()
+	assert.NoError(t, err)
+
+	assert.True(t, strings.HasPrefix(rawKey, keyPrefix))
+	assert.True(t, strings.HasPrefix(rawKey, prefix))
+	assert.Len(t, prefix, displayedPrefixLength)
+	assert.Equal(t, Digest(rawKey), digest)
+	assert.NotContains(t, digest, rawKey)
+
+	otherKey, _, otherDigest, _ := GenerateKey()
+	assert.NotEqual(t, rawKey, otherKey)
+	assert.NotEqual(t, digest, otherDigest)
+}
@@ -5,9 +5,12 @@ import (
 	"compress/gzip"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"os"
 	"path/filepath"
 	"strings"
+
+	"github.com/pkg/errors"
 )
 
 // TarGzDir creates a tar.gz archive and returns it's path.
@@ -71,7 +74,9 @@ func addToArchive(tarWriter *tar.Writer, pathInArchive string, path string, info
 	return err
 }
 
-// ExtractTarGz reads a .tar.gz archive from the reader and extracts it into outputDirPath directory
+// ExtractTarGz reads a .tar.gz archive from the reader and extracts it into outputDirPath directory.
+// The errors of the reader are wrapped, so that the caller can tell a corrupted stream apart,
+// and the reader is read to its end so that the errors following the last file are reported too.
 func ExtractTarGz(r io.Reader, outputDirPath string) error {
 	zipReader, err := gzip.NewReader(r)
 	if err != nil {
@@ -89,7 +94,7 @@ func ExtractTarGz(r io.Reader, outputDirPath string) error {
 		}
 
 		if err != nil {
-			return err
+			return errors.Wrap(err, "Failed to read the archive")
 		}
 
 		switch header.Typeflag {
@@ -104,10 +109,11 @@ func ExtractTarGz(r io.Reader, outputDirPath string) error {
 			if err != nil {
 				return fmt.Errorf("Failed to create file %s", header.Name)
 			}
-			if _, err := io.Copy(outFile, tarReader); err != nil {
-				return fmt.Errorf("Failed to extract file %s", header.Name)
-			}
+			_, err = io.Copy(outFile, tarReader)
 			outFile.Close()
+			if err != nil {
+				return errors.Wrapf(err, "Failed to extract file %s", header.Name)
+			}
 		default:
 			return fmt.Errorf("Tar: uknown type: %v in %s",
 				header.Typeflag,
@@ -115,5 +121,6 @@ func ExtractTarGz(r io.Reader, outputDirPath string) error {
 		}
 	}
 
-	return nil
+	_, err = io.Copy(ioutil.Discard, zipReader)
+	return errors.Wrap(err, "Failed to read the end of the archive")
 }
@@ -17,7 +17,8 @@ const rwxr__r__ os.FileMode = 0744
 
 var filesToBackup = []string{"compose", "config.json", "custom_templates", "edge_jobs", "edge_stacks", "extensions", "portainer.key", "portainer.pub", "tls"}
 
-// Creates a tar.gz system archive and encrypts it if password is not empty. Returns a path to the archive file.
+// Creates a tar.gz system archive along with a manifest describing its content and encrypts it if password is not empty.
+// Returns a path to the archive file.
 func CreateBackupArchive(password string, gate *offlinegate.OfflineGate, datastore portainer.DataStore, filestorePath string) (string, error) {
 	unlock := gate.Lock()
 	defer unlock()
@@ -38,6 +39,10 @@ func CreateBackupArchive(password string, gate *offlinegate.OfflineGate, datasto
 		}
 	}
 
+	if _, err := createManifest(backupDirPath); err != nil {
+		return "", errors.Wrap(err, "Failed to create backup manifest")
+	}
+
 	archivePath, err := archive.TarGzDir(backupDirPath)
 	if err != nil {
 		return "", errors.Wrap(err, "Failed to make an archive")
@@ -76,6 +81,7 @@ func encrypt(path string, passphrase string) (string, error) {
 	if err != nil {
 		return "", err
 	}
+	defer out.Close()
 
 	err = crypto.AesEncrypt(in, out, []byte(passphrase))
 
@@ -0,0 +1,103 @@
+package backup
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/pkg/errors"
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/http/offlinegate"
+)
+
+// Destination represents a remote location backup archives can be uploaded to and restored from
+type Destination interface {
+	// Upload stores the archive under the specified name
+	Upload(name string, archive io.Reader) error
+	// List returns the backups available in the destination, most recent first
+	List() ([]StoredBackup, error)
+	// Download returns a reader to the content of the specified backup
+	Download(name string) (io.ReadCloser, error)
+	// Delete removes the specified backup
+	Delete(name string) error
+}
+
+// NewDestination returns the remote destination configured in the backup settings,
+// or nil when backups are not uploaded anywhere.
+func NewDestination(settings portainer.BackupSettings) (Destination, error) {
+	if settings.S3Settings.Bucket != "" {
+		return NewS3Destination(settings.S3Settings)
+	}
+
+	return nil, nil
+}
+
+// UploadBackupArchive creates a backup archive and uploads it to the destination.
+func UploadBackupArchive(password string, gate *offlinegate.OfflineGate, datastore portainer.DataStore, filestorePath string, destination Destination) (*StoredBackup, error) {
+	archivePath, err := CreateBackupArchive(password, gate, datastore, filestorePath)
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(filepath.Dir(archivePath))
+
+	return uploadArchive(archivePath, storedBackupPrefix+filepath.Base(archivePath), destination)
+}
+
+func uploadArchive(archivePath string, name string, destination Destination) (*StoredBackup, error) {
+	archive, err := os.Open(archivePath)
+	if err != nil {
+		return nil, err
+	}
+	defer archive.Close()
+
+	info, err := archive.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := destination.Upload(name, archive); err != nil {
+		return nil, errors.Wrap(err, "Failed to upload backup archive")
+	}
+
+	return &StoredBackup{
+		Name:         name,
+		Size:         info.Size(),
+		CreationDate: time.Now().Unix(),
+		Encrypted:    IsEncryptedBackup(name),
+	}, nil
+}
+
+// PruneDestination removes the backups of the destination that are not among the keepLast most recent ones
+// or that are older than maxAge. A zero value disables the corresponding rule.
+// Returns the names of the removed backups.
+func PruneDestination(destination Destination, keepLast int, maxAge time.Duration, now time.Time) ([]string, error) {
+	backups, err := destination.List()
+	if err != nil {
+		return nil, err
+	}
+
+	removed := make([]string, 0)
+	for _, backup := range outdatedBackups(backups, keepLast, maxAge, now) {
+		if err := destination.Delete(backup.Name); err != nil {
+			return removed, err
+		}
+		removed = append(removed, backup.Name)
+	}
+
+	return removed, nil
+}
+
+// LatestBackup returns the name of the most recent backup available in the destination.
+func LatestBackup(destination Destination) (string, error) {
+	backups, err := destination.List()
+	if err != nil {
+		return "", err
+	}
+
+	if len(backups) == 0 {
+		return "", ErrStoredBackupNotFound
+	}
+
+	return backups[0].Name, nil
+}
@@ -0,0 +1,106 @@
+package backup
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	portainer "github.com/portainer/portainer/api"
+	"github.com/stretchr/testify/assert"
+)
+
+type memoryDestination struct {
+	backups map[string][]byte
+	dates   map[string]time.Time
+}
+
+func newMemoryDestination() *memoryDestination {
+	return &memoryDestination{backups: map[string][]byte{}, dates: map[string]time.Time{}}
+}
+
+func (d *memoryDestination) Upload(name string, archive io.Reader) error {
+	content, err := ioutil.ReadAll(archive)
+	d.backups[name] = content
+	d.dates[name] = time.Now()
+	return err
+}
+
+func (d *memoryDestination) List() ([]StoredBackup, error) {
+	backups := make([]StoredBackup, 0)
+	for name, content := range d.backups {
+		backups = append(backups, StoredBackup{Name: name, Size: int64(len(content)), CreationDate: d.dates[name].Unix()})
+	}
+	sortBackups(backups)
+	return backups, nil
+}
+
+func (d *memoryDestination) Download(name string) (io.ReadCloser, error) {
+	content, ok := d.backups[name]
+	if !ok {
+		return nil, ErrStoredBackupNotFound
+	}
+	return ioutil.NopCloser(bytes.NewReader(content)), nil
+}
+
+func (d *memoryDestination) Delete(name string) error {
+	delete(d.backups, name)
+	delete(d.dates, name)
+	return nil
+}
+
+func Test_pruneDestination_shouldKeepMostRecentBackups(t *testing.T) {
+	destination := newMemoryDestination()
+	now := time.Now()
+	for index, name := range []string{"portainer-backup_1.tar.gz", "portainer-backup_2.tar.gz", "portainer-backup_3.tar.gz"} {
+		destination.Upload(name, bytes.NewReader([]byte("content")))
+		destination.dates[name] = now.Add(time.Duration(index-3) * time.Hour)
+	}
+
+	removed, err := PruneDestination(destination, 2, 0, now)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"portainer-backup_1.tar.gz"}, removed)
+
+	latest, err := LatestBackup(destination)
+	assert.Nil(t, err)
+	assert.Equal(t, "portainer-backup_3.tar.gz", latest)
+}
+
+func Test_latestBackup_shouldFail_whenDestinationIsEmpty(t *testing.T) {
+	_, err := LatestBackup(newMemoryDestination())
+	assert.Equal(t, ErrStoredBackupNotFound, err)
+}
+
+func Test_newDestination_shouldReturnNil_whenNoBucketIsConfigured(t *testing.T) {
+	destination, err := NewDestination(portainer.BackupSettings{})
+	assert.Nil(t, err)
+	assert.Nil(t, destination)
+}
+
+func Test_validateS3Settings(t *testing.T) {
+	valid := portainer.S3BackupSettings{Endpoint: "https://minio:9000", Bucket: "backups", AccessKeyID: "access", SecretAccessKey: "secret"}
+	assert.Nil(t, ValidateS3Settings(valid))
+
+	withoutBucket := valid
+	withoutBucket.Bucket = ""
+	assert.NotNil(t, ValidateS3Settings(withoutBucket))
+
+	withoutSecret := valid
+	withoutSecret.SecretAccessKey = ""
+	assert.NotNil(t, ValidateS3Settings(withoutSecret))
+
+	invalidEndpoint := valid
+	invalidEndpoint.Endpoint = "minio:9000"
+	assert.NotNil(t, ValidateS3Settings(invalidEndpoint))
+}
+
+func Test_isAllowedS3Endpoint(t *testing.T) {
+	allowed := []string{"https://MinIO:9000/", "https://s3.amazonaws.com"}
+
+	assert.True(t, IsAllowedS3Endpoint("https://minio:9000", allowed))
+	assert.True(t, IsAllowedS3Endpoint("", allowed))
+	assert.False(t, IsAllowedS3Endpoint("http://minio:9000", allowed))
+	assert.False(t, IsAllowedS3Endpoint("https://169.254.169.254", allowed))
+	assert.False(t, IsAllowedS3Endpoint("", nil))
+}
@@ -0,0 +1,212 @@
+package backup
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/boltdb/bolt"
+	"github.com/pkg/errors"
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/bolt/version"
+)
+
+const (
+	manifestFileName = "manifest.json"
+	databaseFileName = "portainer.db"
+
+	// keys of the version bucket, see the bolt/version package
+	versionKey  = "DB_VERSION"
+	instanceKey = "INSTANCE_ID"
+	editionKey  = "EDITION"
+)
+
+// Manifest describes the content of a backup archive
+type Manifest struct {
+	// Version of Portainer that created the backup
+	PortainerVersion string `json:"PortainerVersion" example:"2.6.0"`
+	// Version of the backed up database
+	DBVersion int `json:"DBVersion" example:"30"`
+	// Edition of the backed up database
+	Edition portainer.SoftwareEdition `json:"Edition" example:"1"`
+	// Identifier of the backed up instance
+	InstanceID string `json:"InstanceID" example:"299ab403-70a8-4c05-92f7-bf7a994d50df"`
+	// Backup creation date, as a unix timestamp
+	CreationDate int64 `json:"CreationDate" example:"1622512800"`
+	// SHA-256 checksum of each file of the archive, indexed by their path in the archive
+	Files map[string]string `json:"Files"`
+	// Number of objects stored in each bucket of the database
+	Buckets map[string]int `json:"Buckets"`
+}
+
+// createManifest describes the content of the backup directory and writes the manifest inside it.
+func createManifest(backupDirPath string) (*Manifest, error) {
+	manifest := &Manifest{
+		PortainerVersion: portainer.APIVersion,
+		CreationDate:     time.Now().Unix(),
+	}
+
+	info, err := inspectDatabase(filepath.Join(backupDirPath, databaseFileName))
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to inspect database")
+	}
+	manifest.DBVersion = info.dbVersion
+	manifest.Edition = info.edition
+	manifest.InstanceID = info.instanceID
+	manifest.Buckets = info.buckets
+
+	manifest.Files, err = checksumFiles(backupDirPath)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to compute checksums")
+	}
+
+	content, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	return manifest, ioutil.WriteFile(filepath.Join(backupDirPath, manifestFileName), content, 0600)
+}
+
+func readManifest(dirPath string) (*Manifest, error) {
+	content, err := ioutil.ReadFile(filepath.Join(dirPath, manifestFileName))
+	if err != nil {
+		return nil, err
+	}
+
+	var manifest Manifest
+	err = json.Unmarshal(content, &manifest)
+	if err != nil {
+		return nil, err
+	}
+
+	return &manifest, nil
+}
+
+// checksumFiles computes the SHA-256 checksum of every regular file of the directory, except the manifest.
+func checksumFiles(dirPath string) (map[string]string, error) {
+	checksums := make(map[string]string)
+
+	err := filepath.Walk(dirPath, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+
+		relativePath, err := filepath.Rel(dirPath, path)
+		if err != nil {
+			return err
+		}
+		relativePath = filepath.ToSlash(relativePath)
+
+		if relativePath == manifestFileName {
+			return nil
+		}
+
+		checksum, err := checksumFile(path)
+		if err != nil {
+			return err
+		}
+		checksums[relativePath] = checksum
+
+		return nil
+	})
+
+	return checksums, err
+}
+
+func checksumFile(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+type databaseInfo struct {
+	dbVersion  int
+	edition    portainer.SoftwareEdition
+	instanceID string
+	buckets    map[string]int
+}
+
+// inspectDatabase opens a copy of the database in read-only mode to retrieve
+// its version information and the number of objects stored in each bucket.
+// An empty database file is reported as an empty database.
+func inspectDatabase(path string) (*databaseInfo, error) {
+	info := &databaseInfo{buckets: make(map[string]int)}
+
+	stat, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if stat.Size() == 0 {
+		return info, nil
+	}
+
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: 1 * time.Second, ReadOnly: true})
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	err = db.View(func(tx *bolt.Tx) error {
+		return tx.ForEach(func(name []byte, bucket *bolt.Bucket) error {
+			info.buckets[string(name)] = bucket.Stats().KeyN
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(version.BucketName))
+		if bucket == nil {
+			return nil
+		}
+
+		if value := bucket.Get([]byte(versionKey)); value != nil {
+			info.dbVersion, _ = strconv.Atoi(string(value))
+		}
+
+		if value := bucket.Get([]byte(editionKey)); value != nil {
+			edition, _ := strconv.Atoi(string(value))
+			info.edition = portainer.SoftwareEdition(edition)
+		}
+
+		if value := bucket.Get([]byte(instanceKey)); value != nil {
+			info.instanceID = string(value)
+		}
+
+		return nil
+	})
+
+	return info, err
+}
+
+func sortedKeys(values map[string]string) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -2,14 +2,18 @@ package backup
 
 import (
 	"context"
+	"encoding/binary"
+	"encoding/json"
 	"io"
 	"os"
 	"path/filepath"
 	"time"
 
+	"github.com/boltdb/bolt"
 	"github.com/pkg/errors"
 	portainer "github.com/portainer/portainer/api"
 	"github.com/portainer/portainer/api/archive"
+	"github.com/portainer/portainer/api/bolt/auditlog"
 	"github.com/portainer/portainer/api/crypto"
 	"github.com/portainer/portainer/api/http/offlinegate"
 )
@@ -17,6 +21,7 @@ import (
 var filesToRestore = append(filesToBackup, "portainer.db")
 
 // Restores system state from backup archive, will trigger system shutdown, when finished.
+// The archive is validated before the datastore is stopped, an invalid archive leaves the system untouched.
 func RestoreArchive(archive io.Reader, password string, filestorePath string, gate *offlinegate.OfflineGate, datastore portainer.DataStore, shutdownTrigger context.CancelFunc) error {
 	var err error
 	if password != "" {
@@ -34,9 +39,17 @@ func RestoreArchive(archive io.Reader, password string, filestorePath string, ga
 		return errors.Wrap(err, "cannot extract files from the archive. Please ensure the password is correct and try again")
 	}
 
+	if err = validateExtractedArchive(restorePath).err(); err != nil {
+		return err
+	}
+
 	unlock := gate.Lock()
 	defer unlock()
 
+	if err = keepAuditLogs(datastore, filepath.Join(restorePath, "portainer.db")); err != nil {
+		return errors.Wrap(err, "failed to keep the audit logs")
+	}
+
 	if err = datastore.Close(); err != nil {
 		return errors.Wrap(err, "Failed to stop db")
 	}
@@ -49,6 +62,58 @@ func RestoreArchive(archive io.Reader, password string, filestorePath string, ga
 	return nil
 }
 
+// keepAuditLogs replaces the audit logs of the archived database with the audit logs of the live one,
+// so that restoring a backup does not erase the audit trail. The archived audit logs are kept when the
+// live database has none.
+func keepAuditLogs(datastore portainer.DataStore, databasePath string) error {
+	auditLogs, err := datastore.AuditLog().AuditLogs()
+	if err != nil || len(auditLogs) == 0 {
+		return err
+	}
+
+	db, err := bolt.Open(databasePath, 0600, &bolt.Options{Timeout: 1 * time.Second})
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	return db.Update(func(tx *bolt.Tx) error {
+		err := tx.DeleteBucket([]byte(auditlog.BucketName))
+		if err != nil && err != bolt.ErrBucketNotFound {
+			return err
+		}
+
+		bucket, err := tx.CreateBucket([]byte(auditlog.BucketName))
+		if err != nil {
+			return err
+		}
+
+		for _, auditLog := range auditLogs {
+			data, err := json.Marshal(auditLog)
+			if err != nil {
+				return err
+			}
+
+			key := make([]byte, 8)
+			binary.BigEndian.PutUint64(key, uint64(auditLog.ID))
+
+			err = bucket.Put(key, data)
+			if err != nil {
+				return err
+			}
+
+			if uint64(auditLog.ID) > bucket.Sequence() {
+				err = bucket.SetSequence(uint64(auditLog.ID))
+				if err != nil {
+					return err
+				}
+			}
+		}
+
+		return nil
+	})
+}
+
 func decrypt(r io.Reader, password string) (io.Reader, error) {
 	return crypto.AesDecrypt(r, []byte(password))
 }
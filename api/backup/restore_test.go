@@ -0,0 +1,90 @@
+package backup
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/boltdb/bolt"
+	"github.com/pkg/errors"
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/archive"
+	"github.com/portainer/portainer/api/bolt/auditlog"
+	"github.com/portainer/portainer/api/crypto"
+	i "github.com/portainer/portainer/api/internal/testhelpers"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_keepAuditLogs_replacesTheArchivedAuditLogs(t *testing.T) {
+	is := assert.New(t)
+
+	databasePath := filepath.Join(t.TempDir(), "portainer.db")
+	db, err := bolt.Open(databasePath, 0600, &bolt.Options{Timeout: 1 * time.Second})
+	is.NoError(err)
+	is.NoError(db.Update(func(tx *bolt.Tx) error {
+		bucket, err := tx.CreateBucket([]byte(auditlog.BucketName))
+		if err != nil {
+			return err
+		}
+		return bucket.Put([]byte{0, 0, 0, 0, 0, 0, 0, 1}, []byte(`{"Id":1,"Path":"/api/archived"}`))
+	}))
+	is.NoError(db.Close())
+
+	live := []portainer.AuditLog{{ID: 1, Path: "/api/stacks/1"}, {ID: 2, Path: "/api/stacks/2"}}
+	is.NoError(keepAuditLogs(i.NewDatastore(i.WithAuditLogs(live)), databasePath))
+
+	db, err = bolt.Open(databasePath, 0600, &bolt.Options{Timeout: 1 * time.Second, ReadOnly: true})
+	is.NoError(err)
+	defer db.Close()
+
+	restored := make([]portainer.AuditLog, 0)
+	is.NoError(db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(auditlog.BucketName))
+		is.Equal(uint64(2), bucket.Sequence())
+
+		return bucket.ForEach(func(k, v []byte) error {
+			var auditLog portainer.AuditLog
+			err := json.Unmarshal(v, &auditLog)
+			restored = append(restored, auditLog)
+			return err
+		})
+	}))
+	is.Equal(live, restored)
+}
+
+func Test_RestoreArchive_reportsTheCorruptionAfterTheFirstChunk(t *testing.T) {
+	is := assert.New(t)
+
+	// random content does not compress, the archive spans several encrypted chunks
+	archiveDir := t.TempDir()
+	content := make([]byte, 256*1024)
+	rand.Read(content)
+	is.NoError(ioutil.WriteFile(filepath.Join(archiveDir, "portainer.db"), content, 0600))
+
+	archivePath, err := archive.TarGzDir(archiveDir)
+	is.NoError(err)
+
+	archiveFile, err := os.Open(archivePath)
+	is.NoError(err)
+	defer archiveFile.Close()
+
+	var encrypted bytes.Buffer
+	is.NoError(crypto.AesEncrypt(archiveFile, &encrypted, []byte("passphrase")))
+
+	tampered := append([]byte{}, encrypted.Bytes()...)
+	tampered[len(tampered)/2] ^= 0xff
+
+	err = RestoreArchive(bytes.NewReader(tampered), "passphrase", t.TempDir(), nil, nil, nil)
+	is.True(errors.Is(err, crypto.ErrDecryptionFailed), "a tampered chunk should be reported, got %v", err)
+
+	// the archive is cut in the middle of a chunk, which then fails its authentication
+	truncated := encrypted.Bytes()[:len(encrypted.Bytes())/2]
+
+	err = RestoreArchive(bytes.NewReader(truncated), "passphrase", t.TempDir(), nil, nil, nil)
+	is.True(errors.Is(err, crypto.ErrDecryptionFailed) || errors.Is(err, crypto.ErrTruncatedContent), "a truncated archive should be reported, got %v", err)
+}
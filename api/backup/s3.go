@@ -0,0 +1,206 @@
+package backup
+
+import (
+	"crypto/tls"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+	"github.com/pkg/errors"
+	portainer "github.com/portainer/portainer/api"
+)
+
+const (
+	defaultS3Region   = "us-east-1"
+	defaultS3Endpoint = "https://s3.amazonaws.com"
+)
+
+// S3Destination is a backup destination backed by an S3 compatible object storage
+type S3Destination struct {
+	client   *s3.S3
+	uploader *s3manager.Uploader
+	bucket   string
+	prefix   string
+}
+
+// NewS3Destination creates a backup destination using the S3 settings.
+func NewS3Destination(settings portainer.S3BackupSettings) (*S3Destination, error) {
+	if err := ValidateS3Settings(settings); err != nil {
+		return nil, err
+	}
+
+	region := settings.Region
+	if region == "" {
+		region = defaultS3Region
+	}
+
+	config := aws.NewConfig().
+		WithRegion(region).
+		WithCredentials(credentials.NewStaticCredentials(settings.AccessKeyID, settings.SecretAccessKey, ""))
+
+	if settings.Endpoint != "" {
+		// S3 compatible storages such as MinIO are usually not reachable through virtual hosted buckets
+		config = config.WithEndpoint(settings.Endpoint).WithS3ForcePathStyle(true)
+	}
+
+	if settings.TLSSkipVerify {
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+		config = config.WithHTTPClient(&http.Client{Transport: transport})
+	}
+
+	sess, err := session.NewSession(config)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to create S3 session")
+	}
+
+	return &S3Destination{
+		client:   s3.New(sess),
+		uploader: s3manager.NewUploader(sess),
+		bucket:   settings.Bucket,
+		prefix:   settings.Prefix,
+	}, nil
+}
+
+// ValidateS3Settings ensures that S3 settings can be used to reach a bucket.
+func ValidateS3Settings(settings portainer.S3BackupSettings) error {
+	if settings.Bucket == "" {
+		return errors.New("Invalid S3 bucket name")
+	}
+
+	if settings.AccessKeyID == "" || settings.SecretAccessKey == "" {
+		return errors.New("Invalid S3 credentials")
+	}
+
+	return validateS3Endpoint(settings.Endpoint)
+}
+
+func validateS3Endpoint(endpoint string) error {
+	if endpoint != "" && !strings.HasPrefix(endpoint, "http://") && !strings.HasPrefix(endpoint, "https://") {
+		return errors.New("Invalid S3 endpoint. Must start with http:// or https://")
+	}
+
+	return nil
+}
+
+// IsAllowedS3Endpoint returns whether the endpoint of an object storage is one of the allowed endpoints.
+// The endpoints are compared on their scheme and host, an empty endpoint stands for AWS S3.
+func IsAllowedS3Endpoint(endpoint string, allowed []string) bool {
+	origin, ok := s3EndpointOrigin(endpoint)
+	if !ok {
+		return false
+	}
+
+	for _, allowedEndpoint := range allowed {
+		allowedOrigin, ok := s3EndpointOrigin(allowedEndpoint)
+		if ok && allowedOrigin == origin {
+			return true
+		}
+	}
+
+	return false
+}
+
+func s3EndpointOrigin(endpoint string) (string, bool) {
+	if endpoint == "" {
+		endpoint = defaultS3Endpoint
+	}
+
+	endpointURL, err := url.Parse(endpoint)
+	if err != nil || endpointURL.Host == "" || (endpointURL.Scheme != "http" && endpointURL.Scheme != "https") {
+		return "", false
+	}
+
+	return strings.ToLower(endpointURL.Scheme + "://" + endpointURL.Host), true
+}
+
+// Upload stores the archive under the specified name
+func (destination *S3Destination) Upload(name string, archive io.Reader) error {
+	_, err := destination.uploader.Upload(&s3manager.UploadInput{
+		Bucket: aws.String(destination.bucket),
+		Key:    aws.String(destination.key(name)),
+		Body:   archive,
+	})
+
+	return err
+}
+
+// List returns the backups available in the bucket, most recent first
+func (destination *S3Destination) List() ([]StoredBackup, error) {
+	backups := make([]StoredBackup, 0)
+
+	input := &s3.ListObjectsV2Input{
+		Bucket: aws.String(destination.bucket),
+		Prefix: aws.String(destination.prefix),
+	}
+
+	err := destination.client.ListObjectsV2Pages(input, func(page *s3.ListObjectsV2Output, lastPage bool) bool {
+		for _, object := range page.Contents {
+			name := strings.TrimPrefix(aws.StringValue(object.Key), destination.prefix)
+			if !isStoredBackupName(name) || path.Base(name) != name {
+				continue
+			}
+
+			backups = append(backups, StoredBackup{
+				Name:         name,
+				Size:         aws.Int64Value(object.Size),
+				CreationDate: aws.TimeValue(object.LastModified).Unix(),
+				Encrypted:    IsEncryptedBackup(name),
+			})
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sortBackups(backups)
+
+	return backups, nil
+}
+
+// Download returns a reader to the content of the specified backup
+func (destination *S3Destination) Download(name string) (io.ReadCloser, error) {
+	if !isStoredBackupName(name) || path.Base(name) != name {
+		return nil, ErrStoredBackupNotFound
+	}
+
+	output, err := destination.client.GetObject(&s3.GetObjectInput{
+		Bucket: aws.String(destination.bucket),
+		Key:    aws.String(destination.key(name)),
+	})
+	if aerr, ok := err.(awserr.Error); ok && aerr.Code() == s3.ErrCodeNoSuchKey {
+		return nil, ErrStoredBackupNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return output.Body, nil
+}
+
+// Delete removes the specified backup
+func (destination *S3Destination) Delete(name string) error {
+	if !isStoredBackupName(name) || path.Base(name) != name {
+		return ErrStoredBackupNotFound
+	}
+
+	_, err := destination.client.DeleteObject(&s3.DeleteObjectInput{
+		Bucket: aws.String(destination.bucket),
+		Key:    aws.String(destination.key(name)),
+	})
+
+	return err
+}
+
+func (destination *S3Destination) key(name string) string {
+	return destination.prefix + name
+}
@@ -0,0 +1,190 @@
+package backup
+
+import (
+	"context"
+	"log"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/http/offlinegate"
+	"github.com/robfig/cron/v3"
+)
+
+// Scheduler creates backups on a cron schedule and prunes the stored ones
+// according to the retention policy defined in the backup settings.
+type Scheduler struct {
+	mu            sync.Mutex
+	cron          *cron.Cron
+	gate          *offlinegate.OfflineGate
+	datastore     portainer.DataStore
+	filestorePath string
+	shutdownCtx   context.Context
+}
+
+// NewScheduler creates a new instance of a backup scheduler
+func NewScheduler(gate *offlinegate.OfflineGate, datastore portainer.DataStore, filestorePath string, shutdownCtx context.Context) *Scheduler {
+	return &Scheduler{
+		gate:          gate,
+		datastore:     datastore,
+		filestorePath: filestorePath,
+		shutdownCtx:   shutdownCtx,
+	}
+}
+
+// ValidateBackupSettings ensures that backup settings can be used to schedule backups.
+func ValidateBackupSettings(settings portainer.BackupSettings) error {
+	if settings.Enabled {
+		if _, err := cron.ParseStandard(settings.CronExpression); err != nil {
+			return errors.Wrap(err, "Invalid backup cron expression")
+		}
+	}
+
+	if settings.KeepLast < 0 {
+		return errors.New("Invalid number of backups to keep. Value must be greater or equal to 0")
+	}
+
+	if settings.MaxAge != "" {
+		maxAge, err := time.ParseDuration(settings.MaxAge)
+		if err != nil || maxAge <= 0 {
+			return errors.New("Invalid backup max age. Value must be a positive duration")
+		}
+	}
+
+	if err := validateS3Endpoint(settings.S3Settings.Endpoint); err != nil {
+		return err
+	}
+
+	return ValidateBackupDirectory(settings.Directory)
+}
+
+// Start schedules the backups according to the settings stored in the database.
+// The schedule is stopped when the shutdown context is cancelled.
+func (scheduler *Scheduler) Start() error {
+	settings, err := scheduler.datastore.Settings().Settings()
+	if err != nil {
+		return err
+	}
+
+	err = scheduler.Update(settings.BackupSettings)
+	if err != nil {
+		return err
+	}
+
+	if scheduler.shutdownCtx != nil {
+		go func() {
+			<-scheduler.shutdownCtx.Done()
+			scheduler.Stop()
+		}()
+	}
+
+	return nil
+}
+
+// Update replaces the current schedule with the one defined in the settings.
+func (scheduler *Scheduler) Update(settings portainer.BackupSettings) error {
+	scheduler.Stop()
+
+	if !settings.Enabled {
+		return nil
+	}
+
+	if scheduler.shutdownCtx != nil && scheduler.shutdownCtx.Err() != nil {
+		return nil
+	}
+
+	c := cron.New()
+	_, err := c.AddFunc(settings.CronExpression, scheduler.run)
+	if err != nil {
+		return errors.Wrap(err, "Invalid backup cron expression")
+	}
+
+	scheduler.mu.Lock()
+	defer scheduler.mu.Unlock()
+
+	scheduler.cron = c
+	scheduler.cron.Start()
+
+	return nil
+}
+
+// Stop stops the schedule. Safe to call even if the scheduler wasn't started.
+func (scheduler *Scheduler) Stop() {
+	scheduler.mu.Lock()
+	defer scheduler.mu.Unlock()
+
+	if scheduler.cron == nil {
+		return
+	}
+
+	scheduler.cron.Stop()
+	scheduler.cron = nil
+}
+
+// Run creates a backup in the storage directory, uploads it to the remote destination
+// when one is configured and prunes the outdated backups.
+func (scheduler *Scheduler) Run() (*StoredBackup, error) {
+	settings, err := scheduler.datastore.Settings().Settings()
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to retrieve backup settings")
+	}
+
+	storageDir := StoredBackupsDir(scheduler.filestorePath, settings.BackupSettings)
+
+	backup, err := StoreBackupArchive(settings.BackupSettings.Password, scheduler.gate, scheduler.datastore, scheduler.filestorePath, storageDir)
+	if err != nil {
+		return nil, err
+	}
+
+	var maxAge time.Duration
+	if settings.BackupSettings.MaxAge != "" {
+		maxAge, err = time.ParseDuration(settings.BackupSettings.MaxAge)
+		if err != nil {
+			return backup, errors.Wrap(err, "Invalid backup max age")
+		}
+	}
+
+	removed, err := PruneStoredBackups(storageDir, settings.BackupSettings.KeepLast, maxAge, time.Now())
+	for _, name := range removed {
+		log.Printf("[DEBUG] [backup,scheduler] [message: removed outdated backup] [name: %s]", name)
+	}
+	if err != nil {
+		return backup, errors.Wrap(err, "Failed to prune outdated backups")
+	}
+
+	destination, err := NewDestination(settings.BackupSettings)
+	if err != nil {
+		return backup, errors.Wrap(err, "Invalid backup destination")
+	}
+
+	if destination == nil {
+		return backup, nil
+	}
+
+	_, err = uploadArchive(filepath.Join(storageDir, backup.Name), backup.Name, destination)
+	if err != nil {
+		return backup, err
+	}
+
+	removed, err = PruneDestination(destination, settings.BackupSettings.KeepLast, maxAge, time.Now())
+	for _, name := range removed {
+		log.Printf("[DEBUG] [backup,scheduler] [message: removed outdated backup from destination] [name: %s]", name)
+	}
+	if err != nil {
+		return backup, errors.Wrap(err, "Failed to prune outdated backups from destination")
+	}
+
+	return backup, nil
+}
+
+func (scheduler *Scheduler) run() {
+	backup, err := scheduler.Run()
+	if err != nil {
+		log.Printf("[ERROR] [backup,scheduler] [message: scheduled backup failed] [error: %s]", err)
+		return
+	}
+
+	log.Printf("[INFO] [backup,scheduler] [message: scheduled backup created] [name: %s]", backup.Name)
+}
@@ -0,0 +1,883 @@
+package backup
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/boltdb/bolt"
+	"github.com/pkg/errors"
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/bolt/customtemplate"
+	"github.com/portainer/portainer/api/bolt/endpoint"
+	bolterrors "github.com/portainer/portainer/api/bolt/errors"
+	"github.com/portainer/portainer/api/bolt/resourcecontrol"
+	"github.com/portainer/portainer/api/bolt/stack"
+	"github.com/portainer/portainer/api/bolt/team"
+	"github.com/portainer/portainer/api/bolt/teammembership"
+	"github.com/portainer/portainer/api/bolt/user"
+	"github.com/portainer/portainer/api/filesystem"
+	"github.com/portainer/portainer/api/internal/stackutils"
+)
+
+// ArchiveContent lists the entities of a backup archive that can be restored individually
+type ArchiveContent struct {
+	Stacks          []portainer.Stack          `json:"Stacks"`
+	CustomTemplates []portainer.CustomTemplate `json:"CustomTemplates"`
+	Endpoints       []portainer.Endpoint       `json:"Endpoints"`
+	Users           []portainer.User           `json:"Users"`
+	Teams           []portainer.Team           `json:"Teams"`
+}
+
+// RestoreSelection identifies the entities of a backup archive to restore
+type RestoreSelection struct {
+	StackIDs          []portainer.StackID
+	CustomTemplateIDs []portainer.CustomTemplateID
+	EndpointIDs       []portainer.EndpointID
+	UserIDs           []portainer.UserID
+	TeamIDs           []portainer.TeamID
+}
+
+// IsEmpty returns true when no entity is selected
+func (selection RestoreSelection) IsEmpty() bool {
+	return len(selection.StackIDs) == 0 && len(selection.CustomTemplateIDs) == 0 && len(selection.EndpointIDs) == 0 &&
+		len(selection.UserIDs) == 0 && len(selection.TeamIDs) == 0
+}
+
+// RestoredEntity describes the outcome of the restore of a single entity
+type RestoredEntity struct {
+	// Type of the entity
+	Type string `json:"Type" example:"stack" enums:"stack,customtemplate,endpoint,user,team"`
+	// Identifier of the entity in the archive
+	ID int `json:"Id" example:"1"`
+	// Identifier of the restored entity in the live datastore
+	RestoredID int `json:"RestoredId,omitempty" example:"12"`
+	// Name of the entity
+	Name string `json:"Name" example:"myStack"`
+	// Reason why the entity was not restored
+	Reason string `json:"Reason,omitempty" example:"a stack with the same name already exists"`
+}
+
+// SelectiveRestoreReport lists the entities restored from a backup archive and the ones that were skipped
+type SelectiveRestoreReport struct {
+	Restored []RestoredEntity `json:"Restored"`
+	Skipped  []RestoredEntity `json:"Skipped"`
+}
+
+const (
+	stackEntity          = "stack"
+	customTemplateEntity = "customtemplate"
+	endpointEntity       = "endpoint"
+	userEntity           = "user"
+	teamEntity           = "team"
+)
+
+// archivedData holds the content of the database of an extracted archive
+type archivedData struct {
+	ArchiveContent
+	memberships      []portainer.TeamMembership
+	resourceControls []portainer.ResourceControl
+}
+
+// InspectArchive lists the entities of an archive that can be restored individually.
+// User passwords and two-factor authentication secrets are not returned.
+func InspectArchive(archive io.Reader, password string, filestorePath string) (*ArchiveContent, error) {
+	var content *ArchiveContent
+
+	err := withExtractedArchive(archive, password, filestorePath, func(dirPath string, data *archivedData) error {
+		content = &data.ArchiveContent
+		for idx := range content.Users {
+			content.Users[idx].Password = ""
+			content.Users[idx].PasswordHistory = nil
+			content.Users[idx].TOTP = portainer.TOTPSettings{Enabled: content.Users[idx].TOTP.Enabled}
+		}
+		return nil
+	})
+
+	return content, err
+}
+
+// RestoreEntities merges the selected entities of an archive into the live datastore, along with their files.
+// The restored entities get new identifiers and the references between them are updated accordingly.
+// Entities that conflict with existing ones are skipped and reported. The entities are created in a single
+// transaction, nothing is restored when one of them cannot be.
+func RestoreEntities(archive io.Reader, password string, filestorePath string, datastore portainer.DataStore, selection RestoreSelection) (*SelectiveRestoreReport, error) {
+	var report *SelectiveRestoreReport
+
+	err := withExtractedArchive(archive, password, filestorePath, func(dirPath string, data *archivedData) error {
+		restorer := &entityRestorer{
+			datastore:     datastore,
+			data:          data,
+			sourcePath:    dirPath,
+			filestorePath: filestorePath,
+			report:        &SelectiveRestoreReport{Restored: make([]RestoredEntity, 0), Skipped: make([]RestoredEntity, 0)},
+			batch:         &portainer.EntityBatch{},
+			endpoints:     make(map[portainer.EndpointID]portainer.EndpointID),
+			users:         make(map[portainer.UserID]portainer.UserID),
+			teams:         make(map[portainer.TeamID]portainer.TeamID),
+			stacks:        make(map[portainer.StackID]portainer.StackID),
+		}
+
+		err := restorer.restore(selection)
+		if err == nil {
+			err = errors.Wrap(datastore.CreateEntities(restorer.batch), "unable to save the restored entities in the database")
+		}
+
+		if err != nil {
+			restorer.removeCopiedFiles()
+			return err
+		}
+
+		report = restorer.report
+		return nil
+	})
+
+	return report, err
+}
+
+// withExtractedArchive decrypts, extracts and validates the archive in a temporary directory
+// then reads its database in read-only mode. The directory is removed once fn returns.
+func withExtractedArchive(archive io.Reader, password string, filestorePath string, fn func(dirPath string, data *archivedData) error) error {
+	var err error
+	if password != "" {
+		archive, err = decrypt(archive, password)
+		if err != nil {
+			return errors.Wrap(err, "failed to decrypt the archive")
+		}
+	}
+
+	dirPath := filepath.Join(filestorePath, "restore", "selective-"+time.Now().Format("20060102150405.000000000"))
+	defer os.RemoveAll(dirPath)
+
+	err = extractArchive(archive, dirPath)
+	if err != nil {
+		return errors.Wrap(err, "cannot extract files from the archive. Please ensure the password is correct and try again")
+	}
+
+	if err = validateExtractedArchive(dirPath).err(); err != nil {
+		return err
+	}
+
+	data, err := readArchivedData(filepath.Join(dirPath, databaseFileName))
+	if err != nil {
+		return err
+	}
+
+	return fn(dirPath, data)
+}
+
+// readArchivedData loads the entities that can be restored individually from an archived database.
+// The database must have the same version as the live one since the entities are not migrated.
+func readArchivedData(path string) (*archivedData, error) {
+	info, err := inspectDatabase(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to open the archived database")
+	}
+
+	if info.dbVersion != portainer.DBVersion {
+		return nil, errors.Wrapf(ErrInvalidArchive, "the archived database version (%d) differs from the version of this instance (%d), the backup can only be restored entirely", info.dbVersion, portainer.DBVersion)
+	}
+
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: 1 * time.Second, ReadOnly: true})
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to open the archived database")
+	}
+	defer db.Close()
+
+	data := &archivedData{
+		ArchiveContent: ArchiveContent{
+			Stacks:          make([]portainer.Stack, 0),
+			CustomTemplates: make([]portainer.CustomTemplate, 0),
+			Endpoints:       make([]portainer.Endpoint, 0),
+			Users:           make([]portainer.User, 0),
+			Teams:           make([]portainer.Team, 0),
+		},
+		memberships:      make([]portainer.TeamMembership, 0),
+		resourceControls: make([]portainer.ResourceControl, 0),
+	}
+
+	err = db.View(func(tx *bolt.Tx) error {
+		buckets := []struct {
+			name    string
+			element func(value []byte) error
+		}{
+			{stack.BucketName, func(value []byte) error {
+				var object portainer.Stack
+				err := json.Unmarshal(value, &object)
+				data.Stacks = append(data.Stacks, object)
+				return err
+			}},
+			{customtemplate.BucketName, func(value []byte) error {
+				var object portainer.CustomTemplate
+				err := json.Unmarshal(value, &object)
+				data.CustomTemplates = append(data.CustomTemplates, object)
+				return err
+			}},
+			{endpoint.BucketName, func(value []byte) error {
+				var object portainer.Endpoint
+				err := json.Unmarshal(value, &object)
+				data.Endpoints = append(data.Endpoints, object)
+				return err
+			}},
+			{user.BucketName, func(value []byte) error {
+				var object portainer.User
+				err := json.Unmarshal(value, &object)
+				data.Users = append(data.Users, object)
+				return err
+			}},
+			{team.BucketName, func(value []byte) error {
+				var object portainer.Team
+				err := json.Unmarshal(value, &object)
+				data.Teams = append(data.Teams, object)
+				return err
+			}},
+			{teammembership.BucketName, func(value []byte) error {
+				var object portainer.TeamMembership
+				err := json.Unmarshal(value, &object)
+				data.memberships = append(data.memberships, object)
+				return err
+			}},
+			{resourcecontrol.BucketName, func(value []byte) error {
+				var object portainer.ResourceControl
+				err := json.Unmarshal(value, &object)
+				data.resourceControls = append(data.resourceControls, object)
+				return err
+			}},
+		}
+
+		for _, b := range buckets {
+			bucket := tx.Bucket([]byte(b.name))
+			if bucket == nil {
+				continue
+			}
+
+			err := bucket.ForEach(func(key, value []byte) error {
+				return errors.Wrapf(b.element(value), "unable to read an object from the %s bucket", b.name)
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+
+	return data, err
+}
+
+// entityRestorer merges archived entities into the live datastore.
+// It keeps track of the identifiers given to the restored entities to update the references between them,
+// and collects the entities to create them at once.
+type entityRestorer struct {
+	datastore     portainer.DataStore
+	data          *archivedData
+	sourcePath    string
+	filestorePath string
+	report        *SelectiveRestoreReport
+	endpoints     map[portainer.EndpointID]portainer.EndpointID
+	users         map[portainer.UserID]portainer.UserID
+	teams         map[portainer.TeamID]portainer.TeamID
+	stacks        map[portainer.StackID]portainer.StackID
+	batch         *portainer.EntityBatch
+	copiedDirs    []string
+}
+
+// restore restores the selection in dependency order: teams and users first,
+// then endpoints and finally the stacks and custom templates that may reference them.
+func (restorer *entityRestorer) restore(selection RestoreSelection) error {
+	for _, ID := range selection.TeamIDs {
+		if err := restorer.restoreTeam(ID); err != nil {
+			return err
+		}
+	}
+
+	for _, ID := range selection.UserIDs {
+		if err := restorer.restoreUser(ID); err != nil {
+			return err
+		}
+	}
+
+	if err := restorer.restoreTeamMemberships(); err != nil {
+		return err
+	}
+
+	for _, ID := range selection.EndpointIDs {
+		if err := restorer.restoreEndpoint(ID); err != nil {
+			return err
+		}
+	}
+
+	for _, ID := range selection.StackIDs {
+		if err := restorer.restoreStack(ID); err != nil {
+			return err
+		}
+	}
+
+	for _, ID := range selection.CustomTemplateIDs {
+		if err := restorer.restoreCustomTemplate(ID); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (restorer *entityRestorer) restored(entityType string, ID, restoredID int, name string) {
+	restorer.report.Restored = append(restorer.report.Restored, RestoredEntity{Type: entityType, ID: ID, RestoredID: restoredID, Name: name})
+}
+
+func (restorer *entityRestorer) skipped(entityType string, ID int, name, reason string) {
+	restorer.report.Skipped = append(restorer.report.Skipped, RestoredEntity{Type: entityType, ID: ID, Name: name, Reason: reason})
+}
+
+// copyDir copies a directory of the archive into the file store, the copies are removed when the restore fails
+func (restorer *entityRestorer) copyDir(fromDir, toDir string) error {
+	restorer.copiedDirs = append(restorer.copiedDirs, toDir)
+	return copyDirContent(fromDir, toDir)
+}
+
+func (restorer *entityRestorer) removeCopiedFiles() {
+	for _, dir := range restorer.copiedDirs {
+		os.RemoveAll(dir)
+	}
+}
+
+// alreadySelectedReason is reported for the entities listed more than once in the selection. The conflicts are
+// also checked against the entities of the batch, which are not saved yet, so that two archived entities with
+// the same name are not both restored.
+const alreadySelectedReason = "selected more than once"
+
+func (restorer *entityRestorer) restoreTeam(ID portainer.TeamID) error {
+	archived := restorer.data.team(ID)
+	if archived == nil {
+		restorer.skipped(teamEntity, int(ID), "", "not found in the archive")
+		return nil
+	}
+
+	if _, ok := restorer.teams[ID]; ok {
+		restorer.skipped(teamEntity, int(ID), archived.Name, alreadySelectedReason)
+		return nil
+	}
+
+	exists, err := isFound(restorer.datastore.Team().TeamByName(archived.Name))
+	if err != nil {
+		return errors.Wrap(err, "unable to retrieve teams from the database")
+	}
+	for _, team := range restorer.batch.Teams {
+		exists = exists || strings.EqualFold(team.Name, archived.Name)
+	}
+	if exists {
+		restorer.skipped(teamEntity, int(ID), archived.Name, "a team with the same name already exists")
+		return nil
+	}
+
+	team := *archived
+	team.ID = portainer.TeamID(restorer.datastore.Team().GetNextIdentifier())
+	restorer.batch.Teams = append(restorer.batch.Teams, team)
+
+	restorer.teams[ID] = team.ID
+	restorer.restored(teamEntity, int(ID), int(team.ID), team.Name)
+	return nil
+}
+
+func (restorer *entityRestorer) restoreUser(ID portainer.UserID) error {
+	archived := restorer.data.user(ID)
+	if archived == nil {
+		restorer.skipped(userEntity, int(ID), "", "not found in the archive")
+		return nil
+	}
+
+	if _, ok := restorer.users[ID]; ok {
+		restorer.skipped(userEntity, int(ID), archived.Username, alreadySelectedReason)
+		return nil
+	}
+
+	exists, err := isFound(restorer.datastore.User().UserByUsername(archived.Username))
+	if err != nil {
+		return errors.Wrap(err, "unable to retrieve users from the database")
+	}
+	for _, user := range restorer.batch.Users {
+		exists = exists || strings.EqualFold(user.Username, archived.Username)
+	}
+	if exists {
+		restorer.skipped(userEntity, int(ID), archived.Username, "a user with the same username already exists")
+		return nil
+	}
+
+	user := *archived
+	user.ID = portainer.UserID(restorer.datastore.User().GetNextIdentifier())
+	restorer.batch.Users = append(restorer.batch.Users, user)
+
+	restorer.users[ID] = user.ID
+	restorer.restored(userEntity, int(ID), int(user.ID), user.Username)
+	return nil
+}
+
+// restoreTeamMemberships restores the archived memberships of the restored users and teams
+// when both the user and the team exist in the live datastore.
+func (restorer *entityRestorer) restoreTeamMemberships() error {
+	for _, membership := range restorer.data.memberships {
+		_, userRestored := restorer.users[membership.UserID]
+		_, teamRestored := restorer.teams[membership.TeamID]
+		if !userRestored && !teamRestored {
+			continue
+		}
+
+		userID, userFound, err := restorer.resolveUser(membership.UserID)
+		if err != nil {
+			return err
+		}
+
+		teamID, teamFound, err := restorer.resolveTeam(membership.TeamID)
+		if err != nil {
+			return err
+		}
+
+		if !userFound || !teamFound {
+			continue
+		}
+
+		memberships, err := restorer.datastore.TeamMembership().TeamMembershipsByUserID(userID)
+		if err != nil {
+			return errors.Wrap(err, "unable to retrieve team memberships from the database")
+		}
+
+		if hasMembership(memberships, teamID) {
+			continue
+		}
+
+		restorer.batch.TeamMemberships = append(restorer.batch.TeamMemberships, portainer.TeamMembership{
+			UserID: userID,
+			TeamID: teamID,
+			Role:   membership.Role,
+		})
+	}
+
+	return nil
+}
+
+func (restorer *entityRestorer) restoreEndpoint(ID portainer.EndpointID) error {
+	archived := restorer.data.endpoint(ID)
+	if archived == nil {
+		restorer.skipped(endpointEntity, int(ID), "", "not found in the archive")
+		return nil
+	}
+
+	if archived.Type == portainer.EdgeAgentOnDockerEnvironment || archived.Type == portainer.EdgeAgentOnKubernetesEnvironment {
+		restorer.skipped(endpointEntity, int(ID), archived.Name, "the key of an Edge endpoint is bound to its identifier, it cannot be restored individually")
+		return nil
+	}
+
+	if _, ok := restorer.endpoints[ID]; ok {
+		restorer.skipped(endpointEntity, int(ID), archived.Name, alreadySelectedReason)
+		return nil
+	}
+
+	endpoints, err := restorer.datastore.Endpoint().Endpoints()
+	if err != nil {
+		return errors.Wrap(err, "unable to retrieve endpoints from the database")
+	}
+
+	for _, existing := range append(endpoints, restorer.batch.Endpoints...) {
+		if existing.Name == archived.Name && existing.URL == archived.URL {
+			restorer.skipped(endpointEntity, int(ID), archived.Name, "an endpoint with the same name and URL already exists")
+			return nil
+		}
+	}
+
+	endpoint := *archived
+	endpoint.ID = portainer.EndpointID(restorer.datastore.Endpoint().GetNextIdentifier())
+
+	_, err = restorer.datastore.EndpointGroup().EndpointGroup(endpoint.GroupID)
+	if err != nil && !isNotFound(err) {
+		return errors.Wrap(err, "unable to retrieve endpoint groups from the database")
+	}
+	if isNotFound(err) {
+		endpoint.GroupID = portainer.EndpointGroupID(1)
+	}
+
+	endpoint.TagIDs = make([]portainer.TagID, 0)
+	for _, tagID := range archived.TagIDs {
+		_, err := restorer.datastore.Tag().Tag(tagID)
+		if err != nil && !isNotFound(err) {
+			return errors.Wrap(err, "unable to retrieve tags from the database")
+		}
+		if err == nil {
+			endpoint.TagIDs = append(endpoint.TagIDs, tagID)
+		}
+	}
+
+	endpoint.UserAccessPolicies = make(portainer.UserAccessPolicies)
+	for userID, policy := range archived.UserAccessPolicies {
+		restoredID, found, err := restorer.resolveUser(userID)
+		if err != nil {
+			return err
+		}
+		if found {
+			endpoint.UserAccessPolicies[restoredID] = policy
+		}
+	}
+
+	endpoint.TeamAccessPolicies = make(portainer.TeamAccessPolicies)
+	for teamID, policy := range archived.TeamAccessPolicies {
+		restoredID, found, err := restorer.resolveTeam(teamID)
+		if err != nil {
+			return err
+		}
+		if found {
+			endpoint.TeamAccessPolicies[restoredID] = policy
+		}
+	}
+
+	tlsPath := filepath.Join(restorer.filestorePath, filesystem.TLSStorePath, strconv.Itoa(int(endpoint.ID)))
+	err = restorer.copyDir(filepath.Join(restorer.sourcePath, filesystem.TLSStorePath, strconv.Itoa(int(ID))), tlsPath)
+	if err != nil {
+		return errors.Wrapf(err, "unable to restore the TLS files of endpoint %s", archived.Name)
+	}
+
+	if endpoint.TLSConfig.TLSCACertPath != "" {
+		endpoint.TLSConfig.TLSCACertPath = filepath.Join(tlsPath, filepath.Base(endpoint.TLSConfig.TLSCACertPath))
+	}
+	if endpoint.TLSConfig.TLSCertPath != "" {
+		endpoint.TLSConfig.TLSCertPath = filepath.Join(tlsPath, filepath.Base(endpoint.TLSConfig.TLSCertPath))
+	}
+	if endpoint.TLSConfig.TLSKeyPath != "" {
+		endpoint.TLSConfig.TLSKeyPath = filepath.Join(tlsPath, filepath.Base(endpoint.TLSConfig.TLSKeyPath))
+	}
+
+	restorer.batch.Endpoints = append(restorer.batch.Endpoints, endpoint)
+	restorer.batch.EndpointRelations = append(restorer.batch.EndpointRelations, portainer.EndpointRelation{
+		EndpointID: endpoint.ID,
+		EdgeStacks: map[portainer.EdgeStackID]bool{},
+	})
+
+	restorer.endpoints[ID] = endpoint.ID
+	restorer.restored(endpointEntity, int(ID), int(endpoint.ID), endpoint.Name)
+	return nil
+}
+
+func (restorer *entityRestorer) restoreStack(ID portainer.StackID) error {
+	archived := restorer.data.stack(ID)
+	if archived == nil {
+		restorer.skipped(stackEntity, int(ID), "", "not found in the archive")
+		return nil
+	}
+
+	if _, ok := restorer.stacks[ID]; ok {
+		restorer.skipped(stackEntity, int(ID), archived.Name, alreadySelectedReason)
+		return nil
+	}
+
+	exists, err := isFound(restorer.datastore.Stack().StackByName(archived.Name))
+	if err != nil {
+		return errors.Wrap(err, "unable to retrieve stacks from the database")
+	}
+	for _, stack := range restorer.batch.Stacks {
+		exists = exists || stack.Name == archived.Name
+	}
+	if exists {
+		restorer.skipped(stackEntity, int(ID), archived.Name, "a stack with the same name already exists")
+		return nil
+	}
+
+	endpointID, found, err := restorer.resolveEndpoint(archived.EndpointID)
+	if err != nil {
+		return err
+	}
+	if !found {
+		restorer.skipped(stackEntity, int(ID), archived.Name, "the endpoint of the stack does not exist, select it to restore it along with the stack")
+		return nil
+	}
+
+	stack := *archived
+	stack.ID = portainer.StackID(restorer.datastore.Stack().GetNextIdentifier())
+	stack.EndpointID = endpointID
+	stack.ResourceControl = nil
+	stack.ProjectPath = filepath.Join(restorer.filestorePath, filesystem.ComposeStorePath, strconv.Itoa(int(stack.ID)))
+
+	err = restorer.copyDir(filepath.Join(restorer.sourcePath, filesystem.ComposeStorePath, filepath.Base(archived.ProjectPath)), stack.ProjectPath)
+	if err != nil {
+		return errors.Wrapf(err, "unable to restore the project files of stack %s", archived.Name)
+	}
+
+	restorer.batch.Stacks = append(restorer.batch.Stacks, stack)
+
+	err = restorer.restoreResourceControl(
+		stackutils.ResourceControlID(archived.EndpointID, archived.Name),
+		stackutils.ResourceControlID(stack.EndpointID, stack.Name),
+		portainer.StackResourceControl,
+	)
+	if err != nil {
+		return errors.Wrapf(err, "unable to restore the resource control of stack %s", archived.Name)
+	}
+
+	restorer.stacks[ID] = stack.ID
+	restorer.restored(stackEntity, int(ID), int(stack.ID), stack.Name)
+	return nil
+}
+
+func (restorer *entityRestorer) restoreCustomTemplate(ID portainer.CustomTemplateID) error {
+	archived := restorer.data.customTemplate(ID)
+	if archived == nil {
+		restorer.skipped(customTemplateEntity, int(ID), "", "not found in the archive")
+		return nil
+	}
+
+	customTemplates, err := restorer.datastore.CustomTemplate().CustomTemplates()
+	if err != nil {
+		return errors.Wrap(err, "unable to retrieve custom templates from the database")
+	}
+
+	for _, existing := range append(customTemplates, restorer.batch.CustomTemplates...) {
+		if existing.Title == archived.Title {
+			restorer.skipped(customTemplateEntity, int(ID), archived.Title, "a custom template with the same title already exists")
+			return nil
+		}
+	}
+
+	customTemplate := *archived
+	customTemplate.ID = portainer.CustomTemplateID(restorer.datastore.CustomTemplate().GetNextIdentifier())
+	customTemplate.ResourceControl = nil
+	customTemplate.ProjectPath = filepath.Join(restorer.filestorePath, filesystem.CustomTemplateStorePath, strconv.Itoa(int(customTemplate.ID)))
+
+	userID, found, err := restorer.resolveUser(archived.CreatedByUserID)
+	if err != nil {
+		return err
+	}
+	customTemplate.CreatedByUserID = 0
+	if found {
+		customTemplate.CreatedByUserID = userID
+	}
+
+	err = restorer.copyDir(filepath.Join(restorer.sourcePath, filesystem.CustomTemplateStorePath, filepath.Base(archived.ProjectPath)), customTemplate.ProjectPath)
+	if err != nil {
+		return errors.Wrapf(err, "unable to restore the files of custom template %s", archived.Title)
+	}
+
+	restorer.batch.CustomTemplates = append(restorer.batch.CustomTemplates, customTemplate)
+
+	err = restorer.restoreResourceControl(
+		strconv.Itoa(int(archived.ID)),
+		strconv.Itoa(int(customTemplate.ID)),
+		portainer.CustomTemplateResourceControl,
+	)
+	if err != nil {
+		return errors.Wrapf(err, "unable to restore the resource control of custom template %s", archived.Title)
+	}
+
+	restorer.restored(customTemplateEntity, int(ID), int(customTemplate.ID), customTemplate.Title)
+	return nil
+}
+
+// restoreResourceControl restores the archived resource control of a resource under its new identifier,
+// keeping only the accesses of the users and teams that exist in the live datastore.
+func (restorer *entityRestorer) restoreResourceControl(archivedResourceID, resourceID string, resourceType portainer.ResourceControlType) error {
+	var archived *portainer.ResourceControl
+	for idx := range restorer.data.resourceControls {
+		rc := &restorer.data.resourceControls[idx]
+		if rc.ResourceID == archivedResourceID && rc.Type == resourceType {
+			archived = rc
+			break
+		}
+	}
+
+	if archived == nil {
+		return nil
+	}
+
+	existing, err := restorer.datastore.ResourceControl().ResourceControlByResourceIDAndType(resourceID, resourceType)
+	if err != nil {
+		return err
+	}
+	if existing != nil {
+		return nil
+	}
+
+	resourceControl := &portainer.ResourceControl{
+		ResourceID:         resourceID,
+		SubResourceIDs:     []string{},
+		Type:               resourceType,
+		UserAccesses:       make([]portainer.UserResourceAccess, 0),
+		TeamAccesses:       make([]portainer.TeamResourceAccess, 0),
+		Public:             archived.Public,
+		AdministratorsOnly: archived.AdministratorsOnly,
+		System:             archived.System,
+	}
+
+	for _, access := range archived.UserAccesses {
+		userID, found, err := restorer.resolveUser(access.UserID)
+		if err != nil {
+			return err
+		}
+		if found {
+			resourceControl.UserAccesses = append(resourceControl.UserAccesses, portainer.UserResourceAccess{UserID: userID, AccessLevel: access.AccessLevel})
+		}
+	}
+
+	for _, access := range archived.TeamAccesses {
+		teamID, found, err := restorer.resolveTeam(access.TeamID)
+		if err != nil {
+			return err
+		}
+		if found {
+			resourceControl.TeamAccesses = append(resourceControl.TeamAccesses, portainer.TeamResourceAccess{TeamID: teamID, AccessLevel: access.AccessLevel})
+		}
+	}
+
+	restorer.batch.ResourceControls = append(restorer.batch.ResourceControls, *resourceControl)
+	return nil
+}
+
+// resolveUser returns the identifier in the live datastore of an archived user, matched by username.
+func (restorer *entityRestorer) resolveUser(ID portainer.UserID) (portainer.UserID, bool, error) {
+	if restoredID, ok := restorer.users[ID]; ok {
+		return restoredID, true, nil
+	}
+
+	archived := restorer.data.user(ID)
+	if archived == nil {
+		return 0, false, nil
+	}
+
+	user, err := restorer.datastore.User().UserByUsername(archived.Username)
+	if isNotFound(err) {
+		return 0, false, nil
+	} else if err != nil {
+		return 0, false, errors.Wrap(err, "unable to retrieve users from the database")
+	}
+
+	return user.ID, true, nil
+}
+
+// resolveTeam returns the identifier in the live datastore of an archived team, matched by name.
+func (restorer *entityRestorer) resolveTeam(ID portainer.TeamID) (portainer.TeamID, bool, error) {
+	if restoredID, ok := restorer.teams[ID]; ok {
+		return restoredID, true, nil
+	}
+
+	archived := restorer.data.team(ID)
+	if archived == nil {
+		return 0, false, nil
+	}
+
+	team, err := restorer.datastore.Team().TeamByName(archived.Name)
+	if isNotFound(err) {
+		return 0, false, nil
+	} else if err != nil {
+		return 0, false, errors.Wrap(err, "unable to retrieve teams from the database")
+	}
+
+	return team.ID, true, nil
+}
+
+// resolveEndpoint returns the identifier in the live datastore of an archived endpoint.
+// Endpoint identifiers are never reused, an existing endpoint with the same identifier is the archived one.
+func (restorer *entityRestorer) resolveEndpoint(ID portainer.EndpointID) (portainer.EndpointID, bool, error) {
+	if restoredID, ok := restorer.endpoints[ID]; ok {
+		return restoredID, true, nil
+	}
+
+	_, err := restorer.datastore.Endpoint().Endpoint(ID)
+	if isNotFound(err) {
+		return 0, false, nil
+	} else if err != nil {
+		return 0, false, errors.Wrap(err, "unable to retrieve endpoints from the database")
+	}
+
+	return ID, true, nil
+}
+
+func (data *archivedData) stack(ID portainer.StackID) *portainer.Stack {
+	for idx := range data.Stacks {
+		if data.Stacks[idx].ID == ID {
+			return &data.Stacks[idx]
+		}
+	}
+	return nil
+}
+
+func (data *archivedData) customTemplate(ID portainer.CustomTemplateID) *portainer.CustomTemplate {
+	for idx := range data.CustomTemplates {
+		if data.CustomTemplates[idx].ID == ID {
+			return &data.CustomTemplates[idx]
+		}
+	}
+	return nil
+}
+
+func (data *archivedData) endpoint(ID portainer.EndpointID) *portainer.Endpoint {
+	for idx := range data.Endpoints {
+		if data.Endpoints[idx].ID == ID {
+			return &data.Endpoints[idx]
+		}
+	}
+	return nil
+}
+
+func (data *archivedData) user(ID portainer.UserID) *portainer.User {
+	for idx := range data.Users {
+		if data.Users[idx].ID == ID {
+			return &data.Users[idx]
+		}
+	}
+	return nil
+}
+
+func (data *archivedData) team(ID portainer.TeamID) *portainer.Team {
+	for idx := range data.Teams {
+		if data.Teams[idx].ID == ID {
+			return &data.Teams[idx]
+		}
+	}
+	return nil
+}
+
+func hasMembership(memberships []portainer.TeamMembership, teamID portainer.TeamID) bool {
+	for _, membership := range memberships {
+		if membership.TeamID == teamID {
+			return true
+		}
+	}
+	return false
+}
+
+func isNotFound(err error) bool {
+	return errors.Is(err, bolterrors.ErrObjectNotFound)
+}
+
+// isFound returns whether the lookup of an object by a unique field succeeded
+func isFound(object interface{}, err error) (bool, error) {
+	if isNotFound(err) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// copyDirContent copies the content of a directory of the archive into the file store.
+// A missing source directory is ignored.
+func copyDirContent(fromDir, toDir string) error {
+	if _, err := os.Stat(fromDir); errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+
+	return filepath.Walk(fromDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+
+		relativePath, err := filepath.Rel(fromDir, path)
+		if err != nil {
+			return err
+		}
+
+		return copyFile(path, filepath.Join(toDir, relativePath))
+	})
+}
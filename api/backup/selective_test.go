@@ -0,0 +1,330 @@
+package backup
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/docker/docker/pkg/ioutils"
+	"github.com/pkg/errors"
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/archive"
+	"github.com/portainer/portainer/api/bolt"
+	"github.com/portainer/portainer/api/bolt/bolttest"
+	"github.com/stretchr/testify/assert"
+)
+
+// createSelectiveTestArchive creates a backup archive with a user, a team, an endpoint, a stack and a custom template
+func createSelectiveTestArchive(t *testing.T, dbVersion int) string {
+	store, teardown := bolttest.MustNewTestStore(true)
+	defer teardown()
+
+	store.Version().StoreDBVersion(dbVersion)
+
+	team := &portainer.Team{Name: "devs"}
+	store.Team().CreateTeam(team)
+
+	user := &portainer.User{Username: "bob", Password: "hash", Role: portainer.StandardUserRole}
+	store.User().CreateUser(user)
+
+	store.TeamMembership().CreateTeamMembership(&portainer.TeamMembership{UserID: user.ID, TeamID: team.ID, Role: portainer.TeamMember})
+
+	store.Endpoint().CreateEndpoint(&portainer.Endpoint{
+		ID:      1,
+		Name:    "production",
+		URL:     "tcp://production:2376",
+		Type:    portainer.DockerEnvironment,
+		GroupID: 1,
+		TLSConfig: portainer.TLSConfiguration{
+			TLS:           true,
+			TLSCACertPath: "/data/tls/1/ca.pem",
+		},
+		UserAccessPolicies: portainer.UserAccessPolicies{user.ID: {RoleID: 1}},
+		TeamAccessPolicies: portainer.TeamAccessPolicies{team.ID: {RoleID: 1}},
+	})
+
+	store.Stack().CreateStack(&portainer.Stack{ID: 1, Name: "web", EndpointID: 1, EntryPoint: "docker-compose.yml", ProjectPath: "/data/compose/1"})
+	store.ResourceControl().CreateResourceControl(&portainer.ResourceControl{
+		ResourceID:   "1_web",
+		Type:         portainer.StackResourceControl,
+		UserAccesses: []portainer.UserResourceAccess{{UserID: user.ID, AccessLevel: portainer.ReadWriteAccessLevel}},
+	})
+
+	store.CustomTemplate().CreateCustomTemplate(&portainer.CustomTemplate{ID: 1, Title: "nginx", EntryPoint: "docker-compose.yml", ProjectPath: "/data/custom_templates/1", CreatedByUserID: user.ID})
+
+	dir, _ := ioutils.TempDir("", "backup")
+	defer os.RemoveAll(dir)
+
+	db, _ := os.Create(filepath.Join(dir, databaseFileName))
+	err := store.BackupTo(db)
+	db.Close()
+	assert.Nil(t, err, "Failed to copy database")
+
+	for _, path := range []string{"compose/1/docker-compose.yml", "custom_templates/1/docker-compose.yml", "tls/1/ca.pem"} {
+		os.MkdirAll(filepath.Join(dir, filepath.Dir(path)), 0700)
+		ioutil.WriteFile(filepath.Join(dir, path), []byte(path), 0600)
+	}
+
+	_, err = createManifest(dir)
+	assert.Nil(t, err, "Failed to create manifest")
+
+	archivePath, err := archive.TarGzDir(dir)
+	assert.Nil(t, err, "Failed to create archive")
+
+	// the archive is created inside the directory, move it out before the directory is removed
+	err = os.Rename(archivePath, dir+".tar.gz")
+	assert.Nil(t, err, "Failed to move archive")
+
+	return dir + ".tar.gz"
+}
+
+func restoreTestArchive(t *testing.T, archivePath string, store *bolt.Store, filestorePath string, selection RestoreSelection) (*SelectiveRestoreReport, error) {
+	file, err := os.Open(archivePath)
+	assert.Nil(t, err)
+	defer file.Close()
+
+	return RestoreEntities(file, "", filestorePath, store, selection)
+}
+
+func Test_InspectArchive_shouldListEntitiesWithoutPasswords(t *testing.T) {
+	archivePath := createSelectiveTestArchive(t, portainer.DBVersion)
+	defer os.Remove(archivePath)
+
+	filestorePath, _ := ioutils.TempDir("", "filestore")
+	defer os.RemoveAll(filestorePath)
+
+	file, _ := os.Open(archivePath)
+	defer file.Close()
+
+	content, err := InspectArchive(file, "", filestorePath)
+	assert.Nil(t, err)
+
+	assert.Len(t, content.Stacks, 1)
+	assert.Len(t, content.CustomTemplates, 1)
+	assert.Len(t, content.Endpoints, 1)
+	assert.Len(t, content.Teams, 1)
+	assert.Len(t, content.Users, 1)
+	assert.Equal(t, "bob", content.Users[0].Username)
+	assert.Empty(t, content.Users[0].Password)
+}
+
+func Test_RestoreEntities_shouldMergeSelectedEntitiesAndRemapReferences(t *testing.T) {
+	archivePath := createSelectiveTestArchive(t, portainer.DBVersion)
+	defer os.Remove(archivePath)
+
+	store, teardown := bolttest.MustNewTestStore(true)
+	defer teardown()
+
+	filestorePath, _ := ioutils.TempDir("", "filestore")
+	defer os.RemoveAll(filestorePath)
+
+	// shift the identifiers of the live datastore
+	store.User().CreateUser(&portainer.User{Username: "admin", Role: portainer.AdministratorRole})
+	store.Team().CreateTeam(&portainer.Team{Name: "ops"})
+	store.Endpoint().CreateEndpoint(&portainer.Endpoint{ID: portainer.EndpointID(store.Endpoint().GetNextIdentifier()), Name: "local", GroupID: 1})
+	store.Stack().GetNextIdentifier()
+	store.CustomTemplate().GetNextIdentifier()
+
+	report, err := restoreTestArchive(t, archivePath, store, filestorePath, RestoreSelection{
+		StackIDs:          []portainer.StackID{1},
+		CustomTemplateIDs: []portainer.CustomTemplateID{1},
+		EndpointIDs:       []portainer.EndpointID{1},
+		UserIDs:           []portainer.UserID{1},
+		TeamIDs:           []portainer.TeamID{1},
+	})
+	assert.Nil(t, err)
+	assert.Len(t, report.Restored, 5)
+	assert.Empty(t, report.Skipped)
+
+	user, err := store.User().UserByUsername("bob")
+	assert.Nil(t, err)
+	assert.Equal(t, "hash", user.Password)
+
+	team, err := store.Team().TeamByName("devs")
+	assert.Nil(t, err)
+
+	memberships, _ := store.TeamMembership().TeamMembershipsByUserID(user.ID)
+	assert.Len(t, memberships, 1)
+	assert.Equal(t, team.ID, memberships[0].TeamID)
+
+	endpoints, _ := store.Endpoint().Endpoints()
+	var endpoint portainer.Endpoint
+	for _, e := range endpoints {
+		if e.Name == "production" {
+			endpoint = e
+		}
+	}
+	assert.Equal(t, portainer.EndpointID(2), endpoint.ID)
+	assert.Equal(t, filepath.Join(filestorePath, "tls", "2", "ca.pem"), endpoint.TLSConfig.TLSCACertPath)
+	assert.FileExists(t, endpoint.TLSConfig.TLSCACertPath)
+	assert.Contains(t, endpoint.UserAccessPolicies, user.ID)
+	assert.Contains(t, endpoint.TeamAccessPolicies, team.ID)
+
+	_, err = store.EndpointRelation().EndpointRelation(endpoint.ID)
+	assert.Nil(t, err, "the endpoint relation should be created")
+
+	stack, err := store.Stack().StackByName("web")
+	assert.Nil(t, err)
+	assert.Equal(t, portainer.StackID(2), stack.ID)
+	assert.Equal(t, endpoint.ID, stack.EndpointID)
+	assert.FileExists(t, filepath.Join(stack.ProjectPath, "docker-compose.yml"))
+
+	resourceControl, err := store.ResourceControl().ResourceControlByResourceIDAndType("2_web", portainer.StackResourceControl)
+	assert.Nil(t, err)
+	assert.NotNil(t, resourceControl)
+	assert.Equal(t, user.ID, resourceControl.UserAccesses[0].UserID)
+
+	customTemplate, err := store.CustomTemplate().CustomTemplate(2)
+	assert.Nil(t, err)
+	assert.Equal(t, user.ID, customTemplate.CreatedByUserID)
+	assert.FileExists(t, filepath.Join(customTemplate.ProjectPath, "docker-compose.yml"))
+}
+
+func Test_RestoreEntities_shouldSkipConflictingEntities(t *testing.T) {
+	archivePath := createSelectiveTestArchive(t, portainer.DBVersion)
+	defer os.Remove(archivePath)
+
+	store, teardown := bolttest.MustNewTestStore(true)
+	defer teardown()
+
+	filestorePath, _ := ioutils.TempDir("", "filestore")
+	defer os.RemoveAll(filestorePath)
+
+	store.User().CreateUser(&portainer.User{Username: "bob"})
+	store.Stack().CreateStack(&portainer.Stack{ID: 1, Name: "web", EndpointID: 1})
+
+	report, err := restoreTestArchive(t, archivePath, store, filestorePath, RestoreSelection{
+		StackIDs: []portainer.StackID{1, 5},
+		UserIDs:  []portainer.UserID{1},
+	})
+	assert.Nil(t, err)
+	assert.Empty(t, report.Restored)
+	assert.Len(t, report.Skipped, 3)
+
+	users, _ := store.User().Users()
+	assert.Len(t, users, 1)
+}
+
+func Test_RestoreEntities_shouldRestoreTheEntitiesSelectedTwiceOnce(t *testing.T) {
+	archivePath := createSelectiveTestArchive(t, portainer.DBVersion)
+	defer os.Remove(archivePath)
+
+	store, teardown := bolttest.MustNewTestStore(true)
+	defer teardown()
+
+	filestorePath, _ := ioutils.TempDir("", "filestore")
+	defer os.RemoveAll(filestorePath)
+
+	report, err := restoreTestArchive(t, archivePath, store, filestorePath, RestoreSelection{
+		StackIDs:          []portainer.StackID{1, 1},
+		CustomTemplateIDs: []portainer.CustomTemplateID{1, 1},
+		EndpointIDs:       []portainer.EndpointID{1, 1},
+		UserIDs:           []portainer.UserID{1, 1},
+		TeamIDs:           []portainer.TeamID{1, 1},
+	})
+	assert.Nil(t, err)
+	assert.Len(t, report.Restored, 5)
+	assert.Len(t, report.Skipped, 5)
+
+	users, _ := store.User().Users()
+	assert.Len(t, users, 1)
+	teams, _ := store.Team().Teams()
+	assert.Len(t, teams, 1)
+	endpoints, _ := store.Endpoint().Endpoints()
+	assert.Len(t, endpoints, 1)
+	stacks, _ := store.Stack().Stacks()
+	assert.Len(t, stacks, 1)
+	customTemplates, _ := store.CustomTemplate().CustomTemplates()
+	assert.Len(t, customTemplates, 1)
+}
+
+func Test_RestoreEntities_shouldSkipCustomTemplatesWithTheSameTitle(t *testing.T) {
+	archivePath := createSelectiveTestArchive(t, portainer.DBVersion)
+	defer os.Remove(archivePath)
+
+	store, teardown := bolttest.MustNewTestStore(true)
+	defer teardown()
+
+	filestorePath, _ := ioutils.TempDir("", "filestore")
+	defer os.RemoveAll(filestorePath)
+
+	store.CustomTemplate().CreateCustomTemplate(&portainer.CustomTemplate{ID: portainer.CustomTemplateID(store.CustomTemplate().GetNextIdentifier()), Title: "nginx"})
+
+	report, err := restoreTestArchive(t, archivePath, store, filestorePath, RestoreSelection{CustomTemplateIDs: []portainer.CustomTemplateID{1}})
+	assert.Nil(t, err)
+	assert.Empty(t, report.Restored)
+	assert.Len(t, report.Skipped, 1)
+
+	customTemplates, _ := store.CustomTemplate().CustomTemplates()
+	assert.Len(t, customTemplates, 1)
+}
+
+func Test_RestoreEntities_shouldRestoreNothing_whenAnEntityCannotBeSaved(t *testing.T) {
+	archivePath := createSelectiveTestArchive(t, portainer.DBVersion)
+	defer os.Remove(archivePath)
+
+	store, teardown := bolttest.MustNewTestStore(true)
+	defer teardown()
+
+	filestorePath, _ := ioutils.TempDir("", "filestore")
+	defer os.RemoveAll(filestorePath)
+
+	// uses the identifier the restored custom template is given, without reserving it
+	store.CustomTemplate().CreateCustomTemplate(&portainer.CustomTemplate{ID: 1, Title: "other"})
+
+	report, err := restoreTestArchive(t, archivePath, store, filestorePath, RestoreSelection{
+		StackIDs:          []portainer.StackID{1},
+		CustomTemplateIDs: []portainer.CustomTemplateID{1},
+		EndpointIDs:       []portainer.EndpointID{1},
+		UserIDs:           []portainer.UserID{1},
+		TeamIDs:           []portainer.TeamID{1},
+	})
+	assert.NotNil(t, err)
+	assert.Nil(t, report)
+
+	users, _ := store.User().Users()
+	assert.Empty(t, users)
+	teams, _ := store.Team().Teams()
+	assert.Empty(t, teams)
+	stacks, _ := store.Stack().Stacks()
+	assert.Empty(t, stacks)
+	endpoints, _ := store.Endpoint().Endpoints()
+	assert.Empty(t, endpoints)
+
+	files, _ := ioutil.ReadDir(filepath.Join(filestorePath, "compose"))
+	assert.Empty(t, files, "the copied files should be removed")
+}
+
+func Test_RestoreEntities_shouldSkipStacksWithoutEndpoint(t *testing.T) {
+	archivePath := createSelectiveTestArchive(t, portainer.DBVersion)
+	defer os.Remove(archivePath)
+
+	store, teardown := bolttest.MustNewTestStore(true)
+	defer teardown()
+
+	filestorePath, _ := ioutils.TempDir("", "filestore")
+	defer os.RemoveAll(filestorePath)
+
+	report, err := restoreTestArchive(t, archivePath, store, filestorePath, RestoreSelection{StackIDs: []portainer.StackID{1}})
+	assert.Nil(t, err)
+	assert.Empty(t, report.Restored)
+	assert.Len(t, report.Skipped, 1)
+
+	stacks, _ := store.Stack().Stacks()
+	assert.Empty(t, stacks)
+}
+
+func Test_RestoreEntities_shouldRejectArchiveFromAnotherDatabaseVersion(t *testing.T) {
+	archivePath := createSelectiveTestArchive(t, portainer.DBVersion-1)
+	defer os.Remove(archivePath)
+
+	store, teardown := bolttest.MustNewTestStore(true)
+	defer teardown()
+
+	filestorePath, _ := ioutils.TempDir("", "filestore")
+	defer os.RemoveAll(filestorePath)
+
+	_, err := restoreTestArchive(t, archivePath, store, filestorePath, RestoreSelection{UserIDs: []portainer.UserID{1}})
+	assert.True(t, errors.Is(err, ErrInvalidArchive))
+}
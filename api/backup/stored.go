@@ -0,0 +1,215 @@
+package backup
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/http/offlinegate"
+)
+
+const storedBackupPrefix = "portainer-backup_"
+
+// ErrStoredBackupNotFound is returned when a stored backup does not exist
+var ErrStoredBackupNotFound = errors.New("stored backup not found")
+
+// StoredBackup represents a backup archive kept in the backup directory
+type StoredBackup struct {
+	// Name of the archive file
+	Name string `json:"Name" example:"portainer-backup_2021-06-01_02-00-00.tar.gz.encrypted"`
+	// Size of the archive in bytes
+	Size int64 `json:"Size" example:"8192"`
+	// Backup creation date, as a unix timestamp
+	CreationDate int64 `json:"CreationDate" example:"1622512800"`
+	// Whether the archive is encrypted with a password
+	Encrypted bool `json:"Encrypted" example:"true"`
+}
+
+// StoredBackupsDir returns the absolute path of the directory where backups are stored.
+func StoredBackupsDir(filestorePath string, settings portainer.BackupSettings) string {
+	directory := settings.Directory
+	if directory == "" {
+		directory = portainer.DefaultBackupDirectory
+	}
+
+	return filepath.Join(filestorePath, directory)
+}
+
+// ValidateBackupDirectory ensures that the backup directory stays inside the data folder
+// and does not overlap with the folders used to build or restore archives.
+func ValidateBackupDirectory(directory string) error {
+	if directory == "" {
+		return nil
+	}
+
+	cleaned := filepath.Clean(directory)
+	if filepath.IsAbs(cleaned) || cleaned == "." || cleaned == ".." || strings.HasPrefix(cleaned, ".."+string(filepath.Separator)) {
+		return errors.New("backup directory must be a relative path inside the data folder")
+	}
+
+	topLevel := strings.Split(filepath.ToSlash(cleaned), "/")[0]
+	for _, reserved := range reservedDataPaths() {
+		if topLevel == reserved {
+			return errors.Errorf("backup directory cannot be located inside %s", reserved)
+		}
+	}
+
+	return nil
+}
+
+// StoreBackupArchive creates a backup archive and moves it inside the storage directory.
+func StoreBackupArchive(password string, gate *offlinegate.OfflineGate, datastore portainer.DataStore, filestorePath string, storageDir string) (*StoredBackup, error) {
+	archivePath, err := CreateBackupArchive(password, gate, datastore, filestorePath)
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(filepath.Dir(archivePath))
+
+	if err := os.MkdirAll(storageDir, 0700); err != nil {
+		return nil, errors.Wrap(err, "Failed to create backup storage dir")
+	}
+
+	name := storedBackupPrefix + filepath.Base(archivePath)
+	if err := os.Rename(archivePath, filepath.Join(storageDir, name)); err != nil {
+		return nil, errors.Wrap(err, "Failed to store backup archive")
+	}
+
+	return storedBackup(storageDir, name)
+}
+
+// ListStoredBackups returns the backups present in the storage directory, most recent first.
+func ListStoredBackups(storageDir string) ([]StoredBackup, error) {
+	entries, err := ioutil.ReadDir(storageDir)
+	if errors.Is(err, os.ErrNotExist) {
+		return []StoredBackup{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	backups := make([]StoredBackup, 0)
+	for _, entry := range entries {
+		if entry.IsDir() || !isStoredBackupName(entry.Name()) {
+			continue
+		}
+
+		backups = append(backups, StoredBackup{
+			Name:         entry.Name(),
+			Size:         entry.Size(),
+			CreationDate: entry.ModTime().Unix(),
+			Encrypted:    IsEncryptedBackup(entry.Name()),
+		})
+	}
+
+	sortBackups(backups)
+
+	return backups, nil
+}
+
+// StoredBackupPath returns the path of a stored backup, making sure that the name
+// references an existing backup inside the storage directory.
+func StoredBackupPath(storageDir string, name string) (string, error) {
+	if !isStoredBackupName(name) || filepath.Base(name) != name {
+		return "", ErrStoredBackupNotFound
+	}
+
+	path := filepath.Join(storageDir, name)
+	info, err := os.Stat(path)
+	if errors.Is(err, os.ErrNotExist) || (err == nil && info.IsDir()) {
+		return "", ErrStoredBackupNotFound
+	}
+	if err != nil {
+		return "", err
+	}
+
+	return path, nil
+}
+
+// DeleteStoredBackup removes a backup from the storage directory.
+func DeleteStoredBackup(storageDir string, name string) error {
+	path, err := StoredBackupPath(storageDir, name)
+	if err != nil {
+		return err
+	}
+
+	return os.Remove(path)
+}
+
+// PruneStoredBackups removes the backups that are not among the keepLast most recent ones
+// or that are older than maxAge. A zero value disables the corresponding rule.
+// Returns the names of the removed backups.
+func PruneStoredBackups(storageDir string, keepLast int, maxAge time.Duration, now time.Time) ([]string, error) {
+	backups, err := ListStoredBackups(storageDir)
+	if err != nil {
+		return nil, err
+	}
+
+	removed := make([]string, 0)
+	for _, backup := range outdatedBackups(backups, keepLast, maxAge, now) {
+		if err := os.Remove(filepath.Join(storageDir, backup.Name)); err != nil {
+			return removed, err
+		}
+		removed = append(removed, backup.Name)
+	}
+
+	return removed, nil
+}
+
+// outdatedBackups returns the backups that fall outside of the retention policy.
+// backups must be sorted, most recent first.
+func outdatedBackups(backups []StoredBackup, keepLast int, maxAge time.Duration, now time.Time) []StoredBackup {
+	outdated := make([]StoredBackup, 0)
+	for index, backup := range backups {
+		tooMany := keepLast > 0 && index >= keepLast
+		tooOld := maxAge > 0 && now.Sub(time.Unix(backup.CreationDate, 0)) > maxAge
+		if tooMany || tooOld {
+			outdated = append(outdated, backup)
+		}
+	}
+
+	return outdated
+}
+
+// sortBackups sorts the backups, most recent first
+func sortBackups(backups []StoredBackup) {
+	sort.SliceStable(backups, func(i, j int) bool {
+		if backups[i].CreationDate == backups[j].CreationDate {
+			return backups[i].Name > backups[j].Name
+		}
+		return backups[i].CreationDate > backups[j].CreationDate
+	})
+}
+
+func storedBackup(storageDir string, name string) (*StoredBackup, error) {
+	info, err := os.Stat(filepath.Join(storageDir, name))
+	if err != nil {
+		return nil, err
+	}
+
+	return &StoredBackup{
+		Name:         name,
+		Size:         info.Size(),
+		CreationDate: info.ModTime().Unix(),
+		Encrypted:    IsEncryptedBackup(name),
+	}, nil
+}
+
+// reservedDataPaths returns the entries of the data folder that cannot host stored backups
+func reservedDataPaths() []string {
+	reserved := []string{"backup", "restore", "bin"}
+	return append(reserved, filesToRestore...)
+}
+
+func isStoredBackupName(name string) bool {
+	return strings.HasPrefix(name, storedBackupPrefix) && (strings.HasSuffix(name, ".tar.gz") || strings.HasSuffix(name, ".tar.gz.encrypted"))
+}
+
+// IsEncryptedBackup returns true when the name of a stored backup denotes an encrypted archive.
+func IsEncryptedBackup(name string) bool {
+	return strings.HasSuffix(name, ".encrypted")
+}
@@ -0,0 +1,141 @@
+package backup
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/pkg/ioutils"
+	portainer "github.com/portainer/portainer/api"
+	"github.com/stretchr/testify/assert"
+)
+
+func createStoredBackup(t *testing.T, dir string, name string, modTime time.Time) {
+	path := filepath.Join(dir, name)
+	err := ioutil.WriteFile(path, []byte("content"), 0600)
+	assert.Nil(t, err, "Failed to create backup file")
+
+	err = os.Chtimes(path, modTime, modTime)
+	assert.Nil(t, err, "Failed to set backup modification time")
+}
+
+func backupNames(backups []StoredBackup) []string {
+	names := make([]string, 0)
+	for _, backup := range backups {
+		names = append(names, backup.Name)
+	}
+	return names
+}
+
+func Test_listStoredBackups_shouldReturnEmptyList_whenDirDoesNotExist(t *testing.T) {
+	backups, err := ListStoredBackups("does-not-exist")
+	assert.Nil(t, err)
+	assert.Empty(t, backups)
+}
+
+func Test_listStoredBackups_shouldReturnMostRecentFirstAndSkipOtherFiles(t *testing.T) {
+	tmpdir, _ := ioutils.TempDir("", "backups")
+	defer os.RemoveAll(tmpdir)
+
+	now := time.Now()
+	createStoredBackup(t, tmpdir, "portainer-backup_1.tar.gz", now.Add(-2*time.Hour))
+	createStoredBackup(t, tmpdir, "portainer-backup_2.tar.gz.encrypted", now.Add(-time.Hour))
+	createStoredBackup(t, tmpdir, "other-file.tar.gz", now)
+
+	backups, err := ListStoredBackups(tmpdir)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"portainer-backup_2.tar.gz.encrypted", "portainer-backup_1.tar.gz"}, backupNames(backups))
+	assert.True(t, backups[0].Encrypted)
+	assert.False(t, backups[1].Encrypted)
+}
+
+func Test_pruneStoredBackups(t *testing.T) {
+	now := time.Now()
+
+	tests := []struct {
+		name     string
+		keepLast int
+		maxAge   time.Duration
+		expected []string
+	}{
+		{
+			name:     "keeps everything without retention policy",
+			expected: []string{"portainer-backup_3.tar.gz", "portainer-backup_2.tar.gz", "portainer-backup_1.tar.gz"},
+		},
+		{
+			name:     "keeps the most recent backups",
+			keepLast: 2,
+			expected: []string{"portainer-backup_3.tar.gz", "portainer-backup_2.tar.gz"},
+		},
+		{
+			name:     "removes backups older than max age",
+			maxAge:   36 * time.Hour,
+			expected: []string{"portainer-backup_3.tar.gz", "portainer-backup_2.tar.gz"},
+		},
+		{
+			name:     "combines both rules",
+			keepLast: 2,
+			maxAge:   12 * time.Hour,
+			expected: []string{"portainer-backup_3.tar.gz"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			tmpdir, _ := ioutils.TempDir("", "backups")
+			defer os.RemoveAll(tmpdir)
+
+			createStoredBackup(t, tmpdir, "portainer-backup_1.tar.gz", now.Add(-48*time.Hour))
+			createStoredBackup(t, tmpdir, "portainer-backup_2.tar.gz", now.Add(-24*time.Hour))
+			createStoredBackup(t, tmpdir, "portainer-backup_3.tar.gz", now.Add(-time.Hour))
+
+			_, err := PruneStoredBackups(tmpdir, test.keepLast, test.maxAge, now)
+			assert.Nil(t, err)
+
+			backups, _ := ListStoredBackups(tmpdir)
+			assert.Equal(t, test.expected, backupNames(backups))
+		})
+	}
+}
+
+func Test_storedBackupPath_shouldRejectNamesOutsideOfStorageDir(t *testing.T) {
+	tmpdir, _ := ioutils.TempDir("", "backups")
+	defer os.RemoveAll(tmpdir)
+
+	createStoredBackup(t, tmpdir, "portainer-backup_1.tar.gz", time.Now())
+
+	path, err := StoredBackupPath(tmpdir, "portainer-backup_1.tar.gz")
+	assert.Nil(t, err)
+	assert.Equal(t, filepath.Join(tmpdir, "portainer-backup_1.tar.gz"), path)
+
+	for _, name := range []string{"../portainer-backup_1.tar.gz", "portainer.db", "portainer-backup_2.tar.gz"} {
+		_, err := StoredBackupPath(tmpdir, name)
+		assert.Equal(t, ErrStoredBackupNotFound, err, name)
+	}
+}
+
+func Test_validateBackupSettings(t *testing.T) {
+	tests := []struct {
+		name     string
+		settings portainer.BackupSettings
+		valid    bool
+	}{
+		{name: "disabled schedule", settings: portainer.BackupSettings{}, valid: true},
+		{name: "valid schedule", settings: portainer.BackupSettings{Enabled: true, CronExpression: "0 2 * * *", Directory: "backups/daily", KeepLast: 7, MaxAge: "720h"}, valid: true},
+		{name: "invalid cron expression", settings: portainer.BackupSettings{Enabled: true, CronExpression: "every day"}},
+		{name: "negative keep last", settings: portainer.BackupSettings{KeepLast: -1}},
+		{name: "invalid max age", settings: portainer.BackupSettings{MaxAge: "a month"}},
+		{name: "absolute directory", settings: portainer.BackupSettings{Directory: "/tmp/backups"}},
+		{name: "directory outside of the data folder", settings: portainer.BackupSettings{Directory: "../backups"}},
+		{name: "reserved directory", settings: portainer.BackupSettings{Directory: "compose/backups"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := ValidateBackupSettings(test.settings)
+			assert.Equal(t, test.valid, err == nil, err)
+		})
+	}
+}
@@ -0,0 +1,155 @@
+package backup
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	portainer "github.com/portainer/portainer/api"
+)
+
+// ErrInvalidArchive is returned when a backup archive fails validation
+var ErrInvalidArchive = errors.New("invalid backup archive")
+
+// ValidationReport describes the content of a backup archive and whether it can be restored
+type ValidationReport struct {
+	// Whether the archive can be restored
+	Valid bool `json:"Valid" example:"true"`
+	// Manifest of the archive, missing for archives created by older versions of Portainer
+	Manifest *Manifest `json:"Manifest"`
+	// Files contained in the archive
+	Files []string `json:"Files"`
+	// Number of objects stored in each bucket of the archived database
+	Buckets map[string]int `json:"Buckets"`
+	// Problems preventing the archive from being restored
+	Errors []string `json:"Errors"`
+	// Problems that do not prevent the archive from being restored
+	Warnings []string `json:"Warnings"`
+}
+
+func newValidationReport() *ValidationReport {
+	return &ValidationReport{
+		Files:    make([]string, 0),
+		Buckets:  make(map[string]int),
+		Errors:   make([]string, 0),
+		Warnings: make([]string, 0),
+	}
+}
+
+func (report *ValidationReport) addError(format string, args ...interface{}) {
+	report.Errors = append(report.Errors, fmt.Sprintf(format, args...))
+}
+
+func (report *ValidationReport) addWarning(format string, args ...interface{}) {
+	report.Warnings = append(report.Warnings, fmt.Sprintf(format, args...))
+}
+
+func (report *ValidationReport) err() error {
+	if report.Valid {
+		return nil
+	}
+
+	return errors.Wrap(ErrInvalidArchive, strings.Join(report.Errors, "; "))
+}
+
+// ValidateArchive decrypts and extracts the archive in a temporary directory to inspect its content.
+// It does not interact with the datastore and can be used as a dry-run of a restore.
+func ValidateArchive(archive io.Reader, password string, filestorePath string) *ValidationReport {
+	report := newValidationReport()
+
+	var err error
+	if password != "" {
+		archive, err = decrypt(archive, password)
+		if err != nil {
+			report.addError("unable to decrypt the archive: %s", err)
+			return report
+		}
+	}
+
+	validatePath := filepath.Join(filestorePath, "restore", "validate-"+time.Now().Format("20060102150405.000000000"))
+	defer os.RemoveAll(validatePath)
+
+	err = extractArchive(archive, validatePath)
+	if err != nil {
+		report.addError("unable to extract files from the archive, please ensure the password is correct: %s", err)
+		return report
+	}
+
+	return validateExtractedArchive(validatePath)
+}
+
+// validateExtractedArchive verifies the checksums of the extracted files against the manifest
+// and makes sure that the archived database can be used by this version of Portainer.
+func validateExtractedArchive(dirPath string) *ValidationReport {
+	report := newValidationReport()
+	defer func() {
+		report.Valid = len(report.Errors) == 0
+	}()
+
+	checksums, err := checksumFiles(dirPath)
+	if err != nil {
+		report.addError("unable to read the archive content: %s", err)
+		return report
+	}
+	report.Files = sortedKeys(checksums)
+
+	if _, ok := checksums[databaseFileName]; !ok {
+		report.addError("the archive does not contain a database")
+		return report
+	}
+
+	info, err := inspectDatabase(filepath.Join(dirPath, databaseFileName))
+	if err != nil {
+		report.addError("unable to open the archived database: %s", err)
+		return report
+	}
+	report.Buckets = info.buckets
+
+	if len(info.buckets) == 0 {
+		report.addWarning("the archived database is empty")
+	}
+
+	if info.dbVersion > portainer.DBVersion {
+		report.addError("the archived database version (%d) is newer than the version supported by this instance (%d)", info.dbVersion, portainer.DBVersion)
+	}
+
+	if info.edition != 0 && info.edition != portainer.PortainerCE {
+		report.addError("the archived database was created by another edition of Portainer")
+	}
+
+	manifest, err := readManifest(dirPath)
+	if errors.Is(err, os.ErrNotExist) {
+		report.addWarning("the archive has no manifest, its integrity cannot be verified")
+		return report
+	}
+	if err != nil {
+		report.addError("unable to read the archive manifest: %s", err)
+		return report
+	}
+	report.Manifest = manifest
+
+	if manifest.DBVersion != info.dbVersion {
+		report.addError("the manifest database version (%d) does not match the archived database version (%d)", manifest.DBVersion, info.dbVersion)
+	}
+
+	for _, path := range sortedKeys(manifest.Files) {
+		checksum, ok := checksums[path]
+		if !ok {
+			report.addError("%s is missing from the archive", path)
+		} else if checksum != manifest.Files[path] {
+			report.addError("checksum mismatch for %s", path)
+		}
+	}
+
+	for _, path := range report.Files {
+		if _, ok := manifest.Files[path]; !ok {
+			report.addError("%s is not listed in the manifest", path)
+		}
+	}
+
+	return report
+}
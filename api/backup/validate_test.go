@@ -0,0 +1,123 @@
+package backup
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/boltdb/bolt"
+	"github.com/docker/docker/pkg/ioutils"
+	"github.com/pkg/errors"
+	portainer "github.com/portainer/portainer/api"
+	"github.com/stretchr/testify/assert"
+)
+
+func createTestDatabase(t *testing.T, path string, dbVersion int) {
+	db, err := bolt.Open(path, 0600, nil)
+	assert.Nil(t, err, "Failed to create database")
+	defer db.Close()
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		bucket, err := tx.CreateBucket([]byte("version"))
+		if err != nil {
+			return err
+		}
+		bucket.Put([]byte(versionKey), []byte(strconv.Itoa(dbVersion)))
+		bucket.Put([]byte(instanceKey), []byte("instance-id"))
+
+		stacks, err := tx.CreateBucket([]byte("stacks"))
+		if err != nil {
+			return err
+		}
+		stacks.Put([]byte("1"), []byte("{}"))
+		return stacks.Put([]byte("2"), []byte("{}"))
+	})
+	assert.Nil(t, err, "Failed to populate database")
+}
+
+func createTestBackupDir(t *testing.T, dbVersion int) string {
+	dir, _ := ioutils.TempDir("", "backup")
+
+	createTestDatabase(t, filepath.Join(dir, databaseFileName), dbVersion)
+	os.MkdirAll(filepath.Join(dir, "tls"), 0700)
+	ioutil.WriteFile(filepath.Join(dir, "tls", "cert.pem"), []byte("content"), 0600)
+
+	return dir
+}
+
+func Test_createManifest_shouldDescribeTheBackup(t *testing.T) {
+	dir := createTestBackupDir(t, portainer.DBVersion)
+	defer os.RemoveAll(dir)
+
+	manifest, err := createManifest(dir)
+	assert.Nil(t, err)
+
+	assert.Equal(t, portainer.APIVersion, manifest.PortainerVersion)
+	assert.Equal(t, portainer.DBVersion, manifest.DBVersion)
+	assert.Equal(t, "instance-id", manifest.InstanceID)
+	assert.Equal(t, 2, manifest.Buckets["stacks"])
+	assert.Contains(t, manifest.Files, databaseFileName)
+	assert.Contains(t, manifest.Files, "tls/cert.pem")
+
+	stored, err := readManifest(dir)
+	assert.Nil(t, err)
+	assert.Equal(t, manifest, stored)
+}
+
+func Test_validateExtractedArchive_shouldAcceptUntouchedBackup(t *testing.T) {
+	dir := createTestBackupDir(t, portainer.DBVersion)
+	defer os.RemoveAll(dir)
+
+	createManifest(dir)
+
+	report := validateExtractedArchive(dir)
+	assert.True(t, report.Valid, report.Errors)
+	assert.Empty(t, report.Warnings)
+	assert.Equal(t, []string{"portainer.db", "tls/cert.pem"}, report.Files)
+}
+
+func Test_validateExtractedArchive_shouldDetectChecksumMismatch(t *testing.T) {
+	dir := createTestBackupDir(t, portainer.DBVersion)
+	defer os.RemoveAll(dir)
+
+	createManifest(dir)
+	ioutil.WriteFile(filepath.Join(dir, "tls", "cert.pem"), []byte("tampered"), 0600)
+	ioutil.WriteFile(filepath.Join(dir, "tls", "extra.pem"), []byte("content"), 0600)
+
+	report := validateExtractedArchive(dir)
+	assert.False(t, report.Valid)
+	assert.Contains(t, report.Errors, "checksum mismatch for tls/cert.pem")
+	assert.Contains(t, report.Errors, "tls/extra.pem is not listed in the manifest")
+	assert.True(t, errors.Is(report.err(), ErrInvalidArchive))
+}
+
+func Test_validateExtractedArchive_shouldRejectNewerDatabase(t *testing.T) {
+	dir := createTestBackupDir(t, portainer.DBVersion+1)
+	defer os.RemoveAll(dir)
+
+	createManifest(dir)
+
+	report := validateExtractedArchive(dir)
+	assert.False(t, report.Valid)
+	assert.Len(t, report.Errors, 1)
+}
+
+func Test_validateExtractedArchive_shouldWarnAboutMissingManifest(t *testing.T) {
+	dir := createTestBackupDir(t, portainer.DBVersion)
+	defer os.RemoveAll(dir)
+
+	report := validateExtractedArchive(dir)
+	assert.True(t, report.Valid, report.Errors)
+	assert.Nil(t, report.Manifest)
+	assert.Len(t, report.Warnings, 1)
+}
+
+func Test_validateExtractedArchive_shouldRejectArchiveWithoutDatabase(t *testing.T) {
+	dir, _ := ioutils.TempDir("", "backup")
+	defer os.RemoveAll(dir)
+
+	report := validateExtractedArchive(dir)
+	assert.False(t, report.Valid)
+}
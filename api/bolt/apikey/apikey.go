@@ -0,0 +1,176 @@
+package apikey
+
+import (
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/bolt/errors"
+	"github.com/portainer/portainer/api/bolt/internal"
+
+	"github.com/boltdb/bolt"
+)
+
+const (
+	// BucketName represents the name of the bucket where this service stores data.
+	BucketName = "api_keys"
+)
+
+// digestIndex indexes the API keys by their digest
+var digestIndex = internal.Index{
+	BucketName: "api_keys_by_digest",
+	Keys: func(data []byte) ([]string, error) {
+		var key portainer.APIKey
+		err := internal.UnmarshalObject(data, &key)
+		if err != nil {
+			return nil, err
+		}
+		return []string{key.Digest}, nil
+	},
+}
+
+// Service represents a service for managing API key data.
+type Service struct {
+	connection *internal.DbConnection
+}
+
+// NewService creates a new instance of a service.
+func NewService(connection *internal.DbConnection) (*Service, error) {
+	err := internal.CreateBucket(connection, BucketName)
+	if err != nil {
+		return nil, err
+	}
+
+	err = internal.CreateIndexBuckets(connection, digestIndex)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Service{
+		connection: connection,
+	}, nil
+}
+
+// APIKey returns an API key by ID.
+func (service *Service) APIKey(ID portainer.APIKeyID) (*portainer.APIKey, error) {
+	var key portainer.APIKey
+	identifier := internal.Itob(int(ID))
+
+	err := internal.GetObject(service.connection, BucketName, identifier, &key)
+	if err != nil {
+		return nil, err
+	}
+
+	return &key, nil
+}
+
+// APIKeyByDigest returns the API key matching the specified digest.
+func (service *Service) APIKeyByDigest(digest string) (*portainer.APIKey, error) {
+	var key portainer.APIKey
+
+	err := service.connection.View(func(tx *bolt.Tx) error {
+		keys := digestIndex.Lookup(tx, digest)
+		if len(keys) == 0 {
+			return errors.ErrObjectNotFound
+		}
+
+		return internal.UnmarshalObject(tx.Bucket([]byte(BucketName)).Get(keys[0]), &key)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &key, nil
+}
+
+// APIKeysByUserID returns an array containing all the API keys of a user.
+func (service *Service) APIKeysByUserID(userID portainer.UserID) ([]portainer.APIKey, error) {
+	var keys = make([]portainer.APIKey, 0)
+
+	err := service.connection.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(BucketName))
+
+		cursor := bucket.Cursor()
+		for k, v := cursor.First(); k != nil; k, v = cursor.Next() {
+			var key portainer.APIKey
+			err := internal.UnmarshalObject(v, &key)
+			if err != nil {
+				return err
+			}
+
+			if key.UserID == userID {
+				keys = append(keys, key)
+			}
+		}
+
+		return nil
+	})
+
+	return keys, err
+}
+
+// CreateAPIKey creates a new API key.
+func (service *Service) CreateAPIKey(key *portainer.APIKey) error {
+	return service.connection.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(BucketName))
+
+		id, _ := bucket.NextSequence()
+		key.ID = portainer.APIKeyID(id)
+
+		data, err := internal.MarshalObject(key)
+		if err != nil {
+			return err
+		}
+
+		return internal.PutIndexedObject(tx, BucketName, internal.Itob(int(key.ID)), data, digestIndex)
+	})
+}
+
+// UpdateAPIKey saves an API key.
+func (service *Service) UpdateAPIKey(ID portainer.APIKeyID, key *portainer.APIKey) error {
+	identifier := internal.Itob(int(ID))
+	return internal.UpdateIndexedObject(service.connection, BucketName, identifier, key, digestIndex)
+}
+
+// DeleteAPIKey deletes an API key.
+func (service *Service) DeleteAPIKey(ID portainer.APIKeyID) error {
+	identifier := internal.Itob(int(ID))
+	return internal.DeleteIndexedObject(service.connection, BucketName, identifier, digestIndex)
+}
+
+// DeleteAPIKeysByUserID deletes all the API keys of a user.
+func (service *Service) DeleteAPIKeysByUserID(userID portainer.UserID) error {
+	return service.connection.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(BucketName))
+
+		var keys [][]byte
+		cursor := bucket.Cursor()
+		for k, v := cursor.First(); k != nil; k, v = cursor.Next() {
+			var key portainer.APIKey
+			err := internal.UnmarshalObject(v, &key)
+			if err != nil {
+				return err
+			}
+
+			if key.UserID == userID {
+				keys = append(keys, k)
+			}
+		}
+
+		for _, k := range keys {
+			err := internal.RemoveIndexedObject(tx, BucketName, k, digestIndex)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// Indexes returns the secondary indexes of the API keys, to maintain them when the bucket is written directly.
+func Indexes() []internal.Index {
+	return []internal.Index{digestIndex}
+}
+
+// RebuildIndexes computes the digest index again from the API keys.
+func (service *Service) RebuildIndexes() error {
+	return internal.RebuildIndexes(service.connection, BucketName, digestIndex)
+}
@@ -0,0 +1,132 @@
+package auditlog
+
+import (
+	"encoding/binary"
+
+	"github.com/boltdb/bolt"
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/bolt/internal"
+)
+
+const (
+	// BucketName represents the name of the bucket where this service stores data.
+	BucketName = "audit_logs"
+)
+
+// Service represents a service for managing the audit logs.
+// The audit logs are append-only, they cannot be updated nor removed through the service, the oldest
+// ones are only removed once the retention limit is reached.
+type Service struct {
+	connection *internal.DbConnection
+}
+
+// NewService creates a new instance of a service.
+func NewService(connection *internal.DbConnection) (*Service, error) {
+	err := internal.CreateBucket(connection, BucketName)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Service{
+		connection: connection,
+	}, nil
+}
+
+// AuditLogs returns every audit log, oldest first.
+func (service *Service) AuditLogs() ([]portainer.AuditLog, error) {
+	var auditLogs = make([]portainer.AuditLog, 0)
+
+	err := service.connection.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(BucketName))
+
+		cursor := bucket.Cursor()
+		for k, v := cursor.First(); k != nil; k, v = cursor.Next() {
+			var auditLog portainer.AuditLog
+			err := internal.UnmarshalObject(v, &auditLog)
+			if err != nil {
+				return err
+			}
+			auditLogs = append(auditLogs, auditLog)
+		}
+
+		return nil
+	})
+
+	return auditLogs, err
+}
+
+// FilteredAuditLogs returns a page of the audit logs matching a filter, most recent first, and the number of
+// audit logs matching the filter. The page starts at the start index and holds at most limit audit logs,
+// every matching audit log is returned when limit is 0.
+func (service *Service) FilteredAuditLogs(match func(portainer.AuditLog) bool, start, limit int) ([]portainer.AuditLog, int, error) {
+	var auditLogs = make([]portainer.AuditLog, 0)
+	count := 0
+
+	err := service.connection.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(BucketName))
+
+		cursor := bucket.Cursor()
+		for k, v := cursor.Last(); k != nil; k, v = cursor.Prev() {
+			var auditLog portainer.AuditLog
+			err := internal.UnmarshalObject(v, &auditLog)
+			if err != nil {
+				return err
+			}
+
+			if !match(auditLog) {
+				continue
+			}
+
+			if count >= start && (limit == 0 || len(auditLogs) < limit) {
+				auditLogs = append(auditLogs, auditLog)
+			}
+			count++
+		}
+
+		return nil
+	})
+
+	return auditLogs, count, err
+}
+
+// CreateAuditLogs appends audit logs in a single transaction and sets their identifiers. The oldest audit logs
+// are removed so that at most maxAuditLogs are kept, none is removed when maxAuditLogs is 0.
+func (service *Service) CreateAuditLogs(auditLogs []portainer.AuditLog, maxAuditLogs int) error {
+	return service.connection.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(BucketName))
+
+		for idx := range auditLogs {
+			auditLog := &auditLogs[idx]
+
+			id, _ := bucket.NextSequence()
+			auditLog.ID = portainer.AuditLogID(id)
+
+			data, err := internal.MarshalObject(auditLog)
+			if err != nil {
+				return err
+			}
+
+			err = bucket.Put(internal.Itob(int(auditLog.ID)), data)
+			if err != nil {
+				return err
+			}
+		}
+
+		if maxAuditLogs == 0 {
+			return nil
+		}
+
+		// the identifiers are sequential, the audit logs older than the retention limit are the first ones of the bucket
+		oldestKeptID := int(bucket.Sequence()) - maxAuditLogs + 1
+
+		cursor := bucket.Cursor()
+		for k, _ := cursor.First(); k != nil && int(binary.BigEndian.Uint64(k)) < oldestKeptID; k, _ = cursor.First() {
+			err := bucket.Delete(k)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
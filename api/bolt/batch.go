@@ -0,0 +1,95 @@
+package bolt
+
+import (
+	"strings"
+
+	"github.com/boltdb/bolt"
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/bolt/customtemplate"
+	"github.com/portainer/portainer/api/bolt/endpoint"
+	"github.com/portainer/portainer/api/bolt/endpointrelation"
+	"github.com/portainer/portainer/api/bolt/internal"
+	"github.com/portainer/portainer/api/bolt/resourcecontrol"
+	"github.com/portainer/portainer/api/bolt/stack"
+	"github.com/portainer/portainer/api/bolt/team"
+	"github.com/portainer/portainer/api/bolt/teammembership"
+	"github.com/portainer/portainer/api/bolt/user"
+)
+
+// CreateEntities creates the entities of a batch in a single transaction, none of them is created when one fails.
+// The team memberships and the resource controls are given new identifiers, the other entities are created with
+// their reserved identifier.
+func (store *Store) CreateEntities(batch *portainer.EntityBatch) error {
+	return store.connection.Update(func(tx *bolt.Tx) error {
+		for idx := range batch.Teams {
+			teamObject := &batch.Teams[idx]
+			err := createObject(tx, team.BucketName, internal.Itob(int(teamObject.ID)), teamObject, team.Indexes()...)
+			if err != nil {
+				return err
+			}
+		}
+
+		for idx := range batch.Users {
+			userObject := &batch.Users[idx]
+			userObject.Username = strings.ToLower(userObject.Username)
+			err := createObject(tx, user.BucketName, internal.Itob(int(userObject.ID)), userObject, user.Indexes()...)
+			if err != nil {
+				return err
+			}
+		}
+
+		for idx := range batch.TeamMemberships {
+			membership := &batch.TeamMemberships[idx]
+			id, _ := tx.Bucket([]byte(teammembership.BucketName)).NextSequence()
+			membership.ID = portainer.TeamMembershipID(id)
+			err := createObject(tx, teammembership.BucketName, internal.Itob(int(membership.ID)), membership)
+			if err != nil {
+				return err
+			}
+		}
+
+		for idx := range batch.Endpoints {
+			endpointObject := &batch.Endpoints[idx]
+			err := createObject(tx, endpoint.BucketName, internal.Itob(int(endpointObject.ID)), endpointObject)
+			if err != nil {
+				return err
+			}
+		}
+
+		for idx := range batch.EndpointRelations {
+			relation := &batch.EndpointRelations[idx]
+			err := createObject(tx, endpointrelation.BucketName, internal.Itob(int(relation.EndpointID)), relation)
+			if err != nil {
+				return err
+			}
+		}
+
+		for idx := range batch.Stacks {
+			stackObject := &batch.Stacks[idx]
+			err := createObject(tx, stack.BucketName, internal.Itob(int(stackObject.ID)), stackObject, stack.Indexes()...)
+			if err != nil {
+				return err
+			}
+		}
+
+		for idx := range batch.CustomTemplates {
+			customTemplate := &batch.CustomTemplates[idx]
+			err := createObject(tx, customtemplate.BucketName, internal.Itob(int(customTemplate.ID)), customTemplate)
+			if err != nil {
+				return err
+			}
+		}
+
+		for idx := range batch.ResourceControls {
+			resourceControl := &batch.ResourceControls[idx]
+			id, _ := tx.Bucket([]byte(resourcecontrol.BucketName)).NextSequence()
+			resourceControl.ID = portainer.ResourceControlID(id)
+			err := createObject(tx, resourcecontrol.BucketName, internal.Itob(int(resourceControl.ID)), resourceControl, resourcecontrol.Indexes()...)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
@@ -0,0 +1,621 @@
+package bolt
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/boltdb/bolt"
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/bolt/apikey"
+	"github.com/portainer/portainer/api/bolt/customtemplate"
+	"github.com/portainer/portainer/api/bolt/edgegroup"
+	"github.com/portainer/portainer/api/bolt/edgejob"
+	"github.com/portainer/portainer/api/bolt/edgestack"
+	"github.com/portainer/portainer/api/bolt/endpoint"
+	"github.com/portainer/portainer/api/bolt/endpointgroup"
+	"github.com/portainer/portainer/api/bolt/endpointrelation"
+	"github.com/portainer/portainer/api/bolt/errors"
+	"github.com/portainer/portainer/api/bolt/internal"
+	"github.com/portainer/portainer/api/bolt/issuedtoken"
+	"github.com/portainer/portainer/api/bolt/registry"
+	"github.com/portainer/portainer/api/bolt/resourcecontrol"
+	"github.com/portainer/portainer/api/bolt/settings"
+	"github.com/portainer/portainer/api/bolt/snapshot"
+	"github.com/portainer/portainer/api/bolt/stack"
+	"github.com/portainer/portainer/api/bolt/tag"
+	"github.com/portainer/portainer/api/bolt/team"
+	"github.com/portainer/portainer/api/bolt/teammembership"
+	"github.com/portainer/portainer/api/bolt/user"
+	"github.com/portainer/portainer/api/bolt/webhook"
+	"github.com/portainer/portainer/api/internal/stackutils"
+)
+
+// DeleteEndpointCascade removes an endpoint and the objects referencing it in a single transaction. Its snapshot,
+// relation, stacks, webhooks and the resource controls of its resources are removed, and it is removed from the
+// tags, the edge groups, the edge stacks and the edge jobs. The removed stacks are returned so that their project
+// folders can be removed.
+func (store *Store) DeleteEndpointCascade(ID portainer.EndpointID) ([]portainer.Stack, error) {
+	stacks := make([]portainer.Stack, 0)
+
+	err := store.connection.Update(func(tx *bolt.Tx) error {
+		key := internal.Itob(int(ID))
+		if tx.Bucket([]byte(endpoint.BucketName)).Get(key) == nil {
+			return errors.ErrObjectNotFound
+		}
+
+		// the snapshot lists the resources of the endpoint, it is read before it is removed
+		resources, err := endpointSnapshotResources(tx, ID)
+		if err != nil {
+			return err
+		}
+
+		for _, bucketName := range []string{endpoint.BucketName, endpointrelation.BucketName, snapshot.BucketName} {
+			err := tx.Bucket([]byte(bucketName)).Delete(key)
+			if err != nil {
+				return err
+			}
+		}
+
+		for _, object := range bucketObjects(tx, stack.BucketName) {
+			var s portainer.Stack
+			err := internal.UnmarshalObject(object.data, &s)
+			if err != nil {
+				return err
+			}
+
+			if s.EndpointID != ID {
+				continue
+			}
+
+			err = internal.RemoveIndexedObject(tx, stack.BucketName, object.key, stack.Indexes()...)
+			if err != nil {
+				return err
+			}
+			stacks = append(stacks, s)
+		}
+
+		err = removeEndpointResourceControls(tx, ID, resources)
+		if err != nil {
+			return err
+		}
+
+		err = removeEndpointWebhooks(tx, ID)
+		if err != nil {
+			return err
+		}
+
+		return removeEndpointFromEdge(tx, ID)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return stacks, nil
+}
+
+// snapshotResources represents the resources listed in the raw data of a Docker snapshot, the containers and
+// the networks are identified by their ID and the volumes by their name
+type snapshotResources struct {
+	Containers []struct {
+		ID string `json:"Id"`
+	} `json:"Containers"`
+	Networks []struct {
+		ID string `json:"Id"`
+	} `json:"Networks"`
+	Volumes struct {
+		Volumes []struct {
+			Name string `json:"Name"`
+		} `json:"Volumes"`
+	} `json:"Volumes"`
+}
+
+// endpointSnapshotResources returns the containers, the volumes and the networks listed in the snapshot of an
+// endpoint, by resource control type. The resources also listed in the snapshot of another endpoint, such as
+// the volumes sharing a name, are left out as they cannot be told apart.
+func endpointSnapshotResources(tx *bolt.Tx, ID portainer.EndpointID) (map[portainer.ResourceControlType]map[string]bool, error) {
+	resources := make(map[portainer.ResourceControlType]map[string]bool)
+	otherResources := make(map[portainer.ResourceControlType]map[string]bool)
+	key := internal.Itob(int(ID))
+
+	for _, object := range bucketObjects(tx, snapshot.BucketName) {
+		s, err := snapshot.UnmarshalSnapshot(object.data)
+		if err != nil {
+			return nil, err
+		}
+
+		if s.Docker == nil {
+			continue
+		}
+
+		data, err := internal.MarshalObject(s.Docker.SnapshotRaw)
+		if err != nil {
+			return nil, err
+		}
+
+		var raw snapshotResources
+		err = internal.UnmarshalObject(data, &raw)
+		if err != nil {
+			return nil, err
+		}
+
+		target := otherResources
+		if bytes.Equal(object.key, key) {
+			target = resources
+		}
+
+		for _, container := range raw.Containers {
+			addResource(target, portainer.ContainerResourceControl, container.ID)
+		}
+		for _, network := range raw.Networks {
+			addResource(target, portainer.NetworkResourceControl, network.ID)
+		}
+		for _, volume := range raw.Volumes.Volumes {
+			addResource(target, portainer.VolumeResourceControl, volume.Name)
+		}
+	}
+
+	for resourceType, resourceIDs := range otherResources {
+		for resourceID := range resourceIDs {
+			delete(resources[resourceType], resourceID)
+		}
+	}
+
+	return resources, nil
+}
+
+func addResource(resources map[portainer.ResourceControlType]map[string]bool, resourceType portainer.ResourceControlType, resourceID string) {
+	if resources[resourceType] == nil {
+		resources[resourceType] = make(map[string]bool)
+	}
+	resources[resourceType][resourceID] = true
+}
+
+// removeEndpointResourceControls removes the resource controls of the resources of an endpoint: the ones recording
+// the endpoint, the ones of its stacks and, for the resource controls created before the endpoint was recorded,
+// the ones of the containers, the volumes and the networks listed in its snapshot.
+func removeEndpointResourceControls(tx *bolt.Tx, ID portainer.EndpointID, resources map[portainer.ResourceControlType]map[string]bool) error {
+	prefix := stackutils.ResourceControlID(ID, "")
+
+	for _, object := range bucketObjects(tx, resourcecontrol.BucketName) {
+		var resourceControl portainer.ResourceControl
+		err := internal.UnmarshalObject(object.data, &resourceControl)
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case resourceControl.EndpointID == ID:
+		case resourceControl.Type == portainer.StackResourceControl && strings.HasPrefix(resourceControl.ResourceID, prefix):
+		case resourceControl.EndpointID == 0 && resources[resourceControl.Type][resourceControl.ResourceID]:
+		default:
+			continue
+		}
+
+		err = internal.RemoveIndexedObject(tx, resourcecontrol.BucketName, object.key, resourcecontrol.Indexes()...)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func removeEndpointWebhooks(tx *bolt.Tx, ID portainer.EndpointID) error {
+	for _, object := range bucketObjects(tx, webhook.BucketName) {
+		var w portainer.Webhook
+		err := internal.UnmarshalObject(object.data, &w)
+		if err != nil {
+			return err
+		}
+
+		if w.EndpointID != ID {
+			continue
+		}
+
+		err = internal.RemoveIndexedObject(tx, webhook.BucketName, object.key, webhook.Indexes()...)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// removeEndpointFromEdge removes an endpoint from the tags, the static edge groups, the edge stacks and the edge jobs
+func removeEndpointFromEdge(tx *bolt.Tx, ID portainer.EndpointID) error {
+	for _, object := range bucketObjects(tx, tag.BucketName) {
+		var t portainer.Tag
+		err := internal.UnmarshalObject(object.data, &t)
+		if err != nil {
+			return err
+		}
+
+		if _, ok := t.Endpoints[ID]; !ok {
+			continue
+		}
+		delete(t.Endpoints, ID)
+
+		err = putObject(tx, tag.BucketName, object.key, &t)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, object := range bucketObjects(tx, edgegroup.BucketName) {
+		var edgeGroup portainer.EdgeGroup
+		err := internal.UnmarshalObject(object.data, &edgeGroup)
+		if err != nil {
+			return err
+		}
+
+		endpointIDs := make([]portainer.EndpointID, 0, len(edgeGroup.Endpoints))
+		for _, endpointID := range edgeGroup.Endpoints {
+			if endpointID != ID {
+				endpointIDs = append(endpointIDs, endpointID)
+			}
+		}
+
+		if len(endpointIDs) == len(edgeGroup.Endpoints) {
+			continue
+		}
+		edgeGroup.Endpoints = endpointIDs
+
+		err = putObject(tx, edgegroup.BucketName, object.key, &edgeGroup)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, object := range bucketObjects(tx, edgestack.BucketName) {
+		var edgeStack portainer.EdgeStack
+		err := internal.UnmarshalObject(object.data, &edgeStack)
+		if err != nil {
+			return err
+		}
+
+		if _, ok := edgeStack.Status[ID]; !ok {
+			continue
+		}
+		delete(edgeStack.Status, ID)
+
+		err = putObject(tx, edgestack.BucketName, object.key, &edgeStack)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, object := range bucketObjects(tx, edgejob.BucketName) {
+		var edgeJob portainer.EdgeJob
+		err := internal.UnmarshalObject(object.data, &edgeJob)
+		if err != nil {
+			return err
+		}
+
+		if _, ok := edgeJob.Endpoints[ID]; !ok {
+			continue
+		}
+		delete(edgeJob.Endpoints, ID)
+
+		err = putObject(tx, edgejob.BucketName, object.key, &edgeJob)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// DeleteUserCascade removes a user and the objects referencing it in a single transaction. Its team memberships,
+// API keys and issued tokens are removed, along with its access policies and resource accesses. When transferTo
+// is set, the access policies, the resource accesses, the stacks and the custom templates of the user are given
+// to that user instead.
+func (store *Store) DeleteUserCascade(ID portainer.UserID, transferTo portainer.UserID) error {
+	return store.connection.Update(func(tx *bolt.Tx) error {
+		var deletedUser portainer.User
+		err := getObject(tx, user.BucketName, internal.Itob(int(ID)), &deletedUser)
+		if err != nil {
+			return err
+		}
+
+		var newOwner portainer.User
+		if transferTo != 0 {
+			err := getObject(tx, user.BucketName, internal.Itob(int(transferTo)), &newOwner)
+			if err != nil {
+				return err
+			}
+		}
+
+		err = internal.RemoveIndexedObject(tx, user.BucketName, internal.Itob(int(ID)), user.Indexes()...)
+		if err != nil {
+			return err
+		}
+
+		err = removeUserObjects(tx, ID)
+		if err != nil {
+			return err
+		}
+
+		err = updateAccessPolicies(tx, func(userAccessPolicies portainer.UserAccessPolicies, teamAccessPolicies portainer.TeamAccessPolicies) bool {
+			policy, ok := userAccessPolicies[ID]
+			if !ok {
+				return false
+			}
+			delete(userAccessPolicies, ID)
+
+			if _, exists := userAccessPolicies[transferTo]; transferTo != 0 && !exists {
+				userAccessPolicies[transferTo] = policy
+			}
+			return true
+		})
+		if err != nil {
+			return err
+		}
+
+		err = updateResourceControls(tx, func(resourceControl *portainer.ResourceControl) bool {
+			index := -1
+			hasNewOwner := false
+			for idx, access := range resourceControl.UserAccesses {
+				switch {
+				case access.UserID == ID:
+					index = idx
+				case transferTo != 0 && access.UserID == transferTo:
+					hasNewOwner = true
+				}
+			}
+
+			if index == -1 {
+				return false
+			}
+
+			if transferTo != 0 && !hasNewOwner {
+				resourceControl.UserAccesses[index].UserID = transferTo
+			} else {
+				resourceControl.UserAccesses = append(resourceControl.UserAccesses[:index], resourceControl.UserAccesses[index+1:]...)
+			}
+			return true
+		})
+		if err != nil {
+			return err
+		}
+
+		if transferTo == 0 {
+			return nil
+		}
+
+		return transferUserCreations(tx, &deletedUser, &newOwner)
+	})
+}
+
+// removeUserObjects removes the team memberships, the API keys and the issued tokens of a user
+func removeUserObjects(tx *bolt.Tx, ID portainer.UserID) error {
+	for _, object := range bucketObjects(tx, teammembership.BucketName) {
+		var membership portainer.TeamMembership
+		err := internal.UnmarshalObject(object.data, &membership)
+		if err != nil {
+			return err
+		}
+
+		if membership.UserID == ID {
+			err := tx.Bucket([]byte(teammembership.BucketName)).Delete(object.key)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, object := range bucketObjects(tx, apikey.BucketName) {
+		var apiKey portainer.APIKey
+		err := internal.UnmarshalObject(object.data, &apiKey)
+		if err != nil {
+			return err
+		}
+
+		if apiKey.UserID == ID {
+			err := internal.RemoveIndexedObject(tx, apikey.BucketName, object.key, apikey.Indexes()...)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	// a token that is not recorded is rejected, removing the tokens revokes them
+	for _, object := range bucketObjects(tx, issuedtoken.BucketName) {
+		var token portainer.IssuedToken
+		err := internal.UnmarshalObject(object.data, &token)
+		if err != nil {
+			return err
+		}
+
+		if token.UserID == ID {
+			err := tx.Bucket([]byte(issuedtoken.BucketName)).Delete(object.key)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// transferUserCreations gives the stacks and the custom templates created by a user to another user
+func transferUserCreations(tx *bolt.Tx, previousOwner, newOwner *portainer.User) error {
+	for _, object := range bucketObjects(tx, stack.BucketName) {
+		var s portainer.Stack
+		err := internal.UnmarshalObject(object.data, &s)
+		if err != nil {
+			return err
+		}
+
+		if s.CreatedBy != previousOwner.Username {
+			continue
+		}
+		s.CreatedBy = newOwner.Username
+
+		err = putObject(tx, stack.BucketName, object.key, &s, stack.Indexes()...)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, object := range bucketObjects(tx, customtemplate.BucketName) {
+		var customTemplate portainer.CustomTemplate
+		err := internal.UnmarshalObject(object.data, &customTemplate)
+		if err != nil {
+			return err
+		}
+
+		if customTemplate.CreatedByUserID != previousOwner.ID {
+			continue
+		}
+		customTemplate.CreatedByUserID = newOwner.ID
+
+		err = putObject(tx, customtemplate.BucketName, object.key, &customTemplate)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// DeleteTeamCascade removes a team and the objects referencing it in a single transaction. Its team memberships,
+// access policies and resource accesses are removed, and it is no longer the default team of the OAuth users.
+func (store *Store) DeleteTeamCascade(ID portainer.TeamID) error {
+	return store.connection.Update(func(tx *bolt.Tx) error {
+		key := internal.Itob(int(ID))
+		if tx.Bucket([]byte(team.BucketName)).Get(key) == nil {
+			return errors.ErrObjectNotFound
+		}
+
+		err := internal.RemoveIndexedObject(tx, team.BucketName, key, team.Indexes()...)
+		if err != nil {
+			return err
+		}
+
+		for _, object := range bucketObjects(tx, teammembership.BucketName) {
+			var membership portainer.TeamMembership
+			err := internal.UnmarshalObject(object.data, &membership)
+			if err != nil {
+				return err
+			}
+
+			if membership.TeamID == ID {
+				err := tx.Bucket([]byte(teammembership.BucketName)).Delete(object.key)
+				if err != nil {
+					return err
+				}
+			}
+		}
+
+		err = updateAccessPolicies(tx, func(userAccessPolicies portainer.UserAccessPolicies, teamAccessPolicies portainer.TeamAccessPolicies) bool {
+			if _, ok := teamAccessPolicies[ID]; !ok {
+				return false
+			}
+			delete(teamAccessPolicies, ID)
+			return true
+		})
+		if err != nil {
+			return err
+		}
+
+		err = updateResourceControls(tx, func(resourceControl *portainer.ResourceControl) bool {
+			teamAccesses := make([]portainer.TeamResourceAccess, 0, len(resourceControl.TeamAccesses))
+			for _, access := range resourceControl.TeamAccesses {
+				if access.TeamID != ID {
+					teamAccesses = append(teamAccesses, access)
+				}
+			}
+
+			if len(teamAccesses) == len(resourceControl.TeamAccesses) {
+				return false
+			}
+			resourceControl.TeamAccesses = teamAccesses
+			return true
+		})
+		if err != nil {
+			return err
+		}
+
+		var s portainer.Settings
+		err = getObject(tx, settings.BucketName, []byte(settings.SettingsKey), &s)
+		if err == errors.ErrObjectNotFound || (err == nil && s.OAuthSettings.DefaultTeamID != ID) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		s.OAuthSettings.DefaultTeamID = 0
+		return putObject(tx, settings.BucketName, []byte(settings.SettingsKey), &s)
+	})
+}
+
+// updateAccessPolicies applies an update to the access policies of the endpoints, the endpoint groups and the
+// registries. The update returns whether the policies changed.
+func updateAccessPolicies(tx *bolt.Tx, update func(userAccessPolicies portainer.UserAccessPolicies, teamAccessPolicies portainer.TeamAccessPolicies) bool) error {
+	for _, object := range bucketObjects(tx, endpoint.BucketName) {
+		var e portainer.Endpoint
+		err := internal.UnmarshalObject(object.data, &e)
+		if err != nil {
+			return err
+		}
+
+		if update(e.UserAccessPolicies, e.TeamAccessPolicies) {
+			err := putObject(tx, endpoint.BucketName, object.key, &e)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, object := range bucketObjects(tx, endpointgroup.BucketName) {
+		var group portainer.EndpointGroup
+		err := internal.UnmarshalObject(object.data, &group)
+		if err != nil {
+			return err
+		}
+
+		if update(group.UserAccessPolicies, group.TeamAccessPolicies) {
+			err := putObject(tx, endpointgroup.BucketName, object.key, &group)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, object := range bucketObjects(tx, registry.BucketName) {
+		var r portainer.Registry
+		err := internal.UnmarshalObject(object.data, &r)
+		if err != nil {
+			return err
+		}
+
+		if update(r.UserAccessPolicies, r.TeamAccessPolicies) {
+			err := putObject(tx, registry.BucketName, object.key, &r)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// updateResourceControls applies an update to every resource control, the update returns whether the resource control changed
+func updateResourceControls(tx *bolt.Tx, update func(resourceControl *portainer.ResourceControl) bool) error {
+	for _, object := range bucketObjects(tx, resourcecontrol.BucketName) {
+		var resourceControl portainer.ResourceControl
+		err := internal.UnmarshalObject(object.data, &resourceControl)
+		if err != nil {
+			return err
+		}
+
+		if update(&resourceControl) {
+			err := putObject(tx, resourcecontrol.BucketName, object.key, &resourceControl, resourcecontrol.Indexes()...)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,245 @@
+package bolt_test
+
+import (
+	"testing"
+
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/bolt/bolttest"
+	"github.com/portainer/portainer/api/bolt/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_DeleteEndpointCascade_RemovesTheObjectsOfTheEndpoint(t *testing.T) {
+	is := assert.New(t)
+
+	store, teardown := bolttest.MustNewTestStore(true)
+	defer teardown()
+
+	is.NoError(store.EndpointService.CreateEndpoint(&portainer.Endpoint{ID: 1, Name: "local", GroupID: 1}))
+	is.NoError(store.EndpointService.CreateEndpoint(&portainer.Endpoint{ID: 2, Name: "remote", GroupID: 1}))
+	is.NoError(store.TagService.CreateTag(&portainer.Tag{ID: 1, Name: "tag", Endpoints: map[portainer.EndpointID]bool{1: true, 2: true}}))
+	is.NoError(store.StackService.CreateStack(&portainer.Stack{ID: 1, Name: "web", EndpointID: 1, ProjectPath: "/data/compose/1"}))
+	is.NoError(store.StackService.CreateStack(&portainer.Stack{ID: 2, Name: "db", EndpointID: 2}))
+	is.NoError(store.ResourceControlService.CreateResourceControl(&portainer.ResourceControl{ResourceID: "1_web", Type: portainer.StackResourceControl}))
+	is.NoError(store.WebhookService.CreateWebhook(&portainer.Webhook{Token: "token", ResourceID: "service", EndpointID: 1}))
+	is.NoError(store.EdgeGroupService.CreateEdgeGroup(&portainer.EdgeGroup{Name: "edge", Endpoints: []portainer.EndpointID{1, 2}}))
+	is.NoError(store.EdgeJobService.CreateEdgeJob(&portainer.EdgeJob{Endpoints: map[portainer.EndpointID]portainer.EdgeJobEndpointMeta{1: {}}}))
+	is.NoError(store.SnapshotService.UpdateSnapshot(&portainer.Snapshot{EndpointID: 1}))
+
+	stacks, err := store.DeleteEndpointCascade(1)
+	is.NoError(err)
+	if is.Len(stacks, 1) {
+		is.Equal("/data/compose/1", stacks[0].ProjectPath)
+	}
+
+	_, err = store.EndpointService.Endpoint(1)
+	is.Equal(errors.ErrObjectNotFound, err)
+
+	tag, err := store.TagService.Tag(1)
+	is.NoError(err)
+	is.Equal(map[portainer.EndpointID]bool{2: true}, tag.Endpoints)
+
+	_, err = store.StackService.StackByName("web")
+	is.Equal(errors.ErrObjectNotFound, err)
+	_, err = store.StackService.StackByName("db")
+	is.NoError(err)
+
+	resourceControl, err := store.ResourceControlService.ResourceControlByResourceIDAndType("1_web", portainer.StackResourceControl)
+	is.NoError(err)
+	is.Nil(resourceControl)
+
+	_, err = store.WebhookService.WebhookByToken("token")
+	is.Equal(errors.ErrObjectNotFound, err)
+
+	edgeGroups, err := store.EdgeGroupService.EdgeGroups()
+	is.NoError(err)
+	if is.Len(edgeGroups, 1) {
+		is.Equal([]portainer.EndpointID{2}, edgeGroups[0].Endpoints)
+	}
+
+	edgeJobs, err := store.EdgeJobService.EdgeJobs()
+	is.NoError(err)
+	if is.Len(edgeJobs, 1) {
+		is.Empty(edgeJobs[0].Endpoints)
+	}
+
+	_, err = store.SnapshotService.Snapshot(1)
+	is.Equal(errors.ErrObjectNotFound, err)
+
+	report, err := store.CheckIntegrity(false)
+	is.NoError(err)
+	is.Empty(report.Issues)
+
+	_, err = store.DeleteEndpointCascade(1)
+	is.Equal(errors.ErrObjectNotFound, err)
+}
+
+func Test_DeleteEndpointCascade_RemovesTheResourceControlsOfTheEndpointResources(t *testing.T) {
+	is := assert.New(t)
+
+	store, teardown := bolttest.MustNewTestStore(true)
+	defer teardown()
+
+	snapshotWith := func(endpointID portainer.EndpointID, containerID, networkID, volumeName string) *portainer.Snapshot {
+		return &portainer.Snapshot{EndpointID: endpointID, Docker: &portainer.DockerSnapshot{SnapshotRaw: portainer.DockerSnapshotRaw{
+			Containers: []map[string]interface{}{{"Id": containerID}},
+			Networks:   []map[string]interface{}{{"Id": networkID}},
+			Volumes:    map[string]interface{}{"Volumes": []map[string]interface{}{{"Name": volumeName}, {"Name": "data"}}},
+		}}}
+	}
+
+	is.NoError(store.EndpointService.CreateEndpoint(&portainer.Endpoint{ID: 1, Name: "local", GroupID: 1}))
+	is.NoError(store.EndpointService.CreateEndpoint(&portainer.Endpoint{ID: 2, Name: "remote", GroupID: 1}))
+	is.NoError(store.SnapshotService.UpdateSnapshot(snapshotWith(1, "container1", "network1", "volume1")))
+	is.NoError(store.SnapshotService.UpdateSnapshot(snapshotWith(2, "container2", "network2", "volume2")))
+
+	resourceControls := []portainer.ResourceControl{
+		{ResourceID: "service1", Type: portainer.ServiceResourceControl, EndpointID: 1},
+		{ResourceID: "container1", Type: portainer.ContainerResourceControl},
+		{ResourceID: "network1", Type: portainer.NetworkResourceControl},
+		{ResourceID: "volume1", Type: portainer.VolumeResourceControl},
+		{ResourceID: "service2", Type: portainer.ServiceResourceControl, EndpointID: 2},
+		{ResourceID: "container2", Type: portainer.ContainerResourceControl},
+		{ResourceID: "data", Type: portainer.VolumeResourceControl},
+		{ResourceID: "1", Type: portainer.CustomTemplateResourceControl},
+	}
+	for idx := range resourceControls {
+		is.NoError(store.ResourceControlService.CreateResourceControl(&resourceControls[idx]))
+	}
+
+	_, err := store.DeleteEndpointCascade(1)
+	is.NoError(err)
+
+	remaining, err := store.ResourceControlService.ResourceControls()
+	is.NoError(err)
+
+	resourceIDs := make([]string, 0, len(remaining))
+	for _, resourceControl := range remaining {
+		resourceIDs = append(resourceIDs, resourceControl.ResourceID)
+	}
+	is.ElementsMatch([]string{"service2", "container2", "data", "1"}, resourceIDs)
+}
+
+func Test_DeleteUserCascade_TransfersTheOwnership(t *testing.T) {
+	is := assert.New(t)
+
+	store, teardown := bolttest.MustNewTestStore(true)
+	defer teardown()
+
+	is.NoError(store.UserService.CreateUser(&portainer.User{ID: 1, Username: "admin", Role: portainer.AdministratorRole}))
+	is.NoError(store.UserService.CreateUser(&portainer.User{ID: 2, Username: "leaver", Role: portainer.StandardUserRole}))
+	is.NoError(store.UserService.CreateUser(&portainer.User{ID: 3, Username: "owner", Role: portainer.StandardUserRole}))
+	is.NoError(store.TeamService.CreateTeam(&portainer.Team{ID: 1, Name: "team"}))
+	is.NoError(store.TeamMembershipService.CreateTeamMembership(&portainer.TeamMembership{UserID: 2, TeamID: 1}))
+	is.NoError(store.APIKeyService.CreateAPIKey(&portainer.APIKey{UserID: 2, Digest: "digest"}))
+	_, err := store.APIKeyService.APIKeyByDigest("digest")
+	is.NoError(err)
+	is.NoError(store.IssuedTokenService.CreateIssuedToken(&portainer.IssuedToken{ID: "session", UserID: 2}))
+	is.NoError(store.EndpointService.CreateEndpoint(&portainer.Endpoint{
+		ID:                 1,
+		Name:               "local",
+		GroupID:            1,
+		UserAccessPolicies: portainer.UserAccessPolicies{2: {RoleID: 4}},
+	}))
+	is.NoError(store.RegistryService.CreateRegistry(&portainer.Registry{
+		Name:               "registry",
+		UserAccessPolicies: portainer.UserAccessPolicies{2: {}, 3: {}},
+	}))
+	is.NoError(store.ResourceControlService.CreateResourceControl(&portainer.ResourceControl{
+		ResourceID:   "container",
+		Type:         portainer.ContainerResourceControl,
+		UserAccesses: []portainer.UserResourceAccess{{UserID: 2, AccessLevel: portainer.ReadWriteAccessLevel}},
+	}))
+	is.NoError(store.StackService.CreateStack(&portainer.Stack{ID: 1, Name: "web", EndpointID: 1, CreatedBy: "leaver"}))
+
+	is.NoError(store.DeleteUserCascade(2, 3))
+
+	_, err = store.UserService.UserByUsername("leaver")
+	is.Equal(errors.ErrObjectNotFound, err)
+
+	memberships, err := store.TeamMembershipService.TeamMemberships()
+	is.NoError(err)
+	is.Empty(memberships)
+
+	apiKeys, err := store.APIKeyService.APIKeysByUserID(2)
+	is.NoError(err)
+	is.Empty(apiKeys)
+
+	_, err = store.APIKeyService.APIKeyByDigest("digest")
+	is.Equal(errors.ErrObjectNotFound, err)
+
+	_, err = store.IssuedTokenService.IssuedToken("session")
+	is.Equal(errors.ErrObjectNotFound, err)
+
+	endpoint, err := store.EndpointService.Endpoint(1)
+	is.NoError(err)
+	is.Equal(portainer.UserAccessPolicies{3: {RoleID: 4}}, endpoint.UserAccessPolicies)
+
+	registries, err := store.RegistryService.Registries()
+	is.NoError(err)
+	if is.Len(registries, 1) {
+		is.Equal(portainer.UserAccessPolicies{3: {}}, registries[0].UserAccessPolicies)
+	}
+
+	resourceControl, err := store.ResourceControlService.ResourceControlByResourceIDAndType("container", portainer.ContainerResourceControl)
+	is.NoError(err)
+	is.Equal([]portainer.UserResourceAccess{{UserID: 3, AccessLevel: portainer.ReadWriteAccessLevel}}, resourceControl.UserAccesses)
+
+	stack, err := store.StackService.StackByName("web")
+	is.NoError(err)
+	is.Equal("owner", stack.CreatedBy)
+
+	report, err := store.CheckIntegrity(false)
+	is.NoError(err)
+	is.Empty(report.Issues)
+}
+
+func Test_DeleteTeamCascade_RemovesTheTeamAccesses(t *testing.T) {
+	is := assert.New(t)
+
+	store, teardown := bolttest.MustNewTestStore(true)
+	defer teardown()
+
+	is.NoError(store.UserService.CreateUser(&portainer.User{ID: 1, Username: "admin", Role: portainer.AdministratorRole}))
+	is.NoError(store.TeamService.CreateTeam(&portainer.Team{ID: 1, Name: "team"}))
+	is.NoError(store.TeamMembershipService.CreateTeamMembership(&portainer.TeamMembership{UserID: 1, TeamID: 1}))
+	is.NoError(store.EndpointGroupService.CreateEndpointGroup(&portainer.EndpointGroup{
+		Name:               "group",
+		TeamAccessPolicies: portainer.TeamAccessPolicies{1: {}},
+	}))
+	is.NoError(store.ResourceControlService.CreateResourceControl(&portainer.ResourceControl{
+		ResourceID:   "volume",
+		Type:         portainer.VolumeResourceControl,
+		TeamAccesses: []portainer.TeamResourceAccess{{TeamID: 1, AccessLevel: portainer.ReadWriteAccessLevel}},
+	}))
+
+	settings, err := store.SettingsService.Settings()
+	is.NoError(err)
+	settings.OAuthSettings.DefaultTeamID = 1
+	is.NoError(store.SettingsService.UpdateSettings(settings))
+
+	is.NoError(store.DeleteTeamCascade(1))
+
+	_, err = store.TeamService.TeamByName("team")
+	is.Equal(errors.ErrObjectNotFound, err)
+
+	memberships, err := store.TeamMembershipService.TeamMemberships()
+	is.NoError(err)
+	is.Empty(memberships)
+
+	groups, err := store.EndpointGroupService.EndpointGroups()
+	is.NoError(err)
+	for _, group := range groups {
+		is.Empty(group.TeamAccessPolicies)
+	}
+
+	resourceControl, err := store.ResourceControlService.ResourceControlByResourceIDAndType("volume", portainer.VolumeResourceControl)
+	is.NoError(err)
+	is.Empty(resourceControl.TeamAccesses)
+
+	settings, err = store.SettingsService.Settings()
+	is.NoError(err)
+	is.Equal(portainer.TeamID(0), settings.OAuthSettings.DefaultTeamID)
+
+	is.Equal(errors.ErrObjectNotFound, store.DeleteTeamCascade(1))
+}
@@ -1,6 +1,7 @@
 package bolt
 
 import (
+	"fmt"
 	"io"
 	"log"
 	"path"
@@ -8,6 +9,8 @@ import (
 
 	"github.com/boltdb/bolt"
 	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/bolt/apikey"
+	"github.com/portainer/portainer/api/bolt/auditlog"
 	"github.com/portainer/portainer/api/bolt/customtemplate"
 	"github.com/portainer/portainer/api/bolt/dockerhub"
 	"github.com/portainer/portainer/api/bolt/edgegroup"
@@ -19,12 +22,15 @@ import (
 	"github.com/portainer/portainer/api/bolt/errors"
 	"github.com/portainer/portainer/api/bolt/extension"
 	"github.com/portainer/portainer/api/bolt/internal"
+	"github.com/portainer/portainer/api/bolt/issuedtoken"
+	"github.com/portainer/portainer/api/bolt/jwtsigningkey"
 	"github.com/portainer/portainer/api/bolt/migrator"
 	"github.com/portainer/portainer/api/bolt/registry"
 	"github.com/portainer/portainer/api/bolt/resourcecontrol"
 	"github.com/portainer/portainer/api/bolt/role"
 	"github.com/portainer/portainer/api/bolt/schedule"
 	"github.com/portainer/portainer/api/bolt/settings"
+	"github.com/portainer/portainer/api/bolt/snapshot"
 	"github.com/portainer/portainer/api/bolt/stack"
 	"github.com/portainer/portainer/api/bolt/tag"
 	"github.com/portainer/portainer/api/bolt/team"
@@ -47,6 +53,8 @@ type Store struct {
 	connection              *internal.DbConnection
 	isNew                   bool
 	fileService             portainer.FileService
+	APIKeyService           *apikey.Service
+	AuditLogService         *auditlog.Service
 	CustomTemplateService   *customtemplate.Service
 	DockerHubService        *dockerhub.Service
 	EdgeGroupService        *edgegroup.Service
@@ -56,11 +64,14 @@ type Store struct {
 	EndpointService         *endpoint.Service
 	EndpointRelationService *endpointrelation.Service
 	ExtensionService        *extension.Service
+	IssuedTokenService      *issuedtoken.Service
+	JWTSigningKeyService    *jwtsigningkey.Service
 	RegistryService         *registry.Service
 	ResourceControlService  *resourcecontrol.Service
 	RoleService             *role.Service
 	ScheduleService         *schedule.Service
 	SettingsService         *settings.Service
+	SnapshotService         *snapshot.Service
 	StackService            *stack.Service
 	TagService              *tag.Service
 	TeamMembershipService   *teammembership.Service
@@ -154,6 +165,7 @@ func (store *Store) MigrateData(force bool) error {
 		migratorParams := &migrator.Parameters{
 			DB:                      store.connection.DB,
 			DatabaseVersion:         version,
+			APIKeyService:           store.APIKeyService,
 			EndpointGroupService:    store.EndpointGroupService,
 			EndpointService:         store.EndpointService,
 			EndpointRelationService: store.EndpointRelationService,
@@ -163,25 +175,74 @@ func (store *Store) MigrateData(force bool) error {
 			RoleService:             store.RoleService,
 			ScheduleService:         store.ScheduleService,
 			SettingsService:         store.SettingsService,
+			SnapshotService:         store.SnapshotService,
 			StackService:            store.StackService,
 			TagService:              store.TagService,
 			TeamMembershipService:   store.TeamMembershipService,
+			TeamService:             store.TeamService,
 			UserService:             store.UserService,
 			VersionService:          store.VersionService,
+			WebhookService:          store.WebhookService,
 			FileService:             store.fileService,
 			AuthorizationService:    authorization.NewService(store),
 		}
 		migrator := migrator.NewMigrator(migratorParams)
 
 		log.Printf("Migrating database from version %v to %v.\n", version, portainer.DBVersion)
-		err = migrator.Migrate()
+		return store.migrateWithSnapshot(version, migrator.Migrate)
+	}
+
+	return nil
+}
+
+// migrateWithSnapshot takes a snapshot of the data, then runs the migration. The snapshot is restored when
+// the migration fails. The outcome of the migration is recorded in the migration history.
+func (store *Store) migrateWithSnapshot(version int, migrate func() error) error {
+	err := store.takeMigrationSnapshot()
+	if err != nil {
+		return fmt.Errorf("unable to take the pre-migration snapshot: %w", err)
+	}
+
+	record := portainer.MigrationRecord{
+		FromVersion: version,
+		ToVersion:   portainer.DBVersion,
+		Timestamp:   time.Now().Unix(),
+		Outcome:     portainer.MigrationSucceeded,
+	}
+
+	start := time.Now()
+	migrationErr := migrate()
+	record.Duration = time.Since(start).Milliseconds()
+
+	if migrationErr != nil {
+		log.Printf("An error occurred during database migration: %s\n", migrationErr)
+		record.Outcome = portainer.MigrationRolledBack
+		record.Error = migrationErr.Error()
+
+		err := store.restoreMigrationSnapshot()
 		if err != nil {
-			log.Printf("An error occurred during database migration: %s\n", err)
-			return err
+			log.Printf("An error occurred while restoring the pre-migration snapshot: %s\n", err)
+			record.Outcome = portainer.MigrationFailed
+		} else {
+			log.Printf("Database restored to version %v from the pre-migration snapshot.\n", version)
 		}
 	}
 
-	return nil
+	err = store.recordMigration(record)
+	if err != nil {
+		log.Printf("An error occurred while recording the migration history: %s\n", err)
+	}
+
+	return migrationErr
+}
+
+func (store *Store) recordMigration(record portainer.MigrationRecord) error {
+	history, err := store.VersionService.MigrationHistory()
+	if err != nil {
+		return err
+	}
+
+	return store.VersionService.StoreMigrationHistory(append(history, record))
 }
 
 // BackupTo backs up db to a provided writer.
@@ -0,0 +1,84 @@
+package bolt
+
+import (
+	"time"
+
+	"github.com/boltdb/bolt"
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/bolt/endpoint"
+	"github.com/portainer/portainer/api/bolt/endpointgroup"
+	"github.com/portainer/portainer/api/bolt/internal"
+	"github.com/portainer/portainer/api/bolt/resourcecontrol"
+	"github.com/portainer/portainer/api/internal/authorization"
+)
+
+// RemoveExpiredAccessGrants removes the expired access policies of the endpoints and endpoint groups, and the
+// expired team accesses of the resource controls in a single transaction. It returns the number of grants removed.
+func (store *Store) RemoveExpiredAccessGrants(now time.Time) (int, error) {
+	removed := 0
+
+	err := store.connection.Update(func(tx *bolt.Tx) error {
+		removed = 0
+
+		for _, object := range bucketObjects(tx, endpoint.BucketName) {
+			var e portainer.Endpoint
+			err := internal.UnmarshalObject(object.data, &e)
+			if err != nil {
+				return err
+			}
+
+			count := authorization.RemoveExpiredPolicies(e.UserAccessPolicies, e.TeamAccessPolicies, now, "endpoint", int(e.ID))
+			if count == 0 {
+				continue
+			}
+
+			err = putObject(tx, endpoint.BucketName, object.key, &e)
+			if err != nil {
+				return err
+			}
+			removed += count
+		}
+
+		for _, object := range bucketObjects(tx, endpointgroup.BucketName) {
+			var group portainer.EndpointGroup
+			err := internal.UnmarshalObject(object.data, &group)
+			if err != nil {
+				return err
+			}
+
+			count := authorization.RemoveExpiredPolicies(group.UserAccessPolicies, group.TeamAccessPolicies, now, "endpoint_group", int(group.ID))
+			if count == 0 {
+				continue
+			}
+
+			err = putObject(tx, endpointgroup.BucketName, object.key, &group)
+			if err != nil {
+				return err
+			}
+			removed += count
+		}
+
+		for _, object := range bucketObjects(tx, resourcecontrol.BucketName) {
+			var resourceControl portainer.ResourceControl
+			err := internal.UnmarshalObject(object.data, &resourceControl)
+			if err != nil {
+				return err
+			}
+
+			count := authorization.RemoveExpiredTeamAccesses(&resourceControl, now)
+			if count == 0 {
+				continue
+			}
+
+			err = putObject(tx, resourcecontrol.BucketName, object.key, &resourceControl, resourcecontrol.Indexes()...)
+			if err != nil {
+				return err
+			}
+			removed += count
+		}
+
+		return nil
+	})
+
+	return removed, err
+}
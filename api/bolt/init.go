@@ -41,10 +41,25 @@ func (store *Store) Init() error {
 				},
 			},
 			OAuthSettings: portainer.OAuthSettings{},
+			ProxyAuthSettings: portainer.ProxyAuthSettings{
+				UserHeader:   portainer.DefaultProxyAuthUserHeader,
+				GroupsHeader: portainer.DefaultProxyAuthGroupsHeader,
+			},
 
 			EdgeAgentCheckinInterval: portainer.DefaultEdgeAgentCheckinIntervalInSeconds,
 			TemplatesURL:             portainer.DefaultTemplatesURL,
 			UserSessionTimeout:       portainer.DefaultUserSessionTimeout,
+			AccountLockout: portainer.AccountLockoutSettings{
+				Threshold: portainer.DefaultAccountLockoutThreshold,
+				Duration:  portainer.DefaultAccountLockoutDuration,
+			},
+			RateLimiting: portainer.RateLimitSettings{
+				Authentication: portainer.RateLimitPolicy{
+					MaxRequests: portainer.DefaultAuthenticationRateLimitMaxRequests,
+					Period:      portainer.DefaultAuthenticationRateLimitPeriod,
+					BanDuration: portainer.DefaultAuthenticationRateLimitBanDuration,
+				},
+			},
 		}
 
 		err = store.SettingsService.UpdateSettings(defaultSettings)
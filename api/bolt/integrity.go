@@ -0,0 +1,642 @@
+package bolt
+
+import (
+	"encoding/binary"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/boltdb/bolt"
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/bolt/apikey"
+	"github.com/portainer/portainer/api/bolt/edgegroup"
+	"github.com/portainer/portainer/api/bolt/edgejob"
+	"github.com/portainer/portainer/api/bolt/edgestack"
+	"github.com/portainer/portainer/api/bolt/endpoint"
+	"github.com/portainer/portainer/api/bolt/endpointgroup"
+	"github.com/portainer/portainer/api/bolt/endpointrelation"
+	"github.com/portainer/portainer/api/bolt/internal"
+	"github.com/portainer/portainer/api/bolt/issuedtoken"
+	"github.com/portainer/portainer/api/bolt/registry"
+	"github.com/portainer/portainer/api/bolt/resourcecontrol"
+	"github.com/portainer/portainer/api/bolt/snapshot"
+	"github.com/portainer/portainer/api/bolt/stack"
+	"github.com/portainer/portainer/api/bolt/tag"
+	"github.com/portainer/portainer/api/bolt/team"
+	"github.com/portainer/portainer/api/bolt/teammembership"
+	"github.com/portainer/portainer/api/bolt/user"
+	"github.com/portainer/portainer/api/bolt/webhook"
+)
+
+// CheckIntegrity looks for the references to missing endpoints, endpoint groups, tags, users, teams, edge groups
+// and edge stacks in every bucket of the database. In repair mode, the objects holding them are fixed or removed
+// in a single transaction, nothing is repaired when a fix fails. The project folders of the removed stacks are
+// removed once the transaction is committed.
+func (store *Store) CheckIntegrity(repair bool) (*portainer.IntegrityReport, error) {
+	checker := &integrityChecker{
+		repair:        repair,
+		report:        &portainer.IntegrityReport{Issues: make([]portainer.IntegrityIssue, 0)},
+		removedStacks: make([]portainer.Stack, 0),
+	}
+
+	var err error
+	if repair {
+		err = store.connection.Update(checker.run)
+	} else {
+		err = store.connection.View(checker.run)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	checker.report.Repaired = repair && len(checker.report.Issues) > 0
+	if checker.report.Repaired {
+		log.Printf("[INFO] [bolt,integrity] [message: database references repaired] [issues: %d]", len(checker.report.Issues))
+	}
+
+	for _, removedStack := range checker.removedStacks {
+		if removedStack.ProjectPath == "" {
+			continue
+		}
+
+		err := store.fileService.RemoveDirectory(removedStack.ProjectPath)
+		if err != nil {
+			log.Printf("[WARN] [bolt,integrity] [message: unable to remove the files of a removed stack] [stack: %s] [error: %s]", removedStack.Name, err)
+		}
+	}
+
+	return checker.report, nil
+}
+
+// integrityChecker checks the references of the objects inside a transaction. The objects holding a
+// reference to a missing object are only written in repair mode.
+type integrityChecker struct {
+	tx     *bolt.Tx
+	repair bool
+	report *portainer.IntegrityReport
+	// stacks removed in repair mode, their project folders are removed once the transaction is committed
+	removedStacks []portainer.Stack
+
+	endpoints      map[int]bool
+	endpointGroups map[int]bool
+	tags           map[int]bool
+	teams          map[int]bool
+	users          map[int]bool
+	edgeGroups     map[int]bool
+	edgeStacks     map[int]bool
+}
+
+func (checker *integrityChecker) run(tx *bolt.Tx) error {
+	checker.tx = tx
+	checker.endpoints = checker.identifiers(endpoint.BucketName)
+	checker.endpointGroups = checker.identifiers(endpointgroup.BucketName)
+	checker.tags = checker.identifiers(tag.BucketName)
+	checker.teams = checker.identifiers(team.BucketName)
+	checker.users = checker.identifiers(user.BucketName)
+	checker.edgeGroups = checker.identifiers(edgegroup.BucketName)
+	checker.edgeStacks = checker.identifiers(edgestack.BucketName)
+
+	checks := []func() error{
+		checker.checkEndpoints,
+		checker.checkEndpointGroups,
+		checker.checkTags,
+		checker.checkRegistries,
+		checker.checkStacks,
+		checker.checkWebhooks,
+		checker.checkResourceControls,
+		checker.checkEdgeGroups,
+		checker.checkEdgeStacks,
+		checker.checkEdgeJobs,
+		checker.checkEndpointRelations,
+		checker.checkSnapshots,
+		checker.checkTeamMemberships,
+		checker.checkAPIKeys,
+		checker.checkIssuedTokens,
+	}
+
+	for _, check := range checks {
+		err := check()
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// identifiers returns the identifiers of the objects of a bucket keyed by their integer identifier
+func (checker *integrityChecker) identifiers(bucketName string) map[int]bool {
+	identifiers := make(map[int]bool)
+
+	cursor := checker.tx.Bucket([]byte(bucketName)).Cursor()
+	for k, _ := cursor.First(); k != nil; k, _ = cursor.Next() {
+		identifiers[int(binary.BigEndian.Uint64(k))] = true
+	}
+
+	return identifiers
+}
+
+func (checker *integrityChecker) addIssue(bucketName string, id int, description, repair string) {
+	checker.report.Issues = append(checker.report.Issues, portainer.IntegrityIssue{
+		Bucket:      bucketName,
+		Key:         strconv.Itoa(id),
+		Description: description,
+		Repair:      repair,
+	})
+}
+
+func (checker *integrityChecker) update(bucketName string, key []byte, object interface{}, indexes ...internal.Index) error {
+	if !checker.repair {
+		return nil
+	}
+
+	return putObject(checker.tx, bucketName, key, object, indexes...)
+}
+
+func (checker *integrityChecker) remove(bucketName string, key []byte, indexes ...internal.Index) error {
+	if !checker.repair {
+		return nil
+	}
+
+	return internal.RemoveIndexedObject(checker.tx, bucketName, key, indexes...)
+}
+
+// checkAccessPolicies removes the access policies of the missing users and teams, it returns whether a policy was removed
+func (checker *integrityChecker) checkAccessPolicies(bucketName string, id int, userAccessPolicies portainer.UserAccessPolicies, teamAccessPolicies portainer.TeamAccessPolicies) bool {
+	changed := false
+
+	for userID := range userAccessPolicies {
+		if !checker.users[int(userID)] {
+			checker.addIssue(bucketName, id, fmt.Sprintf("user %d does not exist", userID), "access policy removed")
+			delete(userAccessPolicies, userID)
+			changed = true
+		}
+	}
+
+	for teamID := range teamAccessPolicies {
+		if !checker.teams[int(teamID)] {
+			checker.addIssue(bucketName, id, fmt.Sprintf("team %d does not exist", teamID), "access policy removed")
+			delete(teamAccessPolicies, teamID)
+			changed = true
+		}
+	}
+
+	return changed
+}
+
+// checkTagIDs returns the tags of an object that exist, along with whether a tag was missing
+func (checker *integrityChecker) checkTagIDs(bucketName string, id int, tagIDs []portainer.TagID) ([]portainer.TagID, bool) {
+	existingTagIDs := make([]portainer.TagID, 0, len(tagIDs))
+	for _, tagID := range tagIDs {
+		if !checker.tags[int(tagID)] {
+			checker.addIssue(bucketName, id, fmt.Sprintf("tag %d does not exist", tagID), "tag removed")
+			continue
+		}
+		existingTagIDs = append(existingTagIDs, tagID)
+	}
+
+	return existingTagIDs, len(existingTagIDs) != len(tagIDs)
+}
+
+func (checker *integrityChecker) checkEndpoints() error {
+	for _, object := range bucketObjects(checker.tx, endpoint.BucketName) {
+		var endpointObject portainer.Endpoint
+		err := internal.UnmarshalObject(object.data, &endpointObject)
+		if err != nil {
+			return err
+		}
+
+		changed := checker.checkAccessPolicies(endpoint.BucketName, int(endpointObject.ID), endpointObject.UserAccessPolicies, endpointObject.TeamAccessPolicies)
+
+		var tagsChanged bool
+		endpointObject.TagIDs, tagsChanged = checker.checkTagIDs(endpoint.BucketName, int(endpointObject.ID), endpointObject.TagIDs)
+		changed = changed || tagsChanged
+
+		if !checker.endpointGroups[int(endpointObject.GroupID)] {
+			checker.addIssue(endpoint.BucketName, int(endpointObject.ID), fmt.Sprintf("endpoint group %d does not exist", endpointObject.GroupID), "endpoint moved to the Unassigned group")
+			endpointObject.GroupID = portainer.EndpointGroupID(1)
+			changed = true
+		}
+
+		if changed {
+			err := checker.update(endpoint.BucketName, object.key, &endpointObject)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (checker *integrityChecker) checkEndpointGroups() error {
+	for _, object := range bucketObjects(checker.tx, endpointgroup.BucketName) {
+		var group portainer.EndpointGroup
+		err := internal.UnmarshalObject(object.data, &group)
+		if err != nil {
+			return err
+		}
+
+		changed := checker.checkAccessPolicies(endpointgroup.BucketName, int(group.ID), group.UserAccessPolicies, group.TeamAccessPolicies)
+
+		var tagsChanged bool
+		group.TagIDs, tagsChanged = checker.checkTagIDs(endpointgroup.BucketName, int(group.ID), group.TagIDs)
+
+		if changed || tagsChanged {
+			err := checker.update(endpointgroup.BucketName, object.key, &group)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (checker *integrityChecker) checkTags() error {
+	for _, object := range bucketObjects(checker.tx, tag.BucketName) {
+		var tagObject portainer.Tag
+		err := internal.UnmarshalObject(object.data, &tagObject)
+		if err != nil {
+			return err
+		}
+
+		changed := false
+		for endpointID := range tagObject.Endpoints {
+			if !checker.endpoints[int(endpointID)] {
+				checker.addIssue(tag.BucketName, int(tagObject.ID), fmt.Sprintf("endpoint %d does not exist", endpointID), "endpoint removed from the tag")
+				delete(tagObject.Endpoints, endpointID)
+				changed = true
+			}
+		}
+
+		for groupID := range tagObject.EndpointGroups {
+			if !checker.endpointGroups[int(groupID)] {
+				checker.addIssue(tag.BucketName, int(tagObject.ID), fmt.Sprintf("endpoint group %d does not exist", groupID), "endpoint group removed from the tag")
+				delete(tagObject.EndpointGroups, groupID)
+				changed = true
+			}
+		}
+
+		if changed {
+			err := checker.update(tag.BucketName, object.key, &tagObject)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (checker *integrityChecker) checkRegistries() error {
+	for _, object := range bucketObjects(checker.tx, registry.BucketName) {
+		var registryObject portainer.Registry
+		err := internal.UnmarshalObject(object.data, &registryObject)
+		if err != nil {
+			return err
+		}
+
+		if checker.checkAccessPolicies(registry.BucketName, int(registryObject.ID), registryObject.UserAccessPolicies, registryObject.TeamAccessPolicies) {
+			err := checker.update(registry.BucketName, object.key, &registryObject)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (checker *integrityChecker) checkStacks() error {
+	for _, object := range bucketObjects(checker.tx, stack.BucketName) {
+		var stackObject portainer.Stack
+		err := internal.UnmarshalObject(object.data, &stackObject)
+		if err != nil {
+			return err
+		}
+
+		if !checker.endpoints[int(stackObject.EndpointID)] {
+			checker.addIssue(stack.BucketName, int(stackObject.ID), fmt.Sprintf("endpoint %d does not exist", stackObject.EndpointID), "stack and its project folder removed")
+			err := checker.remove(stack.BucketName, object.key, stack.Indexes()...)
+			if err != nil {
+				return err
+			}
+
+			if checker.repair {
+				checker.removedStacks = append(checker.removedStacks, stackObject)
+			}
+		}
+	}
+
+	return nil
+}
+
+func (checker *integrityChecker) checkWebhooks() error {
+	for _, object := range bucketObjects(checker.tx, webhook.BucketName) {
+		var webhookObject portainer.Webhook
+		err := internal.UnmarshalObject(object.data, &webhookObject)
+		if err != nil {
+			return err
+		}
+
+		if !checker.endpoints[int(webhookObject.EndpointID)] {
+			checker.addIssue(webhook.BucketName, int(webhookObject.ID), fmt.Sprintf("endpoint %d does not exist", webhookObject.EndpointID), "webhook removed")
+			err := checker.remove(webhook.BucketName, object.key, webhook.Indexes()...)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (checker *integrityChecker) checkResourceControls() error {
+	for _, object := range bucketObjects(checker.tx, resourcecontrol.BucketName) {
+		var resourceControl portainer.ResourceControl
+		err := internal.UnmarshalObject(object.data, &resourceControl)
+		if err != nil {
+			return err
+		}
+
+		id := int(resourceControl.ID)
+
+		// the resource identifier of a stack is prefixed with the identifier of its endpoint
+		if resourceControl.Type == portainer.StackResourceControl {
+			prefix := strings.SplitN(resourceControl.ResourceID, "_", 2)[0]
+			endpointID, err := strconv.Atoi(prefix)
+			if err == nil && !checker.endpoints[endpointID] {
+				checker.addIssue(resourcecontrol.BucketName, id, fmt.Sprintf("endpoint %d of stack %s does not exist", endpointID, resourceControl.ResourceID), "resource control removed")
+				err := checker.remove(resourcecontrol.BucketName, object.key, resourcecontrol.Indexes()...)
+				if err != nil {
+					return err
+				}
+				continue
+			}
+		}
+
+		userAccesses := make([]portainer.UserResourceAccess, 0, len(resourceControl.UserAccesses))
+		for _, access := range resourceControl.UserAccesses {
+			if !checker.users[int(access.UserID)] {
+				checker.addIssue(resourcecontrol.BucketName, id, fmt.Sprintf("user %d does not exist", access.UserID), "user access removed")
+				continue
+			}
+			userAccesses = append(userAccesses, access)
+		}
+
+		teamAccesses := make([]portainer.TeamResourceAccess, 0, len(resourceControl.TeamAccesses))
+		for _, access := range resourceControl.TeamAccesses {
+			if !checker.teams[int(access.TeamID)] {
+				checker.addIssue(resourcecontrol.BucketName, id, fmt.Sprintf("team %d does not exist", access.TeamID), "team access removed")
+				continue
+			}
+			teamAccesses = append(teamAccesses, access)
+		}
+
+		if len(userAccesses) != len(resourceControl.UserAccesses) || len(teamAccesses) != len(resourceControl.TeamAccesses) {
+			resourceControl.UserAccesses = userAccesses
+			resourceControl.TeamAccesses = teamAccesses
+
+			err := checker.update(resourcecontrol.BucketName, object.key, &resourceControl, resourcecontrol.Indexes()...)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (checker *integrityChecker) checkEdgeGroups() error {
+	for _, object := range bucketObjects(checker.tx, edgegroup.BucketName) {
+		var edgeGroup portainer.EdgeGroup
+		err := internal.UnmarshalObject(object.data, &edgeGroup)
+		if err != nil {
+			return err
+		}
+
+		endpointIDs := make([]portainer.EndpointID, 0, len(edgeGroup.Endpoints))
+		for _, endpointID := range edgeGroup.Endpoints {
+			if !checker.endpoints[int(endpointID)] {
+				checker.addIssue(edgegroup.BucketName, int(edgeGroup.ID), fmt.Sprintf("endpoint %d does not exist", endpointID), "endpoint removed from the edge group")
+				continue
+			}
+			endpointIDs = append(endpointIDs, endpointID)
+		}
+
+		var tagsChanged bool
+		edgeGroup.TagIDs, tagsChanged = checker.checkTagIDs(edgegroup.BucketName, int(edgeGroup.ID), edgeGroup.TagIDs)
+
+		if tagsChanged || len(endpointIDs) != len(edgeGroup.Endpoints) {
+			edgeGroup.Endpoints = endpointIDs
+
+			err := checker.update(edgegroup.BucketName, object.key, &edgeGroup)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (checker *integrityChecker) checkEdgeStacks() error {
+	for _, object := range bucketObjects(checker.tx, edgestack.BucketName) {
+		var edgeStack portainer.EdgeStack
+		err := internal.UnmarshalObject(object.data, &edgeStack)
+		if err != nil {
+			return err
+		}
+
+		changed := false
+		for endpointID := range edgeStack.Status {
+			if !checker.endpoints[int(endpointID)] {
+				checker.addIssue(edgestack.BucketName, int(edgeStack.ID), fmt.Sprintf("endpoint %d does not exist", endpointID), "endpoint status removed")
+				delete(edgeStack.Status, endpointID)
+				changed = true
+			}
+		}
+
+		edgeGroupIDs := make([]portainer.EdgeGroupID, 0, len(edgeStack.EdgeGroups))
+		for _, edgeGroupID := range edgeStack.EdgeGroups {
+			if !checker.edgeGroups[int(edgeGroupID)] {
+				checker.addIssue(edgestack.BucketName, int(edgeStack.ID), fmt.Sprintf("edge group %d does not exist", edgeGroupID), "edge group removed from the edge stack")
+				continue
+			}
+			edgeGroupIDs = append(edgeGroupIDs, edgeGroupID)
+		}
+
+		if len(edgeGroupIDs) != len(edgeStack.EdgeGroups) {
+			edgeStack.EdgeGroups = edgeGroupIDs
+			changed = true
+		}
+
+		if changed {
+			err := checker.update(edgestack.BucketName, object.key, &edgeStack)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (checker *integrityChecker) checkEdgeJobs() error {
+	for _, object := range bucketObjects(checker.tx, edgejob.BucketName) {
+		var edgeJob portainer.EdgeJob
+		err := internal.UnmarshalObject(object.data, &edgeJob)
+		if err != nil {
+			return err
+		}
+
+		changed := false
+		for endpointID := range edgeJob.Endpoints {
+			if !checker.endpoints[int(endpointID)] {
+				checker.addIssue(edgejob.BucketName, int(edgeJob.ID), fmt.Sprintf("endpoint %d does not exist", endpointID), "endpoint removed from the edge job")
+				delete(edgeJob.Endpoints, endpointID)
+				changed = true
+			}
+		}
+
+		if changed {
+			err := checker.update(edgejob.BucketName, object.key, &edgeJob)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// checkEndpointRelations removes the relations of the missing endpoints, keyed by endpoint identifier, and the
+// missing edge stacks from the relations of the other endpoints
+func (checker *integrityChecker) checkEndpointRelations() error {
+	for _, object := range bucketObjects(checker.tx, endpointrelation.BucketName) {
+		endpointID := int(binary.BigEndian.Uint64(object.key))
+		if !checker.endpoints[endpointID] {
+			checker.addIssue(endpointrelation.BucketName, endpointID, fmt.Sprintf("endpoint %d does not exist", endpointID), "endpoint relation removed")
+			err := checker.remove(endpointrelation.BucketName, object.key)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		var relation portainer.EndpointRelation
+		err := internal.UnmarshalObject(object.data, &relation)
+		if err != nil {
+			return err
+		}
+
+		changed := false
+		for edgeStackID := range relation.EdgeStacks {
+			if !checker.edgeStacks[int(edgeStackID)] {
+				checker.addIssue(endpointrelation.BucketName, endpointID, fmt.Sprintf("edge stack %d does not exist", edgeStackID), "edge stack removed from the endpoint relation")
+				delete(relation.EdgeStacks, edgeStackID)
+				changed = true
+			}
+		}
+
+		if changed {
+			err := checker.update(endpointrelation.BucketName, object.key, &relation)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// checkSnapshots removes the snapshots of the missing endpoints, keyed by endpoint identifier
+func (checker *integrityChecker) checkSnapshots() error {
+	for _, object := range bucketObjects(checker.tx, snapshot.BucketName) {
+		endpointID := int(binary.BigEndian.Uint64(object.key))
+		if !checker.endpoints[endpointID] {
+			checker.addIssue(snapshot.BucketName, endpointID, fmt.Sprintf("endpoint %d does not exist", endpointID), "snapshot removed")
+			err := checker.remove(snapshot.BucketName, object.key)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (checker *integrityChecker) checkTeamMemberships() error {
+	for _, object := range bucketObjects(checker.tx, teammembership.BucketName) {
+		var membership portainer.TeamMembership
+		err := internal.UnmarshalObject(object.data, &membership)
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case !checker.users[int(membership.UserID)]:
+			checker.addIssue(teammembership.BucketName, int(membership.ID), fmt.Sprintf("user %d does not exist", membership.UserID), "team membership removed")
+		case !checker.teams[int(membership.TeamID)]:
+			checker.addIssue(teammembership.BucketName, int(membership.ID), fmt.Sprintf("team %d does not exist", membership.TeamID), "team membership removed")
+		default:
+			continue
+		}
+
+		err = checker.remove(teammembership.BucketName, object.key)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (checker *integrityChecker) checkAPIKeys() error {
+	for _, object := range bucketObjects(checker.tx, apikey.BucketName) {
+		var apiKey portainer.APIKey
+		err := internal.UnmarshalObject(object.data, &apiKey)
+		if err != nil {
+			return err
+		}
+
+		if !checker.users[int(apiKey.UserID)] {
+			checker.addIssue(apikey.BucketName, int(apiKey.ID), fmt.Sprintf("user %d does not exist", apiKey.UserID), "API key removed")
+			err := checker.remove(apikey.BucketName, object.key, apikey.Indexes()...)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// checkIssuedTokens removes the tokens of the missing users, the issued tokens are keyed by token identifier
+func (checker *integrityChecker) checkIssuedTokens() error {
+	for _, object := range bucketObjects(checker.tx, issuedtoken.BucketName) {
+		var token portainer.IssuedToken
+		err := internal.UnmarshalObject(object.data, &token)
+		if err != nil {
+			return err
+		}
+
+		if !checker.users[int(token.UserID)] {
+			checker.report.Issues = append(checker.report.Issues, portainer.IntegrityIssue{
+				Bucket:      issuedtoken.BucketName,
+				Key:         token.ID,
+				Description: fmt.Sprintf("user %d does not exist", token.UserID),
+				Repair:      "issued token removed",
+			})
+
+			err := checker.remove(issuedtoken.BucketName, object.key)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
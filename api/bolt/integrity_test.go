@@ -0,0 +1,158 @@
+package bolt_test
+
+import (
+	"testing"
+
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/bolt"
+	"github.com/portainer/portainer/api/bolt/bolttest"
+	"github.com/portainer/portainer/api/bolt/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+// createDanglingReferences creates the objects of an endpoint, a user, an edge group and an edge stack that
+// no longer exist
+func createDanglingReferences(t *testing.T, store *bolt.Store, projectPath string) {
+	is := assert.New(t)
+
+	is.NoError(store.UserService.CreateUser(&portainer.User{ID: 1, Username: "admin", Role: portainer.AdministratorRole}))
+	is.NoError(store.TeamService.CreateTeam(&portainer.Team{ID: 1, Name: "team"}))
+	is.NoError(store.TagService.CreateTag(&portainer.Tag{
+		ID:        1,
+		Name:      "tag",
+		Endpoints: map[portainer.EndpointID]bool{1: true, 7: true},
+	}))
+	is.NoError(store.EndpointService.CreateEndpoint(&portainer.Endpoint{
+		ID:                 1,
+		Name:               "local",
+		GroupID:            1,
+		TagIDs:             []portainer.TagID{1, 4},
+		UserAccessPolicies: portainer.UserAccessPolicies{1: {}, 5: {}},
+		TeamAccessPolicies: portainer.TeamAccessPolicies{1: {}},
+	}))
+
+	is.NoError(store.StackService.CreateStack(&portainer.Stack{ID: 1, Name: "web", EndpointID: 7, ProjectPath: projectPath}))
+	is.NoError(store.ResourceControlService.CreateResourceControl(&portainer.ResourceControl{
+		ResourceID: "7_web",
+		Type:       portainer.StackResourceControl,
+	}))
+	is.NoError(store.ResourceControlService.CreateResourceControl(&portainer.ResourceControl{
+		ResourceID:   "container",
+		Type:         portainer.ContainerResourceControl,
+		UserAccesses: []portainer.UserResourceAccess{{UserID: 1}, {UserID: 5}},
+	}))
+	is.NoError(store.WebhookService.CreateWebhook(&portainer.Webhook{Token: "token", ResourceID: "service", EndpointID: 7}))
+	is.NoError(store.SnapshotService.UpdateSnapshot(&portainer.Snapshot{EndpointID: 7}))
+	is.NoError(store.EdgeGroupService.CreateEdgeGroup(&portainer.EdgeGroup{ID: 1, Name: "edge", Endpoints: []portainer.EndpointID{1, 7}}))
+	is.NoError(store.EdgeStackService.CreateEdgeStack(&portainer.EdgeStack{ID: 1, Name: "edge", EdgeGroups: []portainer.EdgeGroupID{1, 9}}))
+	is.NoError(store.EndpointRelationService.CreateEndpointRelation(&portainer.EndpointRelation{
+		EndpointID: 1,
+		EdgeStacks: map[portainer.EdgeStackID]bool{1: true, 8: true},
+	}))
+	is.NoError(store.TeamMembershipService.CreateTeamMembership(&portainer.TeamMembership{UserID: 5, TeamID: 1}))
+}
+
+func Test_CheckIntegrity_ReportsTheReferencesToMissingObjects(t *testing.T) {
+	is := assert.New(t)
+
+	store, teardown := bolttest.MustNewTestStore(true)
+	defer teardown()
+
+	projectPath := t.TempDir()
+	createDanglingReferences(t, store, projectPath)
+
+	report, err := store.CheckIntegrity(false)
+	is.NoError(err)
+	is.False(report.Repaired)
+
+	issues := make(map[string]int)
+	for _, issue := range report.Issues {
+		issues[issue.Bucket]++
+	}
+	is.Equal(map[string]int{
+		"endpoints":          2,
+		"tags":               1,
+		"stacks":             1,
+		"webhooks":           1,
+		"resource_control":   2,
+		"edgegroups":         1,
+		"edge_stack":         1,
+		"endpoint_relations": 1,
+		"snapshots":          1,
+		"team_membership":    1,
+	}, issues)
+
+	_, err = store.StackService.StackByName("web")
+	is.NoError(err, "a check should not change the database")
+	is.DirExists(projectPath)
+
+	again, err := store.CheckIntegrity(false)
+	is.NoError(err)
+	is.Equal(report, again)
+}
+
+func Test_CheckIntegrity_RepairsTheReferencesToMissingObjects(t *testing.T) {
+	is := assert.New(t)
+
+	store, teardown := bolttest.MustNewTestStore(true)
+	defer teardown()
+
+	projectPath := t.TempDir()
+	createDanglingReferences(t, store, projectPath)
+
+	report, err := store.CheckIntegrity(true)
+	is.NoError(err)
+	is.True(report.Repaired)
+	is.Len(report.Issues, 12)
+
+	endpoint, err := store.EndpointService.Endpoint(1)
+	is.NoError(err)
+	is.Equal([]portainer.TagID{1}, endpoint.TagIDs)
+	is.Equal(portainer.UserAccessPolicies{1: {}}, endpoint.UserAccessPolicies)
+	is.Equal(portainer.TeamAccessPolicies{1: {}}, endpoint.TeamAccessPolicies)
+
+	tag, err := store.TagService.Tag(1)
+	is.NoError(err)
+	is.Equal(map[portainer.EndpointID]bool{1: true}, tag.Endpoints)
+
+	_, err = store.StackService.StackByName("web")
+	is.Equal(errors.ErrObjectNotFound, err)
+	is.NoDirExists(projectPath)
+
+	edgeStack, err := store.EdgeStackService.EdgeStack(1)
+	is.NoError(err)
+	is.Equal([]portainer.EdgeGroupID{1}, edgeStack.EdgeGroups)
+
+	relation, err := store.EndpointRelationService.EndpointRelation(1)
+	is.NoError(err)
+	is.Equal(map[portainer.EdgeStackID]bool{1: true}, relation.EdgeStacks)
+
+	_, err = store.WebhookService.WebhookByToken("token")
+	is.Equal(errors.ErrObjectNotFound, err)
+
+	resourceControl, err := store.ResourceControlService.ResourceControlByResourceIDAndType("7_web", portainer.StackResourceControl)
+	is.NoError(err)
+	is.Nil(resourceControl)
+
+	resourceControl, err = store.ResourceControlService.ResourceControlByResourceIDAndType("container", portainer.ContainerResourceControl)
+	is.NoError(err)
+	is.Equal([]portainer.UserResourceAccess{{UserID: 1}}, resourceControl.UserAccesses)
+
+	_, err = store.SnapshotService.Snapshot(7)
+	is.Equal(errors.ErrObjectNotFound, err)
+
+	edgeGroups, err := store.EdgeGroupService.EdgeGroups()
+	is.NoError(err)
+	if is.Len(edgeGroups, 1) {
+		is.Equal([]portainer.EndpointID{1}, edgeGroups[0].Endpoints)
+	}
+
+	memberships, err := store.TeamMembershipService.TeamMemberships()
+	is.NoError(err)
+	is.Empty(memberships)
+
+	report, err = store.CheckIntegrity(true)
+	is.NoError(err)
+	is.False(report.Repaired)
+	is.Empty(report.Issues)
+}
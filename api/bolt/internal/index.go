@@ -0,0 +1,180 @@
+package internal
+
+import (
+	"bytes"
+
+	"github.com/boltdb/bolt"
+)
+
+// indexKeySeparator separates the index key from the key of the object in the entries of an index
+const indexKeySeparator = 0x00
+
+// Index is a secondary index of the objects of a bucket, stored in its own bucket. An index entry maps an
+// index key to the key of an object, several objects can share an index key. The entries are kept up to date
+// by writing the objects with PutIndexedObject and removing them with DeleteIndexedObject.
+type Index struct {
+	// BucketName is the name of the bucket holding the entries of the index
+	BucketName string
+	// Keys returns the index keys of an object from its marshalled data
+	Keys func(data []byte) ([]string, error)
+}
+
+// CreateIndexBuckets creates the buckets of the indexes inside a bolt database.
+func CreateIndexBuckets(connection *DbConnection, indexes ...Index) error {
+	for _, index := range indexes {
+		err := CreateBucket(connection, index.BucketName)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Lookup returns the keys of the objects indexed under the index key, in the order of the keys.
+func (index Index) Lookup(tx *bolt.Tx, indexKey string) [][]byte {
+	keys := make([][]byte, 0)
+
+	prefix := append([]byte(indexKey), indexKeySeparator)
+	cursor := tx.Bucket([]byte(index.BucketName)).Cursor()
+	for k, _ := cursor.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, _ = cursor.Next() {
+		key := make([]byte, len(k)-len(prefix))
+		copy(key, k[len(prefix):])
+		keys = append(keys, key)
+	}
+
+	return keys
+}
+
+func (index Index) put(tx *bolt.Tx, key, data []byte) error {
+	indexKeys, err := index.Keys(data)
+	if err != nil {
+		return err
+	}
+
+	bucket := tx.Bucket([]byte(index.BucketName))
+	for _, indexKey := range indexKeys {
+		err := bucket.Put(indexEntry(indexKey, key), []byte{})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (index Index) delete(tx *bolt.Tx, key, data []byte) error {
+	indexKeys, err := index.Keys(data)
+	if err != nil {
+		return err
+	}
+
+	bucket := tx.Bucket([]byte(index.BucketName))
+	for _, indexKey := range indexKeys {
+		err := bucket.Delete(indexEntry(indexKey, key))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func indexEntry(indexKey string, key []byte) []byte {
+	entry := make([]byte, 0, len(indexKey)+1+len(key))
+	entry = append(entry, indexKey...)
+	entry = append(entry, indexKeySeparator)
+	return append(entry, key...)
+}
+
+// PutIndexedObject saves the marshalled data of an object inside a transaction and updates the entries of
+// the indexes of the object.
+func PutIndexedObject(tx *bolt.Tx, bucketName string, key, data []byte, indexes ...Index) error {
+	bucket := tx.Bucket([]byte(bucketName))
+
+	previousData := bucket.Get(key)
+	if previousData != nil {
+		for _, index := range indexes {
+			err := index.delete(tx, key, previousData)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	err := bucket.Put(key, data)
+	if err != nil {
+		return err
+	}
+
+	for _, index := range indexes {
+		err := index.put(tx, key, data)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// UpdateIndexedObject is a generic function used to update an object and its index entries inside a bolt database.
+func UpdateIndexedObject(connection *DbConnection, bucketName string, key []byte, object interface{}, indexes ...Index) error {
+	return connection.Update(func(tx *bolt.Tx) error {
+		data, err := MarshalObject(object)
+		if err != nil {
+			return err
+		}
+
+		return PutIndexedObject(tx, bucketName, key, data, indexes...)
+	})
+}
+
+// RemoveIndexedObject deletes an object inside a transaction, along with the entries of the indexes of the object.
+func RemoveIndexedObject(tx *bolt.Tx, bucketName string, key []byte, indexes ...Index) error {
+	bucket := tx.Bucket([]byte(bucketName))
+
+	data := bucket.Get(key)
+	if data != nil {
+		for _, index := range indexes {
+			err := index.delete(tx, key, data)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return bucket.Delete(key)
+}
+
+// DeleteIndexedObject is a generic function used to delete an object and its index entries inside a bolt database.
+func DeleteIndexedObject(connection *DbConnection, bucketName string, key []byte, indexes ...Index) error {
+	return connection.Update(func(tx *bolt.Tx) error {
+		return RemoveIndexedObject(tx, bucketName, key, indexes...)
+	})
+}
+
+// RebuildIndexes is a generic function used to compute the entries of the indexes of a bucket again,
+// from every object of the bucket.
+func RebuildIndexes(connection *DbConnection, bucketName string, indexes ...Index) error {
+	return connection.Update(func(tx *bolt.Tx) error {
+		for _, index := range indexes {
+			err := tx.DeleteBucket([]byte(index.BucketName))
+			if err != nil && err != bolt.ErrBucketNotFound {
+				return err
+			}
+
+			_, err = tx.CreateBucket([]byte(index.BucketName))
+			if err != nil {
+				return err
+			}
+		}
+
+		cursor := tx.Bucket([]byte(bucketName)).Cursor()
+		for k, v := cursor.First(); k != nil; k, v = cursor.Next() {
+			for _, index := range indexes {
+				err := index.put(tx, k, v)
+				if err != nil {
+					return err
+				}
+			}
+		}
+
+		return nil
+	})
+}
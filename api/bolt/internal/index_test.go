@@ -0,0 +1,100 @@
+package internal
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/boltdb/bolt"
+	"github.com/stretchr/testify/assert"
+)
+
+type indexedObject struct {
+	Name string
+	Tags []string
+}
+
+var testIndex = Index{
+	BucketName: "objects_by_tag",
+	Keys: func(data []byte) ([]string, error) {
+		var object indexedObject
+		err := json.Unmarshal(data, &object)
+		return object.Tags, err
+	},
+}
+
+func newIndexTestConnection(t *testing.T) (*DbConnection, func()) {
+	dir, err := ioutil.TempDir("", "boltdb")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := bolt.Open(filepath.Join(dir, "portainer.db"), 0600, &bolt.Options{Timeout: 1 * time.Second})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	connection := &DbConnection{DB: db}
+	if err := CreateBucket(connection, "objects"); err != nil {
+		t.Fatal(err)
+	}
+	if err := CreateIndexBuckets(connection, testIndex); err != nil {
+		t.Fatal(err)
+	}
+
+	return connection, func() {
+		db.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+func lookup(connection *DbConnection, indexKey string) [][]byte {
+	var keys [][]byte
+	connection.View(func(tx *bolt.Tx) error {
+		keys = testIndex.Lookup(tx, indexKey)
+		return nil
+	})
+	return keys
+}
+
+func Test_IndexedObjects_KeepTheIndexUpToDate(t *testing.T) {
+	is := assert.New(t)
+
+	connection, teardown := newIndexTestConnection(t)
+	defer teardown()
+
+	is.NoError(UpdateIndexedObject(connection, "objects", Itob(1), indexedObject{Name: "first", Tags: []string{"a", "b"}}, testIndex))
+	is.NoError(UpdateIndexedObject(connection, "objects", Itob(2), indexedObject{Name: "second", Tags: []string{"a", "ab"}}, testIndex))
+
+	is.Equal([][]byte{Itob(1), Itob(2)}, lookup(connection, "a"))
+	is.Equal([][]byte{Itob(1)}, lookup(connection, "b"))
+	is.Equal([][]byte{Itob(2)}, lookup(connection, "ab"))
+
+	is.NoError(UpdateIndexedObject(connection, "objects", Itob(1), indexedObject{Name: "first", Tags: []string{"c"}}, testIndex))
+	is.Equal([][]byte{Itob(2)}, lookup(connection, "a"))
+	is.Empty(lookup(connection, "b"))
+	is.Equal([][]byte{Itob(1)}, lookup(connection, "c"))
+
+	is.NoError(DeleteIndexedObject(connection, "objects", Itob(2), testIndex))
+	is.Empty(lookup(connection, "a"))
+	is.Empty(lookup(connection, "ab"))
+}
+
+func Test_RebuildIndexes_IndexesTheObjectsWrittenDirectly(t *testing.T) {
+	is := assert.New(t)
+
+	connection, teardown := newIndexTestConnection(t)
+	defer teardown()
+
+	is.NoError(UpdateIndexedObject(connection, "objects", Itob(1), indexedObject{Name: "first", Tags: []string{"a"}}, testIndex))
+	is.NoError(UpdateObject(connection, "objects", Itob(1), indexedObject{Name: "first", Tags: []string{"b"}}))
+	is.NoError(UpdateObject(connection, "objects", Itob(2), indexedObject{Name: "second", Tags: []string{"b"}}))
+
+	is.NoError(RebuildIndexes(connection, "objects", testIndex))
+
+	is.Empty(lookup(connection, "a"))
+	is.Equal([][]byte{Itob(1), Itob(2)}, lookup(connection, "b"))
+}
@@ -0,0 +1,165 @@
+package issuedtoken
+
+import (
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/bolt/errors"
+	"github.com/portainer/portainer/api/bolt/internal"
+
+	"github.com/boltdb/bolt"
+)
+
+const (
+	// BucketName represents the name of the bucket where this service stores data.
+	BucketName = "issued_tokens"
+)
+
+// Service represents a service for managing issued JWT tokens.
+// Tokens are indexed by their token ID (jti).
+type Service struct {
+	connection *internal.DbConnection
+}
+
+// NewService creates a new instance of a service.
+func NewService(connection *internal.DbConnection) (*Service, error) {
+	err := internal.CreateBucket(connection, BucketName)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Service{
+		connection: connection,
+	}, nil
+}
+
+// IssuedToken returns an issued token by its token ID.
+func (service *Service) IssuedToken(ID string) (*portainer.IssuedToken, error) {
+	var token portainer.IssuedToken
+
+	err := internal.GetObject(service.connection, BucketName, []byte(ID), &token)
+	if err != nil {
+		return nil, err
+	}
+
+	return &token, nil
+}
+
+// IssuedTokens returns an array containing all the issued tokens.
+func (service *Service) IssuedTokens() ([]portainer.IssuedToken, error) {
+	var tokens = make([]portainer.IssuedToken, 0)
+
+	err := service.connection.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(BucketName))
+
+		cursor := bucket.Cursor()
+		for k, v := cursor.First(); k != nil; k, v = cursor.Next() {
+			var token portainer.IssuedToken
+			err := internal.UnmarshalObject(v, &token)
+			if err != nil {
+				return err
+			}
+
+			tokens = append(tokens, token)
+		}
+
+		return nil
+	})
+
+	return tokens, err
+}
+
+// IssuedTokensByUserID returns an array containing all the tokens issued to the specified user.
+func (service *Service) IssuedTokensByUserID(userID portainer.UserID) ([]portainer.IssuedToken, error) {
+	var tokens = make([]portainer.IssuedToken, 0)
+
+	err := service.connection.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(BucketName))
+
+		cursor := bucket.Cursor()
+		for k, v := cursor.First(); k != nil; k, v = cursor.Next() {
+			var token portainer.IssuedToken
+			err := internal.UnmarshalObject(v, &token)
+			if err != nil {
+				return err
+			}
+
+			if token.UserID == userID {
+				tokens = append(tokens, token)
+			}
+		}
+
+		return nil
+	})
+
+	return tokens, err
+}
+
+// CreateIssuedToken records a new issued token.
+func (service *Service) CreateIssuedToken(token *portainer.IssuedToken) error {
+	return internal.UpdateObject(service.connection, BucketName, []byte(token.ID), token)
+}
+
+// UpdateIssuedToken saves an issued token.
+func (service *Service) UpdateIssuedToken(ID string, token *portainer.IssuedToken) error {
+	return internal.UpdateObject(service.connection, BucketName, []byte(ID), token)
+}
+
+// UpdateIssuedTokenActivity records the client and the date of the last request authenticated with a token.
+// The token is read and saved in the same transaction so that a concurrent revocation is not overwritten.
+func (service *Service) UpdateIssuedTokenActivity(ID string, clientIP, userAgent string, lastActivity int64) error {
+	return service.connection.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(BucketName))
+
+		value := bucket.Get([]byte(ID))
+		if value == nil {
+			return errors.ErrObjectNotFound
+		}
+
+		var token portainer.IssuedToken
+		err := internal.UnmarshalObject(value, &token)
+		if err != nil {
+			return err
+		}
+
+		token.ClientIP = clientIP
+		token.UserAgent = userAgent
+		token.LastActivity = lastActivity
+
+		data, err := internal.MarshalObject(&token)
+		if err != nil {
+			return err
+		}
+
+		return bucket.Put([]byte(ID), data)
+	})
+}
+
+// DeleteExpiredIssuedTokens removes the tokens that expired before now, a unix timestamp.
+// Expired tokens are rejected by the JWT signature check and no longer need to be tracked.
+func (service *Service) DeleteExpiredIssuedTokens(now int64) error {
+	return service.connection.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(BucketName))
+
+		var expired [][]byte
+		cursor := bucket.Cursor()
+		for k, v := cursor.First(); k != nil; k, v = cursor.Next() {
+			var token portainer.IssuedToken
+			err := internal.UnmarshalObject(v, &token)
+			if err != nil {
+				return err
+			}
+
+			if token.ExpiresAt < now {
+				expired = append(expired, k)
+			}
+		}
+
+		for _, key := range expired {
+			err := bucket.Delete(key)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
@@ -0,0 +1,82 @@
+package jwtsigningkey
+
+import (
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/bolt/internal"
+
+	"github.com/boltdb/bolt"
+)
+
+const (
+	// BucketName represents the name of the bucket where this service stores data.
+	BucketName = "jwt_signing_keys"
+)
+
+// Service represents a service for managing JWT signing keys.
+type Service struct {
+	connection *internal.DbConnection
+}
+
+// NewService creates a new instance of a service.
+func NewService(connection *internal.DbConnection) (*Service, error) {
+	err := internal.CreateBucket(connection, BucketName)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Service{
+		connection: connection,
+	}, nil
+}
+
+// SigningKeys returns an array containing all the signing keys.
+func (service *Service) SigningKeys() ([]portainer.JWTSigningKey, error) {
+	var keys = make([]portainer.JWTSigningKey, 0)
+
+	err := service.connection.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(BucketName))
+
+		cursor := bucket.Cursor()
+		for k, v := cursor.First(); k != nil; k, v = cursor.Next() {
+			var key portainer.JWTSigningKey
+			err := internal.UnmarshalObject(v, &key)
+			if err != nil {
+				return err
+			}
+			keys = append(keys, key)
+		}
+
+		return nil
+	})
+
+	return keys, err
+}
+
+// CreateSigningKey creates a new signing key.
+func (service *Service) CreateSigningKey(key *portainer.JWTSigningKey) error {
+	return service.connection.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(BucketName))
+
+		id, _ := bucket.NextSequence()
+		key.ID = portainer.JWTSigningKeyID(id)
+
+		data, err := internal.MarshalObject(key)
+		if err != nil {
+			return err
+		}
+
+		return bucket.Put(internal.Itob(int(key.ID)), data)
+	})
+}
+
+// UpdateSigningKey saves a signing key.
+func (service *Service) UpdateSigningKey(ID portainer.JWTSigningKeyID, key *portainer.JWTSigningKey) error {
+	identifier := internal.Itob(int(ID))
+	return internal.UpdateObject(service.connection, BucketName, identifier, key)
+}
+
+// DeleteSigningKey deletes a signing key.
+func (service *Service) DeleteSigningKey(ID portainer.JWTSigningKeyID) error {
+	identifier := internal.Itob(int(ID))
+	return internal.DeleteObject(service.connection, BucketName, identifier)
+}
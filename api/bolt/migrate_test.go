@@ -0,0 +1,81 @@
+package bolt
+
+import (
+	"testing"
+
+	"github.com/boltdb/bolt"
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/bolt/internal"
+	"github.com/portainer/portainer/api/bolt/resourcecontrol"
+	"github.com/portainer/portainer/api/bolt/stack"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_MigrateData_LooksUpTheObjectsWrittenBeforeTheIndexes(t *testing.T) {
+	is := assert.New(t)
+
+	store, teardown := newSnapshotTestStore(t)
+	defer teardown()
+
+	is.NoError(store.Init())
+	is.NoError(store.VersionService.StoreDBVersion(26))
+
+	// the objects of a database prior to version 32 are written without their index entries
+	err := store.connection.Update(func(tx *bolt.Tx) error {
+		objects := map[string]interface{}{
+			stack.BucketName:           &portainer.Stack{ID: 1, Name: "web", EndpointID: 3},
+			resourcecontrol.BucketName: &portainer.ResourceControl{ID: 1, ResourceID: "web", Type: portainer.StackResourceControl},
+		}
+
+		for bucketName, object := range objects {
+			data, err := internal.MarshalObject(object)
+			if err != nil {
+				return err
+			}
+
+			err = tx.Bucket([]byte(bucketName)).Put(internal.Itob(1), data)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	is.NoError(err)
+
+	is.NoError(store.MigrateData(true))
+
+	resourceControl, err := store.ResourceControlService.ResourceControl(1)
+	is.NoError(err)
+	is.Equal("3_web", resourceControl.ResourceID)
+}
+
+func Test_MigrateData_MovesTheKubernetesSnapshotsToTheSnapshotsBucket(t *testing.T) {
+	is := assert.New(t)
+
+	store, teardown := newSnapshotTestStore(t)
+	defer teardown()
+
+	is.NoError(store.Init())
+	is.NoError(store.VersionService.StoreDBVersion(37))
+
+	kubernetesSnapshot := portainer.KubernetesSnapshot{KubernetesVersion: "1.20", NodeCount: 3}
+	is.NoError(store.EndpointService.CreateEndpoint(&portainer.Endpoint{
+		ID:         1,
+		Name:       "kubernetes",
+		Type:       portainer.KubernetesLocalEnvironment,
+		Kubernetes: portainer.KubernetesData{Snapshots: []portainer.KubernetesSnapshot{kubernetesSnapshot}},
+	}))
+
+	is.NoError(store.MigrateData(true))
+
+	endpoint, err := store.EndpointService.Endpoint(1)
+	is.NoError(err)
+	is.Empty(endpoint.Kubernetes.Snapshots)
+
+	snapshot, err := store.SnapshotService.Snapshot(1)
+	is.NoError(err)
+	if is.NotNil(snapshot.Kubernetes) {
+		is.Equal(kubernetesSnapshot, *snapshot.Kubernetes)
+	}
+}
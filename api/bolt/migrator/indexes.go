@@ -0,0 +1,31 @@
+package migrator
+
+// rebuildIndexes computes the secondary indexes of the buckets again from their objects
+func (m *Migrator) rebuildIndexes() error {
+	err := m.resourceControlService.RebuildIndexes()
+	if err != nil {
+		return err
+	}
+
+	err = m.stackService.RebuildIndexes()
+	if err != nil {
+		return err
+	}
+
+	err = m.teamService.RebuildIndexes()
+	if err != nil {
+		return err
+	}
+
+	err = m.userService.RebuildIndexes()
+	if err != nil {
+		return err
+	}
+
+	err = m.apiKeyService.RebuildIndexes()
+	if err != nil {
+		return err
+	}
+
+	return m.webhookService.RebuildIndexes()
+}
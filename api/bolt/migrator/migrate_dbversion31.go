@@ -0,0 +1,23 @@
+package migrator
+
+import portainer "github.com/portainer/portainer/api"
+
+func (m *Migrator) migrateDBVersionTo31() error {
+	return m.updateRateLimitSettingsToDB31()
+}
+
+// updateRateLimitSettingsToDB31 keeps the rate limiting of the authentication routes that used to be hard-coded
+func (m *Migrator) updateRateLimitSettingsToDB31() error {
+	settings, err := m.settingsService.Settings()
+	if err != nil {
+		return err
+	}
+
+	settings.RateLimiting.Authentication = portainer.RateLimitPolicy{
+		MaxRequests: portainer.DefaultAuthenticationRateLimitMaxRequests,
+		Period:      portainer.DefaultAuthenticationRateLimitPeriod,
+		BanDuration: portainer.DefaultAuthenticationRateLimitBanDuration,
+	}
+
+	return m.settingsService.UpdateSettings(settings)
+}
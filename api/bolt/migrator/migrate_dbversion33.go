@@ -0,0 +1,53 @@
+package migrator
+
+import portainer "github.com/portainer/portainer/api"
+
+func (m *Migrator) migrateDBVersionTo33() error {
+	return m.moveEndpointSnapshotsToDB33()
+}
+
+// moveEndpointSnapshotsToDB33 moves the snapshots of the endpoints to the snapshots bucket. The endpoints keep
+// the summary of their Docker snapshot, without the raw data, and no Kubernetes snapshot.
+func (m *Migrator) moveEndpointSnapshotsToDB33() error {
+	endpoints, err := m.endpointService.Endpoints()
+	if err != nil {
+		return err
+	}
+
+	for idx := range endpoints {
+		endpoint := &endpoints[idx]
+
+		if len(endpoint.Snapshots) == 0 && len(endpoint.Kubernetes.Snapshots) == 0 {
+			continue
+		}
+
+		snapshot := &portainer.Snapshot{EndpointID: endpoint.ID}
+
+		if len(endpoint.Snapshots) > 0 {
+			dockerSnapshot := endpoint.Snapshots[0]
+			snapshot.Docker = &dockerSnapshot
+
+			endpoint.Snapshots = endpoint.Snapshots[:1]
+			endpoint.Snapshots[0].SnapshotRaw = portainer.DockerSnapshotRaw{}
+		}
+
+		if len(endpoint.Kubernetes.Snapshots) > 0 {
+			kubernetesSnapshot := endpoint.Kubernetes.Snapshots[0]
+			snapshot.Kubernetes = &kubernetesSnapshot
+
+			endpoint.Kubernetes.Snapshots = []portainer.KubernetesSnapshot{}
+		}
+
+		err := m.snapshotService.UpdateSnapshot(snapshot)
+		if err != nil {
+			return err
+		}
+
+		err = m.endpointService.UpdateEndpoint(endpoint.ID, endpoint)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
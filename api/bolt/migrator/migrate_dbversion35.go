@@ -0,0 +1,39 @@
+package migrator
+
+import portainer "github.com/portainer/portainer/api"
+
+func (m *Migrator) migrateDBVersionTo35() error {
+	return m.updateUsersAuthenticationMethodToDB35()
+}
+
+// updateUsersAuthenticationMethodToDB35 records the authentication method of the existing accounts. The accounts
+// with a password are internal, the others were created for the authentication method that is currently enabled.
+func (m *Migrator) updateUsersAuthenticationMethodToDB35() error {
+	settings, err := m.settingsService.Settings()
+	if err != nil {
+		return err
+	}
+
+	users, err := m.userService.Users()
+	if err != nil {
+		return err
+	}
+
+	for _, user := range users {
+		switch {
+		case user.Password != "":
+			user.AuthenticationMethod = portainer.AuthenticationInternal
+		case settings.AuthenticationMethod != portainer.AuthenticationInternal:
+			user.AuthenticationMethod = settings.AuthenticationMethod
+		default:
+			continue
+		}
+
+		err = m.userService.UpdateUser(user.ID, &user)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
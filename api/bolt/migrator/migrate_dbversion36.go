@@ -0,0 +1,27 @@
+package migrator
+
+import portainer "github.com/portainer/portainer/api"
+
+func (m *Migrator) migrateDBVersionTo36() error {
+	return m.updateAccountLockoutSettingsToDB36()
+}
+
+// updateAccountLockoutSettingsToDB36 enables the default account lockout, the existing databases have no
+// lockout settings yet
+func (m *Migrator) updateAccountLockoutSettingsToDB36() error {
+	settings, err := m.settingsService.Settings()
+	if err != nil {
+		return err
+	}
+
+	if settings.AccountLockout != (portainer.AccountLockoutSettings{}) {
+		return nil
+	}
+
+	settings.AccountLockout = portainer.AccountLockoutSettings{
+		Threshold: portainer.DefaultAccountLockoutThreshold,
+		Duration:  portainer.DefaultAccountLockoutDuration,
+	}
+
+	return m.settingsService.UpdateSettings(settings)
+}
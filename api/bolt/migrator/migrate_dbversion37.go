@@ -0,0 +1,43 @@
+package migrator
+
+import (
+	"strings"
+
+	portainer "github.com/portainer/portainer/api"
+)
+
+// builtInRoleNamesToDB37 are the names of the built-in roles, indexed by their identifier
+var builtInRoleNamesToDB37 = map[portainer.RoleID]string{
+	1: "Endpoint administrator",
+	2: "Helpdesk",
+	3: "Standard user",
+	4: "Read-only user",
+}
+
+func (m *Migrator) migrateDBVersionTo37() error {
+	return m.updateBuiltInRolesToDB37()
+}
+
+// updateBuiltInRolesToDB37 flags the built-in roles. The roles created with the role management API may have
+// taken the identifiers of the built-in roles, so the name must match as well.
+func (m *Migrator) updateBuiltInRolesToDB37() error {
+	roles, err := m.roleService.Roles()
+	if err != nil {
+		return err
+	}
+
+	for _, role := range roles {
+		name, ok := builtInRoleNamesToDB37[role.ID]
+		if !ok || !strings.EqualFold(role.Name, name) {
+			continue
+		}
+
+		role.BuiltIn = true
+		err = m.roleService.UpdateRole(role.ID, &role)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,53 @@
+package migrator
+
+import (
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/bolt/errors"
+)
+
+func (m *Migrator) migrateDBVersionTo38() error {
+	return m.moveKubernetesSnapshotsToDB38()
+}
+
+// moveKubernetesSnapshotsToDB38 removes the Kubernetes snapshots from the endpoints, they are only kept in the
+// snapshots bucket. A snapshot missing from the bucket is moved there.
+func (m *Migrator) moveKubernetesSnapshotsToDB38() error {
+	endpoints, err := m.endpointService.Endpoints()
+	if err != nil {
+		return err
+	}
+
+	for idx := range endpoints {
+		endpoint := &endpoints[idx]
+
+		if len(endpoint.Kubernetes.Snapshots) == 0 {
+			continue
+		}
+
+		snapshot, err := m.snapshotService.Snapshot(endpoint.ID)
+		if err == errors.ErrObjectNotFound {
+			snapshot = &portainer.Snapshot{EndpointID: endpoint.ID}
+		} else if err != nil {
+			return err
+		}
+
+		if snapshot.Kubernetes == nil {
+			kubernetesSnapshot := endpoint.Kubernetes.Snapshots[0]
+			snapshot.Kubernetes = &kubernetesSnapshot
+
+			err := m.snapshotService.UpdateSnapshot(snapshot)
+			if err != nil {
+				return err
+			}
+		}
+
+		endpoint.Kubernetes.Snapshots = []portainer.KubernetesSnapshot{}
+
+		err = m.endpointService.UpdateEndpoint(endpoint.ID, endpoint)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
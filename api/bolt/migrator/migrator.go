@@ -3,6 +3,7 @@ package migrator
 import (
 	"github.com/boltdb/bolt"
 	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/bolt/apikey"
 	"github.com/portainer/portainer/api/bolt/endpoint"
 	"github.com/portainer/portainer/api/bolt/endpointgroup"
 	"github.com/portainer/portainer/api/bolt/endpointrelation"
@@ -12,11 +13,14 @@ import (
 	"github.com/portainer/portainer/api/bolt/role"
 	"github.com/portainer/portainer/api/bolt/schedule"
 	"github.com/portainer/portainer/api/bolt/settings"
+	"github.com/portainer/portainer/api/bolt/snapshot"
 	"github.com/portainer/portainer/api/bolt/stack"
 	"github.com/portainer/portainer/api/bolt/tag"
+	"github.com/portainer/portainer/api/bolt/team"
 	"github.com/portainer/portainer/api/bolt/teammembership"
 	"github.com/portainer/portainer/api/bolt/user"
 	"github.com/portainer/portainer/api/bolt/version"
+	"github.com/portainer/portainer/api/bolt/webhook"
 	"github.com/portainer/portainer/api/internal/authorization"
 )
 
@@ -25,6 +29,7 @@ type (
 	Migrator struct {
 		currentDBVersion        int
 		db                      *bolt.DB
+		apiKeyService           *apikey.Service
 		endpointGroupService    *endpointgroup.Service
 		endpointService         *endpoint.Service
 		endpointRelationService *endpointrelation.Service
@@ -34,11 +39,14 @@ type (
 		roleService             *role.Service
 		scheduleService         *schedule.Service
 		settingsService         *settings.Service
+		snapshotService         *snapshot.Service
 		stackService            *stack.Service
 		tagService              *tag.Service
 		teamMembershipService   *teammembership.Service
+		teamService             *team.Service
 		userService             *user.Service
 		versionService          *version.Service
+		webhookService          *webhook.Service
 		fileService             portainer.FileService
 		authorizationService    *authorization.Service
 	}
@@ -47,6 +55,7 @@ type (
 	Parameters struct {
 		DB                      *bolt.DB
 		DatabaseVersion         int
+		APIKeyService           *apikey.Service
 		EndpointGroupService    *endpointgroup.Service
 		EndpointService         *endpoint.Service
 		EndpointRelationService *endpointrelation.Service
@@ -56,11 +65,14 @@ type (
 		RoleService             *role.Service
 		ScheduleService         *schedule.Service
 		SettingsService         *settings.Service
+		SnapshotService         *snapshot.Service
 		StackService            *stack.Service
 		TagService              *tag.Service
 		TeamMembershipService   *teammembership.Service
+		TeamService             *team.Service
 		UserService             *user.Service
 		VersionService          *version.Service
+		WebhookService          *webhook.Service
 		FileService             portainer.FileService
 		AuthorizationService    *authorization.Service
 	}
@@ -71,6 +83,7 @@ func NewMigrator(parameters *Parameters) *Migrator {
 	return &Migrator{
 		db:                      parameters.DB,
 		currentDBVersion:        parameters.DatabaseVersion,
+		apiKeyService:           parameters.APIKeyService,
 		endpointGroupService:    parameters.EndpointGroupService,
 		endpointService:         parameters.EndpointService,
 		endpointRelationService: parameters.EndpointRelationService,
@@ -80,11 +93,14 @@ func NewMigrator(parameters *Parameters) *Migrator {
 		roleService:             parameters.RoleService,
 		scheduleService:         parameters.ScheduleService,
 		settingsService:         parameters.SettingsService,
+		snapshotService:         parameters.SnapshotService,
 		tagService:              parameters.TagService,
 		teamMembershipService:   parameters.TeamMembershipService,
+		teamService:             parameters.TeamService,
 		stackService:            parameters.StackService,
 		userService:             parameters.UserService,
 		versionService:          parameters.VersionService,
+		webhookService:          parameters.WebhookService,
 		fileService:             parameters.FileService,
 		authorizationService:    parameters.AuthorizationService,
 	}
@@ -92,6 +108,13 @@ func NewMigrator(parameters *Parameters) *Migrator {
 
 // Migrate checks the database version and migrate the existing data to the most recent data model.
 func (m *Migrator) Migrate() error {
+	// The migration steps look up objects by their secondary indexes, which are missing from the databases
+	// prior to version 32, or out of date once a previous migration failed. They are built before the first step.
+	err := m.rebuildIndexes()
+	if err != nil {
+		return err
+	}
+
 	// Portainer < 1.12
 	if m.currentDBVersion < 1 {
 		err := m.updateAdminUserToDBVersion1()
@@ -366,5 +389,54 @@ func (m *Migrator) Migrate() error {
 		}
 	}
 
+	if m.currentDBVersion < 31 {
+		err := m.migrateDBVersionTo31()
+		if err != nil {
+			return err
+		}
+	}
+
+	if m.currentDBVersion < 33 {
+		err := m.migrateDBVersionTo33()
+		if err != nil {
+			return err
+		}
+	}
+
+	if m.currentDBVersion < 35 {
+		err := m.migrateDBVersionTo35()
+		if err != nil {
+			return err
+		}
+	}
+
+	if m.currentDBVersion < 36 {
+		err := m.migrateDBVersionTo36()
+		if err != nil {
+			return err
+		}
+	}
+
+	if m.currentDBVersion < 37 {
+		err := m.migrateDBVersionTo37()
+		if err != nil {
+			return err
+		}
+	}
+
+	if m.currentDBVersion < 38 {
+		err := m.migrateDBVersionTo38()
+		if err != nil {
+			return err
+		}
+	}
+
+	// Some migration steps write the buckets without maintaining the secondary indexes, they are built again
+	// once every step is done.
+	err = m.rebuildIndexes()
+	if err != nil {
+		return err
+	}
+
 	return m.versionService.StoreDBVersion(portainer.DBVersion)
 }
@@ -1,6 +1,8 @@
 package resourcecontrol
 
 import (
+	"strconv"
+
 	portainer "github.com/portainer/portainer/api"
 	"github.com/portainer/portainer/api/bolt/internal"
 
@@ -12,6 +14,38 @@ const (
 	BucketName = "resource_control"
 )
 
+var (
+	// resourceIndex indexes the resource controls by the type and identifier of their resource
+	resourceIndex = internal.Index{
+		BucketName: "resource_control_by_resource",
+		Keys: func(data []byte) ([]string, error) {
+			var resourceControl portainer.ResourceControl
+			err := internal.UnmarshalObject(data, &resourceControl)
+			if err != nil {
+				return nil, err
+			}
+			return []string{resourceIndexKey(resourceControl.ResourceID, resourceControl.Type)}, nil
+		},
+	}
+
+	// subResourceIndex indexes the resource controls by the identifiers of their sub resources
+	subResourceIndex = internal.Index{
+		BucketName: "resource_control_by_sub_resource",
+		Keys: func(data []byte) ([]string, error) {
+			var resourceControl portainer.ResourceControl
+			err := internal.UnmarshalObject(data, &resourceControl)
+			if err != nil {
+				return nil, err
+			}
+			return resourceControl.SubResourceIDs, nil
+		},
+	}
+)
+
+func resourceIndexKey(resourceID string, resourceType portainer.ResourceControlType) string {
+	return strconv.Itoa(int(resourceType)) + ":" + resourceID
+}
+
 // Service represents a service for managing endpoint data.
 type Service struct {
 	connection *internal.DbConnection
@@ -24,6 +58,11 @@ func NewService(connection *internal.DbConnection) (*Service, error) {
 		return nil, err
 	}
 
+	err = internal.CreateIndexBuckets(connection, resourceIndex, subResourceIndex)
+	if err != nil {
+		return nil, err
+	}
+
 	return &Service{
 		connection: connection,
 	}, nil
@@ -45,32 +84,27 @@ func (service *Service) ResourceControl(ID portainer.ResourceControlID) (*portai
 // ResourceControlByResourceIDAndType returns a ResourceControl object by checking if the resourceID is equal
 // to the main ResourceID or in SubResourceIDs. It also performs a check on the resource type. Return nil
 // if no ResourceControl was found.
+// A ResourceControl matching the main ResourceID prevails, otherwise the first ResourceControl
+// holding the resource in its SubResourceIDs is returned, whatever its type.
 func (service *Service) ResourceControlByResourceIDAndType(resourceID string, resourceType portainer.ResourceControlType) (*portainer.ResourceControl, error) {
 	var resourceControl *portainer.ResourceControl
 
 	err := service.connection.View(func(tx *bolt.Tx) error {
-		bucket := tx.Bucket([]byte(BucketName))
-		cursor := bucket.Cursor()
-
-		for k, v := cursor.First(); k != nil; k, v = cursor.Next() {
-			var rc portainer.ResourceControl
-			err := internal.UnmarshalObject(v, &rc)
-			if err != nil {
-				return err
-			}
-
-			if rc.ResourceID == resourceID && rc.Type == resourceType {
-				resourceControl = &rc
-				break
+		keys := resourceIndex.Lookup(tx, resourceIndexKey(resourceID, resourceType))
+		if len(keys) == 0 {
+			keys = subResourceIndex.Lookup(tx, resourceID)
+			if len(keys) == 0 {
+				return nil
 			}
+			keys = keys[:1]
+		}
 
-			for _, subResourceID := range rc.SubResourceIDs {
-				if subResourceID == resourceID {
-					resourceControl = &rc
-					break
-				}
-			}
+		var rc portainer.ResourceControl
+		err := internal.UnmarshalObject(tx.Bucket([]byte(BucketName)).Get(keys[0]), &rc)
+		if err != nil {
+			return err
 		}
+		resourceControl = &rc
 
 		return nil
 	})
@@ -114,18 +148,28 @@ func (service *Service) CreateResourceControl(resourceControl *portainer.Resourc
 			return err
 		}
 
-		return bucket.Put(internal.Itob(int(resourceControl.ID)), data)
+		return internal.PutIndexedObject(tx, BucketName, internal.Itob(int(resourceControl.ID)), data, resourceIndex, subResourceIndex)
 	})
 }
 
 // UpdateResourceControl saves a ResourceControl object.
 func (service *Service) UpdateResourceControl(ID portainer.ResourceControlID, resourceControl *portainer.ResourceControl) error {
 	identifier := internal.Itob(int(ID))
-	return internal.UpdateObject(service.connection, BucketName, identifier, resourceControl)
+	return internal.UpdateIndexedObject(service.connection, BucketName, identifier, resourceControl, resourceIndex, subResourceIndex)
 }
 
 // DeleteResourceControl deletes a ResourceControl object by ID
 func (service *Service) DeleteResourceControl(ID portainer.ResourceControlID) error {
 	identifier := internal.Itob(int(ID))
-	return internal.DeleteObject(service.connection, BucketName, identifier)
+	return internal.DeleteIndexedObject(service.connection, BucketName, identifier, resourceIndex, subResourceIndex)
+}
+
+// Indexes returns the secondary indexes of the resource controls, to maintain them when the bucket is written directly.
+func Indexes() []internal.Index {
+	return []internal.Index{resourceIndex, subResourceIndex}
+}
+
+// RebuildIndexes computes the resource and sub resource indexes again from the resource controls.
+func (service *Service) RebuildIndexes() error {
+	return internal.RebuildIndexes(service.connection, BucketName, resourceIndex, subResourceIndex)
 }
@@ -0,0 +1,69 @@
+package resourcecontrol_test
+
+import (
+	"testing"
+
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/bolt/bolttest"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ResourceControlByResourceIDAndType(t *testing.T) {
+	is := assert.New(t)
+
+	store, teardown := bolttest.MustNewTestStore(false)
+	defer teardown()
+
+	service := store.ResourceControl()
+
+	container := &portainer.ResourceControl{ResourceID: "abc", Type: portainer.ContainerResourceControl}
+	is.NoError(service.CreateResourceControl(container))
+	volume := &portainer.ResourceControl{ResourceID: "abc", Type: portainer.VolumeResourceControl}
+	is.NoError(service.CreateResourceControl(volume))
+	stack := &portainer.ResourceControl{ResourceID: "1_web", Type: portainer.StackResourceControl, SubResourceIDs: []string{"def"}}
+	is.NoError(service.CreateResourceControl(stack))
+
+	resourceControl, err := service.ResourceControlByResourceIDAndType("abc", portainer.VolumeResourceControl)
+	is.NoError(err)
+	if is.NotNil(resourceControl) {
+		is.Equal(volume.ID, resourceControl.ID)
+	}
+
+	resourceControl, err = service.ResourceControlByResourceIDAndType("def", portainer.ContainerResourceControl)
+	is.NoError(err)
+	if is.NotNil(resourceControl) {
+		is.Equal(stack.ID, resourceControl.ID)
+	}
+
+	otherStack := &portainer.ResourceControl{ResourceID: "1_db", Type: portainer.StackResourceControl, SubResourceIDs: []string{"def"}}
+	is.NoError(service.CreateResourceControl(otherStack))
+
+	resourceControl, err = service.ResourceControlByResourceIDAndType("def", portainer.ContainerResourceControl)
+	is.NoError(err)
+	if is.NotNil(resourceControl) {
+		is.Equal(stack.ID, resourceControl.ID, "the first resource control holding the sub-resource should be returned")
+	}
+	is.NoError(service.DeleteResourceControl(otherStack.ID))
+
+	resourceControl, err = service.ResourceControlByResourceIDAndType("abc", portainer.NetworkResourceControl)
+	is.NoError(err)
+	is.Nil(resourceControl)
+
+	volume.ResourceID = "xyz"
+	is.NoError(service.UpdateResourceControl(volume.ID, volume))
+	stack.SubResourceIDs = []string{"ghi"}
+	is.NoError(service.UpdateResourceControl(stack.ID, stack))
+
+	resourceControl, err = service.ResourceControlByResourceIDAndType("abc", portainer.VolumeResourceControl)
+	is.NoError(err)
+	is.Nil(resourceControl)
+
+	resourceControl, err = service.ResourceControlByResourceIDAndType("def", portainer.ContainerResourceControl)
+	is.NoError(err)
+	is.Nil(resourceControl)
+
+	is.NoError(service.DeleteResourceControl(container.ID))
+	resourceControl, err = service.ResourceControlByResourceIDAndType("abc", portainer.ContainerResourceControl)
+	is.NoError(err)
+	is.Nil(resourceControl)
+}
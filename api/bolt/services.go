@@ -2,6 +2,8 @@ package bolt
 
 import (
 	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/bolt/apikey"
+	"github.com/portainer/portainer/api/bolt/auditlog"
 	"github.com/portainer/portainer/api/bolt/customtemplate"
 	"github.com/portainer/portainer/api/bolt/dockerhub"
 	"github.com/portainer/portainer/api/bolt/edgegroup"
@@ -11,11 +13,14 @@ import (
 	"github.com/portainer/portainer/api/bolt/endpointgroup"
 	"github.com/portainer/portainer/api/bolt/endpointrelation"
 	"github.com/portainer/portainer/api/bolt/extension"
+	"github.com/portainer/portainer/api/bolt/issuedtoken"
+	"github.com/portainer/portainer/api/bolt/jwtsigningkey"
 	"github.com/portainer/portainer/api/bolt/registry"
 	"github.com/portainer/portainer/api/bolt/resourcecontrol"
 	"github.com/portainer/portainer/api/bolt/role"
 	"github.com/portainer/portainer/api/bolt/schedule"
 	"github.com/portainer/portainer/api/bolt/settings"
+	"github.com/portainer/portainer/api/bolt/snapshot"
 	"github.com/portainer/portainer/api/bolt/stack"
 	"github.com/portainer/portainer/api/bolt/tag"
 	"github.com/portainer/portainer/api/bolt/team"
@@ -33,6 +38,18 @@ func (store *Store) initServices() error {
 	}
 	store.RoleService = authorizationsetService
 
+	apiKeyService, err := apikey.NewService(store.connection)
+	if err != nil {
+		return err
+	}
+	store.APIKeyService = apiKeyService
+
+	auditLogService, err := auditlog.NewService(store.connection)
+	if err != nil {
+		return err
+	}
+	store.AuditLogService = auditLogService
+
 	customTemplateService, err := customtemplate.NewService(store.connection)
 	if err != nil {
 		return err
@@ -87,6 +104,18 @@ func (store *Store) initServices() error {
 	}
 	store.ExtensionService = extensionService
 
+	issuedTokenService, err := issuedtoken.NewService(store.connection)
+	if err != nil {
+		return err
+	}
+	store.IssuedTokenService = issuedTokenService
+
+	jwtSigningKeyService, err := jwtsigningkey.NewService(store.connection)
+	if err != nil {
+		return err
+	}
+	store.JWTSigningKeyService = jwtSigningKeyService
+
 	registryService, err := registry.NewService(store.connection)
 	if err != nil {
 		return err
@@ -105,6 +134,12 @@ func (store *Store) initServices() error {
 	}
 	store.SettingsService = settingsService
 
+	snapshotService, err := snapshot.NewService(store.connection)
+	if err != nil {
+		return err
+	}
+	store.SnapshotService = snapshotService
+
 	stackService, err := stack.NewService(store.connection)
 	if err != nil {
 		return err
@@ -162,6 +197,16 @@ func (store *Store) initServices() error {
 	return nil
 }
 
+// APIKey gives access to the APIKey data management layer
+func (store *Store) APIKey() portainer.APIKeyService {
+	return store.APIKeyService
+}
+
+// AuditLog gives access to the AuditLog data management layer
+func (store *Store) AuditLog() portainer.AuditLogService {
+	return store.AuditLogService
+}
+
 // CustomTemplate gives access to the CustomTemplate data management layer
 func (store *Store) CustomTemplate() portainer.CustomTemplateService {
 	return store.CustomTemplateService
@@ -202,6 +247,16 @@ func (store *Store) EndpointRelation() portainer.EndpointRelationService {
 	return store.EndpointRelationService
 }
 
+// IssuedToken gives access to the IssuedToken data management layer
+func (store *Store) IssuedToken() portainer.IssuedTokenService {
+	return store.IssuedTokenService
+}
+
+// JWTSigningKey gives access to the JWTSigningKey data management layer
+func (store *Store) JWTSigningKey() portainer.JWTSigningKeyService {
+	return store.JWTSigningKeyService
+}
+
 // Registry gives access to the Registry data management layer
 func (store *Store) Registry() portainer.RegistryService {
 	return store.RegistryService
@@ -222,6 +277,11 @@ func (store *Store) Settings() portainer.SettingsService {
 	return store.SettingsService
 }
 
+// Snapshot gives access to the Snapshot data management layer
+func (store *Store) Snapshot() portainer.EndpointSnapshotService {
+	return store.SnapshotService
+}
+
 // Stack gives access to the Stack data management layer
 func (store *Store) Stack() portainer.StackService {
 	return store.StackService
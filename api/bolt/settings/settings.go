@@ -7,8 +7,9 @@ import (
 
 const (
 	// BucketName represents the name of the bucket where this service stores data.
-	BucketName  = "settings"
-	settingsKey = "SETTINGS"
+	BucketName = "settings"
+	// SettingsKey is the key of the settings object in the bucket
+	SettingsKey = "SETTINGS"
 )
 
 // Service represents a service for managing endpoint data.
@@ -32,7 +33,7 @@ func NewService(connection *internal.DbConnection) (*Service, error) {
 func (service *Service) Settings() (*portainer.Settings, error) {
 	var settings portainer.Settings
 
-	err := internal.GetObject(service.connection, BucketName, []byte(settingsKey), &settings)
+	err := internal.GetObject(service.connection, BucketName, []byte(SettingsKey), &settings)
 	if err != nil {
 		return nil, err
 	}
@@ -42,5 +43,5 @@ func (service *Service) Settings() (*portainer.Settings, error) {
 
 // UpdateSettings persists a Settings object.
 func (service *Service) UpdateSettings(settings *portainer.Settings) error {
-	return internal.UpdateObject(service.connection, BucketName, []byte(settingsKey), settings)
+	return internal.UpdateObject(service.connection, BucketName, []byte(SettingsKey), settings)
 }
@@ -0,0 +1,205 @@
+package bolt
+
+import (
+	"errors"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	portainer "github.com/portainer/portainer/api"
+)
+
+const (
+	// migrationSnapshotDirectory is the folder of the data store holding the copy of the data taken before the last migration
+	migrationSnapshotDirectory = "migration_snapshot"
+)
+
+// migrationSnapshotFolders are the folders of the file store changed by the migrations, they are
+// restored along with the database
+var migrationSnapshotFolders = []string{"compose"}
+
+// ErrNoMigrationSnapshot is returned when there is no pre-migration snapshot to roll back to
+var ErrNoMigrationSnapshot = errors.New("No pre-migration snapshot found")
+
+// takeMigrationSnapshot copies the database and the folders changed by the migrations. The database copy is
+// consistent as it is made in a read transaction. The previous snapshot is replaced once the new one is complete.
+func (store *Store) takeMigrationSnapshot() error {
+	snapshotPath := filepath.Join(store.path, migrationSnapshotDirectory)
+	pendingSnapshotPath := snapshotPath + ".tmp"
+
+	err := os.RemoveAll(pendingSnapshotPath)
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(pendingSnapshotPath, 0700)
+	if err != nil {
+		return err
+	}
+
+	databaseCopy, err := os.OpenFile(filepath.Join(pendingSnapshotPath, databaseFileName), os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+
+	err = store.BackupTo(databaseCopy)
+	if err != nil {
+		databaseCopy.Close()
+		return err
+	}
+
+	err = databaseCopy.Close()
+	if err != nil {
+		return err
+	}
+
+	for _, folder := range migrationSnapshotFolders {
+		err := copySnapshotDir(filepath.Join(store.path, folder), filepath.Join(pendingSnapshotPath, folder))
+		if err != nil {
+			return err
+		}
+	}
+
+	err = os.RemoveAll(snapshotPath)
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(pendingSnapshotPath, snapshotPath)
+}
+
+// restoreMigrationSnapshot replaces the database and the folders changed by the migrations with their
+// pre-migration copy. The database is closed during the restore and opened again afterwards.
+func (store *Store) restoreMigrationSnapshot() error {
+	snapshotPath := filepath.Join(store.path, migrationSnapshotDirectory)
+
+	_, err := os.Stat(filepath.Join(snapshotPath, databaseFileName))
+	if errors.Is(err, os.ErrNotExist) {
+		return ErrNoMigrationSnapshot
+	} else if err != nil {
+		return err
+	}
+
+	err = store.Close()
+	if err != nil {
+		return err
+	}
+
+	databasePath := filepath.Join(store.path, databaseFileName)
+	err = copySnapshotFile(filepath.Join(snapshotPath, databaseFileName), databasePath+".tmp")
+	if err == nil {
+		err = os.Rename(databasePath+".tmp", databasePath)
+	}
+	if err != nil {
+		return err
+	}
+
+	for _, folder := range migrationSnapshotFolders {
+		err := os.RemoveAll(filepath.Join(store.path, folder))
+		if err != nil {
+			return err
+		}
+
+		err = copySnapshotDir(filepath.Join(snapshotPath, folder), filepath.Join(store.path, folder))
+		if err != nil {
+			return err
+		}
+	}
+
+	return store.Open()
+}
+
+// RollbackToSnapshot restores the data taken before the last migration, to run the previous version of
+// Portainer after a bad upgrade. The migration history is kept and records the rollback.
+func (store *Store) RollbackToSnapshot() error {
+	history, err := store.VersionService.MigrationHistory()
+	if err != nil {
+		return err
+	}
+
+	fromVersion, err := store.VersionService.DBVersion()
+	if err != nil {
+		return err
+	}
+
+	record := portainer.MigrationRecord{
+		FromVersion: fromVersion,
+		Timestamp:   time.Now().Unix(),
+		Outcome:     portainer.MigrationReverted,
+	}
+
+	start := time.Now()
+	err = store.restoreMigrationSnapshot()
+	if err != nil {
+		return err
+	}
+
+	record.ToVersion, err = store.VersionService.DBVersion()
+	if err != nil {
+		return err
+	}
+	record.Duration = time.Since(start).Milliseconds()
+
+	log.Printf("[INFO] [bolt,migration] [message: database rolled back to the pre-migration snapshot] [from_version: %d] [to_version: %d]", record.FromVersion, record.ToVersion)
+
+	return store.VersionService.StoreMigrationHistory(append(history, record))
+}
+
+// copySnapshotDir copies a folder and its content, nothing is copied when the folder does not exist
+func copySnapshotDir(fromDir, toDir string) error {
+	_, err := os.Stat(fromDir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	return filepath.Walk(fromDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		relativePath, err := filepath.Rel(fromDir, path)
+		if err != nil {
+			return err
+		}
+		destination := filepath.Join(toDir, relativePath)
+
+		switch {
+		case info.IsDir():
+			return os.MkdirAll(destination, info.Mode().Perm()|0700)
+		case info.Mode()&os.ModeSymlink != 0:
+			return nil // don't copy symlinks
+		}
+
+		return copySnapshotFile(path, destination)
+	})
+}
+
+func copySnapshotFile(src, dst string) error {
+	from, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer from.Close()
+
+	info, err := from.Stat()
+	if err != nil {
+		return err
+	}
+
+	to, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(to, from)
+	if err != nil {
+		to.Close()
+		return err
+	}
+
+	return to.Close()
+}
@@ -0,0 +1,150 @@
+package snapshot
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+
+	"github.com/boltdb/bolt"
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/bolt/errors"
+	"github.com/portainer/portainer/api/bolt/internal"
+)
+
+const (
+	// BucketName represents the name of the bucket where this service stores data.
+	BucketName = "snapshots"
+
+	// compressionThreshold is the size from which the snapshots are stored compressed, the raw data of the
+	// Docker snapshots usually exceeds it
+	compressionThreshold = 4096
+)
+
+// gzipMagic is the header of the compressed data, the uncompressed snapshots are JSON objects and start with '{'
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// Service represents a service for managing the snapshots of the endpoints.
+// The snapshots are keyed by endpoint identifier, the large ones are compressed.
+type Service struct {
+	connection *internal.DbConnection
+}
+
+// NewService creates a new instance of a service.
+func NewService(connection *internal.DbConnection) (*Service, error) {
+	err := internal.CreateBucket(connection, BucketName)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Service{
+		connection: connection,
+	}, nil
+}
+
+// Snapshot returns the snapshot of an endpoint.
+func (service *Service) Snapshot(endpointID portainer.EndpointID) (*portainer.Snapshot, error) {
+	var data []byte
+
+	err := service.connection.View(func(tx *bolt.Tx) error {
+		value := tx.Bucket([]byte(BucketName)).Get(internal.Itob(int(endpointID)))
+		if value == nil {
+			return errors.ErrObjectNotFound
+		}
+
+		data = make([]byte, len(value))
+		copy(data, value)
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return UnmarshalSnapshot(data)
+}
+
+// Snapshots returns the snapshots of every endpoint.
+func (service *Service) Snapshots() ([]portainer.Snapshot, error) {
+	var snapshots = make([]portainer.Snapshot, 0)
+
+	err := service.connection.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(BucketName))
+
+		cursor := bucket.Cursor()
+		for k, v := cursor.First(); k != nil; k, v = cursor.Next() {
+			snapshot, err := UnmarshalSnapshot(v)
+			if err != nil {
+				return err
+			}
+			snapshots = append(snapshots, *snapshot)
+		}
+
+		return nil
+	})
+
+	return snapshots, err
+}
+
+// UpdateSnapshot saves the snapshot of an endpoint.
+func (service *Service) UpdateSnapshot(snapshot *portainer.Snapshot) error {
+	data, err := marshalSnapshot(snapshot)
+	if err != nil {
+		return err
+	}
+
+	return service.connection.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte(BucketName)).Put(internal.Itob(int(snapshot.EndpointID)), data)
+	})
+}
+
+// DeleteSnapshot deletes the snapshot of an endpoint.
+func (service *Service) DeleteSnapshot(endpointID portainer.EndpointID) error {
+	identifier := internal.Itob(int(endpointID))
+	return internal.DeleteObject(service.connection, BucketName, identifier)
+}
+
+func marshalSnapshot(snapshot *portainer.Snapshot) ([]byte, error) {
+	data, err := internal.MarshalObject(snapshot)
+	if err != nil || len(data) < compressionThreshold {
+		return data, err
+	}
+
+	var buffer bytes.Buffer
+	writer := gzip.NewWriter(&buffer)
+
+	_, err = writer.Write(data)
+	if err != nil {
+		return nil, err
+	}
+
+	err = writer.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	return buffer.Bytes(), nil
+}
+
+// UnmarshalSnapshot decodes the snapshot data stored in the bucket, decompressing it when needed.
+func UnmarshalSnapshot(data []byte) (*portainer.Snapshot, error) {
+	if bytes.HasPrefix(data, gzipMagic) {
+		reader, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		defer reader.Close()
+
+		data, err = ioutil.ReadAll(reader)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var snapshot portainer.Snapshot
+	err := internal.UnmarshalObject(data, &snapshot)
+	if err != nil {
+		return nil, err
+	}
+
+	return &snapshot, nil
+}
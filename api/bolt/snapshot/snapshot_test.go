@@ -0,0 +1,45 @@
+package snapshot_test
+
+import (
+	"strings"
+	"testing"
+
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/bolt/bolttest"
+	"github.com/portainer/portainer/api/bolt/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_SnapshotService(t *testing.T) {
+	is := assert.New(t)
+
+	store, teardown := bolttest.MustNewTestStore(false)
+	defer teardown()
+
+	service := store.Snapshot()
+
+	small := &portainer.Snapshot{EndpointID: 1, Kubernetes: &portainer.KubernetesSnapshot{KubernetesVersion: "1.20", NodeCount: 3}}
+	is.NoError(service.UpdateSnapshot(small))
+
+	containers := []interface{}{map[string]interface{}{"Id": strings.Repeat("a", 64), "Labels": strings.Repeat("label ", 2000)}}
+	large := &portainer.Snapshot{EndpointID: 2, Docker: &portainer.DockerSnapshot{DockerVersion: "20.10.6", SnapshotRaw: portainer.DockerSnapshotRaw{Containers: containers}}}
+	is.NoError(service.UpdateSnapshot(large))
+
+	snapshot, err := service.Snapshot(1)
+	is.NoError(err)
+	is.Equal(small, snapshot)
+
+	snapshot, err = service.Snapshot(2)
+	is.NoError(err)
+	is.Equal("20.10.6", snapshot.Docker.DockerVersion)
+	is.Equal(containers, snapshot.Docker.SnapshotRaw.Containers)
+	is.Nil(snapshot.Kubernetes)
+
+	snapshots, err := service.Snapshots()
+	is.NoError(err)
+	is.Len(snapshots, 2)
+
+	is.NoError(service.DeleteSnapshot(1))
+	_, err = service.Snapshot(1)
+	is.Equal(errors.ErrObjectNotFound, err)
+}
@@ -0,0 +1,125 @@
+package bolt
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/filesystem"
+	"github.com/stretchr/testify/assert"
+)
+
+func newSnapshotTestStore(t *testing.T) (*Store, func()) {
+	dataStorePath, err := ioutil.TempDir("", "boltdb")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fileService, err := filesystem.NewService(dataStorePath, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	store, err := NewStore(dataStorePath, fileService)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = store.Open()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = store.VersionService.StoreDBVersion(30)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return store, func() {
+		store.Close()
+		os.RemoveAll(dataStorePath)
+	}
+}
+
+func tagNames(t *testing.T, store *Store) []string {
+	tags, err := store.TagService.Tags()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	names := make([]string, 0)
+	for _, tag := range tags {
+		names = append(names, tag.Name)
+	}
+	return names
+}
+
+func Test_migrateWithSnapshot_RestoresTheSnapshotWhenTheMigrationFails(t *testing.T) {
+	is := assert.New(t)
+
+	store, teardown := newSnapshotTestStore(t)
+	defer teardown()
+
+	is.NoError(store.TagService.CreateTag(&portainer.Tag{Name: "before"}))
+	is.NoError(os.MkdirAll(filepath.Join(store.path, "compose", "1"), 0700))
+	is.NoError(ioutil.WriteFile(filepath.Join(store.path, "compose", "1", "docker-compose.yml"), []byte("version: '3'"), 0600))
+
+	err := store.migrateWithSnapshot(30, func() error {
+		is.NoError(store.TagService.CreateTag(&portainer.Tag{Name: "during"}))
+		is.NoError(store.VersionService.StoreDBVersion(portainer.DBVersion))
+		is.NoError(os.Rename(filepath.Join(store.path, "compose", "1"), filepath.Join(store.path, "compose", "2")))
+		return errors.New("migration failure")
+	})
+	is.Error(err)
+
+	is.Equal([]string{"before"}, tagNames(t, store))
+	is.FileExists(filepath.Join(store.path, "compose", "1", "docker-compose.yml"))
+	is.NoDirExists(filepath.Join(store.path, "compose", "2"))
+
+	version, err := store.VersionService.DBVersion()
+	is.NoError(err)
+	is.Equal(30, version)
+
+	history, err := store.VersionService.MigrationHistory()
+	is.NoError(err)
+	if is.Len(history, 1) {
+		is.Equal(portainer.MigrationRolledBack, history[0].Outcome)
+		is.Equal(30, history[0].FromVersion)
+		is.Equal(portainer.DBVersion, history[0].ToVersion)
+		is.Equal("migration failure", history[0].Error)
+	}
+}
+
+func Test_RollbackToSnapshot_RestoresTheDataBeforeTheLastMigration(t *testing.T) {
+	is := assert.New(t)
+
+	store, teardown := newSnapshotTestStore(t)
+	defer teardown()
+
+	err := store.RollbackToSnapshot()
+	is.Equal(ErrNoMigrationSnapshot, err)
+
+	is.NoError(store.TagService.CreateTag(&portainer.Tag{Name: "before"}))
+
+	err = store.migrateWithSnapshot(30, func() error {
+		is.NoError(store.TagService.CreateTag(&portainer.Tag{Name: "during"}))
+		return store.VersionService.StoreDBVersion(portainer.DBVersion)
+	})
+	is.NoError(err)
+	is.Equal([]string{"before", "during"}, tagNames(t, store))
+
+	is.NoError(store.RollbackToSnapshot())
+	is.Equal([]string{"before"}, tagNames(t, store))
+
+	history, err := store.VersionService.MigrationHistory()
+	is.NoError(err)
+	if is.Len(history, 2) {
+		is.Equal(portainer.MigrationSucceeded, history[0].Outcome)
+		is.Equal(portainer.MigrationReverted, history[1].Outcome)
+		is.Equal(portainer.DBVersion, history[1].FromVersion)
+		is.Equal(30, history[1].ToVersion)
+	}
+}
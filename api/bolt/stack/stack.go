@@ -13,6 +13,19 @@ const (
 	BucketName = "stacks"
 )
 
+// nameIndex indexes the stacks by their name
+var nameIndex = internal.Index{
+	BucketName: "stacks_by_name",
+	Keys: func(data []byte) ([]string, error) {
+		var stack portainer.Stack
+		err := internal.UnmarshalObject(data, &stack)
+		if err != nil {
+			return nil, err
+		}
+		return []string{stack.Name}, nil
+	},
+}
+
 // Service represents a service for managing endpoint data.
 type Service struct {
 	connection *internal.DbConnection
@@ -25,6 +38,11 @@ func NewService(connection *internal.DbConnection) (*Service, error) {
 		return nil, err
 	}
 
+	err = internal.CreateIndexBuckets(connection, nameIndex)
+	if err != nil {
+		return nil, err
+	}
+
 	return &Service{
 		connection: connection,
 	}, nil
@@ -45,33 +63,21 @@ func (service *Service) Stack(ID portainer.StackID) (*portainer.Stack, error) {
 
 // StackByName returns a stack object by name.
 func (service *Service) StackByName(name string) (*portainer.Stack, error) {
-	var stack *portainer.Stack
+	var stack portainer.Stack
 
 	err := service.connection.View(func(tx *bolt.Tx) error {
-		bucket := tx.Bucket([]byte(BucketName))
-		cursor := bucket.Cursor()
-
-		for k, v := cursor.First(); k != nil; k, v = cursor.Next() {
-			var t portainer.Stack
-			err := internal.UnmarshalObject(v, &t)
-			if err != nil {
-				return err
-			}
-
-			if t.Name == name {
-				stack = &t
-				break
-			}
-		}
-
-		if stack == nil {
+		keys := nameIndex.Lookup(tx, name)
+		if len(keys) == 0 {
 			return errors.ErrObjectNotFound
 		}
 
-		return nil
+		return internal.UnmarshalObject(tx.Bucket([]byte(BucketName)).Get(keys[0]), &stack)
 	})
+	if err != nil {
+		return nil, err
+	}
 
-	return stack, err
+	return &stack, nil
 }
 
 // Stacks returns an array containing all the stacks.
@@ -118,18 +124,28 @@ func (service *Service) CreateStack(stack *portainer.Stack) error {
 			return err
 		}
 
-		return bucket.Put(internal.Itob(int(stack.ID)), data)
+		return internal.PutIndexedObject(tx, BucketName, internal.Itob(int(stack.ID)), data, nameIndex)
 	})
 }
 
 // UpdateStack updates a stack.
 func (service *Service) UpdateStack(ID portainer.StackID, stack *portainer.Stack) error {
 	identifier := internal.Itob(int(ID))
-	return internal.UpdateObject(service.connection, BucketName, identifier, stack)
+	return internal.UpdateIndexedObject(service.connection, BucketName, identifier, stack, nameIndex)
 }
 
 // DeleteStack deletes a stack.
 func (service *Service) DeleteStack(ID portainer.StackID) error {
 	identifier := internal.Itob(int(ID))
-	return internal.DeleteObject(service.connection, BucketName, identifier)
+	return internal.DeleteIndexedObject(service.connection, BucketName, identifier, nameIndex)
+}
+
+// Indexes returns the secondary indexes of the stacks, to maintain them when the bucket is written directly.
+func Indexes() []internal.Index {
+	return []internal.Index{nameIndex}
+}
+
+// RebuildIndexes computes the name index again from the stacks.
+func (service *Service) RebuildIndexes() error {
+	return internal.RebuildIndexes(service.connection, BucketName, nameIndex)
 }
@@ -15,6 +15,19 @@ const (
 	BucketName = "teams"
 )
 
+// nameIndex indexes the teams by their lowercase name
+var nameIndex = internal.Index{
+	BucketName: "teams_by_name",
+	Keys: func(data []byte) ([]string, error) {
+		var team portainer.Team
+		err := internal.UnmarshalObject(data, &team)
+		if err != nil {
+			return nil, err
+		}
+		return []string{strings.ToLower(team.Name)}, nil
+	},
+}
+
 // Service represents a service for managing endpoint data.
 type Service struct {
 	connection *internal.DbConnection
@@ -27,6 +40,11 @@ func NewService(connection *internal.DbConnection) (*Service, error) {
 		return nil, err
 	}
 
+	err = internal.CreateIndexBuckets(connection, nameIndex)
+	if err != nil {
+		return nil, err
+	}
+
 	return &Service{
 		connection: connection,
 	}, nil
@@ -47,33 +65,21 @@ func (service *Service) Team(ID portainer.TeamID) (*portainer.Team, error) {
 
 // TeamByName returns a team by name.
 func (service *Service) TeamByName(name string) (*portainer.Team, error) {
-	var team *portainer.Team
+	var team portainer.Team
 
 	err := service.connection.View(func(tx *bolt.Tx) error {
-		bucket := tx.Bucket([]byte(BucketName))
-
-		cursor := bucket.Cursor()
-		for k, v := cursor.First(); k != nil; k, v = cursor.Next() {
-			var t portainer.Team
-			err := internal.UnmarshalObject(v, &t)
-			if err != nil {
-				return err
-			}
-
-			if strings.EqualFold(t.Name, name) {
-				team = &t
-				break
-			}
-		}
-
-		if team == nil {
+		keys := nameIndex.Lookup(tx, strings.ToLower(name))
+		if len(keys) == 0 {
 			return errors.ErrObjectNotFound
 		}
 
-		return nil
+		return internal.UnmarshalObject(tx.Bucket([]byte(BucketName)).Get(keys[0]), &team)
 	})
+	if err != nil {
+		return nil, err
+	}
 
-	return team, err
+	return &team, nil
 }
 
 // Teams return an array containing all the teams.
@@ -102,7 +108,7 @@ func (service *Service) Teams() ([]portainer.Team, error) {
 // UpdateTeam saves a Team.
 func (service *Service) UpdateTeam(ID portainer.TeamID, team *portainer.Team) error {
 	identifier := internal.Itob(int(ID))
-	return internal.UpdateObject(service.connection, BucketName, identifier, team)
+	return internal.UpdateIndexedObject(service.connection, BucketName, identifier, team, nameIndex)
 }
 
 // CreateTeam creates a new Team.
@@ -118,12 +124,27 @@ func (service *Service) CreateTeam(team *portainer.Team) error {
 			return err
 		}
 
-		return bucket.Put(internal.Itob(int(team.ID)), data)
+		return internal.PutIndexedObject(tx, BucketName, internal.Itob(int(team.ID)), data, nameIndex)
 	})
 }
 
+// GetNextIdentifier reserves and returns the next identifier for a team.
+func (service *Service) GetNextIdentifier() int {
+	return internal.GetNextIdentifier(service.connection, BucketName)
+}
+
 // DeleteTeam deletes a Team.
 func (service *Service) DeleteTeam(ID portainer.TeamID) error {
 	identifier := internal.Itob(int(ID))
-	return internal.DeleteObject(service.connection, BucketName, identifier)
+	return internal.DeleteIndexedObject(service.connection, BucketName, identifier, nameIndex)
+}
+
+// Indexes returns the secondary indexes of the teams, to maintain them when the bucket is written directly.
+func Indexes() []internal.Index {
+	return []internal.Index{nameIndex}
+}
+
+// RebuildIndexes computes the name index again from the teams.
+func (service *Service) RebuildIndexes() error {
+	return internal.RebuildIndexes(service.connection, BucketName, nameIndex)
 }
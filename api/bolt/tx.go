@@ -0,0 +1,60 @@
+package bolt
+
+import (
+	"fmt"
+
+	"github.com/boltdb/bolt"
+	"github.com/portainer/portainer/api/bolt/errors"
+	"github.com/portainer/portainer/api/bolt/internal"
+)
+
+// bucketObject is an object of a bucket copied out of a transaction, so that the bucket can be written
+// while its objects are processed
+type bucketObject struct {
+	key  []byte
+	data []byte
+}
+
+// bucketObjects returns a copy of the objects of a bucket
+func bucketObjects(tx *bolt.Tx, bucketName string) []bucketObject {
+	objects := make([]bucketObject, 0)
+
+	cursor := tx.Bucket([]byte(bucketName)).Cursor()
+	for k, v := cursor.First(); k != nil; k, v = cursor.Next() {
+		object := bucketObject{key: make([]byte, len(k)), data: make([]byte, len(v))}
+		copy(object.key, k)
+		copy(object.data, v)
+		objects = append(objects, object)
+	}
+
+	return objects
+}
+
+// getObject retrieves an unmarshalled object inside a transaction
+func getObject(tx *bolt.Tx, bucketName string, key []byte, object interface{}) error {
+	data := tx.Bucket([]byte(bucketName)).Get(key)
+	if data == nil {
+		return errors.ErrObjectNotFound
+	}
+
+	return internal.UnmarshalObject(data, object)
+}
+
+// createObject saves a new object inside a transaction, it fails when an object already uses the key
+func createObject(tx *bolt.Tx, bucketName string, key []byte, object interface{}, indexes ...internal.Index) error {
+	if tx.Bucket([]byte(bucketName)).Get(key) != nil {
+		return fmt.Errorf("an object with the same key already exists in the %s bucket", bucketName)
+	}
+
+	return putObject(tx, bucketName, key, object, indexes...)
+}
+
+// putObject saves an object inside a transaction and updates the entries of its indexes
+func putObject(tx *bolt.Tx, bucketName string, key []byte, object interface{}, indexes ...internal.Index) error {
+	data, err := internal.MarshalObject(object)
+	if err != nil {
+		return err
+	}
+
+	return internal.PutIndexedObject(tx, bucketName, key, data, indexes...)
+}
@@ -15,6 +15,19 @@ const (
 	BucketName = "users"
 )
 
+// usernameIndex indexes the users by their lowercase username
+var usernameIndex = internal.Index{
+	BucketName: "users_by_username",
+	Keys: func(data []byte) ([]string, error) {
+		var user portainer.User
+		err := internal.UnmarshalObject(data, &user)
+		if err != nil {
+			return nil, err
+		}
+		return []string{strings.ToLower(user.Username)}, nil
+	},
+}
+
 // Service represents a service for managing endpoint data.
 type Service struct {
 	connection *internal.DbConnection
@@ -27,6 +40,11 @@ func NewService(connection *internal.DbConnection) (*Service, error) {
 		return nil, err
 	}
 
+	err = internal.CreateIndexBuckets(connection, usernameIndex)
+	if err != nil {
+		return nil, err
+	}
+
 	return &Service{
 		connection: connection,
 	}, nil
@@ -47,34 +65,21 @@ func (service *Service) User(ID portainer.UserID) (*portainer.User, error) {
 
 // UserByUsername returns a user by username.
 func (service *Service) UserByUsername(username string) (*portainer.User, error) {
-	var user *portainer.User
-
-	username = strings.ToLower(username)
+	var user portainer.User
 
 	err := service.connection.View(func(tx *bolt.Tx) error {
-		bucket := tx.Bucket([]byte(BucketName))
-		cursor := bucket.Cursor()
-
-		for k, v := cursor.First(); k != nil; k, v = cursor.Next() {
-			var u portainer.User
-			err := internal.UnmarshalObject(v, &u)
-			if err != nil {
-				return err
-			}
-
-			if strings.EqualFold(u.Username, username) {
-				user = &u
-				break
-			}
-		}
-
-		if user == nil {
+		keys := usernameIndex.Lookup(tx, strings.ToLower(username))
+		if len(keys) == 0 {
 			return errors.ErrObjectNotFound
 		}
-		return nil
+
+		return internal.UnmarshalObject(tx.Bucket([]byte(BucketName)).Get(keys[0]), &user)
 	})
+	if err != nil {
+		return nil, err
+	}
 
-	return user, err
+	return &user, nil
 }
 
 // Users return an array containing all the users.
@@ -128,7 +133,7 @@ func (service *Service) UsersByRole(role portainer.UserRole) ([]portainer.User,
 func (service *Service) UpdateUser(ID portainer.UserID, user *portainer.User) error {
 	identifier := internal.Itob(int(ID))
 	user.Username = strings.ToLower(user.Username)
-	return internal.UpdateObject(service.connection, BucketName, identifier, user)
+	return internal.UpdateIndexedObject(service.connection, BucketName, identifier, user, usernameIndex)
 }
 
 // CreateUser creates a new user.
@@ -145,12 +150,27 @@ func (service *Service) CreateUser(user *portainer.User) error {
 			return err
 		}
 
-		return bucket.Put(internal.Itob(int(user.ID)), data)
+		return internal.PutIndexedObject(tx, BucketName, internal.Itob(int(user.ID)), data, usernameIndex)
 	})
 }
 
+// GetNextIdentifier reserves and returns the next identifier for a user.
+func (service *Service) GetNextIdentifier() int {
+	return internal.GetNextIdentifier(service.connection, BucketName)
+}
+
 // DeleteUser deletes a user.
 func (service *Service) DeleteUser(ID portainer.UserID) error {
 	identifier := internal.Itob(int(ID))
-	return internal.DeleteObject(service.connection, BucketName, identifier)
+	return internal.DeleteIndexedObject(service.connection, BucketName, identifier, usernameIndex)
+}
+
+// Indexes returns the secondary indexes of the users, to maintain them when the bucket is written directly.
+func Indexes() []internal.Index {
+	return []internal.Index{usernameIndex}
+}
+
+// RebuildIndexes computes the username index again from the users.
+func (service *Service) RebuildIndexes() error {
+	return internal.RebuildIndexes(service.connection, BucketName, usernameIndex)
 }
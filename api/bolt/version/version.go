@@ -11,10 +11,11 @@ import (
 
 const (
 	// BucketName represents the name of the bucket where this service stores data.
-	BucketName  = "version"
-	versionKey  = "DB_VERSION"
-	instanceKey = "INSTANCE_ID"
-	editionKey  = "EDITION"
+	BucketName          = "version"
+	versionKey          = "DB_VERSION"
+	instanceKey         = "INSTANCE_ID"
+	editionKey          = "EDITION"
+	migrationHistoryKey = "MIGRATION_HISTORY"
 )
 
 // Service represents a service to manage stored versions.
@@ -117,6 +118,35 @@ func (service *Service) StoreInstanceID(ID string) error {
 	})
 }
 
+// MigrationHistory retrieves the database migrations, oldest first.
+func (service *Service) MigrationHistory() ([]portainer.MigrationRecord, error) {
+	history := make([]portainer.MigrationRecord, 0)
+
+	data, err := service.getKey(migrationHistoryKey)
+	if err == errors.ErrObjectNotFound {
+		return history, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	err = internal.UnmarshalObject(data, &history)
+	return history, err
+}
+
+// StoreMigrationHistory stores the database migrations.
+func (service *Service) StoreMigrationHistory(history []portainer.MigrationRecord) error {
+	return service.connection.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(BucketName))
+
+		data, err := internal.MarshalObject(history)
+		if err != nil {
+			return err
+		}
+
+		return bucket.Put([]byte(migrationHistoryKey), data)
+	})
+}
+
 func (service *Service) getKey(key string) ([]byte, error) {
 	var data []byte
 
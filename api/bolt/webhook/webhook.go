@@ -13,6 +13,34 @@ const (
 	BucketName = "webhooks"
 )
 
+var (
+	// tokenIndex indexes the webhooks by their token
+	tokenIndex = internal.Index{
+		BucketName: "webhooks_by_token",
+		Keys: func(data []byte) ([]string, error) {
+			var webhook portainer.Webhook
+			err := internal.UnmarshalObject(data, &webhook)
+			if err != nil {
+				return nil, err
+			}
+			return []string{webhook.Token}, nil
+		},
+	}
+
+	// resourceIDIndex indexes the webhooks by the identifier of the resource they are associated with
+	resourceIDIndex = internal.Index{
+		BucketName: "webhooks_by_resource_id",
+		Keys: func(data []byte) ([]string, error) {
+			var webhook portainer.Webhook
+			err := internal.UnmarshalObject(data, &webhook)
+			if err != nil {
+				return nil, err
+			}
+			return []string{webhook.ResourceID}, nil
+		},
+	}
+)
+
 // Service represents a service for managing webhook data.
 type Service struct {
 	connection *internal.DbConnection
@@ -25,6 +53,11 @@ func NewService(connection *internal.DbConnection) (*Service, error) {
 		return nil, err
 	}
 
+	err = internal.CreateIndexBuckets(connection, tokenIndex, resourceIDIndex)
+	if err != nil {
+		return nil, err
+	}
+
 	return &Service{
 		connection: connection,
 	}, nil
@@ -68,70 +101,36 @@ func (service *Service) Webhook(ID portainer.WebhookID) (*portainer.Webhook, err
 
 // WebhookByResourceID returns a webhook by the ResourceID it is associated with.
 func (service *Service) WebhookByResourceID(ID string) (*portainer.Webhook, error) {
-	var webhook *portainer.Webhook
-
-	err := service.connection.View(func(tx *bolt.Tx) error {
-		bucket := tx.Bucket([]byte(BucketName))
-		cursor := bucket.Cursor()
-
-		for k, v := cursor.First(); k != nil; k, v = cursor.Next() {
-			var w portainer.Webhook
-			err := internal.UnmarshalObject(v, &w)
-			if err != nil {
-				return err
-			}
-
-			if w.ResourceID == ID {
-				webhook = &w
-				break
-			}
-		}
-
-		if webhook == nil {
-			return errors.ErrObjectNotFound
-		}
-
-		return nil
-	})
-
-	return webhook, err
+	return service.webhookByIndexKey(resourceIDIndex, ID)
 }
 
 // WebhookByToken returns a webhook by the random token it is associated with.
 func (service *Service) WebhookByToken(token string) (*portainer.Webhook, error) {
-	var webhook *portainer.Webhook
-
-	err := service.connection.View(func(tx *bolt.Tx) error {
-		bucket := tx.Bucket([]byte(BucketName))
-		cursor := bucket.Cursor()
-
-		for k, v := cursor.First(); k != nil; k, v = cursor.Next() {
-			var w portainer.Webhook
-			err := internal.UnmarshalObject(v, &w)
-			if err != nil {
-				return err
-			}
+	return service.webhookByIndexKey(tokenIndex, token)
+}
 
-			if w.Token == token {
-				webhook = &w
-				break
-			}
-		}
+func (service *Service) webhookByIndexKey(index internal.Index, indexKey string) (*portainer.Webhook, error) {
+	var webhook portainer.Webhook
 
-		if webhook == nil {
+	err := service.connection.View(func(tx *bolt.Tx) error {
+		keys := index.Lookup(tx, indexKey)
+		if len(keys) == 0 {
 			return errors.ErrObjectNotFound
 		}
 
-		return nil
+		return internal.UnmarshalObject(tx.Bucket([]byte(BucketName)).Get(keys[0]), &webhook)
 	})
+	if err != nil {
+		return nil, err
+	}
 
-	return webhook, err
+	return &webhook, nil
 }
 
 // DeleteWebhook deletes a webhook.
 func (service *Service) DeleteWebhook(ID portainer.WebhookID) error {
 	identifier := internal.Itob(int(ID))
-	return internal.DeleteObject(service.connection, BucketName, identifier)
+	return internal.DeleteIndexedObject(service.connection, BucketName, identifier, tokenIndex, resourceIDIndex)
 }
 
 // CreateWebhook assign an ID to a new webhook and saves it.
@@ -147,6 +146,16 @@ func (service *Service) CreateWebhook(webhook *portainer.Webhook) error {
 			return err
 		}
 
-		return bucket.Put(internal.Itob(int(webhook.ID)), data)
+		return internal.PutIndexedObject(tx, BucketName, internal.Itob(int(webhook.ID)), data, tokenIndex, resourceIDIndex)
 	})
 }
+
+// Indexes returns the secondary indexes of the webhooks, to maintain them when the bucket is written directly.
+func Indexes() []internal.Index {
+	return []internal.Index{tokenIndex, resourceIDIndex}
+}
+
+// RebuildIndexes computes the token and resource indexes again from the webhooks.
+func (service *Service) RebuildIndexes() error {
+	return internal.RebuildIndexes(service.connection, BucketName, tokenIndex, resourceIDIndex)
+}
@@ -22,6 +22,7 @@ var (
 	errSocketOrNamedPipeNotFound     = errors.New("Unable to locate Unix socket or named pipe")
 	errInvalidSnapshotInterval       = errors.New("Invalid snapshot interval")
 	errAdminPassExcludeAdminPassFile = errors.New("Cannot use --admin-password with --admin-password-file")
+	errSSLClientCAWithoutSSL         = errors.New("Cannot use --sslclientca without --ssl")
 )
 
 // ParseFlags parse the CLI flags and return a portainer.Flags struct
@@ -45,12 +46,18 @@ func (*Service) ParseFlags(version string) (*portainer.CLIFlags, error) {
 		SSL:                       kingpin.Flag("ssl", "Secure Portainer instance using SSL").Default(defaultSSL).Bool(),
 		SSLCert:                   kingpin.Flag("sslcert", "Path to the SSL certificate used to secure the Portainer instance").Default(defaultSSLCertPath).String(),
 		SSLKey:                    kingpin.Flag("sslkey", "Path to the SSL key used to secure the Portainer instance").Default(defaultSSLKeyPath).String(),
+		SSLClientCA:               kingpin.Flag("sslclientca", "Path to the CA bundle used to verify the client certificates, enables the client certificate authentication").String(),
+		SSLClientVerify:           kingpin.Flag("sslclientverify", "Whether a client certificate is optional or required when --sslclientca is set. Requiring it also applies to the browsers and the Edge agents").Default(defaultSSLClientVerify).Enum("optional", "required"),
 		SnapshotInterval:          kingpin.Flag("snapshot-interval", "Duration between each endpoint snapshot job").Default(defaultSnapshotInterval).String(),
 		AdminPassword:             kingpin.Flag("admin-password", "Hashed admin password").String(),
 		AdminPasswordFile:         kingpin.Flag("admin-password-file", "Path to the file containing the password for the admin user").String(),
 		Labels:                    pairs(kingpin.Flag("hide-label", "Hide containers with a specific label in the UI").Short('l')),
 		Logo:                      kingpin.Flag("logo", "URL for the logo displayed in the UI").String(),
 		Templates:                 kingpin.Flag("templates", "URL to the templates definitions.").Short('t').String(),
+		RollbackDB:                kingpin.Flag("rollback-db", "Restore the database snapshot taken before the last migration and exit, to run the previous version of Portainer after an upgrade").Bool(),
+		CheckDB:                   kingpin.Flag("check-db", "Check the database for references to missing objects, report them and exit").Bool(),
+		RepairDB:                  kingpin.Flag("repair-db", "Check the database for references to missing objects, repair them and exit").Bool(),
+		RestoreS3Endpoints:        kingpin.Flag("restore-s3-endpoint", "S3 compatible object storage a backup can be restored from before the instance is initialized, can be repeated. Use https://s3.amazonaws.com for AWS S3").Strings(),
 	}
 
 	kingpin.Parse()
@@ -85,6 +92,10 @@ func (*Service) ValidateFlags(flags *portainer.CLIFlags) error {
 		return errAdminPassExcludeAdminPassFile
 	}
 
+	if *flags.SSLClientCA != "" && !*flags.SSL {
+		return errSSLClientCAWithoutSSL
+	}
+
 	return nil
 }
 
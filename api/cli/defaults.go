@@ -16,5 +16,6 @@ const (
 	defaultSSL                 = "false"
 	defaultSSLCertPath         = "/certs/portainer.crt"
 	defaultSSLKeyPath          = "/certs/portainer.key"
+	defaultSSLClientVerify     = "optional"
 	defaultSnapshotInterval    = "5m"
 )
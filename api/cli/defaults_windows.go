@@ -14,5 +14,6 @@ const (
 	defaultSSL                 = "false"
 	defaultSSLCertPath         = "C:\\certs\\portainer.crt"
 	defaultSSLKeyPath          = "C:\\certs\\portainer.key"
+	defaultSSLClientVerify     = "optional"
 	defaultSnapshotInterval    = "5m"
 )
@@ -76,6 +76,78 @@ func initDataStore(dataStorePath string, fileService portainer.FileService) port
 	return store
 }
 
+// rollbackDataStore restores the database snapshot taken before the last migration
+func rollbackDataStore(dataStorePath string, fileService portainer.FileService) {
+	store, err := bolt.NewStore(dataStorePath, fileService)
+	if err != nil {
+		log.Fatalf("failed creating data store: %v", err)
+	}
+
+	if store.IsNew() {
+		log.Fatalf("failed rolling back the database: no database found in %s", dataStorePath)
+	}
+
+	err = store.Open()
+	if err != nil {
+		log.Fatalf("failed opening store: %v", err)
+	}
+	defer store.Close()
+
+	err = store.RollbackToSnapshot()
+	if err != nil {
+		log.Fatalf("failed rolling back the database: %v", err)
+	}
+
+	log.Println("Database rolled back to the pre-migration snapshot. Start the previous version of Portainer to use it.")
+}
+
+// checkDataStore reports the references to missing objects found in the database and repairs them in repair mode.
+// The database must be migrated to the current version, the check relies on the current data model.
+func checkDataStore(dataStorePath string, fileService portainer.FileService, repair bool) {
+	store, err := bolt.NewStore(dataStorePath, fileService)
+	if err != nil {
+		log.Fatalf("failed creating data store: %v", err)
+	}
+
+	if store.IsNew() {
+		log.Fatalf("failed checking the database: no database found in %s", dataStorePath)
+	}
+
+	err = store.Open()
+	if err != nil {
+		log.Fatalf("failed opening store: %v", err)
+	}
+	defer store.Close()
+
+	version, err := store.VersionService.DBVersion()
+	if err != nil {
+		log.Fatalf("failed retrieving the database version: %v", err)
+	}
+
+	if version != portainer.DBVersion {
+		log.Fatalf("failed checking the database: the database version is %d, start this version of Portainer once to migrate it to version %d", version, portainer.DBVersion)
+	}
+
+	report, err := store.CheckIntegrity(repair)
+	if err != nil {
+		log.Fatalf("failed checking the database: %v", err)
+	}
+
+	for _, issue := range report.Issues {
+		log.Printf("[%s/%s] %s: %s", issue.Bucket, issue.Key, issue.Description, issue.Repair)
+	}
+
+	switch {
+	case len(report.Issues) == 0:
+		log.Println("No reference to a missing object found in the database.")
+	case report.Repaired:
+		log.Printf("%d references to missing objects repaired.", len(report.Issues))
+	default:
+		store.Close()
+		log.Fatalf("%d references to missing objects found, use --repair-db to repair them.", len(report.Issues))
+	}
+}
+
 func initComposeStackManager(assetsPath string, dataStorePath string, reverseTunnelService portainer.ReverseTunnelService, proxyManager *proxy.Manager) portainer.ComposeStackManager {
 	composeWrapper := exec.NewComposeWrapper(assetsPath, dataStorePath, proxyManager)
 	if composeWrapper != nil {
@@ -93,7 +165,9 @@ func initKubernetesDeployer(dataStore portainer.DataStore, reverseTunnelService
 	return exec.NewKubernetesDeployer(dataStore, reverseTunnelService, signatureService, assetsPath)
 }
 
-func initJWTService(dataStore portainer.DataStore) (portainer.JWTService, error) {
+// initJWTService creates the JWT service and starts the removal of the expired issued tokens.
+// The signing keys stored in the database are encrypted with the private key of the instance.
+func initJWTService(dataStore portainer.DataStore, fileService portainer.FileService, shutdownCtx context.Context) (portainer.JWTService, error) {
 	settings, err := dataStore.Settings().Settings()
 	if err != nil {
 		return nil, err
@@ -103,10 +177,18 @@ func initJWTService(dataStore portainer.DataStore) (portainer.JWTService, error)
 		settings.UserSessionTimeout = portainer.DefaultUserSessionTimeout
 		dataStore.Settings().UpdateSettings(settings)
 	}
-	jwtService, err := jwt.NewService(settings.UserSessionTimeout)
+
+	privateKey, _, err := fileService.LoadKeyPair()
+	if err != nil {
+		return nil, err
+	}
+
+	jwtService, err := jwt.NewService(settings.UserSessionTimeout, dataStore, privateKey)
 	if err != nil {
 		return nil, err
 	}
+	jwtService.StartIssuedTokenCleanupJob(shutdownCtx)
+
 	return jwtService, nil
 }
 
@@ -354,11 +436,6 @@ func buildServer(flags *portainer.CLIFlags) portainer.Server {
 		log.Fatal(err)
 	}
 
-	jwtService, err := initJWTService(dataStore)
-	if err != nil {
-		log.Fatalf("failed initializing JWT service: %v", err)
-	}
-
 	ldapService := initLDAPService()
 
 	oauthService := initOAuthService()
@@ -369,11 +446,16 @@ func buildServer(flags *portainer.CLIFlags) portainer.Server {
 
 	digitalSignatureService := initDigitalSignatureService()
 
-	err = initKeyPair(fileService, digitalSignatureService)
+	err := initKeyPair(fileService, digitalSignatureService)
 	if err != nil {
 		log.Fatalf("failed initializing key pai: %v", err)
 	}
 
+	jwtService, err := initJWTService(dataStore, fileService, shutdownCtx)
+	if err != nil {
+		log.Fatalf("failed initializing JWT service: %v", err)
+	}
+
 	reverseTunnelService := chisel.NewService(dataStore, shutdownCtx)
 
 	instanceID, err := dataStore.Version().InstanceID()
@@ -392,6 +474,7 @@ func buildServer(flags *portainer.CLIFlags) portainer.Server {
 
 	authorizationService := authorization.NewService(dataStore)
 	authorizationService.K8sClientFactory = kubernetesClientFactory
+	authorizationService.StartGrantExpiryJob(shutdownCtx)
 
 	swarmStackManager, err := initSwarmStackManager(*flags.Assets, *flags.Data, digitalSignatureService, fileService, reverseTunnelService)
 	if err != nil {
@@ -446,9 +529,10 @@ func buildServer(flags *portainer.CLIFlags) portainer.Server {
 		if len(users) == 0 {
 			log.Println("Created admin user with the given password.")
 			user := &portainer.User{
-				Username: "admin",
-				Role:     portainer.AdministratorRole,
-				Password: adminPasswordHash,
+				Username:             "admin",
+				Role:                 portainer.AdministratorRole,
+				Password:             adminPasswordHash,
+				AuthenticationMethod: portainer.AuthenticationInternal,
 			}
 			err := dataStore.User().CreateUser(user)
 			if err != nil {
@@ -487,16 +571,29 @@ func buildServer(flags *portainer.CLIFlags) portainer.Server {
 		SSL:                         *flags.SSL,
 		SSLCert:                     *flags.SSLCert,
 		SSLKey:                      *flags.SSLKey,
+		SSLClientCA:                 *flags.SSLClientCA,
+		SSLClientCertRequired:       *flags.SSLClientVerify == "required",
 		DockerClientFactory:         dockerClientFactory,
 		KubernetesClientFactory:     kubernetesClientFactory,
 		ShutdownCtx:                 shutdownCtx,
 		ShutdownTrigger:             shutdownTrigger,
+		RestoreS3Endpoints:          *flags.RestoreS3Endpoints,
 	}
 }
 
 func main() {
 	flags := initCLI()
 
+	if *flags.RollbackDB {
+		rollbackDataStore(*flags.Data, initFileService(*flags.Data))
+		return
+	}
+
+	if *flags.CheckDB || *flags.RepairDB {
+		checkDataStore(*flags.Data, initFileService(*flags.Data), *flags.RepairDB)
+		return
+	}
+
 	for {
 		server := buildServer(flags)
 		log.Printf("Starting Portainer %s on %s\n", portainer.APIVersion, *flags.Addr)
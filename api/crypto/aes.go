@@ -1,55 +1,321 @@
 package crypto
 
 import (
+	"bufio"
+	"bytes"
 	"crypto/aes"
 	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
 	"io"
 
 	"golang.org/x/crypto/scrypt"
 )
 
-// NOTE: has to go with what is considered to be a simplistic in that it omits any
-// authentication of the encrypted data.
-// Person with better knowledge is welcomed to improve it.
-// sourced from https://golang.org/src/crypto/cipher/example_test.go
+// Encrypted archives are written in a versioned container format:
+//
+//	magic (8 bytes) | version (1 byte) | scrypt N, r, p (3 x uint32 big endian) | salt (16 bytes) | nonce prefix (7 bytes)
+//
+// followed by a sequence of AES-256-GCM sealed chunks. Each chunk holds up to aesChunkSize bytes of plaintext,
+// its nonce is made of the nonce prefix, a 4 bytes chunk counter and a 1 byte flag marking the last chunk,
+// and the whole header is used as additional authenticated data. This detects a wrong passphrase,
+// any tampering with the content, reordered chunks and a truncated archive.
+//
+// Content that does not start with the magic is considered to be in the legacy format
+// (scrypt with an empty salt, AES-256-OFB with a zero IV, no authentication) and can still be decrypted.
+
+const (
+	aesFormatVersion1 byte = 1
+
+	aesKeyLength       = 32
+	aesSaltLength      = 16
+	aesNoncePrefixSize = 7
+	aesChunkSize       = 64 * 1024
+
+	scryptN = 32768
+	scryptR = 8
+	scryptP = 1
+
+	// upper bounds to prevent a crafted header from exhausting memory during key derivation
+	scryptMaxN = 1 << 20
+	scryptMaxR = 32
+	scryptMaxP = 16
+)
+
+var aesMagic = []byte("PTRBKENC")
+
+var aesHeaderSize = len(aesMagic) + 1 + 3*4 + aesSaltLength + aesNoncePrefixSize
+
+var (
+	// ErrDecryptionFailed is returned when the content cannot be authenticated,
+	// either because the passphrase is wrong or because the content was altered.
+	ErrDecryptionFailed = errors.New("unable to decrypt the content, the passphrase is invalid or the content is corrupted")
+	// ErrTruncatedContent is returned when the encrypted content ends before its last chunk.
+	ErrTruncatedContent = errors.New("encrypted content is truncated")
+	// ErrUnsupportedFormatVersion is returned when the encrypted content was produced by an unknown version of the format.
+	ErrUnsupportedFormatVersion = errors.New("unsupported encryption format version")
+)
 
 var emptySalt []byte = make([]byte, 0, 0)
 
-// AesEncrypt reads from input, encrypts with AES-256 and writes to the output.
-// passphrase is used to generate an encryption key.
-func AesEncrypt(input io.Reader, output io.Writer, passphrase []byte) error {
-	// making a 32 bytes key that would correspond to AES-256
-	// don't necessarily need a salt, so just kept in empty
-	key, err := scrypt.Key(passphrase, emptySalt, 32768, 8, 1, 32)
+type aesHeader struct {
+	version     byte
+	n, r, p     uint32
+	salt        []byte
+	noncePrefix []byte
+}
+
+func (header *aesHeader) marshal() []byte {
+	buf := make([]byte, 0, aesHeaderSize)
+	buf = append(buf, aesMagic...)
+	buf = append(buf, header.version)
+
+	var param [4]byte
+	for _, value := range []uint32{header.n, header.r, header.p} {
+		binary.BigEndian.PutUint32(param[:], value)
+		buf = append(buf, param[:]...)
+	}
+
+	buf = append(buf, header.salt...)
+	buf = append(buf, header.noncePrefix...)
+	return buf
+}
+
+func unmarshalAesHeader(raw []byte) (*aesHeader, error) {
+	offset := len(aesMagic)
+
+	header := &aesHeader{version: raw[offset]}
+	if header.version != aesFormatVersion1 {
+		return nil, ErrUnsupportedFormatVersion
+	}
+	offset++
+
+	header.n = binary.BigEndian.Uint32(raw[offset:])
+	header.r = binary.BigEndian.Uint32(raw[offset+4:])
+	header.p = binary.BigEndian.Uint32(raw[offset+8:])
+	offset += 12
+
+	if header.n < 2 || header.n > scryptMaxN || header.r == 0 || header.r > scryptMaxR || header.p == 0 || header.p > scryptMaxP {
+		return nil, errors.New("invalid key derivation parameters")
+	}
+
+	header.salt = raw[offset : offset+aesSaltLength]
+	header.noncePrefix = raw[offset+aesSaltLength : offset+aesSaltLength+aesNoncePrefixSize]
+
+	return header, nil
+}
+
+func (header *aesHeader) aead(passphrase []byte) (cipher.AEAD, error) {
+	key, err := scrypt.Key(passphrase, header.salt, int(header.n), int(header.r), int(header.p), aesKeyLength)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	block, err := aes.NewCipher(key)
 	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
+
+func chunkNonce(prefix []byte, counter uint32, last bool) []byte {
+	nonce := make([]byte, aesNoncePrefixSize+5)
+	copy(nonce, prefix)
+	binary.BigEndian.PutUint32(nonce[aesNoncePrefixSize:], counter)
+	if last {
+		nonce[len(nonce)-1] = 1
+	}
+	return nonce
+}
+
+// AesEncrypt reads from input, encrypts with AES-256-GCM and writes to the output.
+// passphrase is used with a random salt to generate an encryption key.
+func AesEncrypt(input io.Reader, output io.Writer, passphrase []byte) error {
+	header := &aesHeader{
+		version:     aesFormatVersion1,
+		n:           scryptN,
+		r:           scryptR,
+		p:           scryptP,
+		salt:        make([]byte, aesSaltLength),
+		noncePrefix: make([]byte, aesNoncePrefixSize),
+	}
+
+	if _, err := io.ReadFull(rand.Reader, header.salt); err != nil {
 		return err
 	}
 
-	// If the key is unique for each ciphertext, then it's ok to use a zero
-	// IV.
-	var iv [aes.BlockSize]byte
-	stream := cipher.NewOFB(block, iv[:])
+	if _, err := io.ReadFull(rand.Reader, header.noncePrefix); err != nil {
+		return err
+	}
 
-	writer := &cipher.StreamWriter{S: stream, W: output}
-	// Copy the input to the output, encrypting as we go.
-	if _, err := io.Copy(writer, input); err != nil {
+	aead, err := header.aead(passphrase)
+	if err != nil {
 		return err
 	}
 
-	return nil
+	rawHeader := header.marshal()
+	if _, err := output.Write(rawHeader); err != nil {
+		return err
+	}
+
+	reader := bufio.NewReaderSize(input, aesChunkSize)
+	plaintext := make([]byte, aesChunkSize)
+	ciphertext := make([]byte, 0, aesChunkSize+aead.Overhead())
+
+	for counter := uint32(0); ; counter++ {
+		n, err := io.ReadFull(reader, plaintext)
+		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+			return err
+		}
+
+		last := err != nil
+		if !last {
+			if _, peekErr := reader.Peek(1); peekErr == io.EOF {
+				last = true
+			} else if peekErr != nil {
+				return peekErr
+			}
+		}
+
+		if counter == ^uint32(0) && !last {
+			return errors.New("content is too large to be encrypted")
+		}
+
+		ciphertext = aead.Seal(ciphertext[:0], chunkNonce(header.noncePrefix, counter, last), plaintext[:n], rawHeader)
+		if _, err := output.Write(ciphertext); err != nil {
+			return err
+		}
+
+		if last {
+			return nil
+		}
+	}
 }
 
 // AesDecrypt reads from input, decrypts with AES-256 and returns the reader to a read decrypted content from.
 // passphrase is used to generate an encryption key.
+// The first chunk is authenticated before returning, so a wrong passphrase is reported immediately,
+// while any later corruption is reported by the returned reader.
+// Content encrypted with the legacy unauthenticated format is detected and decrypted as well.
 func AesDecrypt(input io.Reader, passphrase []byte) (io.Reader, error) {
+	reader := bufio.NewReaderSize(input, aesChunkSize)
+
+	magic, err := reader.Peek(len(aesMagic))
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+
+	if !bytes.Equal(magic, aesMagic) {
+		return aesDecryptLegacy(reader, passphrase)
+	}
+
+	rawHeader := make([]byte, aesHeaderSize)
+	if _, err := io.ReadFull(reader, rawHeader); err != nil {
+		return nil, ErrTruncatedContent
+	}
+
+	header, err := unmarshalAesHeader(rawHeader)
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := header.aead(passphrase)
+	if err != nil {
+		return nil, err
+	}
+
+	decrypter := &aesChunkReader{
+		input:      reader,
+		aead:       aead,
+		header:     header,
+		rawHeader:  rawHeader,
+		ciphertext: make([]byte, aesChunkSize+aead.Overhead()),
+		buffer:     make([]byte, 0, aesChunkSize),
+	}
+
+	if err := decrypter.nextChunk(); err != nil {
+		return nil, err
+	}
+
+	return decrypter, nil
+}
+
+type aesChunkReader struct {
+	input      *bufio.Reader
+	aead       cipher.AEAD
+	header     *aesHeader
+	rawHeader  []byte
+	counter    uint32
+	ciphertext []byte
+	buffer     []byte
+	plaintext  []byte
+	done       bool
+	err        error
+}
+
+func (reader *aesChunkReader) Read(p []byte) (int, error) {
+	for len(reader.plaintext) == 0 {
+		if reader.err != nil {
+			return 0, reader.err
+		}
+
+		if reader.done {
+			return 0, io.EOF
+		}
+
+		reader.err = reader.nextChunk()
+	}
+
+	n := copy(p, reader.plaintext)
+	reader.plaintext = reader.plaintext[n:]
+	return n, nil
+}
+
+func (reader *aesChunkReader) nextChunk() error {
+	n, err := io.ReadFull(reader.input, reader.ciphertext)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return err
+	}
+
+	last := err != nil
+	if !last {
+		if _, peekErr := reader.input.Peek(1); peekErr == io.EOF {
+			last = true
+		} else if peekErr != nil {
+			return peekErr
+		}
+	}
+
+	if n < reader.aead.Overhead() {
+		return ErrTruncatedContent
+	}
+
+	plaintext, err := reader.aead.Open(reader.buffer[:0], chunkNonce(reader.header.noncePrefix, reader.counter, last), reader.ciphertext[:n], reader.rawHeader)
+	if err != nil {
+		if last {
+			// a non-final chunk that ends the stream means the archive was cut at a chunk boundary
+			if _, openErr := reader.aead.Open(nil, chunkNonce(reader.header.noncePrefix, reader.counter, false), reader.ciphertext[:n], reader.rawHeader); openErr == nil {
+				return ErrTruncatedContent
+			}
+		}
+		return ErrDecryptionFailed
+	}
+
+	reader.plaintext = plaintext
+	reader.done = last
+	reader.counter++
+	return nil
+}
+
+// aesDecryptLegacy decrypts content produced by the legacy format which is
+// simplistic in that it omits any authentication of the encrypted data.
+// Sourced from https://golang.org/src/crypto/cipher/example_test.go
+func aesDecryptLegacy(input io.Reader, passphrase []byte) (io.Reader, error) {
 	// making a 32 bytes key that would correspond to AES-256
-	// don't necessarily need a salt, so just kept in empty
-	key, err := scrypt.Key(passphrase, emptySalt, 32768, 8, 1, 32)
+	// the legacy format didn't use a salt
+	key, err := scrypt.Key(passphrase, emptySalt, scryptN, scryptR, scryptP, aesKeyLength)
 	if err != nil {
 		return nil, err
 	}
@@ -59,8 +325,7 @@ func AesDecrypt(input io.Reader, passphrase []byte) (io.Reader, error) {
 		return nil, err
 	}
 
-	// If the key is unique for each ciphertext, then it's ok to use a zero
-	// IV.
+	// the legacy format relied on the key being unique for each ciphertext to use a zero IV
 	var iv [aes.BlockSize]byte
 	stream := cipher.NewOFB(block, iv[:])
 
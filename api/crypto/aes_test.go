@@ -1,6 +1,10 @@
 package crypto
 
 import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
 	"io"
 	"io/ioutil"
 	"os"
@@ -9,6 +13,7 @@ import (
 
 	"github.com/docker/docker/pkg/ioutils"
 	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/scrypt"
 )
 
 func Test_encryptAndDecrypt_withTheSamePassword(t *testing.T) {
@@ -91,42 +96,91 @@ func Test_encryptAndDecrypt_withEmptyPassword(t *testing.T) {
 	assert.Equal(t, content, decryptedContent, "Original and decrypted content should match")
 }
 
-func Test_decryptWithDifferentPassphrase_shouldProduceWrongResult(t *testing.T) {
-	tmpdir, _ := ioutils.TempDir("", "encrypt")
-	defer os.RemoveAll(tmpdir)
+func Test_decryptWithDifferentPassphrase_shouldFail(t *testing.T) {
+	var encrypted bytes.Buffer
+	err := AesEncrypt(bytes.NewReader([]byte("content")), &encrypted, []byte("passphrase"))
+	assert.Nil(t, err, "Failed to encrypt content")
 
-	var (
-		originFilePath    = filepath.Join(tmpdir, "origin")
-		encryptedFilePath = filepath.Join(tmpdir, "encrypted")
-		decryptedFilePath = filepath.Join(tmpdir, "decrypted")
-	)
+	_, err = AesDecrypt(bytes.NewReader(encrypted.Bytes()), []byte("garbage"))
+	assert.Equal(t, ErrDecryptionFailed, err, "Should not allow to decrypt with wrong passphrase")
+}
 
-	content := []byte("content")
-	ioutil.WriteFile(originFilePath, content, 0600)
+func Test_encryptAndDecrypt_withMultipleChunks(t *testing.T) {
+	content := make([]byte, 3*aesChunkSize+42)
+	rand.Read(content)
 
-	originFile, _ := os.Open(originFilePath)
-	defer originFile.Close()
+	for _, size := range []int{0, aesChunkSize, 2 * aesChunkSize, len(content)} {
+		var encrypted bytes.Buffer
+		err := AesEncrypt(bytes.NewReader(content[:size]), &encrypted, []byte("passphrase"))
+		assert.Nil(t, err, "Failed to encrypt content")
 
-	encryptedFileWriter, _ := os.Create(encryptedFilePath)
-	defer encryptedFileWriter.Close()
+		decryptedReader, err := AesDecrypt(&encrypted, []byte("passphrase"))
+		assert.Nil(t, err, "Failed to decrypt content")
 
-	err := AesEncrypt(originFile, encryptedFileWriter, []byte("passphrase"))
-	assert.Nil(t, err, "Failed to encrypt a file")
-	encryptedContent, err := ioutil.ReadFile(encryptedFilePath)
-	assert.Nil(t, err, "Couldn't read encrypted file")
-	assert.NotEqual(t, encryptedContent, content, "Content wasn't encrypted")
+		decrypted, err := ioutil.ReadAll(decryptedReader)
+		assert.Nil(t, err, "Failed to read decrypted content")
+		assert.Equal(t, content[:size], decrypted, "Original and decrypted content should match")
+	}
+}
 
-	encryptedFileReader, _ := os.Open(encryptedFilePath)
-	defer encryptedFileReader.Close()
+func Test_encrypt_shouldUseRandomSalt(t *testing.T) {
+	var first, second bytes.Buffer
+	AesEncrypt(bytes.NewReader([]byte("content")), &first, []byte("passphrase"))
+	AesEncrypt(bytes.NewReader([]byte("content")), &second, []byte("passphrase"))
 
-	decryptedFileWriter, _ := os.Create(decryptedFilePath)
-	defer decryptedFileWriter.Close()
+	assert.NotEqual(t, first.Bytes(), second.Bytes(), "Same content should not be encrypted the same way twice")
+}
 
-	decryptedReader, err := AesDecrypt(encryptedFileReader, []byte("garbage"))
-	assert.Nil(t, err, "Should allow to decrypt with wrong passphrase")
+func Test_decryptTamperedContent_shouldFail(t *testing.T) {
+	content := make([]byte, 2*aesChunkSize)
+	rand.Read(content)
 
-	io.Copy(decryptedFileWriter, decryptedReader)
+	var encrypted bytes.Buffer
+	err := AesEncrypt(bytes.NewReader(content), &encrypted, []byte("passphrase"))
+	assert.Nil(t, err, "Failed to encrypt content")
 
-	decryptedContent, _ := ioutil.ReadFile(decryptedFilePath)
-	assert.NotEqual(t, content, decryptedContent, "Original and decrypted content should NOT match")
+	tampered := encrypted.Bytes()
+	tampered[len(tampered)-100] ^= 0xff
+
+	decryptedReader, err := AesDecrypt(bytes.NewReader(tampered), []byte("passphrase"))
+	assert.Nil(t, err, "First chunk should still be valid")
+
+	_, err = ioutil.ReadAll(decryptedReader)
+	assert.Equal(t, ErrDecryptionFailed, err, "Should detect tampered content")
+}
+
+func Test_decryptTruncatedContent_shouldFail(t *testing.T) {
+	content := make([]byte, 2*aesChunkSize+10)
+	rand.Read(content)
+
+	var encrypted bytes.Buffer
+	err := AesEncrypt(bytes.NewReader(content), &encrypted, []byte("passphrase"))
+	assert.Nil(t, err, "Failed to encrypt content")
+
+	// drop the last chunk entirely
+	truncated := encrypted.Bytes()[:aesHeaderSize+2*(aesChunkSize+16)]
+
+	decryptedReader, err := AesDecrypt(bytes.NewReader(truncated), []byte("passphrase"))
+	assert.Nil(t, err, "First chunk should still be valid")
+
+	_, err = ioutil.ReadAll(decryptedReader)
+	assert.Equal(t, ErrTruncatedContent, err, "Should detect truncated content")
+}
+
+func Test_decrypt_shouldReadLegacyFormat(t *testing.T) {
+	content := []byte("legacy content")
+
+	key, _ := scrypt.Key([]byte("passphrase"), nil, 32768, 8, 1, 32)
+	block, _ := aes.NewCipher(key)
+	var iv [aes.BlockSize]byte
+
+	var encrypted bytes.Buffer
+	writer := &cipher.StreamWriter{S: cipher.NewOFB(block, iv[:]), W: &encrypted}
+	writer.Write(content)
+
+	decryptedReader, err := AesDecrypt(&encrypted, []byte("passphrase"))
+	assert.Nil(t, err, "Failed to decrypt legacy content")
+
+	decrypted, _ := ioutil.ReadAll(decryptedReader)
+	assert.Equal(t, content, decrypted, "Original and decrypted content should match")
 }
@@ -3,6 +3,7 @@ package crypto
 import (
 	"crypto/tls"
 	"crypto/x509"
+	"errors"
 	"io/ioutil"
 )
 
@@ -24,6 +25,30 @@ func CreateServerTLSConfiguration() *tls.Config {
 	}
 }
 
+// CreateServerMTLSConfiguration creates a tls.Config to be used by servers that verify the client certificates
+// against the CA certificates of a bundle. When required is false, the clients can connect without certificate
+// but a certificate that is presented must be valid.
+func CreateServerMTLSConfiguration(clientCACertPath string, required bool) (*tls.Config, error) {
+	caCert, err := ioutil.ReadFile(clientCACertPath)
+	if err != nil {
+		return nil, err
+	}
+
+	caCertPool := x509.NewCertPool()
+	if !caCertPool.AppendCertsFromPEM(caCert) {
+		return nil, errors.New("no certificate found in the client CA bundle")
+	}
+
+	config := CreateServerTLSConfiguration()
+	config.ClientCAs = caCertPool
+	config.ClientAuth = tls.VerifyClientCertIfGiven
+	if required {
+		config.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+
+	return config, nil
+}
+
 // CreateTLSConfigurationFromBytes initializes a tls.Config using a CA certificate, a certificate and a key
 // loaded from memory.
 func CreateTLSConfigurationFromBytes(caCert, cert, key []byte, skipClientVerification, skipServerVerification bool) (*tls.Config, error) {
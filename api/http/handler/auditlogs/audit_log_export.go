@@ -0,0 +1,122 @@
+package auditlogs
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	httperror "github.com/portainer/libhttp/error"
+	"github.com/portainer/libhttp/request"
+	"github.com/portainer/portainer/api"
+)
+
+const (
+	exportFormatJSONLines = "jsonl"
+	exportFormatCSV       = "csv"
+)
+
+var csvHeader = []string{"Id", "Timestamp", "UserId", "Username", "TeamIds", "EndpointId", "Method", "Path", "Operation", "ResourceId", "Status", "ClientIP"}
+
+// @id AuditLogExport
+// @summary Export audit logs
+// @description Download the audit logs matching the filters, most recent first, as JSON lines or CSV.
+// @description **Access policy**: administrator
+// @tags audit_logs
+// @security jwt
+// @produce octet-stream
+// @param format query string false "Export format, jsonl (default) or csv" Enums(jsonl, csv)
+// @param userId query int false "Only export the requests made by this user"
+// @param endpointId query int false "Only export the requests targeting this endpoint"
+// @param operation query string false "Only export the requests performing this operation" example(DockerContainerStop)
+// @param from query int false "Only export the requests made at or after this Unix timestamp"
+// @param to query int false "Only export the requests made at or before this Unix timestamp"
+// @success 200 "Success"
+// @failure 400 "Invalid request"
+// @failure 500 "Server error"
+// @router /audit_logs/export [get]
+func (handler *Handler) auditLogExport(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	format, _ := request.RetrieveQueryParameter(r, "format", true)
+	if format == "" {
+		format = exportFormatJSONLines
+	}
+
+	if format != exportFormatJSONLines && format != exportFormatCSV {
+		return &httperror.HandlerError{StatusCode: http.StatusBadRequest, Message: "Invalid query parameter: format. Value must be one of: jsonl or csv", Err: errors.New("Invalid export format")}
+	}
+
+	auditLogs, _, handlerErr := handler.filteredAuditLogs(r, 0, 0)
+	if handlerErr != nil {
+		return handlerErr
+	}
+
+	fileName := fmt.Sprintf("portainer-audit-logs_%s.%s", time.Now().Format("2006-01-02_15-04-05"), format)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%s", fileName))
+
+	var err error
+	if format == exportFormatCSV {
+		w.Header().Set("Content-Type", "text/csv")
+		err = writeCSV(w, auditLogs)
+	} else {
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		err = writeJSONLines(w, auditLogs)
+	}
+
+	if err != nil {
+		return &httperror.HandlerError{StatusCode: http.StatusInternalServerError, Message: "Unable to export the audit logs", Err: err}
+	}
+
+	return nil
+}
+
+func writeJSONLines(w http.ResponseWriter, auditLogs []portainer.AuditLog) error {
+	encoder := json.NewEncoder(w)
+	for _, auditLog := range auditLogs {
+		err := encoder.Encode(auditLog)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeCSV(w http.ResponseWriter, auditLogs []portainer.AuditLog) error {
+	writer := csv.NewWriter(w)
+
+	err := writer.Write(csvHeader)
+	if err != nil {
+		return err
+	}
+
+	for _, auditLog := range auditLogs {
+		teamIDs := make([]string, 0, len(auditLog.TeamIDs))
+		for _, teamID := range auditLog.TeamIDs {
+			teamIDs = append(teamIDs, strconv.Itoa(int(teamID)))
+		}
+
+		err := writer.Write([]string{
+			strconv.Itoa(int(auditLog.ID)),
+			strconv.FormatInt(auditLog.Timestamp, 10),
+			strconv.Itoa(int(auditLog.UserID)),
+			auditLog.Username,
+			strings.Join(teamIDs, ";"),
+			strconv.Itoa(int(auditLog.EndpointID)),
+			auditLog.Method,
+			auditLog.Path,
+			string(auditLog.Operation),
+			auditLog.ResourceID,
+			strconv.Itoa(auditLog.Status),
+			auditLog.ClientIP,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
@@ -0,0 +1,58 @@
+package auditlogs
+
+import (
+	"net/http"
+	"strconv"
+
+	httperror "github.com/portainer/libhttp/error"
+	"github.com/portainer/libhttp/request"
+	"github.com/portainer/libhttp/response"
+)
+
+// defaultAuditLogPageSize is the number of audit logs listed when the request has no limit
+const defaultAuditLogPageSize = 100
+
+// @id AuditLogList
+// @summary List audit logs
+// @description List the audit logs of the requests which changed, or attempted to change, a resource, most recent first.
+// @description The number of audit logs matching the filters is returned in the X-Total-Count header.
+// @description **Access policy**: administrator
+// @tags audit_logs
+// @security jwt
+// @produce json
+// @param start query int false "Start listing from this index, starting at 1"
+// @param limit query int false "List at most this number of audit logs, 100 by default"
+// @param userId query int false "Only list the requests made by this user"
+// @param endpointId query int false "Only list the requests targeting this endpoint"
+// @param operation query string false "Only list the requests performing this operation" example(DockerContainerStop)
+// @param from query int false "Only list the requests made at or after this Unix timestamp"
+// @param to query int false "Only list the requests made at or before this Unix timestamp"
+// @success 200 {array} portainer.AuditLog "Success"
+// @failure 400 "Invalid request"
+// @failure 500 "Server error"
+// @router /audit_logs [get]
+func (handler *Handler) auditLogList(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	start, err := request.RetrieveNumericQueryParameter(r, "start", true)
+	if err != nil {
+		return &httperror.HandlerError{StatusCode: http.StatusBadRequest, Message: "Invalid query parameter: start", Err: err}
+	}
+	if start != 0 {
+		start--
+	}
+
+	limit, err := request.RetrieveNumericQueryParameter(r, "limit", true)
+	if err != nil {
+		return &httperror.HandlerError{StatusCode: http.StatusBadRequest, Message: "Invalid query parameter: limit", Err: err}
+	}
+	if limit <= 0 {
+		limit = defaultAuditLogPageSize
+	}
+
+	auditLogs, count, handlerErr := handler.filteredAuditLogs(r, start, limit)
+	if handlerErr != nil {
+		return handlerErr
+	}
+
+	w.Header().Set("X-Total-Count", strconv.Itoa(count))
+	return response.JSON(w, auditLogs)
+}
@@ -0,0 +1,103 @@
+package auditlogs
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+	httperror "github.com/portainer/libhttp/error"
+	"github.com/portainer/libhttp/request"
+	"github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/http/security"
+)
+
+// Handler is the HTTP handler used to query the audit logs.
+type Handler struct {
+	*mux.Router
+	DataStore portainer.DataStore
+}
+
+// NewHandler creates a handler to query the audit logs.
+func NewHandler(bouncer *security.RequestBouncer) *Handler {
+	h := &Handler{
+		Router: mux.NewRouter(),
+	}
+	h.Handle("/audit_logs",
+		bouncer.AdminAccess(httperror.LoggerHandler(h.auditLogList))).Methods(http.MethodGet)
+	h.Handle("/audit_logs/export",
+		bouncer.AdminAccess(httperror.LoggerHandler(h.auditLogExport))).Methods(http.MethodGet)
+
+	return h
+}
+
+// auditLogFilter holds the filters of the audit log queries, a zero value does not filter
+type auditLogFilter struct {
+	userID     portainer.UserID
+	endpointID portainer.EndpointID
+	operation  portainer.Authorization
+	from       int64
+	to         int64
+}
+
+func retrieveAuditLogFilter(r *http.Request) (auditLogFilter, *httperror.HandlerError) {
+	userID, err := request.RetrieveNumericQueryParameter(r, "userId", true)
+	if err != nil {
+		return auditLogFilter{}, &httperror.HandlerError{StatusCode: http.StatusBadRequest, Message: "Invalid query parameter: userId", Err: err}
+	}
+
+	endpointID, err := request.RetrieveNumericQueryParameter(r, "endpointId", true)
+	if err != nil {
+		return auditLogFilter{}, &httperror.HandlerError{StatusCode: http.StatusBadRequest, Message: "Invalid query parameter: endpointId", Err: err}
+	}
+
+	operation, _ := request.RetrieveQueryParameter(r, "operation", true)
+
+	from, err := request.RetrieveNumericQueryParameter(r, "from", true)
+	if err != nil {
+		return auditLogFilter{}, &httperror.HandlerError{StatusCode: http.StatusBadRequest, Message: "Invalid query parameter: from", Err: err}
+	}
+
+	to, err := request.RetrieveNumericQueryParameter(r, "to", true)
+	if err != nil {
+		return auditLogFilter{}, &httperror.HandlerError{StatusCode: http.StatusBadRequest, Message: "Invalid query parameter: to", Err: err}
+	}
+
+	return auditLogFilter{
+		userID:     portainer.UserID(userID),
+		endpointID: portainer.EndpointID(endpointID),
+		operation:  portainer.Authorization(operation),
+		from:       int64(from),
+		to:         int64(to),
+	}, nil
+}
+
+func (filter auditLogFilter) match(auditLog portainer.AuditLog) bool {
+	switch {
+	case filter.userID != 0 && auditLog.UserID != filter.userID:
+		return false
+	case filter.endpointID != 0 && auditLog.EndpointID != filter.endpointID:
+		return false
+	case filter.operation != "" && auditLog.Operation != filter.operation:
+		return false
+	case filter.from != 0 && auditLog.Timestamp < filter.from:
+		return false
+	case filter.to != 0 && auditLog.Timestamp > filter.to:
+		return false
+	}
+	return true
+}
+
+// filteredAuditLogs returns a page of the audit logs matching the filters of the request, most recent first,
+// and the number of audit logs matching the filters. Every matching audit log is returned when limit is 0.
+func (handler *Handler) filteredAuditLogs(r *http.Request, start, limit int) ([]portainer.AuditLog, int, *httperror.HandlerError) {
+	filter, handlerErr := retrieveAuditLogFilter(r)
+	if handlerErr != nil {
+		return nil, 0, handlerErr
+	}
+
+	auditLogs, count, err := handler.DataStore.AuditLog().FilteredAuditLogs(filter.match, start, limit)
+	if err != nil {
+		return nil, 0, &httperror.HandlerError{StatusCode: http.StatusInternalServerError, Message: "Unable to retrieve the audit logs from the database", Err: err}
+	}
+
+	return auditLogs, count, nil
+}
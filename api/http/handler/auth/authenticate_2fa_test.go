@@ -0,0 +1,136 @@
+package auth
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	httperror "github.com/portainer/libhttp/error"
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/bolt/bolttest"
+	"github.com/portainer/portainer/api/crypto"
+	"github.com/portainer/portainer/api/http/security"
+	"github.com/portainer/portainer/api/jwt"
+	"github.com/portainer/portainer/api/twofactor"
+	"github.com/pquerna/otp/totp"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_authenticate_shouldRequireTheSecondFactor(t *testing.T) {
+	store, teardown := bolttest.MustNewTestStore(true)
+	defer teardown()
+
+	jwtService, err := jwt.NewService("24h", store, []byte("secret"))
+	assert.NoError(t, err)
+
+	cryptoService := &crypto.Service{}
+	handler := &Handler{
+		DataStore:           store,
+		CryptoService:       cryptoService,
+		JWTService:          jwtService,
+		twoFactorChallenges: twofactor.NewChallengeStore(),
+		bouncer:             security.NewRequestBouncer(store, jwtService, nil),
+	}
+
+	settings, _ := store.Settings().Settings()
+	settings.TwoFactorRequirement = portainer.TwoFactorRequiredForAdministrators
+	store.Settings().UpdateSettings(settings)
+
+	hash, _ := cryptoService.Hash("password")
+	store.User().CreateUser(&portainer.User{Username: "admin", Password: hash, Role: portainer.AdministratorRole})
+
+	var authResponse authenticateResponse
+	rr := postJSON(handler.authenticate, authenticatePayload{Username: "admin", Password: "password"}, &authResponse)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Empty(t, authResponse.JWT, "no token should be issued before the second factor is provided")
+	assert.True(t, authResponse.TwoFactorEnrolmentRequired)
+
+	var enrolResponse twoFactorEnrolResponse
+	rr = postJSON(handler.twoFactorEnrol, twoFactorEnrolPayload{ChallengeToken: authResponse.ChallengeToken}, &enrolResponse)
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	var twoFactorResponse twoFactorAuthenticateResponse
+	rr = postJSON(handler.authenticateTwoFactor, twoFactorAuthenticatePayload{ChallengeToken: authResponse.ChallengeToken, Code: "000000"}, nil)
+	assert.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+
+	code, _ := totp.GenerateCode(enrolResponse.Secret, time.Now())
+	rr = postJSON(handler.authenticateTwoFactor, twoFactorAuthenticatePayload{ChallengeToken: authResponse.ChallengeToken, Code: code}, &twoFactorResponse)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.NotEmpty(t, twoFactorResponse.JWT)
+	assert.NotEmpty(t, twoFactorResponse.RecoveryCodes)
+
+	rr = postJSON(handler.authenticateTwoFactor, twoFactorAuthenticatePayload{ChallengeToken: authResponse.ChallengeToken, RecoveryCode: twoFactorResponse.RecoveryCodes[0]}, nil)
+	assert.Equal(t, http.StatusUnauthorized, rr.Code, "a challenge should only be exchanged once")
+
+	authResponse = authenticateResponse{}
+	postJSON(handler.authenticate, authenticatePayload{Username: "admin", Password: "password"}, &authResponse)
+	assert.False(t, authResponse.TwoFactorEnrolmentRequired)
+
+	rr = postJSON(handler.twoFactorEnrol, twoFactorEnrolPayload{ChallengeToken: authResponse.ChallengeToken}, nil)
+	assert.Equal(t, http.StatusConflict, rr.Code, "the password alone should not replace an enabled second factor")
+
+	rr = postJSON(handler.authenticateTwoFactor, twoFactorAuthenticatePayload{ChallengeToken: authResponse.ChallengeToken, RecoveryCode: twoFactorResponse.RecoveryCodes[0]}, nil)
+	assert.Equal(t, http.StatusOK, rr.Code)
+}
+
+func Test_authenticate_shouldReplaceTheExpiredPasswordAfterTheSecondFactor(t *testing.T) {
+	store, teardown := bolttest.MustNewTestStore(true)
+	defer teardown()
+
+	jwtService, err := jwt.NewService("24h", store, []byte("secret"))
+	assert.NoError(t, err)
+
+	cryptoService := &crypto.Service{}
+	handler := &Handler{
+		DataStore:           store,
+		CryptoService:       cryptoService,
+		JWTService:          jwtService,
+		twoFactorChallenges: twofactor.NewChallengeStore(),
+		bouncer:             security.NewRequestBouncer(store, jwtService, nil),
+	}
+
+	settings, _ := store.Settings().Settings()
+	settings.PasswordPolicy = portainer.PasswordPolicy{ExpiryDays: 30}
+	store.Settings().UpdateSettings(settings)
+
+	key, _ := totp.Generate(totp.GenerateOpts{Issuer: "Portainer", AccountName: "bob"})
+	hash, _ := cryptoService.Hash("password")
+	user := &portainer.User{
+		Username:           "bob",
+		Password:           hash,
+		PasswordChangeDate: time.Now().AddDate(0, 0, -31).Unix(),
+		TOTP:               portainer.TOTPSettings{Enabled: true, Secret: key.Secret()},
+	}
+	store.User().CreateUser(user)
+
+	var authResponse authenticateResponse
+	rr := postJSON(handler.authenticate, authenticatePayload{Username: "bob", Password: "password", NewPassword: "new-password"}, &authResponse)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.NotEmpty(t, authResponse.ChallengeToken)
+
+	storedUser, _ := store.User().User(user.ID)
+	assert.Equal(t, hash, storedUser.Password, "the password should not be replaced before the second factor is verified")
+
+	code, _ := totp.GenerateCode(key.Secret(), time.Now())
+	rr = postJSON(handler.authenticateTwoFactor, twoFactorAuthenticatePayload{ChallengeToken: authResponse.ChallengeToken, Code: code}, nil)
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	storedUser, _ = store.User().User(user.ID)
+	assert.NoError(t, cryptoService.CompareHashAndData(storedUser.Password, "new-password"))
+}
+
+func postJSON(handlerFunc func(http.ResponseWriter, *http.Request) *httperror.HandlerError, payload interface{}, result interface{}) *httptest.ResponseRecorder {
+	body, _ := json.Marshal(payload)
+	req := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(body))
+	rr := httptest.NewRecorder()
+
+	httperror.LoggerHandler(handlerFunc).ServeHTTP(rr, req)
+
+	if result != nil {
+		json.NewDecoder(rr.Body).Decode(result)
+	}
+	return rr
+}
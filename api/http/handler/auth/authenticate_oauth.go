@@ -4,7 +4,7 @@ import (
 	"errors"
 	"log"
 	"net/http"
-	"time"
+	"strings"
 
 	"github.com/asaskevich/govalidator"
 	httperror "github.com/portainer/libhttp/error"
@@ -12,11 +12,14 @@ import (
 	portainer "github.com/portainer/portainer/api"
 	bolterrors "github.com/portainer/portainer/api/bolt/errors"
 	httperrors "github.com/portainer/portainer/api/http/errors"
+	"github.com/portainer/portainer/api/internal/provisioning"
 )
 
 type oauthPayload struct {
 	// OAuth code returned from OAuth Provided
 	Code string
+	// OAuth state returned from OAuth Provider, required when OpenID Connect is enabled
+	State string
 }
 
 func (payload *oauthPayload) Validate(r *http.Request) error {
@@ -26,21 +29,21 @@ func (payload *oauthPayload) Validate(r *http.Request) error {
 	return nil
 }
 
-func (handler *Handler) authenticateOAuth(code string, settings *portainer.OAuthSettings) (string, *time.Time, error) {
+func (handler *Handler) authenticateOAuth(code, state string, settings *portainer.OAuthSettings) (*portainer.OAuthInfo, error) {
 	if code == "" {
-		return "", nil, errors.New("Invalid OAuth authorization code")
+		return nil, errors.New("Invalid OAuth authorization code")
 	}
 
 	if settings == nil {
-		return "", nil, errors.New("Invalid OAuth configuration")
+		return nil, errors.New("Invalid OAuth configuration")
 	}
 
-	username, expiryTime, err := handler.OAuthService.Authenticate(code, settings)
+	info, err := handler.OAuthService.Authenticate(code, state, settings)
 	if err != nil {
-		return "", nil, err
+		return nil, err
 	}
 
-	return username, expiryTime, nil
+	return info, nil
 }
 
 // @id ValidateOAuth
@@ -70,13 +73,13 @@ func (handler *Handler) validateOAuth(w http.ResponseWriter, r *http.Request) *h
 		return &httperror.HandlerError{StatusCode: http.StatusForbidden, Message: "OAuth authentication is not enabled", Err: errors.New("OAuth authentication is not enabled")}
 	}
 
-	username, expiryTime, err := handler.authenticateOAuth(payload.Code, &settings.OAuthSettings)
+	info, err := handler.authenticateOAuth(payload.Code, payload.State, &settings.OAuthSettings)
 	if err != nil {
 		log.Printf("[DEBUG] - OAuth authentication error: %s", err)
 		return &httperror.HandlerError{StatusCode: http.StatusInternalServerError, Message: "Unable to authenticate through OAuth", Err: httperrors.ErrUnauthorized}
 	}
 
-	user, err := handler.DataStore.User().UserByUsername(username)
+	user, err := handler.DataStore.User().UserByUsername(info.Username)
 	if err != nil && err != bolterrors.ErrObjectNotFound {
 		return &httperror.HandlerError{StatusCode: http.StatusInternalServerError, Message: "Unable to retrieve a user with the specified username from the database", Err: err}
 	}
@@ -86,9 +89,11 @@ func (handler *Handler) validateOAuth(w http.ResponseWriter, r *http.Request) *h
 	}
 
 	if user == nil {
+		role, _ := provisioning.UserRoleFromGroups(info.Groups, settings.OAuthSettings.AdminGroups)
 		user = &portainer.User{
-			Username: username,
-			Role:     portainer.StandardUserRole,
+			Username:             info.Username,
+			Role:                 role,
+			AuthenticationMethod: portainer.AuthenticationOAuth,
 		}
 
 		err = handler.DataStore.User().CreateUser(user)
@@ -111,5 +116,143 @@ func (handler *Handler) validateOAuth(w http.ResponseWriter, r *http.Request) *h
 
 	}
 
-	return handler.writeTokenForOAuth(w, user, expiryTime)
+	if info.GroupsProvided {
+		err = handler.syncOAuthTeams(user, info.Groups, settings.OAuthSettings.DefaultTeamID)
+		if err != nil {
+			log.Printf("[WARN] [http,auth,oauth] [message: unable to synchronize user teams with the OAuth groups] [error: %s]", err)
+		}
+
+		err = provisioning.UpdateUserRoleFromGroups(handler.DataStore, handler.JWTService, user, info.Groups, settings.OAuthSettings.AdminGroups, "oauth")
+		if err != nil {
+			log.Printf("[WARN] [http,auth,oauth] [message: unable to update user role from the OAuth groups] [error: %s]", err)
+		}
+	}
+
+	return handler.writeTokenForOAuth(w, r, user, info.ExpiryTime)
+}
+
+// @id OAuthLogin
+// @summary Start an OpenID Connect login
+// @description Redirects to the authorization endpoint of the OpenID Connect provider. The nonce and the PKCE
+// @description code verifier of the login are kept by Portainer until the code is validated with the same state.
+// @description **Access policy**: public
+// @tags auth
+// @param state query string true "Value generated by the client, the state sent to the provider is generated by Portainer and prefixed with it"
+// @success 302 "Redirect to the authorization endpoint"
+// @failure 400 "Invalid request"
+// @failure 403 "OpenID Connect is not enabled"
+// @failure 500 "Server error"
+// @router /auth/oauth/login [get]
+func (handler *Handler) oauthLogin(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	clientState, err := request.RetrieveQueryParameter(r, "state", false)
+	if err != nil {
+		return &httperror.HandlerError{StatusCode: http.StatusBadRequest, Message: "Invalid query parameter: state", Err: err}
+	}
+
+	settings, err := handler.DataStore.Settings().Settings()
+	if err != nil {
+		return &httperror.HandlerError{StatusCode: http.StatusInternalServerError, Message: "Unable to retrieve settings from the database", Err: err}
+	}
+
+	if settings.AuthenticationMethod != portainer.AuthenticationOAuth || !settings.OAuthSettings.OIDC {
+		return &httperror.HandlerError{StatusCode: http.StatusForbidden, Message: "OpenID Connect authentication is not enabled", Err: errors.New("OpenID Connect authentication is not enabled")}
+	}
+
+	authorizationURL, err := handler.OAuthService.AuthorizationURL(clientState, &settings.OAuthSettings)
+	if err != nil {
+		return &httperror.HandlerError{StatusCode: http.StatusInternalServerError, Message: "Unable to start the OpenID Connect login", Err: err}
+	}
+
+	http.Redirect(w, r, authorizationURL, http.StatusFound)
+	return nil
+}
+
+// syncOAuthTeams synchronizes the team memberships of a user with the groups returned by the identity provider.
+// Missing teams are created and the teams matching a group are managed by the group mapping from then on. The user
+// is added to the teams matching the groups and removed from the other managed teams, except the default team.
+// The memberships of the teams that never matched a group are left untouched.
+func (handler *Handler) syncOAuthTeams(user *portainer.User, groups []string, defaultTeamID portainer.TeamID) error {
+	teams, err := handler.DataStore.Team().Teams()
+	if err != nil {
+		return err
+	}
+
+	for idx := range teams {
+		team := &teams[idx]
+		if team.OAuthGroup || !teamExists(team.Name, groups) {
+			continue
+		}
+
+		team.OAuthGroup = true
+		err = handler.DataStore.Team().UpdateTeam(team.ID, team)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, group := range groups {
+		if teamNameExists(group, teams) {
+			continue
+		}
+
+		team := &portainer.Team{Name: group, OAuthGroup: true}
+		err = handler.DataStore.Team().CreateTeam(team)
+		if err != nil {
+			return err
+		}
+		teams = append(teams, *team)
+	}
+
+	userMemberships, err := handler.DataStore.TeamMembership().TeamMembershipsByUserID(user.ID)
+	if err != nil {
+		return err
+	}
+
+	for _, team := range teams {
+		if !teamExists(team.Name, groups) || teamMembershipExists(team.ID, userMemberships) {
+			continue
+		}
+
+		membership := &portainer.TeamMembership{
+			UserID: user.ID,
+			TeamID: team.ID,
+			Role:   portainer.TeamMember,
+		}
+
+		err = handler.DataStore.TeamMembership().CreateTeamMembership(membership)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, membership := range userMemberships {
+		if membership.TeamID == defaultTeamID {
+			continue
+		}
+
+		team, err := handler.DataStore.Team().Team(membership.TeamID)
+		if err != nil && err != bolterrors.ErrObjectNotFound {
+			return err
+		}
+
+		if team != nil && (!team.OAuthGroup || teamExists(team.Name, groups)) {
+			continue
+		}
+
+		err = handler.DataStore.TeamMembership().DeleteTeamMembership(membership.ID)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func teamNameExists(name string, teams []portainer.Team) bool {
+	for _, team := range teams {
+		if strings.EqualFold(team.Name, name) {
+			return true
+		}
+	}
+	return false
 }
@@ -0,0 +1,88 @@
+package auth
+
+import (
+	"testing"
+
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/bolt/bolttest"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_syncOAuthTeams(t *testing.T) {
+	store, teardown := bolttest.MustNewTestStore(true)
+	defer teardown()
+
+	handler := &Handler{DataStore: store}
+
+	user := &portainer.User{Username: "alice"}
+	store.User().CreateUser(user)
+
+	defaultTeam := &portainer.Team{Name: "everyone"}
+	store.Team().CreateTeam(defaultTeam)
+	devs := &portainer.Team{Name: "Devs"}
+	store.Team().CreateTeam(devs)
+	legacy := &portainer.Team{Name: "legacy", OAuthGroup: true}
+	store.Team().CreateTeam(legacy)
+
+	store.TeamMembership().CreateTeamMembership(&portainer.TeamMembership{UserID: user.ID, TeamID: defaultTeam.ID, Role: portainer.TeamMember})
+	store.TeamMembership().CreateTeamMembership(&portainer.TeamMembership{UserID: user.ID, TeamID: legacy.ID, Role: portainer.TeamMember})
+
+	err := handler.syncOAuthTeams(user, []string{"devs", "ops"}, defaultTeam.ID)
+	assert.NoError(t, err)
+
+	ops, err := store.Team().TeamByName("ops")
+	assert.NoError(t, err, "missing teams should be created")
+
+	memberships, _ := store.TeamMembership().TeamMembershipsByUserID(user.ID)
+	teamIDs := make([]portainer.TeamID, 0)
+	for _, membership := range memberships {
+		teamIDs = append(teamIDs, membership.TeamID)
+	}
+	assert.ElementsMatch(t, []portainer.TeamID{defaultTeam.ID, devs.ID, ops.ID}, teamIDs)
+
+	devs, _ = store.Team().Team(devs.ID)
+	assert.True(t, devs.OAuthGroup, "a team matching a group should be managed by the group mapping")
+
+	err = handler.syncOAuthTeams(user, []string{}, defaultTeam.ID)
+	assert.NoError(t, err)
+
+	memberships, _ = store.TeamMembership().TeamMembershipsByUserID(user.ID)
+	assert.Len(t, memberships, 1)
+	assert.Equal(t, defaultTeam.ID, memberships[0].TeamID)
+}
+
+func Test_syncOAuthTeams_shouldKeepTheMembershipsOfTheUnmanagedTeams(t *testing.T) {
+	store, teardown := bolttest.MustNewTestStore(true)
+	defer teardown()
+
+	handler := &Handler{DataStore: store}
+
+	user := &portainer.User{Username: "alice"}
+	store.User().CreateUser(user)
+
+	manual := &portainer.Team{Name: "support"}
+	store.Team().CreateTeam(manual)
+	store.TeamMembership().CreateTeamMembership(&portainer.TeamMembership{UserID: user.ID, TeamID: manual.ID, Role: portainer.TeamLeader})
+
+	err := handler.syncOAuthTeams(user, []string{"devs"}, 0)
+	assert.NoError(t, err)
+
+	devs, err := store.Team().TeamByName("devs")
+	assert.NoError(t, err)
+
+	memberships, _ := store.TeamMembership().TeamMembershipsByUserID(user.ID)
+	teamIDs := make([]portainer.TeamID, 0)
+	for _, membership := range memberships {
+		teamIDs = append(teamIDs, membership.TeamID)
+	}
+	assert.ElementsMatch(t, []portainer.TeamID{manual.ID, devs.ID}, teamIDs, "a membership assigned by hand should survive a login")
+
+	err = handler.syncOAuthTeams(user, []string{}, 0)
+	assert.NoError(t, err)
+
+	memberships, _ = store.TeamMembership().TeamMembershipsByUserID(user.ID)
+	if assert.Len(t, memberships, 1) {
+		assert.Equal(t, manual.ID, memberships[0].TeamID)
+		assert.Equal(t, portainer.TeamLeader, memberships[0].Role)
+	}
+}
@@ -0,0 +1,156 @@
+package auth
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/bolt/bolttest"
+	"github.com/portainer/portainer/api/crypto"
+	"github.com/portainer/portainer/api/http/security"
+	"github.com/portainer/portainer/api/jwt"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_authenticate_shouldLockTheAccountAfterFailedLogins(t *testing.T) {
+	store, teardown := bolttest.MustNewTestStore(true)
+	defer teardown()
+
+	jwtService, err := jwt.NewService("24h", store, []byte("secret"))
+	assert.NoError(t, err)
+
+	cryptoService := &crypto.Service{}
+	handler := &Handler{DataStore: store, CryptoService: cryptoService, JWTService: jwtService, bouncer: security.NewRequestBouncer(store, jwtService, nil)}
+
+	settings, _ := store.Settings().Settings()
+	settings.AccountLockout = portainer.AccountLockoutSettings{Threshold: 2, Duration: "1h"}
+	store.Settings().UpdateSettings(settings)
+
+	hash, _ := cryptoService.Hash("password")
+	user := &portainer.User{Username: "bob", Password: hash, Role: portainer.StandardUserRole}
+	store.User().CreateUser(user)
+
+	rr := postJSON(handler.authenticate, authenticatePayload{Username: "bob", Password: "wrong"}, nil)
+	assert.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+	rr = postJSON(handler.authenticate, authenticatePayload{Username: "bob", Password: "password"}, nil)
+	assert.Equal(t, http.StatusOK, rr.Code, "a successful login should reset the failed logins")
+
+	postJSON(handler.authenticate, authenticatePayload{Username: "bob", Password: "wrong"}, nil)
+	postJSON(handler.authenticate, authenticatePayload{Username: "bob", Password: "wrong"}, nil)
+
+	rr = postJSON(handler.authenticate, authenticatePayload{Username: "bob", Password: "password"}, nil)
+	assert.Equal(t, http.StatusUnprocessableEntity, rr.Code, "a locked account should be rejected even with the right password")
+
+	unknown := postJSON(handler.authenticate, authenticatePayload{Username: "alice", Password: "password"}, nil)
+	assert.Equal(t, unknown.Body.String(), rr.Body.String(), "a locked account should not be distinguishable from an unknown user")
+
+	user, _ = store.User().User(user.ID)
+	assert.NotZero(t, user.LockedUntil)
+}
+
+func Test_authenticate_shouldRequireANewPasswordWhenExpired(t *testing.T) {
+	store, teardown := bolttest.MustNewTestStore(true)
+	defer teardown()
+
+	jwtService, err := jwt.NewService("24h", store, []byte("secret"))
+	assert.NoError(t, err)
+
+	cryptoService := &crypto.Service{}
+	handler := &Handler{DataStore: store, CryptoService: cryptoService, JWTService: jwtService, bouncer: security.NewRequestBouncer(store, jwtService, nil)}
+
+	settings, _ := store.Settings().Settings()
+	settings.PasswordPolicy = portainer.PasswordPolicy{MinLength: 8, HistorySize: 1, ExpiryDays: 30}
+	store.Settings().UpdateSettings(settings)
+
+	hash, _ := cryptoService.Hash("password")
+	user := &portainer.User{Username: "bob", Password: hash, PasswordChangeDate: time.Now().AddDate(0, 0, -31).Unix()}
+	store.User().CreateUser(user)
+
+	rr := postJSON(handler.authenticate, authenticatePayload{Username: "bob", Password: "password"}, nil)
+	assert.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+
+	rr = postJSON(handler.authenticate, authenticatePayload{Username: "bob", Password: "password", NewPassword: "password"}, nil)
+	assert.Equal(t, http.StatusBadRequest, rr.Code, "the expired password should not be reused")
+
+	var response authenticateResponse
+	rr = postJSON(handler.authenticate, authenticatePayload{Username: "bob", Password: "password", NewPassword: "new-password"}, &response)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.NotEmpty(t, response.JWT)
+
+	rr = postJSON(handler.authenticate, authenticatePayload{Username: "bob", Password: "new-password"}, nil)
+	assert.Equal(t, http.StatusOK, rr.Code)
+}
+
+func Test_authenticate_shouldRecordTheSession(t *testing.T) {
+	store, teardown := bolttest.MustNewTestStore(true)
+	defer teardown()
+
+	jwtService, err := jwt.NewService("24h", store, []byte("secret"))
+	assert.NoError(t, err)
+
+	cryptoService := &crypto.Service{}
+	handler := &Handler{DataStore: store, CryptoService: cryptoService, JWTService: jwtService, bouncer: security.NewRequestBouncer(store, jwtService, nil)}
+
+	hash, _ := cryptoService.Hash("password")
+	user := &portainer.User{Username: "bob", Password: hash, Role: portainer.StandardUserRole}
+	store.User().CreateUser(user)
+
+	rr := postJSON(handler.authenticate, authenticatePayload{Username: "bob", Password: "password"}, nil)
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	sessions, _ := store.IssuedToken().IssuedTokensByUserID(user.ID)
+	assert.Len(t, sessions, 1)
+	assert.Equal(t, "192.0.2.1", sessions[0].ClientIP)
+	assert.NotZero(t, sessions[0].LastActivity)
+}
+
+type testLDAPService struct {
+	portainer.LDAPService
+	groupsErr error
+}
+
+func (service *testLDAPService) AuthenticateUser(username, password string, settings *portainer.LDAPSettings) error {
+	return nil
+}
+
+func (service *testLDAPService) GetUserGroups(username string, settings *portainer.LDAPSettings) ([]string, error) {
+	return nil, service.groupsErr
+}
+
+func Test_authenticate_shouldFailTheLDAPLogin_whenTheGroupsCannotBeRetrieved(t *testing.T) {
+	store, teardown := bolttest.MustNewTestStore(true)
+	defer teardown()
+
+	jwtService, err := jwt.NewService("24h", store, []byte("secret"))
+	assert.NoError(t, err)
+
+	ldapService := &testLDAPService{groupsErr: errors.New("connection reset")}
+	handler := &Handler{DataStore: store, CryptoService: &crypto.Service{}, JWTService: jwtService, LDAPService: ldapService, bouncer: security.NewRequestBouncer(store, jwtService, nil)}
+
+	settings, _ := store.Settings().Settings()
+	settings.AuthenticationMethod = portainer.AuthenticationLDAP
+	settings.LDAPSettings.AdminGroups = []string{"admins"}
+	settings.LDAPSettings.AutoCreateUsers = true
+	store.Settings().UpdateSettings(settings)
+
+	team := &portainer.Team{Name: "admins"}
+	store.Team().CreateTeam(team)
+	user := &portainer.User{Username: "bob", Role: portainer.AdministratorRole}
+	store.User().CreateUser(user)
+	store.TeamMembership().CreateTeamMembership(&portainer.TeamMembership{UserID: user.ID, TeamID: team.ID, Role: portainer.TeamMember})
+
+	rr := postJSON(handler.authenticate, authenticatePayload{Username: "bob", Password: "password"}, nil)
+	assert.Equal(t, http.StatusInternalServerError, rr.Code)
+
+	user, _ = store.User().User(user.ID)
+	assert.Equal(t, portainer.AdministratorRole, user.Role, "the role should be kept when the groups are unknown")
+	memberships, _ := store.TeamMembership().TeamMembershipsByUserID(user.ID)
+	assert.Len(t, memberships, 1)
+
+	rr = postJSON(handler.authenticate, authenticatePayload{Username: "carol", Password: "password"}, nil)
+	assert.Equal(t, http.StatusInternalServerError, rr.Code)
+	_, err = store.User().UserByUsername("carol")
+	assert.Error(t, err, "the user should not be created when the groups are unknown")
+}
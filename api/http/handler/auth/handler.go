@@ -9,6 +9,7 @@ import (
 	"github.com/portainer/portainer/api/http/proxy"
 	"github.com/portainer/portainer/api/http/proxy/factory/kubernetes"
 	"github.com/portainer/portainer/api/http/security"
+	"github.com/portainer/portainer/api/twofactor"
 )
 
 // Handler is the HTTP handler used to handle authentication operations.
@@ -21,20 +22,32 @@ type Handler struct {
 	OAuthService                portainer.OAuthService
 	ProxyManager                *proxy.Manager
 	KubernetesTokenCacheManager *kubernetes.TokenCacheManager
+	twoFactorChallenges         *twofactor.ChallengeStore
+	bouncer                     *security.RequestBouncer
 }
 
 // NewHandler creates a handler to manage authentication operations.
 func NewHandler(bouncer *security.RequestBouncer, rateLimiter *security.RateLimiter) *Handler {
 	h := &Handler{
-		Router: mux.NewRouter(),
+		Router:              mux.NewRouter(),
+		twoFactorChallenges: twofactor.NewChallengeStore(),
+		bouncer:             bouncer,
 	}
 
 	h.Handle("/auth/oauth/validate",
 		rateLimiter.LimitAccess(bouncer.PublicAccess(httperror.LoggerHandler(h.validateOAuth)))).Methods(http.MethodPost)
+	h.Handle("/auth/oauth/login",
+		rateLimiter.LimitAccess(bouncer.PublicAccess(httperror.LoggerHandler(h.oauthLogin)))).Methods(http.MethodGet)
 	h.Handle("/auth",
 		rateLimiter.LimitAccess(bouncer.PublicAccess(httperror.LoggerHandler(h.authenticate)))).Methods(http.MethodPost)
+	h.Handle("/auth/2fa",
+		rateLimiter.LimitAccess(bouncer.PublicAccess(httperror.LoggerHandler(h.authenticateTwoFactor)))).Methods(http.MethodPost)
+	h.Handle("/auth/2fa/enrol",
+		rateLimiter.LimitAccess(bouncer.PublicAccess(httperror.LoggerHandler(h.twoFactorEnrol)))).Methods(http.MethodPost)
 	h.Handle("/auth/logout",
 		bouncer.AuthenticatedAccess(httperror.LoggerHandler(h.logout))).Methods(http.MethodPost)
+	h.Handle("/auth/keys/rotate",
+		bouncer.AdminAccess(httperror.LoggerHandler(h.signingKeyRotate))).Methods(http.MethodPost)
 
 	return h
 }
@@ -10,6 +10,7 @@ import (
 
 // @id Logout
 // @summary Logout
+// @description Revokes the JWT token used to authenticate the request.
 // @security jwt
 // @tags auth
 // @success 204 "Success"
@@ -21,6 +22,11 @@ func (handler *Handler) logout(w http.ResponseWriter, r *http.Request) *httperro
 		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to retrieve user details from authentication token", err}
 	}
 
+	err = handler.JWTService.RevokeToken(tokenData.TokenID)
+	if err != nil {
+		return &httperror.HandlerError{StatusCode: http.StatusInternalServerError, Message: "Unable to revoke authentication token", Err: err}
+	}
+
 	handler.KubernetesTokenCacheManager.RemoveUserFromCache(int(tokenData.ID))
 
 	return response.Empty(w)
@@ -0,0 +1,48 @@
+package auth
+
+import (
+	"net/http"
+
+	httperror "github.com/portainer/libhttp/error"
+	"github.com/portainer/libhttp/request"
+	"github.com/portainer/libhttp/response"
+)
+
+type signingKeyRotatePayload struct {
+	// Invalidate every issued token immediately instead of keeping the tokens signed with the previous key valid until the end of their session
+	RevokeTokens bool `example:"false"`
+}
+
+func (payload *signingKeyRotatePayload) Validate(r *http.Request) error {
+	return nil
+}
+
+// @id SigningKeyRotate
+// @summary Rotate the JWT signing key
+// @description Replaces the key used to sign JWT tokens. Tokens signed with the previous key remain valid
+// @description until the end of their session, unless RevokeTokens is set in which case every user has to log in again.
+// @description **Access policy**: administrator
+// @tags auth
+// @security jwt
+// @accept json
+// @param body body signingKeyRotatePayload false "Rotation details"
+// @success 204 "Success"
+// @failure 400 "Invalid request"
+// @failure 500 "Server error"
+// @router /auth/keys/rotate [post]
+func (handler *Handler) signingKeyRotate(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	var payload signingKeyRotatePayload
+	if r.ContentLength != 0 {
+		err := request.DecodeAndValidateJSONPayload(r, &payload)
+		if err != nil {
+			return &httperror.HandlerError{StatusCode: http.StatusBadRequest, Message: "Invalid request payload", Err: err}
+		}
+	}
+
+	err := handler.JWTService.RotateSigningKey(payload.RevokeTokens)
+	if err != nil {
+		return &httperror.HandlerError{StatusCode: http.StatusInternalServerError, Message: "Unable to rotate the JWT signing key", Err: err}
+	}
+
+	return response.Empty(w)
+}
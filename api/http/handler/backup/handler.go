@@ -8,6 +8,7 @@ import (
 	httperror "github.com/portainer/libhttp/error"
 	portainer "github.com/portainer/portainer/api"
 	"github.com/portainer/portainer/api/adminmonitor"
+	operations "github.com/portainer/portainer/api/backup"
 	"github.com/portainer/portainer/api/http/offlinegate"
 	"github.com/portainer/portainer/api/http/security"
 )
@@ -21,6 +22,8 @@ type Handler struct {
 	filestorePath   string
 	shutdownTrigger context.CancelFunc
 	adminMonitor    *adminmonitor.Monitor
+	// Object storages a backup can be restored from through the public restore
+	RestoreS3Endpoints []string
 }
 
 // NewHandler creates an new instance of backup handler
@@ -37,6 +40,17 @@ func NewHandler(bouncer *security.RequestBouncer, dataStore portainer.DataStore,
 
 	h.Handle("/backup", bouncer.RestrictedAccess(adminAccess(httperror.LoggerHandler(h.backup)))).Methods(http.MethodPost)
 	h.Handle("/restore", bouncer.PublicAccess(httperror.LoggerHandler(h.restore))).Methods(http.MethodPost)
+	h.Handle("/restore/validate", bouncer.PublicAccess(httperror.LoggerHandler(h.restoreValidate))).Methods(http.MethodPost)
+	h.Handle("/backups", bouncer.RestrictedAccess(adminAccess(httperror.LoggerHandler(h.storedBackupList)))).Methods(http.MethodGet)
+	h.Handle("/backups/validate", bouncer.RestrictedAccess(adminAccess(httperror.LoggerHandler(h.backupValidate)))).Methods(http.MethodPost)
+	h.Handle("/backups/s3", bouncer.RestrictedAccess(adminAccess(httperror.LoggerHandler(h.s3BackupList)))).Methods(http.MethodGet)
+	h.Handle("/backups/s3", bouncer.RestrictedAccess(adminAccess(httperror.LoggerHandler(h.s3Backup)))).Methods(http.MethodPost)
+	h.Handle("/backups/{name}", bouncer.RestrictedAccess(adminAccess(httperror.LoggerHandler(h.storedBackupDownload)))).Methods(http.MethodGet)
+	h.Handle("/backups/{name}", bouncer.RestrictedAccess(adminAccess(httperror.LoggerHandler(h.storedBackupDelete)))).Methods(http.MethodDelete)
+	h.Handle("/backups/{name}/restore", bouncer.RestrictedAccess(adminAccess(httperror.LoggerHandler(h.storedBackupRestore)))).Methods(http.MethodPost)
+	h.Handle("/backups/{name}/restore/entities", bouncer.RestrictedAccess(adminAccess(httperror.LoggerHandler(h.storedBackupRestoreEntities)))).Methods(http.MethodPost)
+	h.Handle("/backups/{name}/inspect", bouncer.RestrictedAccess(adminAccess(httperror.LoggerHandler(h.storedBackupInspect)))).Methods(http.MethodPost)
+	h.Handle("/backups/{name}/validate", bouncer.RestrictedAccess(adminAccess(httperror.LoggerHandler(h.storedBackupValidate)))).Methods(http.MethodPost)
 
 	return h
 }
@@ -46,16 +60,27 @@ func adminAccess(next http.Handler) http.Handler {
 		securityContext, err := security.RetrieveRestrictedRequestContext(r)
 		if err != nil {
 			httperror.WriteError(w, http.StatusInternalServerError, "Unable to retrieve user info from request context", err)
+			return
 		}
 
 		if !securityContext.IsAdmin {
 			httperror.WriteError(w, http.StatusUnauthorized, "User is not authorized to perfom the action", nil)
+			return
 		}
 
 		next.ServeHTTP(w, r)
 	})
 }
 
+func (h *Handler) storedBackupsDir() (string, error) {
+	settings, err := h.dataStore.Settings().Settings()
+	if err != nil {
+		return "", err
+	}
+
+	return operations.StoredBackupsDir(h.filestorePath, settings.BackupSettings), nil
+}
+
 func systemWasInitialized(dataStore portainer.DataStore) (bool, error) {
 	users, err := dataStore.User().UsersByRole(portainer.AdministratorRole)
 	if err != nil {
@@ -3,30 +3,50 @@ package backup
 import (
 	"bytes"
 	"io"
+	"io/ioutil"
 	"net/http"
+	"strings"
 
 	"github.com/pkg/errors"
 	httperror "github.com/portainer/libhttp/error"
 	"github.com/portainer/libhttp/request"
+	portainer "github.com/portainer/portainer/api"
 	operations "github.com/portainer/portainer/api/backup"
+	"github.com/portainer/portainer/api/crypto"
 )
 
 type restorePayload struct {
 	FileContent []byte
 	FileName    string
 	Password    string
+	// Settings of the S3 compatible object storage to restore the backup from, used instead of the uploaded file when specified
+	S3Settings *portainer.S3BackupSettings
+	// Name of the backup to restore from the object storage, the most recent backup is used when empty
+	S3BackupName string
+}
+
+func (p *restorePayload) Validate(r *http.Request) error {
+	if p.S3Settings == nil {
+		return errors.New("Invalid S3 settings")
+	}
+	return nil
 }
 
 // @id Restore
 // @summary Triggers a system restore using provided backup file
-// @description Triggers a system restore using provided backup file
+// @description Triggers a system restore using provided backup file.
+// @description The backup can also be retrieved from an S3 compatible object storage by sending a JSON payload with the S3Settings and S3BackupName fields instead of a multipart form.
+// @description The object storage must be one of the endpoints allowed with the --restore-s3-endpoint flag.
 // @description **Access policy**: public
 // @tags backup
 // @param FileContent body []byte true "Content of the backup"
 // @param FileName body string true "File name"
 // @param Password body string false "Password to decrypt the backup with"
+// @param S3Settings body portainer.S3BackupSettings false "Object storage to restore the backup from"
+// @param S3BackupName body string false "Name of the backup stored in the object storage, defaults to the most recent one"
 // @success 200  "Success"
 // @failure 400 "Invalid request"
+// @failure 403 "Restoring from the object storage is not allowed"
 // @failure 500 "Server error"
 // @router /restore [post]
 func (h *Handler) restore(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
@@ -46,8 +66,19 @@ func (h *Handler) restore(w http.ResponseWriter, r *http.Request) *httperror.Han
 		return &httperror.HandlerError{StatusCode: http.StatusBadRequest, Message: "Invalid request payload", Err: err}
 	}
 
-	var archiveReader io.Reader = bytes.NewReader(payload.FileContent)
+	archiveReader, handlerErr := h.restoreArchiveReader(&payload, false)
+	if handlerErr != nil {
+		return handlerErr
+	}
+	defer archiveReader.Close()
+
 	err = operations.RestoreArchive(archiveReader, payload.Password, h.filestorePath, h.gate, h.dataStore, h.shutdownTrigger)
+	if errors.Is(err, crypto.ErrDecryptionFailed) || errors.Is(err, crypto.ErrTruncatedContent) {
+		return &httperror.HandlerError{StatusCode: http.StatusBadRequest, Message: "Invalid password or corrupted backup", Err: err}
+	}
+	if errors.Is(err, operations.ErrInvalidArchive) {
+		return &httperror.HandlerError{StatusCode: http.StatusBadRequest, Message: "The backup cannot be restored", Err: err}
+	}
 	if err != nil {
 		return &httperror.HandlerError{StatusCode: http.StatusInternalServerError, Message: "Failed to restore the backup", Err: err}
 	}
@@ -55,7 +86,59 @@ func (h *Handler) restore(w http.ResponseWriter, r *http.Request) *httperror.Han
 	return nil
 }
 
+// restoreArchiveReader returns the archive of the payload, either uploaded or downloaded from the object storage.
+// The object storage must be allowed unless the request comes from an administrator.
+func (h *Handler) restoreArchiveReader(payload *restorePayload, isAdmin bool) (io.ReadCloser, *httperror.HandlerError) {
+	if payload.S3Settings == nil {
+		return ioutil.NopCloser(bytes.NewReader(payload.FileContent)), nil
+	}
+
+	if !isAdmin && !operations.IsAllowedS3Endpoint(payload.S3Settings.Endpoint, h.RestoreS3Endpoints) {
+		return nil, &httperror.HandlerError{StatusCode: http.StatusForbidden, Message: "Restoring from this object storage is not allowed", Err: errors.New("object storage endpoint not allowed")}
+	}
+
+	archive, name, handlerErr := downloadS3Backup(*payload.S3Settings, payload.S3BackupName)
+	if handlerErr != nil {
+		return nil, handlerErr
+	}
+
+	if !operations.IsEncryptedBackup(name) {
+		payload.Password = ""
+	}
+
+	return archive, nil
+}
+
+func downloadS3Backup(settings portainer.S3BackupSettings, name string) (io.ReadCloser, string, *httperror.HandlerError) {
+	destination, err := operations.NewS3Destination(settings)
+	if err != nil {
+		return nil, "", &httperror.HandlerError{StatusCode: http.StatusBadRequest, Message: "Invalid S3 settings", Err: err}
+	}
+
+	if name == "" {
+		name, err = operations.LatestBackup(destination)
+		if errors.Is(err, operations.ErrStoredBackupNotFound) {
+			return nil, "", &httperror.HandlerError{StatusCode: http.StatusNotFound, Message: "No backup available in the object storage", Err: err}
+		} else if err != nil {
+			return nil, "", &httperror.HandlerError{StatusCode: http.StatusInternalServerError, Message: "Unable to list backups from the object storage", Err: err}
+		}
+	}
+
+	archive, err := destination.Download(name)
+	if errors.Is(err, operations.ErrStoredBackupNotFound) {
+		return nil, "", &httperror.HandlerError{StatusCode: http.StatusNotFound, Message: "Unable to find a backup with the specified name in the object storage", Err: err}
+	} else if err != nil {
+		return nil, "", &httperror.HandlerError{StatusCode: http.StatusInternalServerError, Message: "Unable to download the backup from the object storage", Err: err}
+	}
+
+	return archive, name, nil
+}
+
 func decodeForm(r *http.Request, p *restorePayload) error {
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+		return request.DecodeAndValidateJSONPayload(r, p)
+	}
+
 	content, name, err := request.RetrieveMultiPartFormFile(r, "file")
 	if err != nil {
 		return err
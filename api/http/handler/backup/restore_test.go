@@ -3,6 +3,7 @@ package backup
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
 	"io"
 	"mime/multipart"
@@ -14,6 +15,7 @@ import (
 
 	portainer "github.com/portainer/portainer/api"
 	"github.com/portainer/portainer/api/adminmonitor"
+	operations "github.com/portainer/portainer/api/backup"
 	"github.com/portainer/portainer/api/http/offlinegate"
 	i "github.com/portainer/portainer/api/internal/testhelpers"
 	"github.com/stretchr/testify/assert"
@@ -48,7 +50,7 @@ func Test_restoreArchive_usingCombinationOfPasswords(t *testing.T) {
 
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
-			datastore := i.NewDatastore(i.WithUsers([]portainer.User{}), i.WithEdgeJobs([]portainer.EdgeJob{}))
+			datastore := i.NewDatastore(i.WithUsers([]portainer.User{}), i.WithEdgeJobs([]portainer.EdgeJob{}), i.WithAuditLogs([]portainer.AuditLog{}))
 			adminMonitor := adminmonitor.New(time.Hour, datastore, context.Background())
 
 			h := NewHandler(nil, datastore, offlinegate.NewOfflineGate(), "./test_assets/handler_test", func() {}, adminMonitor)
@@ -71,7 +73,7 @@ func Test_restoreArchive_shouldFailIfSystemWasAlreadyInitialized(t *testing.T) {
 	admin := portainer.User{
 		Role: portainer.AdministratorRole,
 	}
-	datastore := i.NewDatastore(i.WithUsers([]portainer.User{admin}), i.WithEdgeJobs([]portainer.EdgeJob{}))
+	datastore := i.NewDatastore(i.WithUsers([]portainer.User{admin}), i.WithEdgeJobs([]portainer.EdgeJob{}), i.WithAuditLogs([]portainer.AuditLog{}))
 	adminMonitor := adminmonitor.New(time.Hour, datastore, context.Background())
 
 	h := NewHandler(nil, datastore, offlinegate.NewOfflineGate(), "./test_assets/handler_test", func() {}, adminMonitor)
@@ -89,6 +91,77 @@ func Test_restoreArchive_shouldFailIfSystemWasAlreadyInitialized(t *testing.T) {
 	assert.Equal(t, "Cannot restore already initialized instance", restoreErr.Message, "Should fail with certain error")
 }
 
+func Test_restoreArchive_shouldFailWithBadRequest_whenPasswordIsWrong(t *testing.T) {
+	datastore := i.NewDatastore(i.WithUsers([]portainer.User{}), i.WithEdgeJobs([]portainer.EdgeJob{}), i.WithAuditLogs([]portainer.AuditLog{}))
+	adminMonitor := adminmonitor.New(time.Hour, datastore, context.Background())
+
+	h := NewHandler(nil, datastore, offlinegate.NewOfflineGate(), "./test_assets/handler_test", func() {}, adminMonitor)
+
+	//backup
+	archive := backup(t, h, "secret")
+
+	//restore
+	w := httptest.NewRecorder()
+	r, err := prepareMultipartRequest("terces", archive)
+	assert.Nil(t, err, "Shouldn't fail to write multipart form")
+
+	restoreErr := h.restore(w, r)
+	assert.NotNil(t, restoreErr, "Should fail, because the password is wrong")
+	assert.Equal(t, http.StatusBadRequest, restoreErr.StatusCode, "Should fail with bad request")
+}
+
+func Test_restoreValidate_shouldReportArchiveContent(t *testing.T) {
+	datastore := i.NewDatastore(i.WithUsers([]portainer.User{}), i.WithEdgeJobs([]portainer.EdgeJob{}), i.WithAuditLogs([]portainer.AuditLog{}))
+	adminMonitor := adminmonitor.New(time.Hour, datastore, context.Background())
+
+	h := NewHandler(nil, datastore, offlinegate.NewOfflineGate(), "./test_assets/handler_test", func() {}, adminMonitor)
+
+	//backup
+	archive := backup(t, h, "secret")
+
+	//validate
+	w := httptest.NewRecorder()
+	r, err := prepareMultipartRequest("secret", archive)
+	assert.Nil(t, err, "Shouldn't fail to write multipart form")
+
+	validateErr := h.restoreValidate(w, r)
+	assert.Nil(t, validateErr, "Validation should not fail")
+
+	var report operations.ValidationReport
+	err = json.NewDecoder(w.Result().Body).Decode(&report)
+	assert.Nil(t, err, "Should return a validation report")
+	assert.True(t, report.Valid, report.Errors)
+	assert.NotNil(t, report.Manifest, "Should include the manifest")
+	assert.Contains(t, report.Files, "portainer.key")
+	assert.Contains(t, report.Files, "tls/file1")
+}
+
+func Test_backupValidate_shouldReportArchiveContent_whenSystemWasAlreadyInitialized(t *testing.T) {
+	admin := portainer.User{
+		Role: portainer.AdministratorRole,
+	}
+	datastore := i.NewDatastore(i.WithUsers([]portainer.User{admin}), i.WithEdgeJobs([]portainer.EdgeJob{}), i.WithAuditLogs([]portainer.AuditLog{}))
+	adminMonitor := adminmonitor.New(time.Hour, datastore, context.Background())
+
+	h := NewHandler(nil, datastore, offlinegate.NewOfflineGate(), "./test_assets/handler_test", func() {}, adminMonitor)
+
+	//backup
+	archive := backup(t, h, "secret")
+
+	//validate
+	w := httptest.NewRecorder()
+	r, err := prepareMultipartRequest("secret", archive)
+	assert.Nil(t, err, "Shouldn't fail to write multipart form")
+
+	validateErr := h.backupValidate(w, r)
+	assert.Nil(t, validateErr, "Validation should not fail on an initialized instance")
+
+	var report operations.ValidationReport
+	err = json.NewDecoder(w.Result().Body).Decode(&report)
+	assert.Nil(t, err, "Should return a validation report")
+	assert.True(t, report.Valid, report.Errors)
+}
+
 func backup(t *testing.T, h *Handler, password string) []byte {
 	r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(fmt.Sprintf(`{"password":"%s"}`, password)))
 	w := httptest.NewRecorder()
@@ -0,0 +1,81 @@
+package backup
+
+import (
+	"net/http"
+
+	"github.com/pkg/errors"
+	httperror "github.com/portainer/libhttp/error"
+	"github.com/portainer/libhttp/response"
+	operations "github.com/portainer/portainer/api/backup"
+)
+
+// @id RestoreValidate
+// @summary Validates a backup file without restoring it
+// @description Decrypts and inspects the provided backup file and reports its content,
+// @description the checksum mismatches and the version incompatibilities that would prevent it from being restored.
+// @description The datastore is not modified. Accepts the same payload as the restore operation.
+// @description Only available before the instance is initialized, the administrators use /backups/validate afterwards.
+// @description **Access policy**: public
+// @tags backup
+// @produce json
+// @param FileContent body []byte true "Content of the backup"
+// @param FileName body string true "File name"
+// @param Password body string false "Password to decrypt the backup with"
+// @param S3Settings body portainer.S3BackupSettings false "Object storage to retrieve the backup from"
+// @param S3BackupName body string false "Name of the backup stored in the object storage, defaults to the most recent one"
+// @success 200 {object} operations.ValidationReport "Success"
+// @failure 400 "Invalid request"
+// @failure 403 "Restoring from the object storage is not allowed"
+// @failure 500 "Server error"
+// @router /restore/validate [post]
+func (h *Handler) restoreValidate(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	initialized, err := h.adminMonitor.WasInitialized()
+	if err != nil {
+		return &httperror.HandlerError{StatusCode: http.StatusInternalServerError, Message: "Failed to check system initialization", Err: err}
+	}
+	if initialized {
+		return &httperror.HandlerError{StatusCode: http.StatusBadRequest, Message: "Cannot restore already initialized instance", Err: errors.New("system already initialized")}
+	}
+
+	return h.validateArchive(w, r, false)
+}
+
+// @id BackupValidate
+// @summary Validates a backup file without restoring it
+// @description Decrypts and inspects the provided backup file and reports its content,
+// @description the checksum mismatches and the version incompatibilities that would prevent it from being restored.
+// @description The datastore is not modified. Accepts the same payload as the restore operation, at any time.
+// @description **Access policy**: administrator
+// @tags backup
+// @security jwt
+// @produce json
+// @param FileContent body []byte true "Content of the backup"
+// @param FileName body string true "File name"
+// @param Password body string false "Password to decrypt the backup with"
+// @param S3Settings body portainer.S3BackupSettings false "Object storage to retrieve the backup from"
+// @param S3BackupName body string false "Name of the backup stored in the object storage, defaults to the most recent one"
+// @success 200 {object} operations.ValidationReport "Success"
+// @failure 400 "Invalid request"
+// @failure 500 "Server error"
+// @router /backups/validate [post]
+func (h *Handler) backupValidate(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	return h.validateArchive(w, r, true)
+}
+
+func (h *Handler) validateArchive(w http.ResponseWriter, r *http.Request, isAdmin bool) *httperror.HandlerError {
+	var payload restorePayload
+	err := decodeForm(r, &payload)
+	if err != nil {
+		return &httperror.HandlerError{StatusCode: http.StatusBadRequest, Message: "Invalid request payload", Err: err}
+	}
+
+	archiveReader, handlerErr := h.restoreArchiveReader(&payload, isAdmin)
+	if handlerErr != nil {
+		return handlerErr
+	}
+	defer archiveReader.Close()
+
+	report := operations.ValidateArchive(archiveReader, payload.Password, h.filestorePath)
+
+	return response.JSON(w, report)
+}
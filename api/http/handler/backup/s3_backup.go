@@ -0,0 +1,92 @@
+package backup
+
+import (
+	"net/http"
+
+	httperror "github.com/portainer/libhttp/error"
+	"github.com/portainer/libhttp/request"
+	"github.com/portainer/libhttp/response"
+	operations "github.com/portainer/portainer/api/backup"
+)
+
+type s3BackupPayload struct {
+	// Password to encrypt the backup with, the scheduled backups password is used when empty
+	Password string `example:"backup-password"`
+}
+
+func (p *s3BackupPayload) Validate(r *http.Request) error {
+	return nil
+}
+
+// @id S3Backup
+// @summary Creates a backup and uploads it to the S3 compatible object storage
+// @description Creates an archive with a system data snapshot and uploads it to the object storage defined in the backup settings.
+// @description **Access policy**: admin
+// @tags backup
+// @security jwt
+// @accept json
+// @produce json
+// @param body body s3BackupPayload false "Backup details"
+// @success 200 {object} operations.StoredBackup "Success"
+// @failure 400 "Invalid request"
+// @failure 500 "Server error"
+// @router /backups/s3 [post]
+func (h *Handler) s3Backup(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	var payload s3BackupPayload
+	err := request.DecodeAndValidateJSONPayload(r, &payload)
+	if err != nil {
+		return &httperror.HandlerError{StatusCode: http.StatusBadRequest, Message: "Invalid request payload", Err: err}
+	}
+
+	settings, err := h.dataStore.Settings().Settings()
+	if err != nil {
+		return &httperror.HandlerError{StatusCode: http.StatusInternalServerError, Message: "Unable to retrieve the settings from the database", Err: err}
+	}
+
+	destination, err := operations.NewS3Destination(settings.BackupSettings.S3Settings)
+	if err != nil {
+		return &httperror.HandlerError{StatusCode: http.StatusBadRequest, Message: "Invalid S3 backup settings", Err: err}
+	}
+
+	password := payload.Password
+	if password == "" {
+		password = settings.BackupSettings.Password
+	}
+
+	backup, err := operations.UploadBackupArchive(password, h.gate, h.dataStore, h.filestorePath, destination)
+	if err != nil {
+		return &httperror.HandlerError{StatusCode: http.StatusInternalServerError, Message: "Failed to upload backup", Err: err}
+	}
+
+	return response.JSON(w, backup)
+}
+
+// @id S3BackupList
+// @summary List the backups stored in the S3 compatible object storage
+// @description List the backups stored in the object storage defined in the backup settings, most recent first.
+// @description **Access policy**: admin
+// @tags backup
+// @security jwt
+// @produce json
+// @success 200 {array} operations.StoredBackup "Success"
+// @failure 400 "Invalid request"
+// @failure 500 "Server error"
+// @router /backups/s3 [get]
+func (h *Handler) s3BackupList(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	settings, err := h.dataStore.Settings().Settings()
+	if err != nil {
+		return &httperror.HandlerError{StatusCode: http.StatusInternalServerError, Message: "Unable to retrieve the settings from the database", Err: err}
+	}
+
+	destination, err := operations.NewS3Destination(settings.BackupSettings.S3Settings)
+	if err != nil {
+		return &httperror.HandlerError{StatusCode: http.StatusBadRequest, Message: "Invalid S3 backup settings", Err: err}
+	}
+
+	backups, err := destination.List()
+	if err != nil {
+		return &httperror.HandlerError{StatusCode: http.StatusInternalServerError, Message: "Unable to list backups from the object storage", Err: err}
+	}
+
+	return response.JSON(w, backups)
+}
@@ -0,0 +1,44 @@
+package backup
+
+import (
+	"net/http"
+
+	"github.com/pkg/errors"
+	httperror "github.com/portainer/libhttp/error"
+	"github.com/portainer/libhttp/request"
+	"github.com/portainer/libhttp/response"
+	operations "github.com/portainer/portainer/api/backup"
+)
+
+// @id StoredBackupDelete
+// @summary Remove a stored backup
+// @description Remove a backup created by the backup scheduler.
+// @description **Access policy**: admin
+// @tags backup
+// @security jwt
+// @param name path string true "Backup name"
+// @success 204 "Success"
+// @failure 400 "Invalid request"
+// @failure 404 "Backup not found"
+// @failure 500 "Server error"
+// @router /backups/{name} [delete]
+func (h *Handler) storedBackupDelete(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	name, err := request.RetrieveRouteVariableValue(r, "name")
+	if err != nil {
+		return &httperror.HandlerError{StatusCode: http.StatusBadRequest, Message: "Invalid backup name route variable", Err: err}
+	}
+
+	storageDir, err := h.storedBackupsDir()
+	if err != nil {
+		return &httperror.HandlerError{StatusCode: http.StatusInternalServerError, Message: "Unable to retrieve the settings from the database", Err: err}
+	}
+
+	err = operations.DeleteStoredBackup(storageDir, name)
+	if errors.Is(err, operations.ErrStoredBackupNotFound) {
+		return &httperror.HandlerError{StatusCode: http.StatusNotFound, Message: "Unable to find a stored backup with the specified name", Err: err}
+	} else if err != nil {
+		return &httperror.HandlerError{StatusCode: http.StatusInternalServerError, Message: "Unable to remove the stored backup", Err: err}
+	}
+
+	return response.Empty(w)
+}
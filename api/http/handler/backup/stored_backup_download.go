@@ -0,0 +1,48 @@
+package backup
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/pkg/errors"
+	httperror "github.com/portainer/libhttp/error"
+	"github.com/portainer/libhttp/request"
+	operations "github.com/portainer/portainer/api/backup"
+)
+
+// @id StoredBackupDownload
+// @summary Download a stored backup
+// @description Download a backup created by the backup scheduler.
+// @description **Access policy**: admin
+// @tags backup
+// @security jwt
+// @produce octet-stream
+// @param name path string true "Backup name"
+// @success 200 "Success"
+// @failure 400 "Invalid request"
+// @failure 404 "Backup not found"
+// @failure 500 "Server error"
+// @router /backups/{name} [get]
+func (h *Handler) storedBackupDownload(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	name, err := request.RetrieveRouteVariableValue(r, "name")
+	if err != nil {
+		return &httperror.HandlerError{StatusCode: http.StatusBadRequest, Message: "Invalid backup name route variable", Err: err}
+	}
+
+	storageDir, err := h.storedBackupsDir()
+	if err != nil {
+		return &httperror.HandlerError{StatusCode: http.StatusInternalServerError, Message: "Unable to retrieve the settings from the database", Err: err}
+	}
+
+	archivePath, err := operations.StoredBackupPath(storageDir, name)
+	if errors.Is(err, operations.ErrStoredBackupNotFound) {
+		return &httperror.HandlerError{StatusCode: http.StatusNotFound, Message: "Unable to find a stored backup with the specified name", Err: err}
+	} else if err != nil {
+		return &httperror.HandlerError{StatusCode: http.StatusInternalServerError, Message: "Unable to find a stored backup with the specified name", Err: err}
+	}
+
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%s", name))
+	http.ServeFile(w, r, archivePath)
+
+	return nil
+}
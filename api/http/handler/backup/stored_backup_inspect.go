@@ -0,0 +1,90 @@
+package backup
+
+import (
+	"net/http"
+	"os"
+
+	"github.com/pkg/errors"
+	httperror "github.com/portainer/libhttp/error"
+	"github.com/portainer/libhttp/request"
+	"github.com/portainer/libhttp/response"
+	operations "github.com/portainer/portainer/api/backup"
+	"github.com/portainer/portainer/api/crypto"
+)
+
+// @id StoredBackupInspect
+// @summary Lists the entities of a stored backup
+// @description Lists the stacks, custom templates, endpoints, users and teams of a backup created by the backup scheduler
+// @description that can be restored individually. User passwords are not returned.
+// @description **Access policy**: admin
+// @tags backup
+// @security jwt
+// @accept json
+// @produce json
+// @param name path string true "Backup name"
+// @param body body storedBackupRestorePayload false "Inspection details"
+// @success 200 {object} operations.ArchiveContent "Success"
+// @failure 400 "Invalid request"
+// @failure 404 "Backup not found"
+// @failure 500 "Server error"
+// @router /backups/{name}/inspect [post]
+func (h *Handler) storedBackupInspect(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	name, err := request.RetrieveRouteVariableValue(r, "name")
+	if err != nil {
+		return &httperror.HandlerError{StatusCode: http.StatusBadRequest, Message: "Invalid backup name route variable", Err: err}
+	}
+
+	var payload storedBackupRestorePayload
+	err = request.DecodeAndValidateJSONPayload(r, &payload)
+	if err != nil {
+		return &httperror.HandlerError{StatusCode: http.StatusBadRequest, Message: "Invalid request payload", Err: err}
+	}
+
+	archive, password, handlerErr := h.openStoredBackup(name, payload.Password)
+	if handlerErr != nil {
+		return handlerErr
+	}
+	defer archive.Close()
+
+	content, err := operations.InspectArchive(archive, password, h.filestorePath)
+	if handlerErr := selectiveRestoreError(err); handlerErr != nil {
+		return handlerErr
+	}
+
+	return response.JSON(w, content)
+}
+
+// openStoredBackup opens a stored backup and returns the password to decrypt it with.
+func (h *Handler) openStoredBackup(name, password string) (*os.File, string, *httperror.HandlerError) {
+	settings, err := h.dataStore.Settings().Settings()
+	if err != nil {
+		return nil, "", &httperror.HandlerError{StatusCode: http.StatusInternalServerError, Message: "Unable to retrieve the settings from the database", Err: err}
+	}
+
+	archivePath, err := operations.StoredBackupPath(operations.StoredBackupsDir(h.filestorePath, settings.BackupSettings), name)
+	if errors.Is(err, operations.ErrStoredBackupNotFound) {
+		return nil, "", &httperror.HandlerError{StatusCode: http.StatusNotFound, Message: "Unable to find a stored backup with the specified name", Err: err}
+	} else if err != nil {
+		return nil, "", &httperror.HandlerError{StatusCode: http.StatusInternalServerError, Message: "Unable to find a stored backup with the specified name", Err: err}
+	}
+
+	archive, err := os.Open(archivePath)
+	if err != nil {
+		return nil, "", &httperror.HandlerError{StatusCode: http.StatusInternalServerError, Message: "Unable to open the stored backup", Err: err}
+	}
+
+	return archive, storedBackupPassword(name, password, settings.BackupSettings), nil
+}
+
+func selectiveRestoreError(err error) *httperror.HandlerError {
+	if errors.Is(err, crypto.ErrDecryptionFailed) || errors.Is(err, crypto.ErrTruncatedContent) {
+		return &httperror.HandlerError{StatusCode: http.StatusBadRequest, Message: "Invalid password or corrupted backup", Err: err}
+	}
+	if errors.Is(err, operations.ErrInvalidArchive) {
+		return &httperror.HandlerError{StatusCode: http.StatusBadRequest, Message: "The backup cannot be restored selectively", Err: err}
+	}
+	if err != nil {
+		return &httperror.HandlerError{StatusCode: http.StatusInternalServerError, Message: "Unable to read the backup", Err: err}
+	}
+	return nil
+}
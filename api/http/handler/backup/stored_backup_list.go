@@ -0,0 +1,33 @@
+package backup
+
+import (
+	"net/http"
+
+	httperror "github.com/portainer/libhttp/error"
+	"github.com/portainer/libhttp/response"
+	operations "github.com/portainer/portainer/api/backup"
+)
+
+// @id StoredBackupList
+// @summary List stored backups
+// @description List the backups created by the backup scheduler, most recent first.
+// @description **Access policy**: admin
+// @tags backup
+// @security jwt
+// @produce json
+// @success 200 {array} operations.StoredBackup "Success"
+// @failure 500 "Server error"
+// @router /backups [get]
+func (h *Handler) storedBackupList(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	storageDir, err := h.storedBackupsDir()
+	if err != nil {
+		return &httperror.HandlerError{StatusCode: http.StatusInternalServerError, Message: "Unable to retrieve the settings from the database", Err: err}
+	}
+
+	backups, err := operations.ListStoredBackups(storageDir)
+	if err != nil {
+		return &httperror.HandlerError{StatusCode: http.StatusInternalServerError, Message: "Unable to list stored backups", Err: err}
+	}
+
+	return response.JSON(w, backups)
+}
@@ -0,0 +1,94 @@
+package backup
+
+import (
+	"net/http"
+	"os"
+
+	"github.com/pkg/errors"
+	httperror "github.com/portainer/libhttp/error"
+	"github.com/portainer/libhttp/request"
+	portainer "github.com/portainer/portainer/api"
+	operations "github.com/portainer/portainer/api/backup"
+	"github.com/portainer/portainer/api/crypto"
+)
+
+type storedBackupRestorePayload struct {
+	// Password to decrypt the backup with, the scheduled backups password is used when empty
+	Password string `example:"backup-password"`
+}
+
+func (p *storedBackupRestorePayload) Validate(r *http.Request) error {
+	return nil
+}
+
+// @id StoredBackupRestore
+// @summary Triggers a system restore using a stored backup
+// @description Triggers a system restore using a backup created by the backup scheduler. The instance is restarted once the restore is done.
+// @description **Access policy**: admin
+// @tags backup
+// @security jwt
+// @accept json
+// @param name path string true "Backup name"
+// @param body body storedBackupRestorePayload false "Restore details"
+// @success 200 "Success"
+// @failure 400 "Invalid request"
+// @failure 404 "Backup not found"
+// @failure 500 "Server error"
+// @router /backups/{name}/restore [post]
+func (h *Handler) storedBackupRestore(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	name, err := request.RetrieveRouteVariableValue(r, "name")
+	if err != nil {
+		return &httperror.HandlerError{StatusCode: http.StatusBadRequest, Message: "Invalid backup name route variable", Err: err}
+	}
+
+	var payload storedBackupRestorePayload
+	err = request.DecodeAndValidateJSONPayload(r, &payload)
+	if err != nil {
+		return &httperror.HandlerError{StatusCode: http.StatusBadRequest, Message: "Invalid request payload", Err: err}
+	}
+
+	settings, err := h.dataStore.Settings().Settings()
+	if err != nil {
+		return &httperror.HandlerError{StatusCode: http.StatusInternalServerError, Message: "Unable to retrieve the settings from the database", Err: err}
+	}
+
+	archivePath, err := operations.StoredBackupPath(operations.StoredBackupsDir(h.filestorePath, settings.BackupSettings), name)
+	if errors.Is(err, operations.ErrStoredBackupNotFound) {
+		return &httperror.HandlerError{StatusCode: http.StatusNotFound, Message: "Unable to find a stored backup with the specified name", Err: err}
+	} else if err != nil {
+		return &httperror.HandlerError{StatusCode: http.StatusInternalServerError, Message: "Unable to find a stored backup with the specified name", Err: err}
+	}
+
+	archive, err := os.Open(archivePath)
+	if err != nil {
+		return &httperror.HandlerError{StatusCode: http.StatusInternalServerError, Message: "Unable to open the stored backup", Err: err}
+	}
+	defer archive.Close()
+
+	err = operations.RestoreArchive(archive, storedBackupPassword(name, payload.Password, settings.BackupSettings), h.filestorePath, h.gate, h.dataStore, h.shutdownTrigger)
+	if errors.Is(err, crypto.ErrDecryptionFailed) || errors.Is(err, crypto.ErrTruncatedContent) {
+		return &httperror.HandlerError{StatusCode: http.StatusBadRequest, Message: "Invalid password or corrupted backup", Err: err}
+	}
+	if errors.Is(err, operations.ErrInvalidArchive) {
+		return &httperror.HandlerError{StatusCode: http.StatusBadRequest, Message: "The backup cannot be restored", Err: err}
+	}
+	if err != nil {
+		return &httperror.HandlerError{StatusCode: http.StatusInternalServerError, Message: "Failed to restore the backup", Err: err}
+	}
+
+	return nil
+}
+
+// storedBackupPassword returns the password used to decrypt a stored backup,
+// falling back on the scheduled backups password.
+func storedBackupPassword(name string, password string, settings portainer.BackupSettings) string {
+	if !operations.IsEncryptedBackup(name) {
+		return ""
+	}
+
+	if password == "" {
+		return settings.Password
+	}
+
+	return password
+}
@@ -0,0 +1,88 @@
+package backup
+
+import (
+	"net/http"
+
+	"github.com/pkg/errors"
+	httperror "github.com/portainer/libhttp/error"
+	"github.com/portainer/libhttp/request"
+	"github.com/portainer/libhttp/response"
+	portainer "github.com/portainer/portainer/api"
+	operations "github.com/portainer/portainer/api/backup"
+)
+
+type storedBackupRestoreEntitiesPayload struct {
+	// Password to decrypt the backup with, the scheduled backups password is used when empty
+	Password string `example:"backup-password"`
+	// Identifiers of the stacks to restore, along with their project files
+	StackIDs []portainer.StackID `example:"1"`
+	// Identifiers of the custom templates to restore, along with their files
+	CustomTemplateIDs []portainer.CustomTemplateID `example:"1"`
+	// Identifiers of the endpoints to restore, along with their TLS files
+	EndpointIDs []portainer.EndpointID `example:"1"`
+	// Identifiers of the users to restore
+	UserIDs []portainer.UserID `example:"2"`
+	// Identifiers of the teams to restore
+	TeamIDs []portainer.TeamID `example:"1"`
+}
+
+func (payload *storedBackupRestoreEntitiesPayload) Validate(r *http.Request) error {
+	if payload.selection().IsEmpty() {
+		return errors.New("Invalid selection. At least one entity must be selected")
+	}
+	return nil
+}
+
+func (payload *storedBackupRestoreEntitiesPayload) selection() operations.RestoreSelection {
+	return operations.RestoreSelection{
+		StackIDs:          payload.StackIDs,
+		CustomTemplateIDs: payload.CustomTemplateIDs,
+		EndpointIDs:       payload.EndpointIDs,
+		UserIDs:           payload.UserIDs,
+		TeamIDs:           payload.TeamIDs,
+	}
+}
+
+// @id StoredBackupRestoreEntities
+// @summary Restores individual entities from a stored backup
+// @description Merges the selected stacks, custom templates, endpoints, users and teams of a backup created by the backup scheduler
+// @description into the current instance, without restarting it. The restored entities get new identifiers,
+// @description the entities conflicting with existing ones are skipped. The backup must have been created by the same version of Portainer.
+// @description The entities are restored in a single transaction, nothing is restored when one of them cannot be.
+// @description **Access policy**: admin
+// @tags backup
+// @security jwt
+// @accept json
+// @produce json
+// @param name path string true "Backup name"
+// @param body body storedBackupRestoreEntitiesPayload true "Entities to restore"
+// @success 200 {object} operations.SelectiveRestoreReport "Success"
+// @failure 400 "Invalid request"
+// @failure 404 "Backup not found"
+// @failure 500 "Server error"
+// @router /backups/{name}/restore/entities [post]
+func (h *Handler) storedBackupRestoreEntities(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	name, err := request.RetrieveRouteVariableValue(r, "name")
+	if err != nil {
+		return &httperror.HandlerError{StatusCode: http.StatusBadRequest, Message: "Invalid backup name route variable", Err: err}
+	}
+
+	var payload storedBackupRestoreEntitiesPayload
+	err = request.DecodeAndValidateJSONPayload(r, &payload)
+	if err != nil {
+		return &httperror.HandlerError{StatusCode: http.StatusBadRequest, Message: "Invalid request payload", Err: err}
+	}
+
+	archive, password, handlerErr := h.openStoredBackup(name, payload.Password)
+	if handlerErr != nil {
+		return handlerErr
+	}
+	defer archive.Close()
+
+	report, err := operations.RestoreEntities(archive, password, h.filestorePath, h.dataStore, payload.selection())
+	if handlerErr := selectiveRestoreError(err); handlerErr != nil {
+		return handlerErr
+	}
+
+	return response.JSON(w, report)
+}
@@ -0,0 +1,63 @@
+package backup
+
+import (
+	"net/http"
+	"os"
+
+	"github.com/pkg/errors"
+	httperror "github.com/portainer/libhttp/error"
+	"github.com/portainer/libhttp/request"
+	"github.com/portainer/libhttp/response"
+	operations "github.com/portainer/portainer/api/backup"
+)
+
+// @id StoredBackupValidate
+// @summary Validates a stored backup without restoring it
+// @description Decrypts and inspects a backup created by the backup scheduler and reports its content,
+// @description the checksum mismatches and the version incompatibilities that would prevent it from being restored.
+// @description **Access policy**: admin
+// @tags backup
+// @security jwt
+// @accept json
+// @produce json
+// @param name path string true "Backup name"
+// @param body body storedBackupRestorePayload false "Validation details"
+// @success 200 {object} operations.ValidationReport "Success"
+// @failure 400 "Invalid request"
+// @failure 404 "Backup not found"
+// @failure 500 "Server error"
+// @router /backups/{name}/validate [post]
+func (h *Handler) storedBackupValidate(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	name, err := request.RetrieveRouteVariableValue(r, "name")
+	if err != nil {
+		return &httperror.HandlerError{StatusCode: http.StatusBadRequest, Message: "Invalid backup name route variable", Err: err}
+	}
+
+	var payload storedBackupRestorePayload
+	err = request.DecodeAndValidateJSONPayload(r, &payload)
+	if err != nil {
+		return &httperror.HandlerError{StatusCode: http.StatusBadRequest, Message: "Invalid request payload", Err: err}
+	}
+
+	settings, err := h.dataStore.Settings().Settings()
+	if err != nil {
+		return &httperror.HandlerError{StatusCode: http.StatusInternalServerError, Message: "Unable to retrieve the settings from the database", Err: err}
+	}
+
+	archivePath, err := operations.StoredBackupPath(operations.StoredBackupsDir(h.filestorePath, settings.BackupSettings), name)
+	if errors.Is(err, operations.ErrStoredBackupNotFound) {
+		return &httperror.HandlerError{StatusCode: http.StatusNotFound, Message: "Unable to find a stored backup with the specified name", Err: err}
+	} else if err != nil {
+		return &httperror.HandlerError{StatusCode: http.StatusInternalServerError, Message: "Unable to find a stored backup with the specified name", Err: err}
+	}
+
+	archive, err := os.Open(archivePath)
+	if err != nil {
+		return &httperror.HandlerError{StatusCode: http.StatusInternalServerError, Message: "Unable to open the stored backup", Err: err}
+	}
+	defer archive.Close()
+
+	report := operations.ValidateArchive(archive, storedBackupPassword(name, payload.Password, settings.BackupSettings), h.filestorePath)
+
+	return response.JSON(w, report)
+}
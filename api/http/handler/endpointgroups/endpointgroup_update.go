@@ -9,6 +9,7 @@ import (
 	"github.com/portainer/libhttp/response"
 	portainer "github.com/portainer/portainer/api"
 	"github.com/portainer/portainer/api/bolt/errors"
+	"github.com/portainer/portainer/api/internal/authorization"
 	"github.com/portainer/portainer/api/internal/tag"
 )
 
@@ -24,7 +25,7 @@ type endpointGroupUpdatePayload struct {
 }
 
 func (payload *endpointGroupUpdatePayload) Validate(r *http.Request) error {
-	return nil
+	return authorization.ValidateAccessPolicies(payload.UserAccessPolicies, payload.TeamAccessPolicies)
 }
 
 // @id EndpointGroupUpdate
@@ -13,11 +13,14 @@ import (
 // @id EndpointInspect
 // @summary Inspect an endpoint
 // @description Retrieve details about an endpoint.
+// @description The raw data of the Docker snapshot, every container, image, volume and network, is only retrieved when includeSnapshotRaw is set,
+// @description and only for the administrators and the endpoint administrators.
 // @description **Access policy**: restricted
 // @tags endpoints
 // @security jwt
 // @produce json
 // @param id path int true "Endpoint identifier"
+// @param includeSnapshotRaw query bool false "Include the raw data of the Docker snapshot"
 // @success 200 {object} portainer.Endpoint "Success"
 // @failure 400 "Invalid request"
 // @failure 404 "Endpoint not found"
@@ -42,6 +45,32 @@ func (handler *Handler) endpointInspect(w http.ResponseWriter, r *http.Request)
 	}
 
 	hideFields(endpoint)
+
+	err = handler.setKubernetesSnapshot(endpoint)
+	if err != nil {
+		return &httperror.HandlerError{StatusCode: http.StatusInternalServerError, Message: "Unable to retrieve the endpoint snapshot from the database", Err: err}
+	}
+
+	includeSnapshotRaw, _ := request.RetrieveBooleanQueryParameter(r, "includeSnapshotRaw", true)
+	if includeSnapshotRaw && len(endpoint.Snapshots) > 0 {
+		// the raw data holds every resource of the endpoint, whatever the resource controls
+		canAccessResources, err := handler.canAccessEndpointResources(r, endpoint.ID)
+		if err != nil {
+			return &httperror.HandlerError{StatusCode: http.StatusInternalServerError, Message: "Unable to retrieve the authorizations of the user", Err: err}
+		}
+
+		if canAccessResources {
+			snapshot, err := handler.DataStore.Snapshot().Snapshot(endpoint.ID)
+			if err != nil && err != errors.ErrObjectNotFound {
+				return &httperror.HandlerError{StatusCode: http.StatusInternalServerError, Message: "Unable to retrieve the endpoint snapshot from the database", Err: err}
+			}
+
+			if snapshot != nil && snapshot.Docker != nil {
+				endpoint.Snapshots[0].SnapshotRaw = snapshot.Docker.SnapshotRaw
+			}
+		}
+	}
+
 	endpoint.ComposeSyntaxMaxVersion = handler.ComposeStackManager.ComposeSyntaxMaxVersion()
 
 	return response.JSON(w, endpoint)
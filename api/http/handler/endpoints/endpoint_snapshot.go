@@ -53,7 +53,6 @@ func (handler *Handler) endpointSnapshot(w http.ResponseWriter, r *http.Request)
 	}
 
 	latestEndpointReference.Snapshots = endpoint.Snapshots
-	latestEndpointReference.Kubernetes.Snapshots = endpoint.Kubernetes.Snapshots
 
 	err = handler.DataStore.Endpoint().UpdateEndpoint(latestEndpointReference.ID, latestEndpointReference)
 	if err != nil {
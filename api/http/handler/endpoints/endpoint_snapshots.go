@@ -45,7 +45,6 @@ func (handler *Handler) endpointSnapshots(w http.ResponseWriter, r *http.Request
 		}
 
 		latestEndpointReference.Snapshots = endpoint.Snapshots
-		latestEndpointReference.Kubernetes.Snapshots = endpoint.Kubernetes.Snapshots
 
 		err = handler.DataStore.Endpoint().UpdateEndpoint(latestEndpointReference.ID, latestEndpointReference)
 		if err != nil {
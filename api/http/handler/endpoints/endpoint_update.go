@@ -11,6 +11,7 @@ import (
 	portainer "github.com/portainer/portainer/api"
 	"github.com/portainer/portainer/api/bolt/errors"
 	"github.com/portainer/portainer/api/http/client"
+	"github.com/portainer/portainer/api/internal/authorization"
 	"github.com/portainer/portainer/api/internal/edge"
 	"github.com/portainer/portainer/api/internal/tag"
 )
@@ -50,7 +51,7 @@ type endpointUpdatePayload struct {
 }
 
 func (payload *endpointUpdatePayload) Validate(r *http.Request) error {
-	return nil
+	return authorization.ValidateAccessPolicies(payload.UserAccessPolicies, payload.TeamAccessPolicies)
 }
 
 // @id EndpointUpdate
@@ -3,9 +3,11 @@ package endpoints
 import (
 	httperror "github.com/portainer/libhttp/error"
 	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/bolt/errors"
 	"github.com/portainer/portainer/api/http/proxy"
 	"github.com/portainer/portainer/api/http/security"
 	"github.com/portainer/portainer/api/internal/authorization"
+	"github.com/portainer/portainer/api/internal/endpointutils"
 
 	"net/http"
 
@@ -19,6 +21,47 @@ func hideFields(endpoint *portainer.Endpoint) {
 	}
 }
 
+// setKubernetesSnapshot sets the Kubernetes snapshot of an endpoint from the snapshots bucket,
+// the endpoints do not keep it
+func (handler *Handler) setKubernetesSnapshot(endpoint *portainer.Endpoint) error {
+	if !endpointutils.IsKubernetesEndpoint(endpoint) {
+		return nil
+	}
+
+	snapshot, err := handler.DataStore.Snapshot().Snapshot(endpoint.ID)
+	if err == errors.ErrObjectNotFound {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	if snapshot.Kubernetes != nil {
+		endpoint.Kubernetes.Snapshots = []portainer.KubernetesSnapshot{*snapshot.Kubernetes}
+	}
+
+	return nil
+}
+
+// canAccessEndpointResources returns true when the user of a request can access every resource of an endpoint,
+// as the administrators and the endpoint administrators do
+func (handler *Handler) canAccessEndpointResources(r *http.Request, endpointID portainer.EndpointID) (bool, error) {
+	securityContext, err := security.RetrieveRestrictedRequestContext(r)
+	if err != nil {
+		return false, err
+	}
+
+	if securityContext.IsAdmin {
+		return true, nil
+	}
+
+	user, err := handler.DataStore.User().User(securityContext.UserID)
+	if err != nil {
+		return false, err
+	}
+
+	return user.EndpointAuthorizations[endpointID][portainer.EndpointResourcesAccess], nil
+}
+
 // Handler is the HTTP handler used to handle endpoint operations.
 type Handler struct {
 	*mux.Router
@@ -33,7 +76,7 @@ type Handler struct {
 }
 
 // NewHandler creates a handler to manage endpoint operations.
-func NewHandler(bouncer *security.RequestBouncer) *Handler {
+func NewHandler(bouncer *security.RequestBouncer, edgeRateLimiter *security.RateLimiter) *Handler {
 	h := &Handler{
 		Router:         mux.NewRouter(),
 		requestBouncer: bouncer,
@@ -62,6 +105,6 @@ func NewHandler(bouncer *security.RequestBouncer) *Handler {
 	h.Handle("/endpoints/{id}/snapshot",
 		bouncer.AdminAccess(httperror.LoggerHandler(h.endpointSnapshot))).Methods(http.MethodPost)
 	h.Handle("/endpoints/{id}/status",
-		bouncer.PublicAccess(httperror.LoggerHandler(h.endpointStatusInspect))).Methods(http.MethodGet)
+		edgeRateLimiter.LimitAccess(bouncer.PublicAccess(httperror.LoggerHandler(h.endpointStatusInspect)))).Methods(http.MethodGet)
 	return h
 }
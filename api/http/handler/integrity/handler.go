@@ -0,0 +1,29 @@
+package integrity
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+	httperror "github.com/portainer/libhttp/error"
+	"github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/http/security"
+)
+
+// Handler is the HTTP handler used to check the integrity of the database.
+type Handler struct {
+	*mux.Router
+	DataStore portainer.DataStore
+}
+
+// NewHandler creates a handler to check the integrity of the database.
+func NewHandler(bouncer *security.RequestBouncer) *Handler {
+	h := &Handler{
+		Router: mux.NewRouter(),
+	}
+	h.Handle("/integrity",
+		bouncer.AdminAccess(httperror.LoggerHandler(h.integrityCheck))).Methods(http.MethodGet)
+	h.Handle("/integrity/repair",
+		bouncer.AdminAccess(httperror.LoggerHandler(h.integrityRepair))).Methods(http.MethodPost)
+
+	return h
+}
@@ -0,0 +1,28 @@
+package integrity
+
+import (
+	"net/http"
+
+	httperror "github.com/portainer/libhttp/error"
+	"github.com/portainer/libhttp/response"
+)
+
+// @id IntegrityCheck
+// @summary Check the database integrity
+// @description Look for the references to missing endpoints, endpoint groups, tags, users and teams in the database.
+// @description The issues are reported along with the repair applied by the repair operation, nothing is changed.
+// @description **Access policy**: administrator
+// @tags integrity
+// @security jwt
+// @produce json
+// @success 200 {object} portainer.IntegrityReport "Success"
+// @failure 500 "Server error"
+// @router /integrity [get]
+func (handler *Handler) integrityCheck(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	report, err := handler.DataStore.CheckIntegrity(false)
+	if err != nil {
+		return &httperror.HandlerError{StatusCode: http.StatusInternalServerError, Message: "Unable to check the integrity of the database", Err: err}
+	}
+
+	return response.JSON(w, report)
+}
@@ -0,0 +1,28 @@
+package integrity
+
+import (
+	"net/http"
+
+	httperror "github.com/portainer/libhttp/error"
+	"github.com/portainer/libhttp/response"
+)
+
+// @id IntegrityRepair
+// @summary Repair the database integrity
+// @description Fix or remove the objects referencing missing endpoints, endpoint groups, tags, users and teams.
+// @description The repairs are applied in a single transaction, nothing is changed when one of them fails.
+// @description **Access policy**: administrator
+// @tags integrity
+// @security jwt
+// @produce json
+// @success 200 {object} portainer.IntegrityReport "Success"
+// @failure 500 "Server error"
+// @router /integrity/repair [post]
+func (handler *Handler) integrityRepair(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	report, err := handler.DataStore.CheckIntegrity(true)
+	if err != nil {
+		return &httperror.HandlerError{StatusCode: http.StatusInternalServerError, Message: "Unable to repair the integrity of the database", Err: err}
+	}
+
+	return response.JSON(w, report)
+}
@@ -1,12 +1,15 @@
 package resourcecontrols
 
 import (
+	"errors"
 	"net/http"
+	"time"
 
 	"github.com/gorilla/mux"
 	httperror "github.com/portainer/libhttp/error"
 	"github.com/portainer/portainer/api"
 	"github.com/portainer/portainer/api/http/security"
+	"github.com/portainer/portainer/api/internal/authorization"
 )
 
 // Handler is the HTTP handler used to handle resource control operations.
@@ -28,3 +31,92 @@ func NewHandler(bouncer *security.RequestBouncer) *Handler {
 		bouncer.AdminAccess(httperror.LoggerHandler(h.resourceControlDelete))).Methods(http.MethodDelete)
 	return h
 }
+
+type teamAccessGrantPayload struct {
+	// Team identifier
+	TeamID int `example:"7" validate:"required"`
+	// Unix timestamp at which the access expires and is removed
+	ExpiresAt int64 `example:"1587399600" validate:"required"`
+	// Reason the access is granted
+	Reason string `example:"On-call incident #42"`
+}
+
+// validateTeamAccessGrants ensures that the grants expire in the future and do not duplicate the permanent team accesses
+func validateTeamAccessGrants(grants []teamAccessGrantPayload, teams []int) error {
+	now := time.Now().Unix()
+
+	teamIDs := make(map[int]bool)
+	for _, teamID := range teams {
+		teamIDs[teamID] = true
+	}
+
+	for _, grant := range grants {
+		if grant.TeamID <= 0 {
+			return errors.New("invalid payload: invalid team access grant team identifier")
+		}
+		if grant.ExpiresAt <= now {
+			return errors.New("invalid payload: team access grants must expire in the future")
+		}
+		if teamIDs[grant.TeamID] {
+			return errors.New("invalid payload: a team cannot be granted more than one access")
+		}
+		teamIDs[grant.TeamID] = true
+	}
+
+	return nil
+}
+
+// teamResourceAccesses returns the read-write accesses of the teams, followed by the accesses granted until an expiry date
+func teamResourceAccesses(teams []int, grants []teamAccessGrantPayload) []portainer.TeamResourceAccess {
+	teamAccesses := make([]portainer.TeamResourceAccess, 0, len(teams)+len(grants))
+	for _, v := range teams {
+		teamAccess := portainer.TeamResourceAccess{
+			TeamID:      portainer.TeamID(v),
+			AccessLevel: portainer.ReadWriteAccessLevel,
+		}
+		teamAccesses = append(teamAccesses, teamAccess)
+	}
+
+	for _, grant := range grants {
+		teamAccess := portainer.TeamResourceAccess{
+			TeamID:      portainer.TeamID(grant.TeamID),
+			AccessLevel: portainer.ReadWriteAccessLevel,
+			ExpiresAt:   grant.ExpiresAt,
+			Reason:      grant.Reason,
+		}
+		teamAccesses = append(teamAccesses, teamAccess)
+	}
+
+	return teamAccesses
+}
+
+// keepTeamAccessGrants returns the read-write accesses of the teams, followed by the existing accesses granted until
+// an expiry date. A team with an existing grant keeps its grant, it is not given a permanent access.
+func keepTeamAccessGrants(teams []int, existingAccesses []portainer.TeamResourceAccess) []portainer.TeamResourceAccess {
+	now := time.Now()
+
+	grants := make([]portainer.TeamResourceAccess, 0)
+	grantedTeams := make(map[portainer.TeamID]bool)
+	for _, access := range existingAccesses {
+		if access.ExpiresAt == 0 || authorization.TeamResourceAccessExpired(access, now) {
+			continue
+		}
+		grants = append(grants, access)
+		grantedTeams[access.TeamID] = true
+	}
+
+	teamAccesses := make([]portainer.TeamResourceAccess, 0, len(teams)+len(grants))
+	for _, v := range teams {
+		if grantedTeams[portainer.TeamID(v)] {
+			continue
+		}
+
+		teamAccess := portainer.TeamResourceAccess{
+			TeamID:      portainer.TeamID(v),
+			AccessLevel: portainer.ReadWriteAccessLevel,
+		}
+		teamAccesses = append(teamAccesses, teamAccess)
+	}
+
+	return append(teamAccesses, grants...)
+}
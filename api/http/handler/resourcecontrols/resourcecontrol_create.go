@@ -9,6 +9,7 @@ import (
 	"github.com/portainer/libhttp/request"
 	"github.com/portainer/libhttp/response"
 	portainer "github.com/portainer/portainer/api"
+	bolterrors "github.com/portainer/portainer/api/bolt/errors"
 )
 
 type resourceControlCreatePayload struct {
@@ -25,8 +26,12 @@ type resourceControlCreatePayload struct {
 	Users []int `example:"1,4"`
 	// List of team identifiers with access to the associated resource
 	Teams []int `example:"56,7"`
+	// List of teams with an access to the associated resource which expires
+	TeamGrants []teamAccessGrantPayload
 	// List of Docker resources that will inherit this access control
 	SubResourceIDs []string `example:"617c5f22bb9b023d6daab7cba43a57576f83492867bc767d1c59416b065e5f08"`
+	// Endpoint of the resource, its resource controls are removed along with the endpoint
+	EndpointID int `example:"1"`
 }
 
 var (
@@ -43,14 +48,15 @@ func (payload *resourceControlCreatePayload) Validate(r *http.Request) error {
 		return errors.New("invalid payload: invalid type")
 	}
 
-	if len(payload.Users) == 0 && len(payload.Teams) == 0 && !payload.Public && !payload.AdministratorsOnly {
-		return errors.New("invalid payload: must specify Users, Teams, Public or AdministratorsOnly")
+	if len(payload.Users) == 0 && len(payload.Teams) == 0 && len(payload.TeamGrants) == 0 && !payload.Public && !payload.AdministratorsOnly {
+		return errors.New("invalid payload: must specify Users, Teams, TeamGrants, Public or AdministratorsOnly")
 	}
 
 	if payload.Public && payload.AdministratorsOnly {
 		return errors.New("invalid payload: cannot set both public and administrators only flags to true")
 	}
-	return nil
+
+	return validateTeamAccessGrants(payload.TeamGrants, payload.Teams)
 }
 
 // @id ResourceControlCreate
@@ -96,6 +102,15 @@ func (handler *Handler) resourceControlCreate(w http.ResponseWriter, r *http.Req
 		return &httperror.HandlerError{http.StatusBadRequest, "Invalid type value. Value must be one of: container, service, volume, network, secret, stack or config", errInvalidResourceControlType}
 	}
 
+	if payload.EndpointID != 0 {
+		_, err := handler.DataStore.Endpoint().Endpoint(portainer.EndpointID(payload.EndpointID))
+		if err == bolterrors.ErrObjectNotFound {
+			return &httperror.HandlerError{StatusCode: http.StatusBadRequest, Message: "Unable to find an endpoint with the specified identifier inside the database", Err: err}
+		} else if err != nil {
+			return &httperror.HandlerError{StatusCode: http.StatusInternalServerError, Message: "Unable to find an endpoint with the specified identifier inside the database", Err: err}
+		}
+	}
+
 	rc, err := handler.DataStore.ResourceControl().ResourceControlByResourceIDAndType(payload.ResourceID, resourceControlType)
 	if err != nil {
 		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to retrieve resource controls from the database", err}
@@ -113,15 +128,6 @@ func (handler *Handler) resourceControlCreate(w http.ResponseWriter, r *http.Req
 		userAccesses = append(userAccesses, userAccess)
 	}
 
-	var teamAccesses = make([]portainer.TeamResourceAccess, 0)
-	for _, v := range payload.Teams {
-		teamAccess := portainer.TeamResourceAccess{
-			TeamID:      portainer.TeamID(v),
-			AccessLevel: portainer.ReadWriteAccessLevel,
-		}
-		teamAccesses = append(teamAccesses, teamAccess)
-	}
-
 	resourceControl := portainer.ResourceControl{
 		ResourceID:         payload.ResourceID,
 		SubResourceIDs:     payload.SubResourceIDs,
@@ -129,7 +135,8 @@ func (handler *Handler) resourceControlCreate(w http.ResponseWriter, r *http.Req
 		Public:             payload.Public,
 		AdministratorsOnly: payload.AdministratorsOnly,
 		UserAccesses:       userAccesses,
-		TeamAccesses:       teamAccesses,
+		TeamAccesses:       teamResourceAccesses(payload.Teams, payload.TeamGrants),
+		EndpointID:         portainer.EndpointID(payload.EndpointID),
 	}
 
 	err = handler.DataStore.ResourceControl().CreateResourceControl(&resourceControl)
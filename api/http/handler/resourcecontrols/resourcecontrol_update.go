@@ -20,19 +20,23 @@ type resourceControlUpdatePayload struct {
 	Users []int `example:"4"`
 	// List of team identifiers with access to the associated resource
 	Teams []int `example:"7"`
+	// List of teams with an access to the associated resource which expires. The existing grants are kept when omitted,
+	// they are replaced otherwise
+	TeamGrants []teamAccessGrantPayload
 	// Permit access to resource only to admins
 	AdministratorsOnly bool `example:"true"`
 }
 
 func (payload *resourceControlUpdatePayload) Validate(r *http.Request) error {
-	if len(payload.Users) == 0 && len(payload.Teams) == 0 && !payload.Public && !payload.AdministratorsOnly {
-		return errors.New("invalid payload: must specify Users, Teams, Public or AdministratorsOnly")
+	if len(payload.Users) == 0 && len(payload.Teams) == 0 && len(payload.TeamGrants) == 0 && !payload.Public && !payload.AdministratorsOnly {
+		return errors.New("invalid payload: must specify Users, Teams, TeamGrants, Public or AdministratorsOnly")
 	}
 
 	if payload.Public && payload.AdministratorsOnly {
 		return errors.New("invalid payload: cannot set public and administrators only")
 	}
-	return nil
+
+	return validateTeamAccessGrants(payload.TeamGrants, payload.Teams)
 }
 
 // @id ResourceControlUpdate
@@ -92,15 +96,11 @@ func (handler *Handler) resourceControlUpdate(w http.ResponseWriter, r *http.Req
 	}
 	resourceControl.UserAccesses = userAccesses
 
-	var teamAccesses = make([]portainer.TeamResourceAccess, 0)
-	for _, v := range payload.Teams {
-		teamAccess := portainer.TeamResourceAccess{
-			TeamID:      portainer.TeamID(v),
-			AccessLevel: portainer.ReadWriteAccessLevel,
-		}
-		teamAccesses = append(teamAccesses, teamAccess)
+	if payload.TeamGrants != nil {
+		resourceControl.TeamAccesses = teamResourceAccesses(payload.Teams, payload.TeamGrants)
+	} else {
+		resourceControl.TeamAccesses = keepTeamAccessGrants(payload.Teams, resourceControl.TeamAccesses)
 	}
-	resourceControl.TeamAccesses = teamAccesses
 
 	if !security.AuthorizedResourceControlUpdate(resourceControl, securityContext) {
 		return &httperror.HandlerError{http.StatusForbidden, "Permission denied to update the resource control", httperrors.ErrResourceAccessDenied}
@@ -1,18 +1,28 @@
 package roles
 
 import (
+	"errors"
 	"net/http"
+	"strings"
 
 	"github.com/gorilla/mux"
 	httperror "github.com/portainer/libhttp/error"
 	"github.com/portainer/portainer/api"
 	"github.com/portainer/portainer/api/http/security"
+	"github.com/portainer/portainer/api/internal/authorization"
+)
+
+var (
+	errRoleAlreadyExists = errors.New("A role with the same name already exists")
+	errRoleInUse         = errors.New("The role is referenced by an access policy")
+	errBuiltInRole       = errors.New("Built-in roles cannot be modified")
 )
 
 // Handler is the HTTP handler used to handle role operations.
 type Handler struct {
 	*mux.Router
-	DataStore portainer.DataStore
+	DataStore            portainer.DataStore
+	AuthorizationService *authorization.Service
 }
 
 // NewHandler creates a handler to manage role operations.
@@ -22,6 +32,39 @@ func NewHandler(bouncer *security.RequestBouncer) *Handler {
 	}
 	h.Handle("/roles",
 		bouncer.AdminAccess(httperror.LoggerHandler(h.roleList))).Methods(http.MethodGet)
+	h.Handle("/roles",
+		bouncer.AdminAccess(httperror.LoggerHandler(h.roleCreate))).Methods(http.MethodPost)
+	h.Handle("/roles/{id}",
+		bouncer.AdminAccess(httperror.LoggerHandler(h.roleInspect))).Methods(http.MethodGet)
+	h.Handle("/roles/{id}",
+		bouncer.AdminAccess(httperror.LoggerHandler(h.roleUpdate))).Methods(http.MethodPut)
+	h.Handle("/roles/{id}",
+		bouncer.AdminAccess(httperror.LoggerHandler(h.roleDelete))).Methods(http.MethodDelete)
+	h.Handle("/roles/{id}/clone",
+		bouncer.AdminAccess(httperror.LoggerHandler(h.roleClone))).Methods(http.MethodPost)
 
 	return h
 }
+
+// roleNameExists returns true if another role than the excluded one has the name, case insensitive
+func (handler *Handler) roleNameExists(name string, excludedID portainer.RoleID) (bool, error) {
+	roles, err := handler.DataStore.Role().Roles()
+	if err != nil {
+		return false, err
+	}
+
+	for _, role := range roles {
+		if role.ID != excludedID && strings.EqualFold(role.Name, name) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func authorizationSet(operations []portainer.Authorization) portainer.Authorizations {
+	authorizations := make(portainer.Authorizations)
+	for _, operation := range operations {
+		authorizations[operation] = true
+	}
+	return authorizations
+}
@@ -0,0 +1,101 @@
+package roles
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/asaskevich/govalidator"
+	httperror "github.com/portainer/libhttp/error"
+	"github.com/portainer/libhttp/request"
+	"github.com/portainer/libhttp/response"
+	portainer "github.com/portainer/portainer/api"
+	bolterrors "github.com/portainer/portainer/api/bolt/errors"
+)
+
+type roleClonePayload struct {
+	// Name of the new role
+	Name string `example:"Operator (copy)" validate:"required"`
+	// Description of the new role, defaults to the description of the cloned role
+	Description *string `example:"Manage the containers and the stacks of an endpoint"`
+	// Priority of the new role, defaults to the priority of the cloned role
+	Priority *int `example:"5"`
+}
+
+func (payload *roleClonePayload) Validate(r *http.Request) error {
+	if govalidator.IsNull(payload.Name) {
+		return errors.New("Invalid role name")
+	}
+	if payload.Priority != nil && *payload.Priority < 1 {
+		return errors.New("Invalid role priority. Value must be greater than 0")
+	}
+	return nil
+}
+
+// @id RoleClone
+// @summary Clone a role
+// @description Create a new role with the authorizations of an existing role.
+// @description **Access policy**: administrator
+// @tags roles
+// @security jwt
+// @accept json
+// @produce json
+// @param id path int true "Identifier of the role to clone"
+// @param body body roleClonePayload true "New role details"
+// @success 200 {object} portainer.Role "Success"
+// @failure 400 "Invalid request"
+// @failure 404 "Role not found"
+// @failure 409 "Role already exists"
+// @failure 500 "Server error"
+// @router /roles/{id}/clone [post]
+func (handler *Handler) roleClone(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	roleID, err := request.RetrieveNumericRouteVariableValue(r, "id")
+	if err != nil {
+		return &httperror.HandlerError{StatusCode: http.StatusBadRequest, Message: "Invalid role identifier route variable", Err: err}
+	}
+
+	var payload roleClonePayload
+	err = request.DecodeAndValidateJSONPayload(r, &payload)
+	if err != nil {
+		return &httperror.HandlerError{StatusCode: http.StatusBadRequest, Message: "Invalid request payload", Err: err}
+	}
+
+	source, err := handler.DataStore.Role().Role(portainer.RoleID(roleID))
+	if err == bolterrors.ErrObjectNotFound {
+		return &httperror.HandlerError{StatusCode: http.StatusNotFound, Message: "Unable to find a role with the specified identifier inside the database", Err: err}
+	} else if err != nil {
+		return &httperror.HandlerError{StatusCode: http.StatusInternalServerError, Message: "Unable to find a role with the specified identifier inside the database", Err: err}
+	}
+
+	exists, err := handler.roleNameExists(payload.Name, 0)
+	if err != nil {
+		return &httperror.HandlerError{StatusCode: http.StatusInternalServerError, Message: "Unable to retrieve roles from the database", Err: err}
+	}
+	if exists {
+		return &httperror.HandlerError{StatusCode: http.StatusConflict, Message: "A role with the same name already exists", Err: errRoleAlreadyExists}
+	}
+
+	role := &portainer.Role{
+		Name:           payload.Name,
+		Description:    source.Description,
+		Authorizations: make(portainer.Authorizations),
+		Priority:       source.Priority,
+	}
+	for operation, authorized := range source.Authorizations {
+		role.Authorizations[operation] = authorized
+	}
+
+	if payload.Description != nil {
+		role.Description = *payload.Description
+	}
+
+	if payload.Priority != nil {
+		role.Priority = *payload.Priority
+	}
+
+	err = handler.DataStore.Role().CreateRole(role)
+	if err != nil {
+		return &httperror.HandlerError{StatusCode: http.StatusInternalServerError, Message: "Unable to persist the role inside the database", Err: err}
+	}
+
+	return response.JSON(w, role)
+}
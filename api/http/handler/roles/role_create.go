@@ -0,0 +1,81 @@
+package roles
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/asaskevich/govalidator"
+	httperror "github.com/portainer/libhttp/error"
+	"github.com/portainer/libhttp/request"
+	"github.com/portainer/libhttp/response"
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/internal/authorization"
+)
+
+type roleCreatePayload struct {
+	// Role name
+	Name string `example:"Operator" validate:"required"`
+	// Role description
+	Description string `example:"Manage the containers and the stacks of an endpoint"`
+	// Operations authorized by the role
+	Authorizations []portainer.Authorization `example:"DockerContainerList,DockerContainerStart" validate:"required"`
+	// Priority of the role, when several roles apply to a user on an endpoint the role with the highest priority is used
+	Priority int `example:"5" validate:"required"`
+}
+
+func (payload *roleCreatePayload) Validate(r *http.Request) error {
+	if govalidator.IsNull(payload.Name) {
+		return errors.New("Invalid role name")
+	}
+	if len(payload.Authorizations) == 0 {
+		return errors.New("Invalid role authorizations. At least one authorization is required")
+	}
+	if payload.Priority < 1 {
+		return errors.New("Invalid role priority. Value must be greater than 0")
+	}
+	return authorization.ValidateAuthorizations(payload.Authorizations)
+}
+
+// @id RoleCreate
+// @summary Create a new role
+// @description Create a new role from a set of authorizations. The role can then be used in the access policies of the endpoints and endpoint groups.
+// @description **Access policy**: administrator
+// @tags roles
+// @security jwt
+// @accept json
+// @produce json
+// @param body body roleCreatePayload true "Role details"
+// @success 200 {object} portainer.Role "Success"
+// @failure 400 "Invalid request"
+// @failure 409 "Role already exists"
+// @failure 500 "Server error"
+// @router /roles [post]
+func (handler *Handler) roleCreate(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	var payload roleCreatePayload
+	err := request.DecodeAndValidateJSONPayload(r, &payload)
+	if err != nil {
+		return &httperror.HandlerError{StatusCode: http.StatusBadRequest, Message: "Invalid request payload", Err: err}
+	}
+
+	exists, err := handler.roleNameExists(payload.Name, 0)
+	if err != nil {
+		return &httperror.HandlerError{StatusCode: http.StatusInternalServerError, Message: "Unable to retrieve roles from the database", Err: err}
+	}
+	if exists {
+		return &httperror.HandlerError{StatusCode: http.StatusConflict, Message: "A role with the same name already exists", Err: errRoleAlreadyExists}
+	}
+
+	role := &portainer.Role{
+		Name:           payload.Name,
+		Description:    payload.Description,
+		Authorizations: authorizationSet(payload.Authorizations),
+		Priority:       payload.Priority,
+	}
+
+	err = handler.DataStore.Role().CreateRole(role)
+	if err != nil {
+		return &httperror.HandlerError{StatusCode: http.StatusInternalServerError, Message: "Unable to persist the role inside the database", Err: err}
+	}
+
+	return response.JSON(w, role)
+}
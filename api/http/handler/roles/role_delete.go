@@ -0,0 +1,111 @@
+package roles
+
+import (
+	"net/http"
+
+	httperror "github.com/portainer/libhttp/error"
+	"github.com/portainer/libhttp/request"
+	"github.com/portainer/libhttp/response"
+	portainer "github.com/portainer/portainer/api"
+	bolterrors "github.com/portainer/portainer/api/bolt/errors"
+)
+
+// @id RoleDelete
+// @summary Remove a role
+// @description Remove a role. A role cannot be removed while an access policy of an endpoint, an endpoint group
+// @description or a registry uses it. The built-in roles cannot be removed.
+// @description **Access policy**: administrator
+// @tags roles
+// @security jwt
+// @param id path int true "Role identifier"
+// @success 204 "Success"
+// @failure 400 "Invalid request"
+// @failure 403 "Built-in role"
+// @failure 404 "Role not found"
+// @failure 409 "Role used by an access policy"
+// @failure 500 "Server error"
+// @router /roles/{id} [delete]
+func (handler *Handler) roleDelete(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	roleID, err := request.RetrieveNumericRouteVariableValue(r, "id")
+	if err != nil {
+		return &httperror.HandlerError{StatusCode: http.StatusBadRequest, Message: "Invalid role identifier route variable", Err: err}
+	}
+
+	role, err := handler.DataStore.Role().Role(portainer.RoleID(roleID))
+	if err == bolterrors.ErrObjectNotFound {
+		return &httperror.HandlerError{StatusCode: http.StatusNotFound, Message: "Unable to find a role with the specified identifier inside the database", Err: err}
+	} else if err != nil {
+		return &httperror.HandlerError{StatusCode: http.StatusInternalServerError, Message: "Unable to find a role with the specified identifier inside the database", Err: err}
+	}
+
+	if role.BuiltIn {
+		return &httperror.HandlerError{StatusCode: http.StatusForbidden, Message: "Built-in roles cannot be removed", Err: errBuiltInRole}
+	}
+
+	inUse, err := handler.roleInUse(portainer.RoleID(roleID))
+	if err != nil {
+		return &httperror.HandlerError{StatusCode: http.StatusInternalServerError, Message: "Unable to retrieve access policies from the database", Err: err}
+	}
+	if inUse {
+		return &httperror.HandlerError{StatusCode: http.StatusConflict, Message: "The role is used by an access policy and cannot be removed", Err: errRoleInUse}
+	}
+
+	err = handler.DataStore.Role().DeleteRole(portainer.RoleID(roleID))
+	if err != nil {
+		return &httperror.HandlerError{StatusCode: http.StatusInternalServerError, Message: "Unable to remove the role from the database", Err: err}
+	}
+
+	return response.Empty(w)
+}
+
+// roleInUse returns true if an access policy of an endpoint, an endpoint group or a registry references the role
+func (handler *Handler) roleInUse(roleID portainer.RoleID) (bool, error) {
+	endpoints, err := handler.DataStore.Endpoint().Endpoints()
+	if err != nil {
+		return false, err
+	}
+
+	for _, endpoint := range endpoints {
+		if policiesReferenceRole(endpoint.UserAccessPolicies, endpoint.TeamAccessPolicies, roleID) {
+			return true, nil
+		}
+	}
+
+	endpointGroups, err := handler.DataStore.EndpointGroup().EndpointGroups()
+	if err != nil {
+		return false, err
+	}
+
+	for _, endpointGroup := range endpointGroups {
+		if policiesReferenceRole(endpointGroup.UserAccessPolicies, endpointGroup.TeamAccessPolicies, roleID) {
+			return true, nil
+		}
+	}
+
+	registries, err := handler.DataStore.Registry().Registries()
+	if err != nil {
+		return false, err
+	}
+
+	for _, registry := range registries {
+		if policiesReferenceRole(registry.UserAccessPolicies, registry.TeamAccessPolicies, roleID) {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+func policiesReferenceRole(userPolicies portainer.UserAccessPolicies, teamPolicies portainer.TeamAccessPolicies, roleID portainer.RoleID) bool {
+	for _, policy := range userPolicies {
+		if policy.RoleID == roleID {
+			return true
+		}
+	}
+	for _, policy := range teamPolicies {
+		if policy.RoleID == roleID {
+			return true
+		}
+	}
+	return false
+}
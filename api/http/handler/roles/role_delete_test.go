@@ -0,0 +1,79 @@
+package roles
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/bolt/bolttest"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_roleDelete(t *testing.T) {
+	store, teardown := bolttest.MustNewTestStore(true)
+	defer teardown()
+
+	handler := &Handler{DataStore: store}
+
+	usedRole := &portainer.Role{Name: "used", Priority: 5}
+	unusedRole := &portainer.Role{Name: "unused", Priority: 6}
+	registryRole := &portainer.Role{Name: "registry", Priority: 7}
+	builtInRole := &portainer.Role{Name: "Endpoint administrator", Priority: 1, BuiltIn: true}
+	assert.NoError(t, store.Role().CreateRole(usedRole))
+	assert.NoError(t, store.Role().CreateRole(unusedRole))
+	assert.NoError(t, store.Role().CreateRole(registryRole))
+	assert.NoError(t, store.Role().CreateRole(builtInRole))
+
+	registry := &portainer.Registry{Name: "registry", TeamAccessPolicies: portainer.TeamAccessPolicies{1: {RoleID: registryRole.ID}}}
+	assert.NoError(t, store.Registry().CreateRegistry(registry))
+
+	endpointGroup := &portainer.EndpointGroup{
+		ID:                 2,
+		Name:               "group",
+		UserAccessPolicies: portainer.UserAccessPolicies{},
+		TeamAccessPolicies: portainer.TeamAccessPolicies{1: {RoleID: usedRole.ID}},
+	}
+	assert.NoError(t, store.EndpointGroup().CreateEndpointGroup(endpointGroup))
+
+	deleteRole := func(roleID string) int {
+		r := httptest.NewRequest(http.MethodDelete, "/roles/"+roleID, nil)
+		r = mux.SetURLVars(r, map[string]string{"id": roleID})
+		w := httptest.NewRecorder()
+
+		if handlerErr := handler.roleDelete(w, r); handlerErr != nil {
+			return handlerErr.StatusCode
+		}
+		return w.Code
+	}
+
+	t.Run("a role referenced by an access policy cannot be removed", func(t *testing.T) {
+		assert.Equal(t, http.StatusConflict, deleteRole("1"))
+
+		_, err := store.Role().Role(usedRole.ID)
+		assert.NoError(t, err)
+	})
+
+	t.Run("an unused role is removed", func(t *testing.T) {
+		assert.Equal(t, http.StatusNoContent, deleteRole("2"))
+
+		_, err := store.Role().Role(unusedRole.ID)
+		assert.Error(t, err)
+	})
+
+	t.Run("a role referenced by a registry access policy cannot be removed", func(t *testing.T) {
+		assert.Equal(t, http.StatusConflict, deleteRole("3"))
+	})
+
+	t.Run("a built-in role cannot be removed", func(t *testing.T) {
+		assert.Equal(t, http.StatusForbidden, deleteRole("4"))
+
+		_, err := store.Role().Role(builtInRole.ID)
+		assert.NoError(t, err)
+	})
+
+	t.Run("an unknown role is not found", func(t *testing.T) {
+		assert.Equal(t, http.StatusNotFound, deleteRole("42"))
+	})
+}
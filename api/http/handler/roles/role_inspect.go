@@ -0,0 +1,40 @@
+package roles
+
+import (
+	"net/http"
+
+	httperror "github.com/portainer/libhttp/error"
+	"github.com/portainer/libhttp/request"
+	"github.com/portainer/libhttp/response"
+	portainer "github.com/portainer/portainer/api"
+	bolterrors "github.com/portainer/portainer/api/bolt/errors"
+)
+
+// @id RoleInspect
+// @summary Inspect a role
+// @description Retrieve details about a role.
+// @description **Access policy**: administrator
+// @tags roles
+// @security jwt
+// @produce json
+// @param id path int true "Role identifier"
+// @success 200 {object} portainer.Role "Success"
+// @failure 400 "Invalid request"
+// @failure 404 "Role not found"
+// @failure 500 "Server error"
+// @router /roles/{id} [get]
+func (handler *Handler) roleInspect(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	roleID, err := request.RetrieveNumericRouteVariableValue(r, "id")
+	if err != nil {
+		return &httperror.HandlerError{StatusCode: http.StatusBadRequest, Message: "Invalid role identifier route variable", Err: err}
+	}
+
+	role, err := handler.DataStore.Role().Role(portainer.RoleID(roleID))
+	if err == bolterrors.ErrObjectNotFound {
+		return &httperror.HandlerError{StatusCode: http.StatusNotFound, Message: "Unable to find a role with the specified identifier inside the database", Err: err}
+	} else if err != nil {
+		return &httperror.HandlerError{StatusCode: http.StatusInternalServerError, Message: "Unable to find a role with the specified identifier inside the database", Err: err}
+	}
+
+	return response.JSON(w, role)
+}
@@ -0,0 +1,117 @@
+package roles
+
+import (
+	"errors"
+	"net/http"
+
+	httperror "github.com/portainer/libhttp/error"
+	"github.com/portainer/libhttp/request"
+	"github.com/portainer/libhttp/response"
+	portainer "github.com/portainer/portainer/api"
+	bolterrors "github.com/portainer/portainer/api/bolt/errors"
+	"github.com/portainer/portainer/api/internal/authorization"
+)
+
+type roleUpdatePayload struct {
+	// Role name
+	Name *string `example:"Operator"`
+	// Role description
+	Description *string `example:"Manage the containers and the stacks of an endpoint"`
+	// Operations authorized by the role, replaces the current authorizations of the role
+	Authorizations []portainer.Authorization `example:"DockerContainerList,DockerContainerStart"`
+	// Priority of the role
+	Priority *int `example:"5"`
+}
+
+func (payload *roleUpdatePayload) Validate(r *http.Request) error {
+	if payload.Name != nil && *payload.Name == "" {
+		return errors.New("Invalid role name")
+	}
+	if payload.Priority != nil && *payload.Priority < 1 {
+		return errors.New("Invalid role priority. Value must be greater than 0")
+	}
+	if payload.Authorizations != nil {
+		if len(payload.Authorizations) == 0 {
+			return errors.New("Invalid role authorizations. At least one authorization is required")
+		}
+		return authorization.ValidateAuthorizations(payload.Authorizations)
+	}
+	return nil
+}
+
+// @id RoleUpdate
+// @summary Update a role
+// @description Update a role. The authorizations of the users are computed again, which applies the changes
+// @description to every access policy using the role.
+// @description **Access policy**: administrator
+// @tags roles
+// @security jwt
+// @accept json
+// @produce json
+// @param id path int true "Role identifier"
+// @param body body roleUpdatePayload true "Role details"
+// @success 200 {object} portainer.Role "Success"
+// @failure 400 "Invalid request"
+// @failure 403 "Built-in role"
+// @failure 404 "Role not found"
+// @failure 409 "Role name already used"
+// @failure 500 "Server error"
+// @router /roles/{id} [put]
+func (handler *Handler) roleUpdate(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	roleID, err := request.RetrieveNumericRouteVariableValue(r, "id")
+	if err != nil {
+		return &httperror.HandlerError{StatusCode: http.StatusBadRequest, Message: "Invalid role identifier route variable", Err: err}
+	}
+
+	var payload roleUpdatePayload
+	err = request.DecodeAndValidateJSONPayload(r, &payload)
+	if err != nil {
+		return &httperror.HandlerError{StatusCode: http.StatusBadRequest, Message: "Invalid request payload", Err: err}
+	}
+
+	role, err := handler.DataStore.Role().Role(portainer.RoleID(roleID))
+	if err == bolterrors.ErrObjectNotFound {
+		return &httperror.HandlerError{StatusCode: http.StatusNotFound, Message: "Unable to find a role with the specified identifier inside the database", Err: err}
+	} else if err != nil {
+		return &httperror.HandlerError{StatusCode: http.StatusInternalServerError, Message: "Unable to find a role with the specified identifier inside the database", Err: err}
+	}
+
+	if role.BuiltIn {
+		return &httperror.HandlerError{StatusCode: http.StatusForbidden, Message: "Built-in roles cannot be updated", Err: errBuiltInRole}
+	}
+
+	if payload.Name != nil {
+		exists, err := handler.roleNameExists(*payload.Name, role.ID)
+		if err != nil {
+			return &httperror.HandlerError{StatusCode: http.StatusInternalServerError, Message: "Unable to retrieve roles from the database", Err: err}
+		}
+		if exists {
+			return &httperror.HandlerError{StatusCode: http.StatusConflict, Message: "A role with the same name already exists", Err: errRoleAlreadyExists}
+		}
+		role.Name = *payload.Name
+	}
+
+	if payload.Description != nil {
+		role.Description = *payload.Description
+	}
+
+	if payload.Authorizations != nil {
+		role.Authorizations = authorizationSet(payload.Authorizations)
+	}
+
+	if payload.Priority != nil {
+		role.Priority = *payload.Priority
+	}
+
+	err = handler.DataStore.Role().UpdateRole(role.ID, role)
+	if err != nil {
+		return &httperror.HandlerError{StatusCode: http.StatusInternalServerError, Message: "Unable to persist role changes inside the database", Err: err}
+	}
+
+	err = handler.AuthorizationService.UpdateUsersAuthorizations()
+	if err != nil {
+		return &httperror.HandlerError{StatusCode: http.StatusInternalServerError, Message: "Unable to update user authorizations", Err: err}
+	}
+
+	return response.JSON(w, role)
+}
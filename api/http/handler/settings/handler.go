@@ -6,28 +6,37 @@ import (
 	"github.com/gorilla/mux"
 	httperror "github.com/portainer/libhttp/error"
 	"github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/backup"
 	"github.com/portainer/portainer/api/http/security"
+	"github.com/portainer/portainer/api/ldap"
 )
 
 func hideFields(settings *portainer.Settings) {
 	settings.LDAPSettings.Password = ""
 	settings.OAuthSettings.ClientSecret = ""
+	settings.BackupSettings.Password = ""
+	settings.BackupSettings.S3Settings.SecretAccessKey = ""
 }
 
 // Handler is the HTTP handler used to handle settings operations.
 type Handler struct {
 	*mux.Router
-	DataStore       portainer.DataStore
-	FileService     portainer.FileService
-	JWTService      portainer.JWTService
-	LDAPService     portainer.LDAPService
-	SnapshotService portainer.SnapshotService
+	DataStore        portainer.DataStore
+	FileService      portainer.FileService
+	JWTService       portainer.JWTService
+	LDAPService      portainer.LDAPService
+	SnapshotService  portainer.SnapshotService
+	BackupScheduler  *backup.Scheduler
+	LDAPSyncService  *ldap.SyncService
+	RateLimitManager *security.RateLimitManager
+	bouncer          *security.RequestBouncer
 }
 
 // NewHandler creates a handler to manage settings operations.
 func NewHandler(bouncer *security.RequestBouncer) *Handler {
 	h := &Handler{
-		Router: mux.NewRouter(),
+		Router:  mux.NewRouter(),
+		bouncer: bouncer,
 	}
 	h.Handle("/settings",
 		bouncer.AdminAccess(httperror.LoggerHandler(h.settingsInspect))).Methods(http.MethodGet)
@@ -37,6 +46,12 @@ func NewHandler(bouncer *security.RequestBouncer) *Handler {
 		bouncer.PublicAccess(httperror.LoggerHandler(h.settingsPublic))).Methods(http.MethodGet)
 	h.Handle("/settings/authentication/checkLDAP",
 		bouncer.AdminAccess(httperror.LoggerHandler(h.settingsLDAPCheck))).Methods(http.MethodPut)
+	h.Handle("/settings/authentication/syncLDAP",
+		bouncer.AdminAccess(httperror.LoggerHandler(h.settingsLDAPSync))).Methods(http.MethodPost)
+	h.Handle("/settings/ratelimits/bans",
+		bouncer.AdminAccess(httperror.LoggerHandler(h.settingsRateLimitBanList))).Methods(http.MethodGet)
+	h.Handle("/settings/ratelimits/bans",
+		bouncer.AdminAccess(httperror.LoggerHandler(h.settingsRateLimitUnban))).Methods(http.MethodDelete)
 
 	return h
 }
@@ -0,0 +1,47 @@
+package settings
+
+import (
+	"errors"
+	"net/http"
+
+	httperror "github.com/portainer/libhttp/error"
+	"github.com/portainer/libhttp/request"
+	"github.com/portainer/libhttp/response"
+	portainer "github.com/portainer/portainer/api"
+)
+
+var errLDAPNotEnabled = errors.New("LDAP authentication is not enabled")
+
+// @id SettingsLDAPSync
+// @summary Synchronize the team memberships with the LDAP groups
+// @description Reconcile the team memberships of the LDAP users with the LDAP groups and return the changes.
+// @description Only the teams matching a group name are synchronized. Use dryRun to preview the changes.
+// @description **Access policy**: administrator
+// @tags settings
+// @security jwt
+// @produce json
+// @param dryRun query boolean false "Compute the changes without applying them"
+// @success 200 {object} ldap.SyncReport "Success"
+// @failure 400 "Invalid request"
+// @failure 403 "LDAP authentication is not enabled"
+// @failure 500 "Server error"
+// @router /settings/authentication/syncLDAP [post]
+func (handler *Handler) settingsLDAPSync(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	dryRun, _ := request.RetrieveBooleanQueryParameter(r, "dryRun", true)
+
+	settings, err := handler.DataStore.Settings().Settings()
+	if err != nil {
+		return &httperror.HandlerError{StatusCode: http.StatusInternalServerError, Message: "Unable to retrieve the settings from the database", Err: err}
+	}
+
+	if settings.AuthenticationMethod != portainer.AuthenticationLDAP {
+		return &httperror.HandlerError{StatusCode: http.StatusForbidden, Message: "LDAP authentication is not enabled", Err: errLDAPNotEnabled}
+	}
+
+	report, err := handler.LDAPSyncService.Sync(dryRun)
+	if err != nil {
+		return &httperror.HandlerError{StatusCode: http.StatusInternalServerError, Message: "Unable to synchronize the team memberships with the LDAP groups", Err: err}
+	}
+
+	return response.JSON(w, report)
+}
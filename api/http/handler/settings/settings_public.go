@@ -9,6 +9,9 @@ import (
 	portainer "github.com/portainer/portainer/api"
 )
 
+// oidcLoginURI is the Portainer API route starting an OpenID Connect login, the UI appends the state parameter
+const oidcLoginURI = "api/auth/oauth/login"
+
 type publicSettingsResponse struct {
 	// URL to a logo that will be displayed on the login page as well as on top of the sidebar. Will use default Portainer logo when value is empty string
 	LogoURL string `json:"LogoURL" example:"https://mycompany.mydomain.tld/logo.png"`
@@ -22,6 +25,8 @@ type publicSettingsResponse struct {
 	OAuthLogoutURI string `json:"OAuthLogoutURI" example:"https://gitlab.com/oauth/logout"`
 	// Whether telemetry is enabled
 	EnableTelemetry bool `json:"EnableTelemetry" example:"true"`
+	// Rules enforced when the password of an internal user is set
+	PasswordPolicy portainer.PasswordPolicy `json:"PasswordPolicy"`
 }
 
 // @id SettingsPublic
@@ -49,10 +54,16 @@ func generatePublicSettings(appSettings *portainer.Settings) *publicSettingsResp
 		AuthenticationMethod:      appSettings.AuthenticationMethod,
 		EnableEdgeComputeFeatures: appSettings.EnableEdgeComputeFeatures,
 		EnableTelemetry:           appSettings.EnableTelemetry,
+		PasswordPolicy:            appSettings.PasswordPolicy,
 	}
 	//if OAuth authentication is on, compose the related fields from application settings
 	if publicSettings.AuthenticationMethod == portainer.AuthenticationOAuth {
 		publicSettings.OAuthLogoutURI = appSettings.OAuthSettings.LogoutURI
+		//with OpenID Connect, Portainer redirects to the provider to generate the nonce and the PKCE code challenge
+		if appSettings.OAuthSettings.OIDC {
+			publicSettings.OAuthLoginURI = oidcLoginURI
+			return publicSettings
+		}
 		publicSettings.OAuthLoginURI = fmt.Sprintf("%s?response_type=code&client_id=%s&redirect_uri=%s&scope=%s",
 			appSettings.OAuthSettings.AuthorizationURI,
 			appSettings.OAuthSettings.ClientID,
@@ -68,3 +68,15 @@ func TestGeneratePublicSettingsWithoutSSO(t *testing.T) {
 		t.Errorf("wrong OAuthLogoutURI, want: %s, got: %s", dummyOAuthLogoutURI, publicSettings.OAuthLogoutURI)
 	}
 }
+
+func TestGeneratePublicSettingsWithOIDC(t *testing.T) {
+	setup()
+	mockAppSettings.OAuthSettings.OIDC = true
+	publicSettings := generatePublicSettings(mockAppSettings)
+	if publicSettings.OAuthLoginURI != oidcLoginURI {
+		t.Errorf("wrong OAuthLoginURI when OpenID Connect is enabled, want: %s, got: %s", oidcLoginURI, publicSettings.OAuthLoginURI)
+	}
+	if publicSettings.OAuthLogoutURI != dummyOAuthLogoutURI {
+		t.Errorf("wrong OAuthLogoutURI, want: %s, got: %s", dummyOAuthLogoutURI, publicSettings.OAuthLogoutURI)
+	}
+}
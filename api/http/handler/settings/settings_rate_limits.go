@@ -0,0 +1,54 @@
+package settings
+
+import (
+	"errors"
+	"net/http"
+
+	httperror "github.com/portainer/libhttp/error"
+	"github.com/portainer/libhttp/request"
+	"github.com/portainer/libhttp/response"
+)
+
+var errAddressNotBanned = errors.New("The address is not banned")
+
+// @id SettingsRateLimitBanList
+// @summary List the addresses banned by the rate limiting
+// @description List the client addresses currently banned by the rate limiting policy of each class of routes.
+// @description **Access policy**: administrator
+// @tags settings
+// @security jwt
+// @produce json
+// @success 200 {array} security.RateLimitBan "Success"
+// @failure 500 "Server error"
+// @router /settings/ratelimits/bans [get]
+func (handler *Handler) settingsRateLimitBanList(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	return response.JSON(w, handler.RateLimitManager.Bans())
+}
+
+// @id SettingsRateLimitUnban
+// @summary Unban an address
+// @description Remove the ban of a client address from the rate limiting policy of a class of routes,
+// @description or from every policy when no class is specified.
+// @description **Access policy**: administrator
+// @tags settings
+// @security jwt
+// @param address query string true "Banned address"
+// @param class query string false "Class of routes" Enums(authentication, webhooks, edge_checkin, api)
+// @success 204 "Success"
+// @failure 400 "Invalid request"
+// @failure 404 "Address not banned"
+// @router /settings/ratelimits/bans [delete]
+func (handler *Handler) settingsRateLimitUnban(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	address, err := request.RetrieveQueryParameter(r, "address", false)
+	if err != nil {
+		return &httperror.HandlerError{StatusCode: http.StatusBadRequest, Message: "Invalid query parameter: address", Err: err}
+	}
+
+	class, _ := request.RetrieveQueryParameter(r, "class", true)
+
+	if !handler.RateLimitManager.Unban(address, class) {
+		return &httperror.HandlerError{StatusCode: http.StatusNotFound, Message: "The address is not banned", Err: errAddressNotBanned}
+	}
+
+	return response.Empty(w)
+}
@@ -10,7 +10,11 @@ import (
 	"github.com/portainer/libhttp/request"
 	"github.com/portainer/libhttp/response"
 	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/backup"
 	"github.com/portainer/portainer/api/filesystem"
+	"github.com/portainer/portainer/api/http/security"
+	"github.com/portainer/portainer/api/internal/passwordpolicy"
+	"github.com/portainer/portainer/api/ldap"
 )
 
 type settingsUpdatePayload struct {
@@ -18,10 +22,14 @@ type settingsUpdatePayload struct {
 	LogoURL *string `example:"https://mycompany.mydomain.tld/logo.png"`
 	// A list of label name & value that will be used to hide containers when querying containers
 	BlackListedLabels []portainer.Pair
-	// Active authentication method for the Portainer instance. Valid values are: 1 for internal, 2 for LDAP, or 3 for oauth
+	// Active authentication method for the Portainer instance. Valid values are: 1 for internal, 2 for LDAP, 3 for oauth or 4 for trusted proxy
 	AuthenticationMethod *int                     `example:"1"`
 	LDAPSettings         *portainer.LDAPSettings  `example:""`
 	OAuthSettings        *portainer.OAuthSettings `example:""`
+	// Authentication by a trusted reverse proxy
+	ProxyAuthSettings *portainer.ProxyAuthSettings `example:""`
+	// Users authenticated by the client certificates verified by the mutual TLS
+	ClientCertificateAuth *portainer.ClientCertificateAuthSettings `example:""`
 	// The interval in which endpoint snapshots are created
 	SnapshotInterval *string `example:"5m"`
 	// URL to the templates that will be displayed in the UI when navigating to App Templates
@@ -34,11 +42,28 @@ type settingsUpdatePayload struct {
 	UserSessionTimeout *string `example:"5m"`
 	// Whether telemetry is enabled
 	EnableTelemetry *bool `example:"false"`
+	// Scheduled backups settings, the current S3 secret key is kept when empty
+	BackupSettings *backupSettingsPayload `example:""`
+	// Users required to use two-factor authentication. Valid values are: 0 (none), 1 (administrators) or 2 (everyone)
+	TwoFactorRequirement *int `example:"1"`
+	// Rules enforced when the password of an internal user is set
+	PasswordPolicy *portainer.PasswordPolicy `example:""`
+	// Temporary lockout of the accounts after consecutive failed logins
+	AccountLockout *portainer.AccountLockoutSettings `example:""`
+	// Rate limiting policies of the API
+	RateLimiting *portainer.RateLimitSettings `example:""`
+}
+
+type backupSettingsPayload struct {
+	portainer.BackupSettings
+	// Password used to encrypt the scheduled backups. The current password is kept when omitted,
+	// backups are no longer encrypted when empty
+	Password *string `example:"backup-password"`
 }
 
 func (payload *settingsUpdatePayload) Validate(r *http.Request) error {
-	if payload.AuthenticationMethod != nil && *payload.AuthenticationMethod != 1 && *payload.AuthenticationMethod != 2 && *payload.AuthenticationMethod != 3 {
-		return errors.New("Invalid authentication method value. Value must be one of: 1 (internal), 2 (LDAP/AD) or 3 (OAuth)")
+	if payload.AuthenticationMethod != nil && (*payload.AuthenticationMethod < 1 || *payload.AuthenticationMethod > 4) {
+		return errors.New("Invalid authentication method value. Value must be one of: 1 (internal), 2 (LDAP/AD), 3 (OAuth) or 4 (trusted proxy)")
 	}
 	if payload.LogoURL != nil && *payload.LogoURL != "" && !govalidator.IsURL(*payload.LogoURL) {
 		return errors.New("Invalid logo URL. Must correspond to a valid URL format")
@@ -52,6 +77,57 @@ func (payload *settingsUpdatePayload) Validate(r *http.Request) error {
 			return errors.New("Invalid user session timeout")
 		}
 	}
+	if payload.ClientCertificateAuth != nil {
+		err := security.ValidateClientCertificateAuthSettings(*payload.ClientCertificateAuth)
+		if err != nil {
+			return err
+		}
+	}
+	if payload.LDAPSettings != nil {
+		err := ldap.ValidateSyncInterval(payload.LDAPSettings.SyncInterval)
+		if err != nil {
+			return err
+		}
+	}
+	if payload.OAuthSettings != nil && payload.OAuthSettings.OIDC && !govalidator.IsURL(payload.OAuthSettings.IssuerURL) {
+		return errors.New("Invalid OpenID Connect issuer URL. Must correspond to a valid URL format")
+	}
+	if payload.OAuthSettings != nil && !payload.OAuthSettings.OIDC && (payload.OAuthSettings.GroupsClaim != "" || len(payload.OAuthSettings.AdminGroups) > 0) {
+		return errors.New("Invalid OAuth groups settings. The groups are only read from the ID token, OpenID Connect must be enabled")
+	}
+	if payload.OAuthSettings != nil && len(payload.OAuthSettings.AdminGroups) > 0 && payload.OAuthSettings.GroupsClaim == "" {
+		return errors.New("Invalid OAuth admin groups. A groups claim is required to map groups to the administrator role")
+	}
+	if payload.BackupSettings != nil {
+		err := backup.ValidateBackupSettings(payload.BackupSettings.BackupSettings)
+		if err != nil {
+			return err
+		}
+	}
+	if payload.TwoFactorRequirement != nil && (*payload.TwoFactorRequirement < 0 || *payload.TwoFactorRequirement > 2) {
+		return errors.New("Invalid two-factor authentication requirement value. Value must be one of: 0 (none), 1 (administrators) or 2 (everyone)")
+	}
+	if payload.PasswordPolicy != nil {
+		err := passwordpolicy.ValidatePolicy(*payload.PasswordPolicy)
+		if err != nil {
+			return err
+		}
+	}
+	if payload.AccountLockout != nil && payload.AccountLockout.Threshold < 0 {
+		return errors.New("Invalid account lockout threshold. Value must be a positive number, 0 disables the lockout")
+	}
+	if payload.RateLimiting != nil {
+		err := security.ValidateRateLimitSettings(*payload.RateLimiting)
+		if err != nil {
+			return err
+		}
+	}
+	if payload.AccountLockout != nil && payload.AccountLockout.Threshold > 0 {
+		duration, err := time.ParseDuration(payload.AccountLockout.Duration)
+		if err != nil || duration <= 0 {
+			return errors.New("Invalid account lockout duration")
+		}
+	}
 
 	return nil
 }
@@ -120,6 +196,21 @@ func (handler *Handler) settingsUpdate(w http.ResponseWriter, r *http.Request) *
 		settings.OAuthSettings.ClientSecret = clientSecret
 	}
 
+	if payload.ProxyAuthSettings != nil {
+		settings.ProxyAuthSettings = *payload.ProxyAuthSettings
+	}
+
+	if settings.AuthenticationMethod == portainer.AuthenticationProxy && (payload.ProxyAuthSettings != nil || payload.AuthenticationMethod != nil) {
+		err := security.ValidateProxyAuthSettings(settings.ProxyAuthSettings)
+		if err != nil {
+			return &httperror.HandlerError{StatusCode: http.StatusBadRequest, Message: err.Error(), Err: err}
+		}
+	}
+
+	if payload.ClientCertificateAuth != nil {
+		settings.ClientCertificateAuth = *payload.ClientCertificateAuth
+	}
+
 	if payload.EnableEdgeComputeFeatures != nil {
 		settings.EnableEdgeComputeFeatures = *payload.EnableEdgeComputeFeatures
 	}
@@ -147,6 +238,43 @@ func (handler *Handler) settingsUpdate(w http.ResponseWriter, r *http.Request) *
 		settings.EnableTelemetry = *payload.EnableTelemetry
 	}
 
+	if payload.BackupSettings != nil {
+		backupPassword := settings.BackupSettings.Password
+		if payload.BackupSettings.Password != nil {
+			backupPassword = *payload.BackupSettings.Password
+		}
+		s3SecretAccessKey := payload.BackupSettings.S3Settings.SecretAccessKey
+		if s3SecretAccessKey == "" {
+			s3SecretAccessKey = settings.BackupSettings.S3Settings.SecretAccessKey
+		}
+		settings.BackupSettings = payload.BackupSettings.BackupSettings
+		settings.BackupSettings.Password = backupPassword
+		settings.BackupSettings.S3Settings.SecretAccessKey = s3SecretAccessKey
+	}
+
+	if payload.TwoFactorRequirement != nil {
+		settings.TwoFactorRequirement = portainer.TwoFactorRequirement(*payload.TwoFactorRequirement)
+	}
+
+	if payload.PasswordPolicy != nil {
+		settings.PasswordPolicy = *payload.PasswordPolicy
+	}
+
+	if payload.AccountLockout != nil {
+		settings.AccountLockout = *payload.AccountLockout
+	}
+
+	if payload.RateLimiting != nil {
+		settings.RateLimiting = *payload.RateLimiting
+	}
+
+	if payload.LDAPSettings != nil || payload.AuthenticationMethod != nil {
+		err := handler.LDAPSyncService.Update(settings)
+		if err != nil {
+			return &httperror.HandlerError{StatusCode: http.StatusInternalServerError, Message: "Unable to update LDAP synchronization schedule", Err: err}
+		}
+	}
+
 	tlsError := handler.updateTLS(settings)
 	if tlsError != nil {
 		return tlsError
@@ -157,6 +285,27 @@ func (handler *Handler) settingsUpdate(w http.ResponseWriter, r *http.Request) *
 		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to persist settings changes inside the database", err}
 	}
 
+	// the policies are only applied once saved, so that the limiters always match the stored settings
+	if payload.RateLimiting != nil {
+		err := handler.RateLimitManager.Update(settings.RateLimiting)
+		if err != nil {
+			return &httperror.HandlerError{StatusCode: http.StatusInternalServerError, Message: "Unable to update rate limiting policies", Err: err}
+		}
+	}
+
+	// likewise, the backup schedule is only re-armed once the backup settings are saved
+	if payload.BackupSettings != nil {
+		err := handler.BackupScheduler.Update(settings.BackupSettings)
+		if err != nil {
+			return &httperror.HandlerError{StatusCode: http.StatusInternalServerError, Message: "Unable to update backup schedule", Err: err}
+		}
+	}
+
+	err = handler.bouncer.UpdateProxyAuthSettings(settings)
+	if err != nil {
+		return &httperror.HandlerError{StatusCode: http.StatusInternalServerError, Message: "Unable to update proxy authentication settings", Err: err}
+	}
+
 	return response.JSON(w, settings)
 }
 
@@ -3,7 +3,6 @@ package teams
 import (
 	"net/http"
 
-	"github.com/pkg/errors"
 	httperror "github.com/portainer/libhttp/error"
 	"github.com/portainer/libhttp/request"
 	"github.com/portainer/libhttp/response"
@@ -13,7 +12,7 @@ import (
 
 // @id TeamDelete
 // @summary Remove a team
-// @description Remove a team.
+// @description Remove a team, along with its team memberships, access policies and resource accesses.
 // @description **Access policy**: administrator
 // @tags teams
 // @security jwt
@@ -36,37 +35,17 @@ func (handler *Handler) teamDelete(w http.ResponseWriter, r *http.Request) *http
 		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to find a team with the specified identifier inside the database", err}
 	}
 
-	err = handler.DataStore.Team().DeleteTeam(portainer.TeamID(teamID))
+	// the namespace access policies are stored in the clusters, they are updated first so that a cluster
+	// that cannot be reached keeps the team in the database
+	err = handler.AuthorizationService.RemoveTeamNamespaceAccessPolicies(portainer.TeamID(teamID))
 	if err != nil {
-		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to delete the team from the database", err}
+		return &httperror.HandlerError{StatusCode: http.StatusInternalServerError, Message: "Unable to remove the team from the namespace access policies", Err: err}
 	}
 
-	err = handler.DataStore.TeamMembership().DeleteTeamMembershipByTeamID(portainer.TeamID(teamID))
+	err = handler.DataStore.DeleteTeamCascade(portainer.TeamID(teamID))
 	if err != nil {
-		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to delete associated team memberships from the database", err}
-	}
-
-	// update default team if deleted team was default
-	err = handler.updateDefaultTeamIfDeleted(portainer.TeamID(teamID))
-	if err != nil {
-		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to reset default team", err}
+		return &httperror.HandlerError{StatusCode: http.StatusInternalServerError, Message: "Unable to delete the team from the database", Err: err}
 	}
 
 	return response.Empty(w)
 }
-
-// updateDefaultTeamIfDeleted resets the default team to nil if default team was the deleted team
-func (handler *Handler) updateDefaultTeamIfDeleted(teamID portainer.TeamID) error {
-	settings, err := handler.DataStore.Settings().Settings()
-	if err != nil {
-		return errors.Wrap(err, "failed to fetch settings")
-	}
-
-	if teamID != settings.OAuthSettings.DefaultTeamID {
-		return nil
-	}
-
-	settings.OAuthSettings.DefaultTeamID = 0
-	err = handler.DataStore.Settings().UpdateSettings(settings)
-	return errors.Wrap(err, "failed to update settings")
-}
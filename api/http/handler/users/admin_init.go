@@ -30,7 +30,7 @@ func (payload *adminInitPayload) Validate(r *http.Request) error {
 
 // @id UserAdminInit
 // @summary Initialize administrator account
-// @description Initialize the 'admin' user account.
+// @description Initialize the 'admin' user account. The password must comply with the password policy.
 // @description **Access policy**: public
 // @tags
 // @accept json
@@ -58,13 +58,14 @@ func (handler *Handler) adminInit(w http.ResponseWriter, r *http.Request) *httpe
 	}
 
 	user := &portainer.User{
-		Username: payload.Username,
-		Role:     portainer.AdministratorRole,
+		Username:             payload.Username,
+		Role:                 portainer.AdministratorRole,
+		AuthenticationMethod: portainer.AuthenticationInternal,
 	}
 
-	user.Password, err = handler.CryptoService.Hash(payload.Password)
-	if err != nil {
-		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to hash user password", errCryptoHashFailure}
+	handlerErr := handler.setPassword(user, payload.Password)
+	if handlerErr != nil {
+		return handlerErr
 	}
 
 	err = handler.DataStore.User().CreateUser(user)
@@ -2,10 +2,13 @@ package users
 
 import (
 	"errors"
+	"time"
 
 	httperror "github.com/portainer/libhttp/error"
 	"github.com/portainer/portainer/api"
 	"github.com/portainer/portainer/api/http/security"
+	"github.com/portainer/portainer/api/internal/authorization"
+	"github.com/portainer/portainer/api/internal/passwordpolicy"
 
 	"net/http"
 
@@ -18,17 +21,49 @@ var (
 	errAdminCannotRemoveSelf      = errors.New("Cannot remove your own user account. Contact another administrator")
 	errCannotRemoveLastLocalAdmin = errors.New("Cannot remove the last local administrator account")
 	errCryptoHashFailure          = errors.New("Unable to hash data")
+	errInvalidAPIKeyExpiryDate    = errors.New("Invalid API key expiry date, it must be in the future")
+	errTwoFactorAlreadyEnabled    = errors.New("Two-factor authentication is already enabled")
+	errTransferToRemovedUser      = errors.New("Cannot transfer the ownership to the removed user")
 )
 
 func hideFields(user *portainer.User) {
 	user.Password = ""
+	user.TOTP = portainer.TOTPSettings{Enabled: user.TOTP.Enabled}
+	user.PasswordHistory = nil
+}
+
+// setPassword ensures that the password complies with the password policy of the settings and sets it.
+// The caller must persist the user.
+func (handler *Handler) setPassword(user *portainer.User, password string) *httperror.HandlerError {
+	settings, err := handler.DataStore.Settings().Settings()
+	if err != nil {
+		return &httperror.HandlerError{StatusCode: http.StatusInternalServerError, Message: "Unable to retrieve settings from the database", Err: err}
+	}
+
+	err = passwordpolicy.Check(handler.CryptoService, user, settings.PasswordPolicy, password)
+	if err != nil {
+		return &httperror.HandlerError{StatusCode: http.StatusBadRequest, Message: err.Error(), Err: err}
+	}
+
+	err = passwordpolicy.SetPassword(handler.CryptoService, user, settings.PasswordPolicy, password, time.Now())
+	if err != nil {
+		return &httperror.HandlerError{StatusCode: http.StatusInternalServerError, Message: "Unable to hash user password", Err: errCryptoHashFailure}
+	}
+
+	return nil
+}
+
+func hideAPIKeyFields(key *portainer.APIKey) {
+	key.Digest = ""
 }
 
 // Handler is the HTTP handler used to handle user operations.
 type Handler struct {
 	*mux.Router
-	DataStore     portainer.DataStore
-	CryptoService portainer.CryptoService
+	DataStore            portainer.DataStore
+	CryptoService        portainer.CryptoService
+	JWTService           portainer.JWTService
+	AuthorizationService *authorization.Service
 }
 
 // NewHandler creates a handler to manage user operations.
@@ -40,16 +75,42 @@ func NewHandler(bouncer *security.RequestBouncer, rateLimiter *security.RateLimi
 		bouncer.AdminAccess(httperror.LoggerHandler(h.userCreate))).Methods(http.MethodPost)
 	h.Handle("/users",
 		bouncer.RestrictedAccess(httperror.LoggerHandler(h.userList))).Methods(http.MethodGet)
+	h.Handle("/users/lockouts",
+		bouncer.AdminAccess(httperror.LoggerHandler(h.userLockoutList))).Methods(http.MethodGet)
+	h.Handle("/users/sessions",
+		bouncer.AdminAccess(httperror.LoggerHandler(h.sessionList))).Methods(http.MethodGet)
 	h.Handle("/users/{id}",
 		bouncer.RestrictedAccess(httperror.LoggerHandler(h.userInspect))).Methods(http.MethodGet)
 	h.Handle("/users/{id}",
 		bouncer.AuthenticatedAccess(httperror.LoggerHandler(h.userUpdate))).Methods(http.MethodPut)
 	h.Handle("/users/{id}",
 		bouncer.AdminAccess(httperror.LoggerHandler(h.userDelete))).Methods(http.MethodDelete)
+	h.Handle("/users/{id}/authorizations",
+		bouncer.AdminAccess(httperror.LoggerHandler(h.userAuthorizationsExplain))).Methods(http.MethodGet)
 	h.Handle("/users/{id}/memberships",
 		bouncer.RestrictedAccess(httperror.LoggerHandler(h.userMemberships))).Methods(http.MethodGet)
 	h.Handle("/users/{id}/passwd",
 		rateLimiter.LimitAccess(bouncer.AuthenticatedAccess(httperror.LoggerHandler(h.userUpdatePassword)))).Methods(http.MethodPut)
+	h.Handle("/users/{id}/tokens",
+		bouncer.AuthenticatedAccess(httperror.LoggerHandler(h.userCreateAccessToken))).Methods(http.MethodPost)
+	h.Handle("/users/{id}/tokens",
+		bouncer.AuthenticatedAccess(httperror.LoggerHandler(h.userGetAccessTokens))).Methods(http.MethodGet)
+	h.Handle("/users/{id}/tokens/{keyID}",
+		bouncer.AuthenticatedAccess(httperror.LoggerHandler(h.userRemoveAccessToken))).Methods(http.MethodDelete)
+	h.Handle("/users/{id}/sessions",
+		bouncer.AuthenticatedAccess(httperror.LoggerHandler(h.userSessionList))).Methods(http.MethodGet)
+	h.Handle("/users/{id}/sessions",
+		bouncer.AuthenticatedAccess(httperror.LoggerHandler(h.userSessionRevokeAll))).Methods(http.MethodDelete)
+	h.Handle("/users/{id}/sessions/{sessionID}",
+		bouncer.AuthenticatedAccess(httperror.LoggerHandler(h.userSessionRevoke))).Methods(http.MethodDelete)
+	h.Handle("/users/{id}/2fa",
+		bouncer.AuthenticatedAccess(httperror.LoggerHandler(h.userTwoFactorEnrol))).Methods(http.MethodPost)
+	h.Handle("/users/{id}/2fa",
+		bouncer.AuthenticatedAccess(httperror.LoggerHandler(h.userTwoFactorDisable))).Methods(http.MethodDelete)
+	h.Handle("/users/{id}/2fa/verify",
+		rateLimiter.LimitAccess(bouncer.AuthenticatedAccess(httperror.LoggerHandler(h.userTwoFactorVerify)))).Methods(http.MethodPost)
+	h.Handle("/users/{id}/lockout",
+		bouncer.AdminAccess(httperror.LoggerHandler(h.userLockoutClear))).Methods(http.MethodDelete)
 	h.Handle("/users/admin/check",
 		bouncer.PublicAccess(httperror.LoggerHandler(h.adminCheck))).Methods(http.MethodGet)
 	h.Handle("/users/admin/init",
@@ -0,0 +1,204 @@
+package users
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/asaskevich/govalidator"
+	httperror "github.com/portainer/libhttp/error"
+	"github.com/portainer/libhttp/request"
+	"github.com/portainer/libhttp/response"
+	portainer "github.com/portainer/portainer/api"
+	bolterrors "github.com/portainer/portainer/api/bolt/errors"
+	httperrors "github.com/portainer/portainer/api/http/errors"
+	"github.com/portainer/portainer/api/http/security"
+	"github.com/portainer/portainer/api/twofactor"
+)
+
+type userTwoFactorEnrolResponse struct {
+	// Secret to configure in the authenticator application
+	Secret string `json:"secret" example:"JBSWY3DPEHPK3PXP"`
+	// URI usually displayed as a QR code
+	ProvisioningURI string `json:"provisioningURI" example:"otpauth://totp/Portainer:admin?issuer=Portainer&secret=JBSWY3DPEHPK3PXP"`
+}
+
+type userTwoFactorVerifyPayload struct {
+	// Code generated by the authenticator application
+	Code string `example:"123456" validate:"required"`
+}
+
+type userTwoFactorVerifyResponse struct {
+	// Recovery codes, they are not displayed again
+	RecoveryCodes []string `json:"recoveryCodes" example:"0a1b2-c3d4e"`
+}
+
+func (payload *userTwoFactorVerifyPayload) Validate(r *http.Request) error {
+	if govalidator.IsNull(payload.Code) {
+		return errors.New("Invalid code")
+	}
+	return nil
+}
+
+// @id UserTwoFactorEnrol
+// @summary Start a two-factor authentication enrolment
+// @description Generates a new TOTP secret for the user. Two-factor authentication is enabled once a code
+// @description generated with this secret is verified. Only the user can enrol.
+// @description **Access policy**: authenticated
+// @tags users
+// @security jwt
+// @produce json
+// @param id path int true "User identifier"
+// @success 200 {object} userTwoFactorEnrolResponse "Success"
+// @failure 400 "Invalid request"
+// @failure 403 "Permission denied"
+// @failure 404 "User not found"
+// @failure 409 "Two-factor authentication is already enabled"
+// @failure 500 "Server error"
+// @router /users/{id}/2fa [post]
+func (handler *Handler) userTwoFactorEnrol(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	user, handlerErr := handler.twoFactorUser(r, false)
+	if handlerErr != nil {
+		return handlerErr
+	}
+
+	if user.TOTP.Enabled {
+		return &httperror.HandlerError{StatusCode: http.StatusConflict, Message: "Two-factor authentication is already enabled", Err: errTwoFactorAlreadyEnabled}
+	}
+
+	secret, provisioningURI, err := twofactor.StartEnrolment(user)
+	if err != nil {
+		return &httperror.HandlerError{StatusCode: http.StatusInternalServerError, Message: "Unable to generate two-factor authentication secret", Err: err}
+	}
+
+	err = handler.DataStore.User().UpdateUser(user.ID, user)
+	if err != nil {
+		return &httperror.HandlerError{StatusCode: http.StatusInternalServerError, Message: "Unable to persist user changes inside the database", Err: err}
+	}
+
+	return response.JSON(w, &userTwoFactorEnrolResponse{Secret: secret, ProvisioningURI: provisioningURI})
+}
+
+// @id UserTwoFactorVerify
+// @summary Complete a two-factor authentication enrolment
+// @description Verifies a code generated with the secret returned by the enrolment and enables two-factor authentication.
+// @description The recovery codes are only returned by this request. Only the user can complete the enrolment.
+// @description **Access policy**: authenticated
+// @tags users
+// @security jwt
+// @accept json
+// @produce json
+// @param id path int true "User identifier"
+// @param body body userTwoFactorVerifyPayload true "Code"
+// @success 200 {object} userTwoFactorVerifyResponse "Success"
+// @failure 400 "Invalid request"
+// @failure 403 "Permission denied"
+// @failure 404 "User not found"
+// @failure 409 "Two-factor authentication is already enabled"
+// @failure 422 "Invalid code"
+// @failure 500 "Server error"
+// @router /users/{id}/2fa/verify [post]
+func (handler *Handler) userTwoFactorVerify(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	var payload userTwoFactorVerifyPayload
+	err := request.DecodeAndValidateJSONPayload(r, &payload)
+	if err != nil {
+		return &httperror.HandlerError{StatusCode: http.StatusBadRequest, Message: "Invalid request payload", Err: err}
+	}
+
+	user, handlerErr := handler.twoFactorUser(r, false)
+	if handlerErr != nil {
+		return handlerErr
+	}
+
+	if user.TOTP.Enabled {
+		return &httperror.HandlerError{StatusCode: http.StatusConflict, Message: "Two-factor authentication is already enabled", Err: errTwoFactorAlreadyEnabled}
+	}
+
+	recoveryCodes, err := twofactor.CompleteEnrolment(user, payload.Code, time.Now())
+	if err == twofactor.ErrInvalidCode || err == twofactor.ErrNoPendingEnrolment {
+		return &httperror.HandlerError{StatusCode: http.StatusUnprocessableEntity, Message: "Invalid two-factor authentication code", Err: err}
+	} else if err != nil {
+		return &httperror.HandlerError{StatusCode: http.StatusInternalServerError, Message: "Unable to verify the two-factor authentication code", Err: err}
+	}
+
+	err = handler.DataStore.User().UpdateUser(user.ID, user)
+	if err != nil {
+		return &httperror.HandlerError{StatusCode: http.StatusInternalServerError, Message: "Unable to persist user changes inside the database", Err: err}
+	}
+
+	return response.JSON(w, &userTwoFactorVerifyResponse{RecoveryCodes: recoveryCodes})
+}
+
+// @id UserTwoFactorDisable
+// @summary Disable two-factor authentication
+// @description Disables two-factor authentication and removes the secret and recovery codes of the user.
+// @description Administrators can disable it for any user, for example when the authenticator was lost. Users cannot
+// @description disable it for themselves when two-factor authentication is required by the settings.
+// @description **Access policy**: authenticated
+// @tags users
+// @security jwt
+// @param id path int true "User identifier"
+// @success 204 "Success"
+// @failure 400 "Invalid request"
+// @failure 403 "Permission denied"
+// @failure 404 "User not found"
+// @failure 500 "Server error"
+// @router /users/{id}/2fa [delete]
+func (handler *Handler) userTwoFactorDisable(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	user, handlerErr := handler.twoFactorUser(r, true)
+	if handlerErr != nil {
+		return handlerErr
+	}
+
+	tokenData, err := security.RetrieveTokenData(r)
+	if err != nil {
+		return &httperror.HandlerError{StatusCode: http.StatusInternalServerError, Message: "Unable to retrieve user authentication token", Err: err}
+	}
+
+	if tokenData.Role != portainer.AdministratorRole {
+		settings, err := handler.DataStore.Settings().Settings()
+		if err != nil {
+			return &httperror.HandlerError{StatusCode: http.StatusInternalServerError, Message: "Unable to retrieve settings from the database", Err: err}
+		}
+
+		if twofactor.Required(user, settings.TwoFactorRequirement) {
+			return &httperror.HandlerError{StatusCode: http.StatusForbidden, Message: "Two-factor authentication is required by the administrator", Err: httperrors.ErrUnauthorized}
+		}
+	}
+
+	user.TOTP = portainer.TOTPSettings{}
+
+	err = handler.DataStore.User().UpdateUser(user.ID, user)
+	if err != nil {
+		return &httperror.HandlerError{StatusCode: http.StatusInternalServerError, Message: "Unable to persist user changes inside the database", Err: err}
+	}
+
+	return response.Empty(w)
+}
+
+// twoFactorUser retrieves the user targeted by a two-factor authentication request. Only the user can
+// manage its enrolment, administrators are also allowed when allowAdmin is set.
+func (handler *Handler) twoFactorUser(r *http.Request, allowAdmin bool) (*portainer.User, *httperror.HandlerError) {
+	userID, err := request.RetrieveNumericRouteVariableValue(r, "id")
+	if err != nil {
+		return nil, &httperror.HandlerError{StatusCode: http.StatusBadRequest, Message: "Invalid user identifier route variable", Err: err}
+	}
+
+	tokenData, err := security.RetrieveTokenData(r)
+	if err != nil {
+		return nil, &httperror.HandlerError{StatusCode: http.StatusInternalServerError, Message: "Unable to retrieve user authentication token", Err: err}
+	}
+
+	if tokenData.ID != portainer.UserID(userID) && !(allowAdmin && tokenData.Role == portainer.AdministratorRole) {
+		return nil, &httperror.HandlerError{StatusCode: http.StatusForbidden, Message: "Permission denied to manage the two-factor authentication of the user", Err: httperrors.ErrUnauthorized}
+	}
+
+	user, err := handler.DataStore.User().User(portainer.UserID(userID))
+	if err == bolterrors.ErrObjectNotFound {
+		return nil, &httperror.HandlerError{StatusCode: http.StatusNotFound, Message: "Unable to find a user with the specified identifier inside the database", Err: err}
+	} else if err != nil {
+		return nil, &httperror.HandlerError{StatusCode: http.StatusInternalServerError, Message: "Unable to find a user with the specified identifier inside the database", Err: err}
+	}
+
+	return user, nil
+}
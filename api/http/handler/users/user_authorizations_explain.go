@@ -0,0 +1,122 @@
+package users
+
+import (
+	"errors"
+	"net/http"
+
+	httperror "github.com/portainer/libhttp/error"
+	"github.com/portainer/libhttp/request"
+	"github.com/portainer/libhttp/response"
+	portainer "github.com/portainer/portainer/api"
+	bolterrors "github.com/portainer/portainer/api/bolt/errors"
+	"github.com/portainer/portainer/api/internal/authorization"
+)
+
+// @id UserAuthorizationsExplain
+// @summary Explain the authorizations of a user on an endpoint
+// @description Retrieve the effective role of a user on an endpoint, the access policy it comes from and the
+// @description authorizations it grants. Every access policy applying to the user is listed in evaluation order.
+// @description When an operation is specified, the response tells whether it is authorized. When a resource
+// @description is specified, the response contains the resource control decision and the rule that produced it.
+// @description **Access policy**: administrator
+// @tags users
+// @security jwt
+// @produce json
+// @param id path int true "User identifier"
+// @param endpointId query int true "Endpoint identifier"
+// @param operation query string false "Operation to check" example(DockerContainerList)
+// @param resourceId query string false "Identifier of the resource to check"
+// @param resourceType query int false "Type of the resource to check, matches any type when omitted" Enums(1,2,3,4,5,6,7,8,9)
+// @success 200 {object} authorization.EndpointAccessExplanation "Success"
+// @failure 400 "Invalid request"
+// @failure 404 "User or endpoint not found"
+// @failure 500 "Server error"
+// @router /users/{id}/authorizations [get]
+func (handler *Handler) userAuthorizationsExplain(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	userID, err := request.RetrieveNumericRouteVariableValue(r, "id")
+	if err != nil {
+		return &httperror.HandlerError{StatusCode: http.StatusBadRequest, Message: "Invalid user identifier route variable", Err: err}
+	}
+
+	endpointID, err := request.RetrieveNumericQueryParameter(r, "endpointId", false)
+	if err != nil {
+		return &httperror.HandlerError{StatusCode: http.StatusBadRequest, Message: "Invalid query parameter: endpointId", Err: err}
+	}
+
+	operation, _ := request.RetrieveQueryParameter(r, "operation", true)
+	if operation != "" && !authorization.IsKnownOperation(portainer.Authorization(operation)) {
+		return &httperror.HandlerError{StatusCode: http.StatusBadRequest, Message: "Invalid query parameter: operation", Err: errors.New("Unknown authorization: " + operation)}
+	}
+
+	resourceID, _ := request.RetrieveQueryParameter(r, "resourceId", true)
+
+	resourceType, err := request.RetrieveNumericQueryParameter(r, "resourceType", true)
+	if err != nil {
+		return &httperror.HandlerError{StatusCode: http.StatusBadRequest, Message: "Invalid query parameter: resourceType", Err: err}
+	}
+
+	user, err := handler.DataStore.User().User(portainer.UserID(userID))
+	if err == bolterrors.ErrObjectNotFound {
+		return &httperror.HandlerError{StatusCode: http.StatusNotFound, Message: "Unable to find a user with the specified identifier inside the database", Err: err}
+	} else if err != nil {
+		return &httperror.HandlerError{StatusCode: http.StatusInternalServerError, Message: "Unable to find a user with the specified identifier inside the database", Err: err}
+	}
+
+	endpoint, err := handler.DataStore.Endpoint().Endpoint(portainer.EndpointID(endpointID))
+	if err == bolterrors.ErrObjectNotFound {
+		return &httperror.HandlerError{StatusCode: http.StatusNotFound, Message: "Unable to find an endpoint with the specified identifier inside the database", Err: err}
+	} else if err != nil {
+		return &httperror.HandlerError{StatusCode: http.StatusInternalServerError, Message: "Unable to find an endpoint with the specified identifier inside the database", Err: err}
+	}
+
+	explanation, err := handler.AuthorizationService.ExplainEndpointAccess(user, endpoint)
+	if err != nil {
+		return &httperror.HandlerError{StatusCode: http.StatusInternalServerError, Message: "Unable to compute the authorizations of the user", Err: err}
+	}
+
+	if operation != "" {
+		explanation.ExplainOperation(portainer.Authorization(operation))
+	}
+
+	if resourceID != "" {
+		resourceControls, err := handler.DataStore.ResourceControl().ResourceControls()
+		if err != nil {
+			return &httperror.HandlerError{StatusCode: http.StatusInternalServerError, Message: "Unable to retrieve resource controls from the database", Err: err}
+		}
+
+		memberships, err := handler.DataStore.TeamMembership().TeamMembershipsByUserID(user.ID)
+		if err != nil {
+			return &httperror.HandlerError{StatusCode: http.StatusInternalServerError, Message: "Unable to retrieve user team memberships from the database", Err: err}
+		}
+
+		userTeamIDs := make([]portainer.TeamID, 0)
+		for _, membership := range memberships {
+			userTeamIDs = append(userTeamIDs, membership.TeamID)
+		}
+
+		resourceControl := findResourceControl(resourceID, portainer.ResourceControlType(resourceType), resourceControls)
+		explanation.ResourceControlDecision = authorization.ExplainResourceControl(resourceID, resourceControl, user, userTeamIDs)
+	}
+
+	return response.JSON(w, explanation)
+}
+
+// findResourceControl returns the resource control of a resource, or nil when there is none.
+// Any type of resource matches when resourceType is 0.
+func findResourceControl(resourceID string, resourceType portainer.ResourceControlType, resourceControls []portainer.ResourceControl) *portainer.ResourceControl {
+	if resourceType != 0 {
+		return authorization.GetResourceControlByResourceIDAndType(resourceID, resourceType, resourceControls)
+	}
+
+	for idx, resourceControl := range resourceControls {
+		if resourceControl.ResourceID == resourceID {
+			return &resourceControls[idx]
+		}
+		for _, subResourceID := range resourceControl.SubResourceIDs {
+			if subResourceID == resourceID {
+				return &resourceControls[idx]
+			}
+		}
+	}
+	return nil
+}
@@ -34,7 +34,7 @@ func (payload *userCreatePayload) Validate(r *http.Request) error {
 
 // @id UserCreate
 // @summary Create a new user
-// @description Create a new Portainer user.
+// @description Create a new Portainer user. The password must comply with the password policy.
 // @description Only team leaders and administrators can create users.
 // @description Only administrators can create an administrator user account.
 // @description **Access policy**: restricted
@@ -77,20 +77,21 @@ func (handler *Handler) userCreate(w http.ResponseWriter, r *http.Request) *http
 		return &httperror.HandlerError{http.StatusConflict, "Another user with the same username already exists", errUserAlreadyExists}
 	}
 
-	user = &portainer.User{
-		Username: payload.Username,
-		Role:     portainer.UserRole(payload.Role),
-	}
-
 	settings, err := handler.DataStore.Settings().Settings()
 	if err != nil {
 		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to retrieve settings from the database", err}
 	}
 
+	user = &portainer.User{
+		Username:             payload.Username,
+		Role:                 portainer.UserRole(payload.Role),
+		AuthenticationMethod: settings.AuthenticationMethod,
+	}
+
 	if settings.AuthenticationMethod == portainer.AuthenticationInternal {
-		user.Password, err = handler.CryptoService.Hash(payload.Password)
-		if err != nil {
-			return &httperror.HandlerError{http.StatusInternalServerError, "Unable to hash user password", errCryptoHashFailure}
+		handlerErr := handler.setPassword(user, payload.Password)
+		if handlerErr != nil {
+			return handlerErr
 		}
 	}
 
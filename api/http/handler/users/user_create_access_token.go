@@ -0,0 +1,105 @@
+package users
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/asaskevich/govalidator"
+	httperror "github.com/portainer/libhttp/error"
+	"github.com/portainer/libhttp/request"
+	"github.com/portainer/libhttp/response"
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/apikey"
+	httperrors "github.com/portainer/portainer/api/http/errors"
+	"github.com/portainer/portainer/api/http/security"
+)
+
+type userAccessTokenCreatePayload struct {
+	// Description of the token
+	Description string `validate:"required" example:"github-action-deploy"`
+	// Expiry date of the token, as a unix timestamp. The token never expires when omitted
+	ExpiryDate int64 `example:"1640995200"`
+}
+
+func (payload *userAccessTokenCreatePayload) Validate(r *http.Request) error {
+	if govalidator.IsNull(payload.Description) {
+		return errors.New("Invalid description. Must not be empty")
+	}
+	if payload.ExpiryDate != 0 && payload.ExpiryDate <= time.Now().Unix() {
+		return errInvalidAPIKeyExpiryDate
+	}
+	return nil
+}
+
+type accessTokenResponse struct {
+	// The raw API key, it is only returned once and must be sent in the X-API-Key header
+	RawAPIKey string           `json:"rawAPIKey" example:"ptr_4Ih5C3PUJ8hpEbzmbTqHEt9nsb4OK0VgcEeHvMB8c6s"`
+	APIKey    portainer.APIKey `json:"apiKey"`
+}
+
+// @id UserGenerateAPIKey
+// @summary Generate an API key for a user
+// @description Generates an API key for a user. The raw key is only returned once, Portainer only stores its digest.
+// @description Only the user can generate API keys for themselves.
+// @description **Access policy**: authenticated
+// @tags users
+// @security jwt
+// @accept json
+// @produce json
+// @param id path int true "User identifier"
+// @param body body userAccessTokenCreatePayload true "details"
+// @success 200 {object} accessTokenResponse "Created"
+// @failure 400 "Invalid request"
+// @failure 403 "Permission denied"
+// @failure 404 "User not found"
+// @failure 500 "Server error"
+// @router /users/{id}/tokens [post]
+func (handler *Handler) userCreateAccessToken(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	userID, err := request.RetrieveNumericRouteVariableValue(r, "id")
+	if err != nil {
+		return &httperror.HandlerError{StatusCode: http.StatusBadRequest, Message: "Invalid user identifier route variable", Err: err}
+	}
+
+	var payload userAccessTokenCreatePayload
+	err = request.DecodeAndValidateJSONPayload(r, &payload)
+	if err != nil {
+		return &httperror.HandlerError{StatusCode: http.StatusBadRequest, Message: "Invalid request payload", Err: err}
+	}
+
+	tokenData, err := security.RetrieveTokenData(r)
+	if err != nil {
+		return &httperror.HandlerError{StatusCode: http.StatusInternalServerError, Message: "Unable to retrieve user authentication token", Err: err}
+	}
+
+	if tokenData.ID != portainer.UserID(userID) {
+		return &httperror.HandlerError{StatusCode: http.StatusForbidden, Message: "Permission denied to create user access token", Err: httperrors.ErrUnauthorized}
+	}
+
+	user, err := handler.DataStore.User().User(portainer.UserID(userID))
+	if err != nil {
+		return &httperror.HandlerError{StatusCode: http.StatusNotFound, Message: "Unable to find a user", Err: err}
+	}
+
+	rawKey, prefix, digest, err := apikey.GenerateKey()
+	if err != nil {
+		return &httperror.HandlerError{StatusCode: http.StatusInternalServerError, Message: "Unable to generate API key", Err: err}
+	}
+
+	key := &portainer.APIKey{
+		UserID:       user.ID,
+		Description:  payload.Description,
+		Prefix:       prefix,
+		Digest:       digest,
+		CreationDate: time.Now().Unix(),
+		ExpiryDate:   payload.ExpiryDate,
+	}
+
+	err = handler.DataStore.APIKey().CreateAPIKey(key)
+	if err != nil {
+		return &httperror.HandlerError{StatusCode: http.StatusInternalServerError, Message: "Unable to persist the API key inside the database", Err: err}
+	}
+
+	hideAPIKeyFields(key)
+	return response.JSON(w, accessTokenResponse{RawAPIKey: rawKey, APIKey: *key})
+}
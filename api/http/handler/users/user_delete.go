@@ -14,13 +14,16 @@ import (
 
 // @id UserDelete
 // @summary Remove a user
-// @description Remove a user.
+// @description Remove a user, along with its team memberships, API keys, sessions, access policies and resource accesses.
+// @description When transferTo is set, the access policies, the resource accesses, the stacks and the custom templates
+// @description of the user are given to that user instead.
 // @description **Access policy**: administrator
 // @tags users
 // @security jwt
 // @accept json
 // @produce json
 // @param id path int true "User identifier"
+// @param transferTo query int false "Identifier of the user receiving the ownership of the resources of the removed user"
 // @success 204 "Success"
 // @failure 400 "Invalid request"
 // @failure 403 "Permission denied"
@@ -46,6 +49,11 @@ func (handler *Handler) userDelete(w http.ResponseWriter, r *http.Request) *http
 		return &httperror.HandlerError{http.StatusForbidden, "Cannot remove your own user account. Contact another administrator", errAdminCannotRemoveSelf}
 	}
 
+	transferTo, _ := request.RetrieveNumericQueryParameter(r, "transferTo", true)
+	if transferTo == userID {
+		return &httperror.HandlerError{StatusCode: http.StatusBadRequest, Message: "Invalid transferTo query parameter", Err: errTransferToRemovedUser}
+	}
+
 	user, err := handler.DataStore.User().User(portainer.UserID(userID))
 	if err == bolterrors.ErrObjectNotFound {
 		return &httperror.HandlerError{http.StatusNotFound, "Unable to find a user with the specified identifier inside the database", err}
@@ -53,16 +61,25 @@ func (handler *Handler) userDelete(w http.ResponseWriter, r *http.Request) *http
 		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to find a user with the specified identifier inside the database", err}
 	}
 
+	if transferTo != 0 {
+		_, err := handler.DataStore.User().User(portainer.UserID(transferTo))
+		if err == bolterrors.ErrObjectNotFound {
+			return &httperror.HandlerError{StatusCode: http.StatusBadRequest, Message: "Unable to find the user receiving the ownership inside the database", Err: err}
+		} else if err != nil {
+			return &httperror.HandlerError{StatusCode: http.StatusInternalServerError, Message: "Unable to find the user receiving the ownership inside the database", Err: err}
+		}
+	}
+
 	if user.Role == portainer.AdministratorRole {
-		return handler.deleteAdminUser(w, user)
+		return handler.deleteAdminUser(w, user, portainer.UserID(transferTo))
 	}
 
-	return handler.deleteUser(w, user)
+	return handler.deleteUser(w, user, portainer.UserID(transferTo))
 }
 
-func (handler *Handler) deleteAdminUser(w http.ResponseWriter, user *portainer.User) *httperror.HandlerError {
+func (handler *Handler) deleteAdminUser(w http.ResponseWriter, user *portainer.User, transferTo portainer.UserID) *httperror.HandlerError {
 	if user.Password == "" {
-		return handler.deleteUser(w, user)
+		return handler.deleteUser(w, user, transferTo)
 	}
 
 	users, err := handler.DataStore.User().Users()
@@ -81,18 +98,20 @@ func (handler *Handler) deleteAdminUser(w http.ResponseWriter, user *portainer.U
 		return &httperror.HandlerError{http.StatusInternalServerError, "Cannot remove local administrator user", errCannotRemoveLastLocalAdmin}
 	}
 
-	return handler.deleteUser(w, user)
+	return handler.deleteUser(w, user, transferTo)
 }
 
-func (handler *Handler) deleteUser(w http.ResponseWriter, user *portainer.User) *httperror.HandlerError {
-	err := handler.DataStore.User().DeleteUser(user.ID)
+func (handler *Handler) deleteUser(w http.ResponseWriter, user *portainer.User, transferTo portainer.UserID) *httperror.HandlerError {
+	// the namespace access policies are stored in the clusters, they are updated first so that a cluster
+	// that cannot be reached keeps the user in the database
+	err := handler.AuthorizationService.RemoveUserNamespaceAccessPolicies(user.ID, transferTo)
 	if err != nil {
-		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to remove user from the database", err}
+		return &httperror.HandlerError{StatusCode: http.StatusInternalServerError, Message: "Unable to remove the user from the namespace access policies", Err: err}
 	}
 
-	err = handler.DataStore.TeamMembership().DeleteTeamMembershipByUserID(user.ID)
+	err = handler.DataStore.DeleteUserCascade(user.ID, transferTo)
 	if err != nil {
-		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to remove user memberships from the database", err}
+		return &httperror.HandlerError{StatusCode: http.StatusInternalServerError, Message: "Unable to remove user from the database", Err: err}
 	}
 
 	return response.Empty(w)
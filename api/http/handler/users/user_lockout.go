@@ -0,0 +1,84 @@
+package users
+
+import (
+	"log"
+	"net/http"
+	"time"
+
+	httperror "github.com/portainer/libhttp/error"
+	"github.com/portainer/libhttp/request"
+	"github.com/portainer/libhttp/response"
+	portainer "github.com/portainer/portainer/api"
+	bolterrors "github.com/portainer/portainer/api/bolt/errors"
+	"github.com/portainer/portainer/api/http/security"
+)
+
+// @id UserLockoutList
+// @summary List locked accounts
+// @description List the users whose account is temporarily locked after too many failed logins.
+// @description **Access policy**: administrator
+// @tags users
+// @security jwt
+// @produce json
+// @success 200 {array} portainer.User "Success"
+// @failure 500 "Server error"
+// @router /users/lockouts [get]
+func (handler *Handler) userLockoutList(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	users, err := handler.DataStore.User().Users()
+	if err != nil {
+		return &httperror.HandlerError{StatusCode: http.StatusInternalServerError, Message: "Unable to retrieve users from the database", Err: err}
+	}
+
+	now := time.Now()
+	lockedUsers := make([]portainer.User, 0)
+	for _, user := range users {
+		if security.AccountLocked(&user, now) {
+			hideFields(&user)
+			lockedUsers = append(lockedUsers, user)
+		}
+	}
+
+	return response.JSON(w, lockedUsers)
+}
+
+// @id UserLockoutClear
+// @summary Unlock an account
+// @description Clear the lockout and the failed logins of a user.
+// @description **Access policy**: administrator
+// @tags users
+// @security jwt
+// @param id path int true "User identifier"
+// @success 204 "Success"
+// @failure 400 "Invalid request"
+// @failure 404 "User not found"
+// @failure 500 "Server error"
+// @router /users/{id}/lockout [delete]
+func (handler *Handler) userLockoutClear(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	userID, err := request.RetrieveNumericRouteVariableValue(r, "id")
+	if err != nil {
+		return &httperror.HandlerError{StatusCode: http.StatusBadRequest, Message: "Invalid user identifier route variable", Err: err}
+	}
+
+	user, err := handler.DataStore.User().User(portainer.UserID(userID))
+	if err == bolterrors.ErrObjectNotFound {
+		return &httperror.HandlerError{StatusCode: http.StatusNotFound, Message: "Unable to find a user with the specified identifier inside the database", Err: err}
+	} else if err != nil {
+		return &httperror.HandlerError{StatusCode: http.StatusInternalServerError, Message: "Unable to find a user with the specified identifier inside the database", Err: err}
+	}
+
+	if !security.ResetFailedLogins(user) {
+		return response.Empty(w)
+	}
+
+	err = handler.DataStore.User().UpdateUser(user.ID, user)
+	if err != nil {
+		return &httperror.HandlerError{StatusCode: http.StatusInternalServerError, Message: "Unable to persist user changes inside the database", Err: err}
+	}
+
+	tokenData, err := security.RetrieveTokenData(r)
+	if err == nil {
+		log.Printf("[INFO] [http,users] [audit] [message: account unlocked] [user: %s] [by: %s]", user.Username, tokenData.Username)
+	}
+
+	return response.Empty(w)
+}
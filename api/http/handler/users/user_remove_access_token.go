@@ -0,0 +1,67 @@
+package users
+
+import (
+	"net/http"
+
+	httperror "github.com/portainer/libhttp/error"
+	"github.com/portainer/libhttp/request"
+	"github.com/portainer/libhttp/response"
+	portainer "github.com/portainer/portainer/api"
+	bolterrors "github.com/portainer/portainer/api/bolt/errors"
+	httperrors "github.com/portainer/portainer/api/http/errors"
+	"github.com/portainer/portainer/api/http/security"
+)
+
+// @id UserRemoveAPIKey
+// @summary Remove an API key associated to a user
+// @description Removes an API key associated to a user. The key is revoked immediately.
+// @description Only the user or an administrator can remove the API keys of a user.
+// @description **Access policy**: authenticated
+// @tags users
+// @security jwt
+// @param id path int true "User identifier"
+// @param keyID path int true "API key identifier"
+// @success 204 "Success"
+// @failure 400 "Invalid request"
+// @failure 403 "Permission denied"
+// @failure 404 "API key not found"
+// @failure 500 "Server error"
+// @router /users/{id}/tokens/{keyID} [delete]
+func (handler *Handler) userRemoveAccessToken(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	userID, err := request.RetrieveNumericRouteVariableValue(r, "id")
+	if err != nil {
+		return &httperror.HandlerError{StatusCode: http.StatusBadRequest, Message: "Invalid user identifier route variable", Err: err}
+	}
+
+	keyID, err := request.RetrieveNumericRouteVariableValue(r, "keyID")
+	if err != nil {
+		return &httperror.HandlerError{StatusCode: http.StatusBadRequest, Message: "Invalid API key identifier route variable", Err: err}
+	}
+
+	tokenData, err := security.RetrieveTokenData(r)
+	if err != nil {
+		return &httperror.HandlerError{StatusCode: http.StatusInternalServerError, Message: "Unable to retrieve user authentication token", Err: err}
+	}
+
+	if tokenData.Role != portainer.AdministratorRole && tokenData.ID != portainer.UserID(userID) {
+		return &httperror.HandlerError{StatusCode: http.StatusForbidden, Message: "Permission denied to remove user access token", Err: httperrors.ErrUnauthorized}
+	}
+
+	key, err := handler.DataStore.APIKey().APIKey(portainer.APIKeyID(keyID))
+	if err == bolterrors.ErrObjectNotFound {
+		return &httperror.HandlerError{StatusCode: http.StatusNotFound, Message: "Unable to find an API key with the specified identifier inside the database", Err: err}
+	} else if err != nil {
+		return &httperror.HandlerError{StatusCode: http.StatusInternalServerError, Message: "Unable to find an API key with the specified identifier inside the database", Err: err}
+	}
+
+	if key.UserID != portainer.UserID(userID) {
+		return &httperror.HandlerError{StatusCode: http.StatusNotFound, Message: "Unable to find an API key with the specified identifier inside the database", Err: bolterrors.ErrObjectNotFound}
+	}
+
+	err = handler.DataStore.APIKey().DeleteAPIKey(key.ID)
+	if err != nil {
+		return &httperror.HandlerError{StatusCode: http.StatusInternalServerError, Message: "Unable to remove the API key from the database", Err: err}
+	}
+
+	return response.Empty(w)
+}
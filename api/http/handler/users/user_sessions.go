@@ -0,0 +1,230 @@
+package users
+
+import (
+	"log"
+	"net/http"
+	"sort"
+	"time"
+
+	httperror "github.com/portainer/libhttp/error"
+	"github.com/portainer/libhttp/request"
+	"github.com/portainer/libhttp/response"
+	portainer "github.com/portainer/portainer/api"
+	bolterrors "github.com/portainer/portainer/api/bolt/errors"
+	httperrors "github.com/portainer/portainer/api/http/errors"
+	"github.com/portainer/portainer/api/http/security"
+)
+
+type sessionResponse struct {
+	portainer.IssuedToken
+	// Name of the user the session belongs to
+	Username string `json:"Username" example:"bob"`
+	// Whether the session is the one used to authenticate the request
+	Current bool `json:"Current" example:"true"`
+}
+
+// @id UserSessionList
+// @summary List the sessions of a user
+// @description List the active sessions of a user, most recently active first. A session is created for each
+// @description JWT issued to the user and ends when the token expires or is revoked.
+// @description Only the user or an administrator can retrieve the sessions of a user.
+// @description **Access policy**: authenticated
+// @tags users
+// @security jwt
+// @produce json
+// @param id path int true "User identifier"
+// @success 200 {array} sessionResponse "Success"
+// @failure 400 "Invalid request"
+// @failure 403 "Permission denied"
+// @failure 404 "User not found"
+// @failure 500 "Server error"
+// @router /users/{id}/sessions [get]
+func (handler *Handler) userSessionList(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	user, tokenData, handlerErr := handler.sessionUser(r)
+	if handlerErr != nil {
+		return handlerErr
+	}
+
+	tokens, err := handler.DataStore.IssuedToken().IssuedTokensByUserID(user.ID)
+	if err != nil {
+		return &httperror.HandlerError{StatusCode: http.StatusInternalServerError, Message: "Unable to retrieve sessions from the database", Err: err}
+	}
+
+	return response.JSON(w, activeSessions(tokens, map[portainer.UserID]string{user.ID: user.Username}, tokenData.TokenID))
+}
+
+// @id SessionList
+// @summary List the sessions of every user
+// @description List the active sessions of every user, most recently active first.
+// @description **Access policy**: administrator
+// @tags users
+// @security jwt
+// @produce json
+// @success 200 {array} sessionResponse "Success"
+// @failure 500 "Server error"
+// @router /users/sessions [get]
+func (handler *Handler) sessionList(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	tokenData, err := security.RetrieveTokenData(r)
+	if err != nil {
+		return &httperror.HandlerError{StatusCode: http.StatusInternalServerError, Message: "Unable to retrieve user authentication token", Err: err}
+	}
+
+	tokens, err := handler.DataStore.IssuedToken().IssuedTokens()
+	if err != nil {
+		return &httperror.HandlerError{StatusCode: http.StatusInternalServerError, Message: "Unable to retrieve sessions from the database", Err: err}
+	}
+
+	users, err := handler.DataStore.User().Users()
+	if err != nil {
+		return &httperror.HandlerError{StatusCode: http.StatusInternalServerError, Message: "Unable to retrieve users from the database", Err: err}
+	}
+
+	usernames := make(map[portainer.UserID]string)
+	for _, user := range users {
+		usernames[user.ID] = user.Username
+	}
+
+	return response.JSON(w, activeSessions(tokens, usernames, tokenData.TokenID))
+}
+
+// @id UserSessionRevoke
+// @summary Revoke a session of a user
+// @description Revoke a session of a user. The JWT of the session is rejected immediately.
+// @description Only the user or an administrator can revoke the sessions of a user.
+// @description **Access policy**: authenticated
+// @tags users
+// @security jwt
+// @param id path int true "User identifier"
+// @param sessionID path string true "Session identifier"
+// @success 204 "Success"
+// @failure 400 "Invalid request"
+// @failure 403 "Permission denied"
+// @failure 404 "Session not found"
+// @failure 500 "Server error"
+// @router /users/{id}/sessions/{sessionID} [delete]
+func (handler *Handler) userSessionRevoke(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	sessionID, err := request.RetrieveRouteVariableValue(r, "sessionID")
+	if err != nil {
+		return &httperror.HandlerError{StatusCode: http.StatusBadRequest, Message: "Invalid session identifier route variable", Err: err}
+	}
+
+	user, tokenData, handlerErr := handler.sessionUser(r)
+	if handlerErr != nil {
+		return handlerErr
+	}
+
+	token, err := handler.DataStore.IssuedToken().IssuedToken(sessionID)
+	if err != nil && err != bolterrors.ErrObjectNotFound {
+		return &httperror.HandlerError{StatusCode: http.StatusInternalServerError, Message: "Unable to find a session with the specified identifier inside the database", Err: err}
+	}
+
+	if err == bolterrors.ErrObjectNotFound || token.UserID != user.ID || token.Revoked {
+		return &httperror.HandlerError{StatusCode: http.StatusNotFound, Message: "Unable to find a session with the specified identifier inside the database", Err: bolterrors.ErrObjectNotFound}
+	}
+
+	err = handler.JWTService.RevokeToken(token.ID)
+	if err != nil {
+		return &httperror.HandlerError{StatusCode: http.StatusInternalServerError, Message: "Unable to revoke the session", Err: err}
+	}
+
+	log.Printf("[INFO] [http,users] [audit] [message: session revoked] [user: %s] [client_ip: %s] [by: %s]", user.Username, token.ClientIP, tokenData.Username)
+
+	return response.Empty(w)
+}
+
+// @id UserSessionRevokeAll
+// @summary Revoke all the sessions of a user
+// @description Revoke all the sessions of a user. When keepCurrent is set, the session used to authenticate
+// @description the request is kept, which signs the user out everywhere else.
+// @description Only the user or an administrator can revoke the sessions of a user.
+// @description **Access policy**: authenticated
+// @tags users
+// @security jwt
+// @param id path int true "User identifier"
+// @param keepCurrent query boolean false "Keep the session used to authenticate the request"
+// @success 204 "Success"
+// @failure 400 "Invalid request"
+// @failure 403 "Permission denied"
+// @failure 404 "User not found"
+// @failure 500 "Server error"
+// @router /users/{id}/sessions [delete]
+func (handler *Handler) userSessionRevokeAll(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	keepCurrent, _ := request.RetrieveBooleanQueryParameter(r, "keepCurrent", true)
+
+	user, tokenData, handlerErr := handler.sessionUser(r)
+	if handlerErr != nil {
+		return handlerErr
+	}
+
+	exceptTokenID := ""
+	if keepCurrent {
+		exceptTokenID = tokenData.TokenID
+	}
+
+	err := handler.JWTService.RevokeUserTokens(user.ID, exceptTokenID)
+	if err != nil {
+		return &httperror.HandlerError{StatusCode: http.StatusInternalServerError, Message: "Unable to revoke the sessions of the user", Err: err}
+	}
+
+	log.Printf("[INFO] [http,users] [audit] [message: all sessions revoked] [user: %s] [keep_current: %t] [by: %s]", user.Username, keepCurrent, tokenData.Username)
+
+	return response.Empty(w)
+}
+
+// sessionUser returns the user of the route, which must be the authenticated user unless the request
+// is made by an administrator, and the token data of the request.
+func (handler *Handler) sessionUser(r *http.Request) (*portainer.User, *portainer.TokenData, *httperror.HandlerError) {
+	userID, err := request.RetrieveNumericRouteVariableValue(r, "id")
+	if err != nil {
+		return nil, nil, &httperror.HandlerError{StatusCode: http.StatusBadRequest, Message: "Invalid user identifier route variable", Err: err}
+	}
+
+	tokenData, err := security.RetrieveTokenData(r)
+	if err != nil {
+		return nil, nil, &httperror.HandlerError{StatusCode: http.StatusInternalServerError, Message: "Unable to retrieve user authentication token", Err: err}
+	}
+
+	if tokenData.Role != portainer.AdministratorRole && tokenData.ID != portainer.UserID(userID) {
+		return nil, nil, &httperror.HandlerError{StatusCode: http.StatusForbidden, Message: "Permission denied to manage the sessions of the user", Err: httperrors.ErrUnauthorized}
+	}
+
+	user, err := handler.DataStore.User().User(portainer.UserID(userID))
+	if err == bolterrors.ErrObjectNotFound {
+		return nil, nil, &httperror.HandlerError{StatusCode: http.StatusNotFound, Message: "Unable to find a user with the specified identifier inside the database", Err: err}
+	} else if err != nil {
+		return nil, nil, &httperror.HandlerError{StatusCode: http.StatusInternalServerError, Message: "Unable to find a user with the specified identifier inside the database", Err: err}
+	}
+
+	return user, tokenData, nil
+}
+
+// activeSessions returns the tokens that are neither revoked nor expired, most recently active first
+func activeSessions(tokens []portainer.IssuedToken, usernames map[portainer.UserID]string, currentTokenID string) []sessionResponse {
+	now := time.Now().Unix()
+
+	sessions := make([]sessionResponse, 0)
+	for _, token := range tokens {
+		if token.Revoked || token.ExpiresAt <= now {
+			continue
+		}
+
+		sessions = append(sessions, sessionResponse{
+			IssuedToken: token,
+			Username:    usernames[token.UserID],
+			Current:     token.ID == currentTokenID,
+		})
+	}
+
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessionActivity(&sessions[i].IssuedToken) > sessionActivity(&sessions[j].IssuedToken)
+	})
+
+	return sessions
+}
+
+func sessionActivity(token *portainer.IssuedToken) int64 {
+	if token.LastActivity != 0 {
+		return token.LastActivity
+	}
+	return token.IssuedAt
+}
@@ -35,6 +35,7 @@ func (payload *userUpdatePayload) Validate(r *http.Request) error {
 // @id UserUpdate
 // @summary Update a user
 // @description Update user details. A regular user account can only update his details.
+// @description The password must comply with the password policy.
 // @description **Access policy**: authenticated
 // @tags users
 // @security jwt
@@ -93,15 +94,25 @@ func (handler *Handler) userUpdate(w http.ResponseWriter, r *http.Request) *http
 		user.Username = payload.Username
 	}
 
+	revokeTokens := false
 	if payload.Password != "" {
-		user.Password, err = handler.CryptoService.Hash(payload.Password)
-		if err != nil {
-			return &httperror.HandlerError{http.StatusInternalServerError, "Unable to hash user password", errCryptoHashFailure}
+		handlerErr := handler.setPassword(user, payload.Password)
+		if handlerErr != nil {
+			return handlerErr
 		}
+		revokeTokens = true
+	}
+
+	// the role is embedded in the tokens, every token of the user must be revoked when it changes
+	exceptTokenID := ""
+	if tokenData.ID == user.ID {
+		exceptTokenID = tokenData.TokenID
 	}
 
-	if payload.Role != 0 {
+	if payload.Role != 0 && portainer.UserRole(payload.Role) != user.Role {
 		user.Role = portainer.UserRole(payload.Role)
+		revokeTokens = true
+		exceptTokenID = ""
 	}
 
 	err = handler.DataStore.User().UpdateUser(user.ID, user)
@@ -109,5 +120,13 @@ func (handler *Handler) userUpdate(w http.ResponseWriter, r *http.Request) *http
 		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to persist user changes inside the database", err}
 	}
 
+	if revokeTokens {
+		err = handler.JWTService.RevokeUserTokens(user.ID, exceptTokenID)
+		if err != nil {
+			return &httperror.HandlerError{StatusCode: http.StatusInternalServerError, Message: "Unable to revoke user tokens", Err: err}
+		}
+	}
+
+	hideFields(user)
 	return response.JSON(w, user)
 }
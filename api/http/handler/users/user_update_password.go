@@ -33,7 +33,7 @@ func (payload *userUpdatePasswordPayload) Validate(r *http.Request) error {
 
 // @id UserUpdatePassword
 // @summary Update password for a user
-// @description Update password for the specified user.
+// @description Update password for the specified user. The new password must comply with the password policy.
 // @description **Access policy**: authenticated
 // @tags users
 // @security jwt
@@ -80,9 +80,9 @@ func (handler *Handler) userUpdatePassword(w http.ResponseWriter, r *http.Reques
 		return &httperror.HandlerError{http.StatusForbidden, "Specified password do not match actual password", httperrors.ErrUnauthorized}
 	}
 
-	user.Password, err = handler.CryptoService.Hash(payload.NewPassword)
-	if err != nil {
-		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to hash user password", errCryptoHashFailure}
+	handlerErr := handler.setPassword(user, payload.NewPassword)
+	if handlerErr != nil {
+		return handlerErr
 	}
 
 	err = handler.DataStore.User().UpdateUser(user.ID, user)
@@ -90,5 +90,16 @@ func (handler *Handler) userUpdatePassword(w http.ResponseWriter, r *http.Reques
 		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to persist user changes inside the database", err}
 	}
 
+	// keep the token used to change the password valid, the other sessions of the user are closed
+	exceptTokenID := ""
+	if tokenData.ID == user.ID {
+		exceptTokenID = tokenData.TokenID
+	}
+
+	err = handler.JWTService.RevokeUserTokens(user.ID, exceptTokenID)
+	if err != nil {
+		return &httperror.HandlerError{StatusCode: http.StatusInternalServerError, Message: "Unable to revoke user tokens", Err: err}
+	}
+
 	return response.Empty(w)
 }
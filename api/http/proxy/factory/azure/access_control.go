@@ -62,6 +62,7 @@ func (transport *Transport) createPrivateResourceControl(
 	userID portainer.UserID) (*portainer.ResourceControl, error) {
 
 	resourceControl := authorization.NewPrivateResourceControl(resourceIdentifier, resourceType, userID)
+	resourceControl.EndpointID = transport.endpoint.ID
 
 	err := transport.dataStore.ResourceControl().CreateResourceControl(resourceControl)
 	if err != nil {
@@ -51,6 +51,7 @@ func getUniqueElements(items string) []string {
 func (transport *Transport) newResourceControlFromPortainerLabels(labelsObject map[string]interface{}, resourceID string, resourceType portainer.ResourceControlType) (*portainer.ResourceControl, error) {
 	if labelsObject[resourceLabelForPortainerPublicResourceControl] != nil {
 		resourceControl := authorization.NewPublicResourceControl(resourceID, resourceType)
+		resourceControl.EndpointID = transport.endpoint.ID
 
 		err := transport.dataStore.ResourceControl().CreateResourceControl(resourceControl)
 		if err != nil {
@@ -97,6 +98,7 @@ func (transport *Transport) newResourceControlFromPortainerLabels(labelsObject m
 		}
 
 		resourceControl := authorization.NewRestrictedResourceControl(resourceID, resourceType, userIDs, teamIDs)
+		resourceControl.EndpointID = transport.endpoint.ID
 
 		err := transport.dataStore.ResourceControl().CreateResourceControl(resourceControl)
 		if err != nil {
@@ -111,6 +113,7 @@ func (transport *Transport) newResourceControlFromPortainerLabels(labelsObject m
 
 func (transport *Transport) createPrivateResourceControl(resourceIdentifier string, resourceType portainer.ResourceControlType, userID portainer.UserID) (*portainer.ResourceControl, error) {
 	resourceControl := authorization.NewPrivateResourceControl(resourceIdentifier, resourceType, userID)
+	resourceControl.EndpointID = transport.endpoint.ID
 
 	err := transport.dataStore.ResourceControl().CreateResourceControl(resourceControl)
 	if err != nil {
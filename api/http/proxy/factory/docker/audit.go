@@ -0,0 +1,116 @@
+package docker
+
+import (
+	"net/http"
+	"strings"
+
+	portainer "github.com/portainer/portainer/api"
+)
+
+// dockerCollectionOperations are the operations of the Docker API requests on a collection, keyed by path
+var dockerCollectionOperations = map[string]portainer.Authorization{
+	"build":             portainer.OperationDockerImageBuild,
+	"build/cancel":      portainer.OperationDockerBuildCancel,
+	"build/prune":       portainer.OperationDockerBuildPrune,
+	"commit":            portainer.OperationDockerImageCommit,
+	"configs/create":    portainer.OperationDockerConfigCreate,
+	"containers/create": portainer.OperationDockerContainerCreate,
+	"containers/prune":  portainer.OperationDockerContainerPrune,
+	"images/create":     portainer.OperationDockerImageCreate,
+	"images/load":       portainer.OperationDockerImageLoad,
+	"images/prune":      portainer.OperationDockerImagePrune,
+	"networks/create":   portainer.OperationDockerNetworkCreate,
+	"networks/prune":    portainer.OperationDockerNetworkPrune,
+	"plugins/create":    portainer.OperationDockerPluginCreate,
+	"plugins/pull":      portainer.OperationDockerPluginPull,
+	"secrets/create":    portainer.OperationDockerSecretCreate,
+	"services/create":   portainer.OperationDockerServiceCreate,
+	"session":           portainer.OperationDockerSessionStart,
+	"swarm/init":        portainer.OperationDockerSwarmInit,
+	"swarm/join":        portainer.OperationDockerSwarmJoin,
+	"swarm/leave":       portainer.OperationDockerSwarmLeave,
+	"swarm/unlock":      portainer.OperationDockerSwarmUnlock,
+	"swarm/update":      portainer.OperationDockerSwarmUpdate,
+	"volumes/create":    portainer.OperationDockerVolumeCreate,
+	"volumes/prune":     portainer.OperationDockerVolumePrune,
+	"v2/browse/delete":  portainer.OperationDockerAgentBrowseDelete,
+	"v2/browse/put":     portainer.OperationDockerAgentBrowsePut,
+	"v2/browse/rename":  portainer.OperationDockerAgentBrowseRename,
+}
+
+// dockerDeleteOperations are the operations removing a Docker resource, keyed by collection
+var dockerDeleteOperations = map[string]portainer.Authorization{
+	"configs":    portainer.OperationDockerConfigDelete,
+	"containers": portainer.OperationDockerContainerDelete,
+	"images":     portainer.OperationDockerImageDelete,
+	"networks":   portainer.OperationDockerNetworkDelete,
+	"nodes":      portainer.OperationDockerNodeDelete,
+	"plugins":    portainer.OperationDockerPluginDelete,
+	"secrets":    portainer.OperationDockerSecretDelete,
+	"services":   portainer.OperationDockerServiceDelete,
+	"volumes":    portainer.OperationDockerVolumeDelete,
+}
+
+// dockerActionOperations are the operations of the actions on a Docker resource, keyed by collection and action
+var dockerActionOperations = map[string]portainer.Authorization{
+	"configs/update":      portainer.OperationDockerConfigUpdate,
+	"containers/archive":  portainer.OperationDockerContainerPutContainerArchive,
+	"containers/attach":   portainer.OperationDockerContainerAttach,
+	"containers/exec":     portainer.OperationDockerContainerExec,
+	"containers/kill":     portainer.OperationDockerContainerKill,
+	"containers/pause":    portainer.OperationDockerContainerPause,
+	"containers/rename":   portainer.OperationDockerContainerRename,
+	"containers/resize":   portainer.OperationDockerContainerResize,
+	"containers/restart":  portainer.OperationDockerContainerRestart,
+	"containers/start":    portainer.OperationDockerContainerStart,
+	"containers/stop":     portainer.OperationDockerContainerStop,
+	"containers/unpause":  portainer.OperationDockerContainerUnpause,
+	"containers/update":   portainer.OperationDockerContainerUpdate,
+	"containers/wait":     portainer.OperationDockerContainerWait,
+	"exec/resize":         portainer.OperationDockerExecResize,
+	"exec/start":          portainer.OperationDockerExecStart,
+	"images/push":         portainer.OperationDockerImagePush,
+	"images/tag":          portainer.OperationDockerImageTag,
+	"networks/connect":    portainer.OperationDockerNetworkConnect,
+	"networks/disconnect": portainer.OperationDockerNetworkDisconnect,
+	"nodes/update":        portainer.OperationDockerNodeUpdate,
+	"plugins/disable":     portainer.OperationDockerPluginDisable,
+	"plugins/enable":      portainer.OperationDockerPluginEnable,
+	"plugins/push":        portainer.OperationDockerPluginPush,
+	"plugins/set":         portainer.OperationDockerPluginSet,
+	"plugins/upgrade":     portainer.OperationDockerPluginUpgrade,
+	"secrets/update":      portainer.OperationDockerSecretUpdate,
+	"services/update":     portainer.OperationDockerServiceUpdate,
+}
+
+// dockerOperation returns the operation of a Docker API request and the identifier of the resource it targets.
+// The operation is OperationDockerUndefined when it cannot be identified.
+// The names of the images and plugins can contain slashes, the action is then the last segment of the path.
+func dockerOperation(method, requestPath string) (portainer.Authorization, string) {
+	requestPath = strings.Trim(requestPath, "/")
+
+	if operation, ok := dockerCollectionOperations[requestPath]; ok {
+		return operation, ""
+	}
+
+	segments := strings.Split(requestPath, "/")
+	if len(segments) < 2 {
+		return portainer.OperationDockerUndefined, ""
+	}
+	collection := segments[0]
+
+	if method == http.MethodDelete {
+		if operation, ok := dockerDeleteOperations[collection]; ok {
+			return operation, strings.Join(segments[1:], "/")
+		}
+	}
+
+	if len(segments) > 2 {
+		action := segments[len(segments)-1]
+		if operation, ok := dockerActionOperations[collection+"/"+action]; ok {
+			return operation, strings.Join(segments[1:len(segments)-1], "/")
+		}
+	}
+
+	return portainer.OperationDockerUndefined, strings.Join(segments[1:], "/")
+}
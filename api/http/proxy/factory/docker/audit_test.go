@@ -0,0 +1,35 @@
+package docker
+
+import (
+	"net/http"
+	"testing"
+
+	portainer "github.com/portainer/portainer/api"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_dockerOperation(t *testing.T) {
+	cases := []struct {
+		method             string
+		path               string
+		expectedOperation  portainer.Authorization
+		expectedResourceID string
+	}{
+		{http.MethodPost, "/containers/create", portainer.OperationDockerContainerCreate, ""},
+		{http.MethodPost, "/containers/617c5f22bb9b/stop", portainer.OperationDockerContainerStop, "617c5f22bb9b"},
+		{http.MethodDelete, "/containers/617c5f22bb9b", portainer.OperationDockerContainerDelete, "617c5f22bb9b"},
+		{http.MethodPost, "/images/portainer/agent:latest/push", portainer.OperationDockerImagePush, "portainer/agent:latest"},
+		{http.MethodDelete, "/images/portainer/agent:latest", portainer.OperationDockerImageDelete, "portainer/agent:latest"},
+		{http.MethodPost, "/swarm/init", portainer.OperationDockerSwarmInit, ""},
+		{http.MethodPost, "/containers/617c5f22bb9b/unknown", portainer.OperationDockerUndefined, "617c5f22bb9b/unknown"},
+		{http.MethodPost, "/unknown", portainer.OperationDockerUndefined, ""},
+	}
+
+	for _, test := range cases {
+		t.Run(test.method+" "+test.path, func(t *testing.T) {
+			operation, resourceID := dockerOperation(test.method, test.path)
+			assert.Equal(t, test.expectedOperation, operation)
+			assert.Equal(t, test.expectedResourceID, resourceID)
+		})
+	}
+}
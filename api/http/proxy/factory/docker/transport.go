@@ -17,6 +17,7 @@ import (
 	"github.com/portainer/portainer/api/docker"
 	"github.com/portainer/portainer/api/http/proxy/factory/responseutils"
 	"github.com/portainer/portainer/api/http/security"
+	"github.com/portainer/portainer/api/internal/audit"
 	"github.com/portainer/portainer/api/internal/authorization"
 )
 
@@ -100,6 +101,27 @@ func (transport *Transport) ProxyDockerRequest(request *http.Request) (*http.Res
 		request.Header.Set(portainer.PortainerAgentSignatureHeader, signature)
 	}
 
+	if !audit.IsMutating(request.Method) {
+		return transport.proxyDockerRequest(request, requestPath)
+	}
+
+	auditLog := &portainer.AuditLog{
+		EndpointID: transport.endpoint.ID,
+		Method:     request.Method,
+		Path:       requestPath,
+	}
+	auditLog.Operation, auditLog.ResourceID = dockerOperation(request.Method, requestPath)
+
+	response, err := transport.proxyDockerRequest(request, requestPath)
+	if response != nil {
+		auditLog.Status = response.StatusCode
+	}
+	audit.Record(request, auditLog)
+
+	return response, err
+}
+
+func (transport *Transport) proxyDockerRequest(request *http.Request, requestPath string) (*http.Response, error) {
 	switch {
 	case strings.HasPrefix(requestPath, "/configs"):
 		return transport.proxyConfigRequest(request)
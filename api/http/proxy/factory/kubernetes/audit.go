@@ -0,0 +1,46 @@
+package kubernetes
+
+import (
+	"net/http"
+	"strings"
+
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/internal/audit"
+)
+
+// roundTripWithAudit sends a request and records an audit log when the request is not a read.
+// There is no operation for the Kubernetes API, the audit logs only identify the resource.
+func roundTripWithAudit(endpointID portainer.EndpointID, request *http.Request, roundTrip func(*http.Request) (*http.Response, error)) (*http.Response, error) {
+	if !audit.IsMutating(request.Method) {
+		return roundTrip(request)
+	}
+
+	auditLog := &portainer.AuditLog{
+		EndpointID: endpointID,
+		Method:     request.Method,
+		Path:       request.URL.Path,
+		ResourceID: kubernetesResourceID(request.URL.Path),
+	}
+
+	response, err := roundTrip(request)
+	if response != nil {
+		auditLog.Status = response.StatusCode
+	}
+	audit.Record(request, auditLog)
+
+	return response, err
+}
+
+// kubernetesResourceID returns the path of a resource without the API group and version,
+// e.g. namespaces/default/pods/web for /api/v1/namespaces/default/pods/web
+func kubernetesResourceID(requestPath string) string {
+	segments := strings.Split(strings.Trim(requestPath, "/"), "/")
+
+	switch {
+	case len(segments) > 2 && segments[0] == "api":
+		return strings.Join(segments[2:], "/")
+	case len(segments) > 3 && segments[0] == "apis":
+		return strings.Join(segments[3:], "/")
+	}
+	return strings.Join(segments, "/")
+}
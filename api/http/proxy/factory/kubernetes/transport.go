@@ -18,6 +18,7 @@ import (
 
 type (
 	localTransport struct {
+		dataStore          portainer.DataStore
 		httpTransport      *http.Transport
 		tokenManager       *tokenManager
 		endpointIdentifier portainer.EndpointID
@@ -41,17 +42,19 @@ type (
 )
 
 // NewLocalTransport returns a new transport that can be used to send requests to the local Kubernetes API
-func NewLocalTransport(tokenManager *tokenManager) (*localTransport, error) {
+func NewLocalTransport(datastore portainer.DataStore, endpointIdentifier portainer.EndpointID, tokenManager *tokenManager) (*localTransport, error) {
 	config, err := crypto.CreateTLSConfigurationFromBytes(nil, nil, nil, true, true)
 	if err != nil {
 		return nil, err
 	}
 
 	transport := &localTransport{
+		dataStore: datastore,
 		httpTransport: &http.Transport{
 			TLSClientConfig: config,
 		},
-		tokenManager: tokenManager,
+		tokenManager:       tokenManager,
+		endpointIdentifier: endpointIdentifier,
 	}
 
 	return transport, nil
@@ -66,18 +69,19 @@ func (transport *localTransport) RoundTrip(request *http.Request) (*http.Respons
 
 	request.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
 
-	return transport.httpTransport.RoundTrip(request)
+	return roundTripWithAudit(transport.endpointIdentifier, request, transport.httpTransport.RoundTrip)
 }
 
 // NewAgentTransport returns a new transport that can be used to send signed requests to a Portainer agent
-func NewAgentTransport(datastore portainer.DataStore, signatureService portainer.DigitalSignatureService, tlsConfig *tls.Config, tokenManager *tokenManager) *agentTransport {
+func NewAgentTransport(datastore portainer.DataStore, signatureService portainer.DigitalSignatureService, tlsConfig *tls.Config, endpointIdentifier portainer.EndpointID, tokenManager *tokenManager) *agentTransport {
 	transport := &agentTransport{
 		dataStore: datastore,
 		httpTransport: &http.Transport{
 			TLSClientConfig: tlsConfig,
 		},
-		tokenManager:     tokenManager,
-		signatureService: signatureService,
+		tokenManager:       tokenManager,
+		signatureService:   signatureService,
+		endpointIdentifier: endpointIdentifier,
 	}
 
 	return transport
@@ -104,7 +108,7 @@ func (transport *agentTransport) RoundTrip(request *http.Request) (*http.Respons
 	request.Header.Set(portainer.PortainerAgentPublicKeyHeader, transport.signatureService.EncodedPublicKey())
 	request.Header.Set(portainer.PortainerAgentSignatureHeader, signature)
 
-	return transport.httpTransport.RoundTrip(request)
+	return roundTripWithAudit(transport.endpointIdentifier, request, transport.httpTransport.RoundTrip)
 }
 
 // NewEdgeTransport returns a new transport that can be used to send signed requests to a Portainer Edge agent
@@ -133,7 +137,7 @@ func (transport *edgeTransport) RoundTrip(request *http.Request) (*http.Response
 		decorateAgentRequest(request, transport.dataStore)
 	}
 
-	response, err := transport.httpTransport.RoundTrip(request)
+	response, err := roundTripWithAudit(transport.endpointIdentifier, request, transport.httpTransport.RoundTrip)
 
 	if err == nil {
 		transport.reverseTunnelService.SetTunnelStatusToActive(transport.endpointIdentifier)
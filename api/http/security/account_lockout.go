@@ -0,0 +1,47 @@
+package security
+
+import (
+	"time"
+
+	portainer "github.com/portainer/portainer/api"
+)
+
+// AccountLocked returns whether the account of the user is temporarily locked. Unlike the RateLimiter
+// which bans an IP address, the lockout protects an account against attempts coming from many addresses.
+func AccountLocked(user *portainer.User, now time.Time) bool {
+	return user.LockedUntil > now.Unix()
+}
+
+// RecordFailedLogin records a failed login of the user and locks the account once the threshold of
+// the settings is reached. It returns true when the account became locked, the caller must persist the user.
+func RecordFailedLogin(user *portainer.User, settings portainer.AccountLockoutSettings, now time.Time) bool {
+	if settings.Threshold <= 0 {
+		return false
+	}
+
+	user.FailedLoginAttempts++
+	if user.FailedLoginAttempts < settings.Threshold {
+		return false
+	}
+
+	duration, err := time.ParseDuration(settings.Duration)
+	if err != nil || duration <= 0 {
+		duration, _ = time.ParseDuration(portainer.DefaultAccountLockoutDuration)
+	}
+
+	user.FailedLoginAttempts = 0
+	user.LockedUntil = now.Add(duration).Unix()
+	return true
+}
+
+// ResetFailedLogins clears the failed logins and the lockout of the user. It returns true when the user
+// was updated, the caller must persist the user.
+func ResetFailedLogins(user *portainer.User) bool {
+	if user.FailedLoginAttempts == 0 && user.LockedUntil == 0 {
+		return false
+	}
+
+	user.FailedLoginAttempts = 0
+	user.LockedUntil = 0
+	return true
+}
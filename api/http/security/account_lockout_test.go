@@ -0,0 +1,30 @@
+package security
+
+import (
+	"testing"
+	"time"
+
+	portainer "github.com/portainer/portainer/api"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecordFailedLogin(t *testing.T) {
+	settings := portainer.AccountLockoutSettings{Threshold: 3, Duration: "10m"}
+	user := &portainer.User{}
+	now := time.Now()
+
+	assert.False(t, RecordFailedLogin(user, settings, now))
+	assert.False(t, RecordFailedLogin(user, settings, now))
+	assert.False(t, AccountLocked(user, now))
+
+	assert.True(t, RecordFailedLogin(user, settings, now))
+	assert.True(t, AccountLocked(user, now))
+	assert.False(t, AccountLocked(user, now.Add(11*time.Minute)), "the lockout should be temporary")
+
+	assert.True(t, ResetFailedLogins(user))
+	assert.False(t, AccountLocked(user, now))
+	assert.False(t, ResetFailedLogins(user))
+
+	assert.False(t, RecordFailedLogin(user, portainer.AccountLockoutSettings{}, now), "a zero threshold should disable the lockout")
+	assert.Zero(t, user.FailedLoginAttempts)
+}
@@ -1,7 +1,10 @@
 package security
 
 import (
+	"time"
+
 	"github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/internal/authorization"
 )
 
 // AuthorizedResourceControlAccess checks whether the user can alter an existing resource control.
@@ -117,14 +120,16 @@ func AuthorizedRegistryAccess(registry *portainer.Registry, userID portainer.Use
 }
 
 func authorizedAccess(userID portainer.UserID, memberships []portainer.TeamMembership, userAccessPolicies portainer.UserAccessPolicies, teamAccessPolicies portainer.TeamAccessPolicies) bool {
-	_, userAccess := userAccessPolicies[userID]
-	if userAccess {
+	now := time.Now()
+
+	policy, userAccess := userAccessPolicies[userID]
+	if userAccess && !authorization.AccessPolicyExpired(policy, now) {
 		return true
 	}
 
 	for _, membership := range memberships {
-		_, teamAccess := teamAccessPolicies[membership.TeamID]
-		if teamAccess {
+		policy, teamAccess := teamAccessPolicies[membership.TeamID]
+		if teamAccess && !authorization.AccessPolicyExpired(policy, now) {
 			return true
 		}
 	}
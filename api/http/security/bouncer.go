@@ -2,20 +2,31 @@ package security
 
 import (
 	"errors"
+	"log"
 	"net/http"
 	"strings"
+	"time"
 
 	httperror "github.com/portainer/libhttp/error"
 	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/apikey"
 	bolterrors "github.com/portainer/portainer/api/bolt/errors"
 	httperrors "github.com/portainer/portainer/api/http/errors"
 )
 
+// apiKeyLastUsedUpdateInterval is the minimum number of seconds between two updates of the last usage date of an API key
+const apiKeyLastUsedUpdateInterval = 60
+
+// sessionActivityUpdateInterval is the minimum number of seconds between two updates of the last activity of a session
+const sessionActivityUpdateInterval = 60
+
 type (
 	// RequestBouncer represents an entity that manages API request accesses
 	RequestBouncer struct {
-		dataStore  portainer.DataStore
-		jwtService portainer.JWTService
+		dataStore        portainer.DataStore
+		jwtService       portainer.JWTService
+		rateLimitManager *RateLimitManager
+		proxyAuth        *proxyAuthState
 	}
 
 	// RestrictedRequestContext is a data structure containing information
@@ -28,12 +39,23 @@ type (
 	}
 )
 
-// NewRequestBouncer initializes a new RequestBouncer
-func NewRequestBouncer(dataStore portainer.DataStore, jwtService portainer.JWTService) *RequestBouncer {
+// NewRequestBouncer initializes a new RequestBouncer. The rate limit manager is used to resolve
+// the address of the clients behind trusted proxies, it can be nil.
+func NewRequestBouncer(dataStore portainer.DataStore, jwtService portainer.JWTService, rateLimitManager *RateLimitManager) *RequestBouncer {
 	return &RequestBouncer{
-		dataStore:  dataStore,
-		jwtService: jwtService,
+		dataStore:        dataStore,
+		jwtService:       jwtService,
+		rateLimitManager: rateLimitManager,
+		proxyAuth:        &proxyAuthState{},
+	}
+}
+
+// ClientAddress returns the address of the client of a request
+func (bouncer *RequestBouncer) ClientAddress(r *http.Request) string {
+	if bouncer.rateLimitManager != nil {
+		return bouncer.rateLimitManager.ClientAddress(r)
 	}
+	return remoteIP(r.RemoteAddr)
 }
 
 // PublicAccess defines a security check for public API endpoints.
@@ -220,12 +242,39 @@ func (bouncer *RequestBouncer) mwUpgradeToRestrictedRequest(next http.Handler) h
 
 // mwCheckAuthentication provides Authentication middleware for handlers
 //
-// It parses the JWT token and adds the parsed token data to the http context
+// It parses the JWT token or the API key, or trusts the identity headers of a trusted proxy when the proxy
+// authentication is enabled, or the verified client certificate of a request without JWT, rejects revoked
+// tokens and adds the token data to the http context
 func (bouncer *RequestBouncer) mwCheckAuthentication(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		var tokenData *portainer.TokenData
 		var token string
 
+		if rawAPIKey := r.Header.Get(portainer.APIKeyHeader); rawAPIKey != "" {
+			var handlerErr *httperror.HandlerError
+			tokenData, handlerErr = bouncer.apiKeyTokenData(rawAPIKey)
+			if handlerErr != nil {
+				httperror.WriteError(w, handlerErr.StatusCode, handlerErr.Message, handlerErr.Err)
+				return
+			}
+
+			ctx := storeTokenData(r, tokenData)
+			next.ServeHTTP(w, r.WithContext(ctx))
+			return
+		}
+
+		tokenData, handlerErr := bouncer.proxyAuthTokenData(r)
+		if handlerErr != nil {
+			httperror.WriteError(w, handlerErr.StatusCode, handlerErr.Message, handlerErr.Err)
+			return
+		}
+
+		if tokenData != nil {
+			ctx := storeTokenData(r, tokenData)
+			next.ServeHTTP(w, r.WithContext(ctx))
+			return
+		}
+
 		// Optionally, token might be set via the "token" query parameter.
 		// For example, in websocket requests
 		token = r.URL.Query().Get("token")
@@ -237,7 +286,20 @@ func (bouncer *RequestBouncer) mwCheckAuthentication(next http.Handler) http.Han
 			token = strings.TrimPrefix(token, "Bearer ")
 		}
 
+		// a verified client certificate authenticates the requests made without JWT
 		if token == "" {
+			tokenData, handlerErr = bouncer.clientCertificateTokenData(r)
+			if handlerErr != nil {
+				httperror.WriteError(w, handlerErr.StatusCode, handlerErr.Message, handlerErr.Err)
+				return
+			}
+
+			if tokenData != nil {
+				ctx := storeTokenData(r, tokenData)
+				next.ServeHTTP(w, r.WithContext(ctx))
+				return
+			}
+
 			httperror.WriteError(w, http.StatusUnauthorized, "Unauthorized", httperrors.ErrUnauthorized)
 			return
 		}
@@ -249,6 +311,17 @@ func (bouncer *RequestBouncer) mwCheckAuthentication(next http.Handler) http.Han
 			return
 		}
 
+		issuedToken, err := bouncer.dataStore.IssuedToken().IssuedToken(tokenData.TokenID)
+		if err != nil && err != bolterrors.ErrObjectNotFound {
+			httperror.WriteError(w, http.StatusInternalServerError, "Unable to retrieve token details from the database", err)
+			return
+		}
+
+		if err == bolterrors.ErrObjectNotFound || issuedToken.Revoked || issuedToken.UserID != tokenData.ID {
+			httperror.WriteError(w, http.StatusUnauthorized, "Revoked JWT token", httperrors.ErrUnauthorized)
+			return
+		}
+
 		_, err = bouncer.dataStore.User().User(tokenData.ID)
 		if err != nil && err == bolterrors.ErrObjectNotFound {
 			httperror.WriteError(w, http.StatusUnauthorized, "Unauthorized", httperrors.ErrUnauthorized)
@@ -258,12 +331,69 @@ func (bouncer *RequestBouncer) mwCheckAuthentication(next http.Handler) http.Han
 			return
 		}
 
+		bouncer.recordSessionActivity(issuedToken, r)
+
 		ctx := storeTokenData(r, tokenData)
 		next.ServeHTTP(w, r.WithContext(ctx))
 		return
 	})
 }
 
+// recordSessionActivity records the client and the date of the request on the session of the token.
+// The activity is only refreshed periodically, or when the client changes, to avoid a write on every request.
+func (bouncer *RequestBouncer) recordSessionActivity(token *portainer.IssuedToken, r *http.Request) {
+	now := time.Now().Unix()
+	clientIP := bouncer.ClientAddress(r)
+	userAgent := r.UserAgent()
+
+	if now-token.LastActivity < sessionActivityUpdateInterval && token.ClientIP == clientIP && token.UserAgent == userAgent {
+		return
+	}
+
+	err := bouncer.dataStore.IssuedToken().UpdateIssuedTokenActivity(token.ID, clientIP, userAgent, now)
+	if err != nil {
+		log.Printf("[WARN] [http,security] [message: unable to update session activity] [error: %s]", err)
+	}
+}
+
+// apiKeyTokenData authenticates a request using an API key. The token data is built from the current
+// state of the user owning the key and the last usage date of the key is recorded.
+func (bouncer *RequestBouncer) apiKeyTokenData(rawAPIKey string) (*portainer.TokenData, *httperror.HandlerError) {
+	key, err := bouncer.dataStore.APIKey().APIKeyByDigest(apikey.Digest(rawAPIKey))
+	if err == bolterrors.ErrObjectNotFound {
+		return nil, &httperror.HandlerError{StatusCode: http.StatusUnauthorized, Message: "Invalid API key", Err: httperrors.ErrUnauthorized}
+	} else if err != nil {
+		return nil, &httperror.HandlerError{StatusCode: http.StatusInternalServerError, Message: "Unable to retrieve API key details from the database", Err: err}
+	}
+
+	now := time.Now().Unix()
+	if key.ExpiryDate != 0 && key.ExpiryDate <= now {
+		return nil, &httperror.HandlerError{StatusCode: http.StatusUnauthorized, Message: "Expired API key", Err: httperrors.ErrUnauthorized}
+	}
+
+	user, err := bouncer.dataStore.User().User(key.UserID)
+	if err == bolterrors.ErrObjectNotFound {
+		return nil, &httperror.HandlerError{StatusCode: http.StatusUnauthorized, Message: "Unauthorized", Err: httperrors.ErrUnauthorized}
+	} else if err != nil {
+		return nil, &httperror.HandlerError{StatusCode: http.StatusInternalServerError, Message: "Unable to retrieve user details from the database", Err: err}
+	}
+
+	// the last usage date is only refreshed periodically to avoid a write on every request
+	if now-key.LastUsedDate >= apiKeyLastUsedUpdateInterval {
+		key.LastUsedDate = now
+		err = bouncer.dataStore.APIKey().UpdateAPIKey(key.ID, key)
+		if err != nil {
+			log.Printf("[WARN] [http,security] [message: unable to update API key last usage date] [error: %s]", err)
+		}
+	}
+
+	return &portainer.TokenData{
+		ID:       user.ID,
+		Username: user.Username,
+		Role:     user.Role,
+	}, nil
+}
+
 // mwSecureHeaders provides secure headers middleware for handlers.
 func mwSecureHeaders(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -0,0 +1,102 @@
+package security
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/apikey"
+	"github.com/portainer/portainer/api/bolt/bolttest"
+	"github.com/portainer/portainer/api/jwt"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_mwCheckAuthentication_withAPIKey(t *testing.T) {
+	store, teardown := bolttest.MustNewTestStore(true)
+	defer teardown()
+
+	user := &portainer.User{Username: "bob", Role: portainer.StandardUserRole}
+	store.User().CreateUser(user)
+
+	rawKey, prefix, digest, err := apikey.GenerateKey()
+	assert.NoError(t, err)
+	key := &portainer.APIKey{UserID: user.ID, Prefix: prefix, Digest: digest}
+	store.APIKey().CreateAPIKey(key)
+
+	expiredRawKey, _, expiredDigest, _ := apikey.GenerateKey()
+	store.APIKey().CreateAPIKey(&portainer.APIKey{UserID: user.ID, Digest: expiredDigest, ExpiryDate: time.Now().Add(-time.Hour).Unix()})
+
+	bouncer := NewRequestBouncer(store, nil, nil)
+
+	var tokenData *portainer.TokenData
+	handler := bouncer.mwCheckAuthentication(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tokenData, _ = RetrieveTokenData(r)
+	}))
+
+	tests := []struct {
+		name       string
+		rawKey     string
+		wantStatus int
+	}{
+		{"valid key", rawKey, http.StatusOK},
+		{"unknown key", "ptr_unknown", http.StatusUnauthorized},
+		{"expired key", expiredRawKey, http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set(portainer.APIKeyHeader, tt.rawKey)
+			rr := httptest.NewRecorder()
+
+			handler.ServeHTTP(rr, req)
+			assert.Equal(t, tt.wantStatus, rr.Code)
+		})
+	}
+
+	assert.Equal(t, user.ID, tokenData.ID)
+	assert.Equal(t, portainer.StandardUserRole, tokenData.Role)
+
+	key, _ = store.APIKey().APIKey(key.ID)
+	assert.NotZero(t, key.LastUsedDate, "the last usage date should be recorded")
+}
+
+func Test_mwCheckAuthentication_shouldRecordSessionActivity(t *testing.T) {
+	store, teardown := bolttest.MustNewTestStore(true)
+	defer teardown()
+
+	jwtService, err := jwt.NewService("24h", store, []byte("secret"))
+	assert.NoError(t, err)
+
+	user := &portainer.User{Username: "bob", Role: portainer.StandardUserRole}
+	store.User().CreateUser(user)
+
+	tokenData := &portainer.TokenData{ID: user.ID, Username: user.Username, Role: user.Role}
+	token, err := jwtService.GenerateToken(tokenData)
+	assert.NoError(t, err)
+
+	bouncer := NewRequestBouncer(store, jwtService, nil)
+	handler := bouncer.mwCheckAuthentication(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("User-Agent", "portainer-test")
+	rr := httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	session, _ := store.IssuedToken().IssuedToken(tokenData.TokenID)
+	assert.Equal(t, "192.0.2.1", session.ClientIP)
+	assert.Equal(t, "portainer-test", session.UserAgent)
+	assert.NotZero(t, session.LastActivity)
+
+	err = jwtService.RevokeToken(tokenData.TokenID)
+	assert.NoError(t, err)
+
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusUnauthorized, rr.Code, "a revoked session should be rejected")
+}
@@ -0,0 +1,146 @@
+package security
+
+import (
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	httperror "github.com/portainer/libhttp/error"
+	portainer "github.com/portainer/portainer/api"
+	bolterrors "github.com/portainer/portainer/api/bolt/errors"
+	httperrors "github.com/portainer/portainer/api/http/errors"
+	"github.com/portainer/portainer/api/twofactor"
+)
+
+// Fields of a client certificate that can identify the client
+const (
+	clientCertificateCommonName = "cn"
+	clientCertificateEmail      = "email"
+	clientCertificateDNS        = "dns"
+	clientCertificateURI        = "uri"
+)
+
+// ValidateClientCertificateAuthSettings ensures that the settings can be used to map client certificates to users
+func ValidateClientCertificateAuthSettings(settings portainer.ClientCertificateAuthSettings) error {
+	switch settings.IdentityField {
+	case "", clientCertificateCommonName, clientCertificateEmail, clientCertificateDNS, clientCertificateURI:
+	default:
+		return errors.New("Invalid client certificate identity field. Value must be one of: cn, email, dns or uri")
+	}
+
+	identities := make(map[string]bool)
+	for _, mapping := range settings.Mappings {
+		if strings.TrimSpace(mapping.Identity) == "" || strings.TrimSpace(mapping.Username) == "" {
+			return errors.New("Invalid client certificate mapping. Identity and username are required")
+		}
+		if identities[mapping.Identity] {
+			return fmt.Errorf("Duplicate client certificate mapping for identity %s", mapping.Identity)
+		}
+		identities[mapping.Identity] = true
+	}
+
+	return nil
+}
+
+// clientCertificateTokenData authenticates a request using the client certificate verified during the TLS handshake.
+// No token data is returned when the request has no verified certificate. The user is the one mapped to the identity
+// of the certificate, or the one named after it when enabled. As a certificate cannot provide a second factor, the
+// users who must use two-factor authentication are rejected, as well as the locked accounts and the requests that
+// change the state of the server and may have been forged by another site, since browsers send certificates on their own.
+func (bouncer *RequestBouncer) clientCertificateTokenData(r *http.Request) (*portainer.TokenData, *httperror.HandlerError) {
+	if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 || len(r.TLS.VerifiedChains[0]) == 0 {
+		return nil, nil
+	}
+
+	settings, err := bouncer.dataStore.Settings().Settings()
+	if err != nil {
+		return nil, &httperror.HandlerError{StatusCode: http.StatusInternalServerError, Message: "Unable to retrieve settings from the database", Err: err}
+	}
+	certificateSettings := settings.ClientCertificateAuth
+
+	identity := clientCertificateIdentity(r.TLS.VerifiedChains[0][0], certificateSettings.IdentityField)
+	if identity == "" {
+		return nil, &httperror.HandlerError{StatusCode: http.StatusUnauthorized, Message: "Unable to identify the client certificate", Err: httperrors.ErrUnauthorized}
+	}
+
+	username := ""
+	if certificateSettings.IdentityAsUsername {
+		username = identity
+	}
+	for _, mapping := range certificateSettings.Mappings {
+		if mapping.Identity == identity {
+			username = mapping.Username
+			break
+		}
+	}
+
+	if username == "" {
+		return nil, &httperror.HandlerError{StatusCode: http.StatusUnauthorized, Message: "No user is mapped to the client certificate", Err: httperrors.ErrUnauthorized}
+	}
+
+	err = checkCrossSiteRequest(r)
+	if err != nil {
+		return nil, &httperror.HandlerError{StatusCode: http.StatusForbidden, Message: "Cross-site request rejected", Err: err}
+	}
+
+	user, err := bouncer.dataStore.User().UserByUsername(username)
+	if err == bolterrors.ErrObjectNotFound {
+		if !certificateSettings.AutoCreateUsers {
+			return nil, &httperror.HandlerError{StatusCode: http.StatusUnauthorized, Message: "No user matches the client certificate", Err: httperrors.ErrUnauthorized}
+		}
+
+		user = &portainer.User{
+			Username: username,
+			Role:     portainer.StandardUserRole,
+		}
+
+		err = bouncer.dataStore.User().CreateUser(user)
+		if err != nil {
+			return nil, &httperror.HandlerError{StatusCode: http.StatusInternalServerError, Message: "Unable to persist user inside the database", Err: err}
+		}
+
+		log.Printf("[INFO] [http,security] [audit] [message: user created from client certificate] [user: %s] [identity: %s]", user.Username, identity)
+	} else if err != nil {
+		return nil, &httperror.HandlerError{StatusCode: http.StatusInternalServerError, Message: "Unable to retrieve user details from the database", Err: err}
+	}
+
+	if AccountLocked(user, time.Now()) {
+		return nil, &httperror.HandlerError{StatusCode: http.StatusUnauthorized, Message: "Unauthorized", Err: httperrors.ErrUnauthorized}
+	}
+
+	if user.TOTP.Enabled || twofactor.Required(user, settings.TwoFactorRequirement) {
+		return nil, &httperror.HandlerError{StatusCode: http.StatusUnauthorized, Message: "Two-factor authentication is required, the client certificate cannot be used", Err: httperrors.ErrUnauthorized}
+	}
+
+	return &portainer.TokenData{
+		ID:       user.ID,
+		Username: user.Username,
+		Role:     user.Role,
+	}, nil
+}
+
+// clientCertificateIdentity returns the value of the field identifying the client, or an empty string
+// when the certificate does not have this field
+func clientCertificateIdentity(certificate *x509.Certificate, field string) string {
+	switch field {
+	case clientCertificateEmail:
+		if len(certificate.EmailAddresses) > 0 {
+			return certificate.EmailAddresses[0]
+		}
+	case clientCertificateDNS:
+		if len(certificate.DNSNames) > 0 {
+			return certificate.DNSNames[0]
+		}
+	case clientCertificateURI:
+		if len(certificate.URIs) > 0 {
+			return certificate.URIs[0].String()
+		}
+	default:
+		return certificate.Subject.CommonName
+	}
+	return ""
+}
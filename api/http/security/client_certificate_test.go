@@ -0,0 +1,119 @@
+package security
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/bolt/bolttest"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_mwCheckAuthentication_withClientCertificate(t *testing.T) {
+	store, teardown := bolttest.MustNewTestStore(true)
+	defer teardown()
+
+	settings, _ := store.Settings().Settings()
+	settings.ClientCertificateAuth = portainer.ClientCertificateAuthSettings{
+		IdentityField: "dns",
+		Mappings:      []portainer.ClientCertificateMapping{{Identity: "runner.example.com", Username: "ci"}},
+	}
+	store.Settings().UpdateSettings(settings)
+
+	user := &portainer.User{Username: "ci", Role: portainer.StandardUserRole}
+	store.User().CreateUser(user)
+
+	bouncer := NewRequestBouncer(store, nil, nil)
+
+	var tokenData *portainer.TokenData
+	handler := bouncer.mwCheckAuthentication(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tokenData, _ = RetrieveTokenData(r)
+	}))
+
+	tests := []struct {
+		name        string
+		certificate *x509.Certificate
+		wantStatus  int
+	}{
+		{"mapped identity", &x509.Certificate{Subject: pkix.Name{CommonName: "runner"}, DNSNames: []string{"runner.example.com"}}, http.StatusOK},
+		{"unknown identity", &x509.Certificate{DNSNames: []string{"other.example.com"}}, http.StatusUnauthorized},
+		{"missing identity field", &x509.Certificate{Subject: pkix.Name{CommonName: "runner"}}, http.StatusUnauthorized},
+		{"no certificate", nil, http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.certificate != nil {
+				req.TLS = &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{tt.certificate}}}
+			}
+			rr := httptest.NewRecorder()
+
+			handler.ServeHTTP(rr, req)
+			assert.Equal(t, tt.wantStatus, rr.Code)
+		})
+	}
+
+	assert.Equal(t, user.ID, tokenData.ID)
+}
+
+func Test_mwCheckAuthentication_withClientCertificate_shouldEnforceTheAccountState(t *testing.T) {
+	store, teardown := bolttest.MustNewTestStore(true)
+	defer teardown()
+
+	settings, _ := store.Settings().Settings()
+	settings.TwoFactorRequirement = 0
+	store.Settings().UpdateSettings(settings)
+
+	store.User().CreateUser(&portainer.User{Username: "admin", Role: portainer.AdministratorRole})
+	store.User().CreateUser(&portainer.User{Username: "locked", Role: portainer.StandardUserRole, LockedUntil: time.Now().Add(time.Hour).Unix()})
+	store.User().CreateUser(&portainer.User{Username: "totp", Role: portainer.StandardUserRole, TOTP: portainer.TOTPSettings{Enabled: true}})
+
+	bouncer := NewRequestBouncer(store, nil, nil)
+	handler := bouncer.mwCheckAuthentication(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	serve := func(method, commonName string, headers map[string]string) int {
+		req := httptest.NewRequest(method, "https://portainer.example.com/api/stacks", nil)
+		req.TLS = &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{{Subject: pkix.Name{CommonName: commonName}}}}}
+		for key, value := range headers {
+			req.Header.Set(key, value)
+		}
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr.Code
+	}
+
+	assert.Equal(t, http.StatusUnauthorized, serve(http.MethodGet, "admin", nil), "an identity without mapping should be rejected by default")
+
+	settings.ClientCertificateAuth.IdentityAsUsername = true
+	store.Settings().UpdateSettings(settings)
+
+	assert.Equal(t, http.StatusOK, serve(http.MethodGet, "admin", nil))
+	assert.Equal(t, http.StatusUnauthorized, serve(http.MethodGet, "locked", nil))
+	assert.Equal(t, http.StatusUnauthorized, serve(http.MethodGet, "totp", nil))
+
+	assert.Equal(t, http.StatusForbidden, serve(http.MethodPost, "admin", nil))
+	assert.Equal(t, http.StatusOK, serve(http.MethodPost, "admin", map[string]string{"Origin": "https://portainer.example.com"}))
+	assert.Equal(t, http.StatusOK, serve(http.MethodPost, "admin", map[string]string{portainer.CSRFProtectionHeader: "1"}))
+
+	settings.TwoFactorRequirement = portainer.TwoFactorRequiredForAdministrators
+	store.Settings().UpdateSettings(settings)
+
+	assert.Equal(t, http.StatusUnauthorized, serve(http.MethodGet, "admin", nil), "a certificate cannot replace a required second factor")
+}
+
+func TestValidateClientCertificateAuthSettings(t *testing.T) {
+	assert.NoError(t, ValidateClientCertificateAuthSettings(portainer.ClientCertificateAuthSettings{}))
+	assert.NoError(t, ValidateClientCertificateAuthSettings(portainer.ClientCertificateAuthSettings{IdentityField: "email"}))
+	assert.Error(t, ValidateClientCertificateAuthSettings(portainer.ClientCertificateAuthSettings{IdentityField: "serial"}))
+	assert.Error(t, ValidateClientCertificateAuthSettings(portainer.ClientCertificateAuthSettings{Mappings: []portainer.ClientCertificateMapping{{Identity: "runner"}}}))
+	assert.Error(t, ValidateClientCertificateAuthSettings(portainer.ClientCertificateAuthSettings{Mappings: []portainer.ClientCertificateMapping{
+		{Identity: "runner", Username: "ci"},
+		{Identity: "runner", Username: "admin"},
+	}}))
+}
@@ -6,6 +6,7 @@ import (
 	"net/http"
 
 	"github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/internal/audit"
 )
 
 type (
@@ -18,7 +19,9 @@ const (
 )
 
 // storeTokenData stores a TokenData object inside the request context and returns the enhanced context.
+// The user is also recorded in the audit logs of the request.
 func storeTokenData(request *http.Request, tokenData *portainer.TokenData) context.Context {
+	audit.SetTokenData(request, tokenData)
 	return context.WithValue(request.Context(), contextAuthenticationKey, tokenData)
 }
 
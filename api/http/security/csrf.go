@@ -0,0 +1,35 @@
+package security
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+
+	portainer "github.com/portainer/portainer/api"
+)
+
+// checkCrossSiteRequest protects the requests authenticated by credentials that a browser sends on its own from
+// cross-site request forgery. The requests that change the state of the server must come from the same origin,
+// or carry the CSRF protection header, which a browser cannot add to a cross-origin request without a CORS preflight.
+func checkCrossSiteRequest(r *http.Request) error {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return nil
+	}
+
+	if r.Header.Get(portainer.CSRFProtectionHeader) != "" {
+		return nil
+	}
+
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return errCrossSiteRequest
+	}
+
+	originURL, err := url.Parse(origin)
+	if err != nil || !strings.EqualFold(originURL.Host, r.Host) {
+		return errCrossSiteRequest
+	}
+
+	return nil
+}
@@ -4,4 +4,5 @@ import "errors"
 
 var (
 	ErrAuthorizationRequired = errors.New("Authorization required for this operation")
+	errCrossSiteRequest      = errors.New("Cross-site request rejected, the origin of the request is unknown")
 )
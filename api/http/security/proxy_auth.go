@@ -0,0 +1,248 @@
+package security
+
+import (
+	"errors"
+	"log"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	httperror "github.com/portainer/libhttp/error"
+	portainer "github.com/portainer/portainer/api"
+	bolterrors "github.com/portainer/portainer/api/bolt/errors"
+	httperrors "github.com/portainer/portainer/api/http/errors"
+	"github.com/portainer/portainer/api/internal/provisioning"
+)
+
+// ValidateProxyAuthSettings ensures that the settings can be used to authenticate the requests of trusted proxies
+func ValidateProxyAuthSettings(settings portainer.ProxyAuthSettings) error {
+	if strings.TrimSpace(settings.UserHeader) == "" {
+		return errors.New("Invalid proxy authentication user header")
+	}
+
+	if len(settings.TrustedProxies) == 0 {
+		return errors.New("At least one trusted proxy is required by the proxy authentication")
+	}
+
+	_, err := parseIPNets(settings.TrustedProxies)
+	if err != nil {
+		return errors.New("Invalid proxy authentication trusted proxies. Values must be IP addresses or CIDR ranges")
+	}
+
+	return nil
+}
+
+// proxyAuthState caches the proxy authentication settings, so that they are not read on every request, and the
+// sessions of the users authenticated by the proxy. A user is only provisioned when a session is created.
+type proxyAuthState struct {
+	mu              sync.Mutex
+	loaded          bool
+	enabled         bool
+	settings        portainer.ProxyAuthSettings
+	trustedProxies  []*net.IPNet
+	sessionDuration time.Duration
+	sessions        map[string]proxyAuthSession
+}
+
+// proxyAuthSession represents a user provisioned with the groups sent by the proxy
+type proxyAuthSession struct {
+	userID    portainer.UserID
+	groups    string
+	expiresAt time.Time
+}
+
+// UpdateProxyAuthSettings replaces the cached proxy authentication settings with the ones of the settings. The current
+// sessions are ended, so that the users are provisioned again with the new settings.
+func (bouncer *RequestBouncer) UpdateProxyAuthSettings(settings *portainer.Settings) error {
+	trustedProxies, err := parseIPNets(settings.ProxyAuthSettings.TrustedProxies)
+	if err != nil {
+		return err
+	}
+
+	sessionDuration, err := time.ParseDuration(settings.UserSessionTimeout)
+	if err != nil {
+		sessionDuration, _ = time.ParseDuration(portainer.DefaultUserSessionTimeout)
+	}
+
+	state := bouncer.proxyAuth
+	state.mu.Lock()
+	defer state.mu.Unlock()
+
+	state.loaded = true
+	state.enabled = settings.AuthenticationMethod == portainer.AuthenticationProxy
+	state.settings = settings.ProxyAuthSettings
+	state.trustedProxies = trustedProxies
+	state.sessionDuration = sessionDuration
+	state.sessions = make(map[string]proxyAuthSession)
+
+	return nil
+}
+
+// proxyAuthTokenData authenticates a request using the identity headers set by a trusted proxy. No token data
+// is returned when the authentication method is not enabled, or when the request has no identity, in which case
+// the other credentials of the request are used. The identity headers of a client that is not a trusted proxy are
+// rejected, as well as the requests that change the state of the server and may have been forged by another site.
+// The users are provisioned the same way as the LDAP users when their session is created: unknown users are
+// created when enabled, and the team memberships and the role are updated from the groups of the user.
+func (bouncer *RequestBouncer) proxyAuthTokenData(r *http.Request) (*portainer.TokenData, *httperror.HandlerError) {
+	state, err := bouncer.loadProxyAuthState()
+	if err != nil {
+		return nil, &httperror.HandlerError{StatusCode: http.StatusInternalServerError, Message: "Unable to retrieve settings from the database", Err: err}
+	}
+
+	state.mu.Lock()
+	enabled, proxySettings, trustedProxies := state.enabled, state.settings, state.trustedProxies
+	state.mu.Unlock()
+
+	if !enabled {
+		return nil, nil
+	}
+
+	username := strings.TrimSpace(r.Header.Get(proxySettings.UserHeader))
+	if username == "" {
+		return nil, nil
+	}
+
+	// the headers are only trusted when set by the proxy itself, X-Forwarded-For is ignored
+	if !containsIP(trustedProxies, remoteIP(r.RemoteAddr)) {
+		log.Printf("[WARN] [http,security] [message: proxy authentication headers received from an untrusted client] [client: %s]", r.RemoteAddr)
+		return nil, &httperror.HandlerError{StatusCode: http.StatusUnauthorized, Message: "Unauthorized", Err: httperrors.ErrUnauthorized}
+	}
+
+	err = checkCrossSiteRequest(r)
+	if err != nil {
+		return nil, &httperror.HandlerError{StatusCode: http.StatusForbidden, Message: "Cross-site request rejected", Err: err}
+	}
+
+	groupsHeader := ""
+	if proxySettings.GroupsHeader != "" {
+		groupsHeader = r.Header.Get(proxySettings.GroupsHeader)
+	}
+
+	if userID, ok := state.session(username, groupsHeader); ok {
+		user, err := bouncer.dataStore.User().User(userID)
+		if err == nil {
+			return &portainer.TokenData{ID: user.ID, Username: user.Username, Role: user.Role}, nil
+		} else if err != bolterrors.ErrObjectNotFound {
+			return nil, &httperror.HandlerError{StatusCode: http.StatusInternalServerError, Message: "Unable to retrieve user details from the database", Err: err}
+		}
+	}
+
+	user, handlerErr := bouncer.provisionProxyAuthUser(username, groupsHeader, proxySettings)
+	if handlerErr != nil {
+		return nil, handlerErr
+	}
+
+	state.createSession(username, groupsHeader, user.ID)
+
+	return &portainer.TokenData{
+		ID:       user.ID,
+		Username: user.Username,
+		Role:     user.Role,
+	}, nil
+}
+
+// session returns the user of the current session of a username, as long as the proxy sends the same groups
+func (state *proxyAuthState) session(username, groups string) (portainer.UserID, bool) {
+	state.mu.Lock()
+	defer state.mu.Unlock()
+
+	session, ok := state.sessions[strings.ToLower(username)]
+	if !ok || session.groups != groups || time.Now().After(session.expiresAt) {
+		return 0, false
+	}
+
+	return session.userID, true
+}
+
+// createSession starts a session for a provisioned user and removes the expired ones
+func (state *proxyAuthState) createSession(username, groups string, userID portainer.UserID) {
+	state.mu.Lock()
+	defer state.mu.Unlock()
+
+	now := time.Now()
+	for key, session := range state.sessions {
+		if now.After(session.expiresAt) {
+			delete(state.sessions, key)
+		}
+	}
+
+	state.sessions[strings.ToLower(username)] = proxyAuthSession{userID: userID, groups: groups, expiresAt: now.Add(state.sessionDuration)}
+}
+
+// loadProxyAuthState returns the proxy authentication state, the settings are read from the database on first use
+func (bouncer *RequestBouncer) loadProxyAuthState() (*proxyAuthState, error) {
+	state := bouncer.proxyAuth
+
+	state.mu.Lock()
+	loaded := state.loaded
+	state.mu.Unlock()
+
+	if loaded {
+		return state, nil
+	}
+
+	settings, err := bouncer.dataStore.Settings().Settings()
+	if err != nil {
+		return nil, err
+	}
+
+	return state, bouncer.UpdateProxyAuthSettings(settings)
+}
+
+// provisionProxyAuthUser returns the user authenticated by the proxy, created when unknown and enabled, after
+// updating its team memberships and role from its groups
+func (bouncer *RequestBouncer) provisionProxyAuthUser(username, groupsHeader string, proxySettings portainer.ProxyAuthSettings) (*portainer.User, *httperror.HandlerError) {
+	groups := proxyAuthGroups(groupsHeader)
+
+	user, err := bouncer.dataStore.User().UserByUsername(username)
+	if err == bolterrors.ErrObjectNotFound {
+		if !proxySettings.AutoCreateUsers {
+			return nil, &httperror.HandlerError{StatusCode: http.StatusUnauthorized, Message: "Unauthorized", Err: httperrors.ErrUnauthorized}
+		}
+
+		role, _ := provisioning.UserRoleFromGroups(groups, proxySettings.AdminGroups)
+		user = &portainer.User{
+			Username:             username,
+			Role:                 role,
+			AuthenticationMethod: portainer.AuthenticationProxy,
+		}
+
+		err = bouncer.dataStore.User().CreateUser(user)
+		if err != nil {
+			return nil, &httperror.HandlerError{StatusCode: http.StatusInternalServerError, Message: "Unable to persist user inside the database", Err: err}
+		}
+
+		log.Printf("[INFO] [http,security] [audit] [message: user created from proxy authentication] [user: %s]", user.Username)
+	} else if err != nil {
+		return nil, &httperror.HandlerError{StatusCode: http.StatusInternalServerError, Message: "Unable to retrieve user details from the database", Err: err}
+	}
+
+	if proxySettings.GroupsHeader != "" {
+		err = provisioning.AddUserIntoTeams(bouncer.dataStore, user, groups)
+		if err != nil {
+			log.Printf("[WARN] [http,security] [message: unable to automatically add user into teams] [user: %s] [error: %s]", user.Username, err)
+		}
+
+		err = provisioning.UpdateUserRoleFromGroups(bouncer.dataStore, bouncer.jwtService, user, groups, proxySettings.AdminGroups, "proxy")
+		if err != nil {
+			log.Printf("[WARN] [http,security] [message: unable to update user role from groups] [user: %s] [error: %s]", user.Username, err)
+		}
+	}
+
+	return user, nil
+}
+
+// proxyAuthGroups splits the comma separated groups of a header
+func proxyAuthGroups(header string) []string {
+	groups := make([]string, 0)
+	for _, group := range strings.Split(header, ",") {
+		group = strings.TrimSpace(group)
+		if group != "" {
+			groups = append(groups, group)
+		}
+	}
+	return groups
+}
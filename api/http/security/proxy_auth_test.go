@@ -0,0 +1,133 @@
+package security
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/bolt/bolttest"
+	"github.com/portainer/portainer/api/jwt"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_mwCheckAuthentication_withProxyAuthentication(t *testing.T) {
+	store, teardown := bolttest.MustNewTestStore(true)
+	defer teardown()
+
+	jwtService, err := jwt.NewService("24h", store, []byte("secret"))
+	assert.NoError(t, err)
+
+	settings, _ := store.Settings().Settings()
+	settings.AuthenticationMethod = portainer.AuthenticationProxy
+	settings.ProxyAuthSettings.TrustedProxies = []string{"10.0.0.0/8"}
+	settings.ProxyAuthSettings.AutoCreateUsers = true
+	settings.ProxyAuthSettings.AdminGroups = []string{"admins"}
+	store.Settings().UpdateSettings(settings)
+
+	team := &portainer.Team{Name: "devs"}
+	store.Team().CreateTeam(team)
+
+	bouncer := NewRequestBouncer(store, jwtService, nil)
+
+	var tokenData *portainer.TokenData
+	handler := bouncer.mwCheckAuthentication(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tokenData, _ = RetrieveTokenData(r)
+	}))
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		username   string
+		groups     string
+		wantStatus int
+	}{
+		{"trusted proxy", "10.1.2.3:4567", "alice", "Devs, admins", http.StatusOK},
+		{"untrusted client", "192.0.2.1:4567", "mallory", "admins", http.StatusUnauthorized},
+		{"trusted proxy without identity", "10.1.2.3:4567", "", "", http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.RemoteAddr = tt.remoteAddr
+			req.Header.Set(portainer.DefaultProxyAuthUserHeader, tt.username)
+			req.Header.Set(portainer.DefaultProxyAuthGroupsHeader, tt.groups)
+			rr := httptest.NewRecorder()
+
+			handler.ServeHTTP(rr, req)
+			assert.Equal(t, tt.wantStatus, rr.Code)
+		})
+	}
+
+	assert.Equal(t, "alice", tokenData.Username)
+	assert.Equal(t, portainer.AdministratorRole, tokenData.Role)
+
+	memberships, _ := store.TeamMembership().TeamMembershipsByUserID(tokenData.ID)
+	assert.Len(t, memberships, 1)
+	assert.Equal(t, team.ID, memberships[0].TeamID)
+
+	_, err = store.User().UserByUsername("mallory")
+	assert.Error(t, err, "the headers of an untrusted client should be ignored")
+}
+
+func Test_mwCheckAuthentication_withProxyAuthentication_shouldProvisionOncePerSession(t *testing.T) {
+	store, teardown := bolttest.MustNewTestStore(true)
+	defer teardown()
+
+	jwtService, err := jwt.NewService("24h", store, []byte("secret"))
+	assert.NoError(t, err)
+
+	settings, _ := store.Settings().Settings()
+	settings.AuthenticationMethod = portainer.AuthenticationProxy
+	settings.ProxyAuthSettings.TrustedProxies = []string{"10.0.0.1"}
+	settings.ProxyAuthSettings.AutoCreateUsers = true
+	store.Settings().UpdateSettings(settings)
+
+	team := &portainer.Team{Name: "devs"}
+	store.Team().CreateTeam(team)
+
+	bouncer := NewRequestBouncer(store, jwtService, nil)
+	handler := bouncer.mwCheckAuthentication(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	serve := func(method, groups string, headers map[string]string) int {
+		req := httptest.NewRequest(method, "http://portainer.example.com/api/stacks", nil)
+		req.RemoteAddr = "10.0.0.1:4567"
+		req.Header.Set(portainer.DefaultProxyAuthUserHeader, "alice")
+		req.Header.Set(portainer.DefaultProxyAuthGroupsHeader, groups)
+		for key, value := range headers {
+			req.Header.Set(key, value)
+		}
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr.Code
+	}
+
+	assert.Equal(t, http.StatusOK, serve(http.MethodGet, "devs", nil))
+	alice, err := store.User().UserByUsername("alice")
+	assert.NoError(t, err)
+
+	memberships, _ := store.TeamMembership().TeamMembershipsByUserID(alice.ID)
+	assert.Len(t, memberships, 1)
+	store.TeamMembership().DeleteTeamMembership(memberships[0].ID)
+
+	assert.Equal(t, http.StatusOK, serve(http.MethodGet, "devs", nil))
+	memberships, _ = store.TeamMembership().TeamMembershipsByUserID(alice.ID)
+	assert.Empty(t, memberships, "the user should not be provisioned again during the session")
+
+	assert.Equal(t, http.StatusOK, serve(http.MethodGet, "devs, ops", nil))
+	memberships, _ = store.TeamMembership().TeamMembershipsByUserID(alice.ID)
+	assert.Len(t, memberships, 1, "the user should be provisioned again when the groups change")
+
+	assert.Equal(t, http.StatusForbidden, serve(http.MethodPost, "devs, ops", nil))
+	assert.Equal(t, http.StatusForbidden, serve(http.MethodPost, "devs, ops", map[string]string{"Origin": "https://attacker.example.com"}))
+	assert.Equal(t, http.StatusOK, serve(http.MethodPost, "devs, ops", map[string]string{"Origin": "http://portainer.example.com"}))
+	assert.Equal(t, http.StatusOK, serve(http.MethodPost, "devs, ops", map[string]string{portainer.CSRFProtectionHeader: "1"}))
+}
+
+func TestValidateProxyAuthSettings(t *testing.T) {
+	assert.NoError(t, ValidateProxyAuthSettings(portainer.ProxyAuthSettings{UserHeader: "X-Forwarded-User", TrustedProxies: []string{"10.0.0.1", "172.16.0.0/12"}}))
+	assert.Error(t, ValidateProxyAuthSettings(portainer.ProxyAuthSettings{UserHeader: "X-Forwarded-User"}))
+	assert.Error(t, ValidateProxyAuthSettings(portainer.ProxyAuthSettings{UserHeader: "X-Forwarded-User", TrustedProxies: []string{"proxy"}}))
+	assert.Error(t, ValidateProxyAuthSettings(portainer.ProxyAuthSettings{TrustedProxies: []string{"10.0.0.1"}}))
+}
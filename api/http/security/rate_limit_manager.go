@@ -0,0 +1,271 @@
+package security
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	portainer "github.com/portainer/portainer/api"
+)
+
+// Classes of routes sharing a rate limiting policy
+const (
+	// RateLimitAuthentication is the class of the authentication routes
+	RateLimitAuthentication = "authentication"
+	// RateLimitWebhooks is the class of the public webhook execution route
+	RateLimitWebhooks = "webhooks"
+	// RateLimitEdgeCheckin is the class of the routes used by the Edge agents to check in
+	RateLimitEdgeCheckin = "edge_checkin"
+	// RateLimitAPI is the class of every API request
+	RateLimitAPI = "api"
+)
+
+// RateLimitBan represents an address banned by the rate limiter of a class of routes
+type RateLimitBan struct {
+	// Class of routes the address is banned from
+	Class string `json:"Class" example:"authentication"`
+	// Banned address
+	Address string `json:"Address" example:"203.0.113.10"`
+	// Unix timestamp of the end of the ban
+	ExpiresAt int64 `json:"ExpiresAt" example:"1587399600"`
+}
+
+// RateLimitManager holds the rate limiters of each class of routes, as well as the trusted proxies and the
+// allowlist they share. The policies can be updated at runtime, the current bans are kept.
+type RateLimitManager struct {
+	mu             sync.RWMutex
+	limiters       map[string]*RateLimiter
+	trustedProxies []*net.IPNet
+	allowlist      []*net.IPNet
+}
+
+// NewRateLimitManager creates the rate limiters of each class of routes according to the settings
+func NewRateLimitManager(settings portainer.RateLimitSettings) (*RateLimitManager, error) {
+	manager := &RateLimitManager{
+		limiters: make(map[string]*RateLimiter),
+	}
+
+	for _, class := range []string{RateLimitAuthentication, RateLimitWebhooks, RateLimitEdgeCheckin, RateLimitAPI} {
+		manager.limiters[class] = newRateLimiter(manager)
+	}
+
+	err := manager.Update(settings)
+	if err != nil {
+		return nil, err
+	}
+
+	return manager, nil
+}
+
+// ValidateRateLimitSettings ensures that the settings can be used to configure the rate limiters
+func ValidateRateLimitSettings(settings portainer.RateLimitSettings) error {
+	_, err := parseRateLimitSettings(settings)
+	return err
+}
+
+// Limiter returns the rate limiter of a class of routes
+func (manager *RateLimitManager) Limiter(class string) *RateLimiter {
+	return manager.limiters[class]
+}
+
+// Update replaces the policies, the trusted proxies and the allowlist with the ones of the settings
+func (manager *RateLimitManager) Update(settings portainer.RateLimitSettings) error {
+	parsed, err := parseRateLimitSettings(settings)
+	if err != nil {
+		return err
+	}
+
+	for class, policy := range parsed.policies {
+		manager.limiters[class].setPolicy(policy.maxRequests, policy.period, policy.banDuration)
+	}
+
+	manager.mu.Lock()
+	defer manager.mu.Unlock()
+
+	manager.trustedProxies = parsed.trustedProxies
+	manager.allowlist = parsed.allowlist
+
+	return nil
+}
+
+// Bans returns the addresses currently banned, sorted by class and address
+func (manager *RateLimitManager) Bans() []RateLimitBan {
+	bans := make([]RateLimitBan, 0)
+	for class, limiter := range manager.limiters {
+		for address, expiresAt := range limiter.bans() {
+			bans = append(bans, RateLimitBan{Class: class, Address: address, ExpiresAt: expiresAt.Unix()})
+		}
+	}
+
+	sort.Slice(bans, func(i, j int) bool {
+		if bans[i].Class != bans[j].Class {
+			return bans[i].Class < bans[j].Class
+		}
+		return bans[i].Address < bans[j].Address
+	})
+
+	return bans
+}
+
+// Unban removes the ban of an address from the rate limiter of a class, or from every rate limiter
+// when class is empty. It returns true if the address was banned.
+func (manager *RateLimitManager) Unban(address, class string) bool {
+	if ip := net.ParseIP(address); ip != nil {
+		address = ip.String()
+	}
+
+	unbanned := false
+	for limiterClass, limiter := range manager.limiters {
+		if class != "" && class != limiterClass {
+			continue
+		}
+
+		if limiter.unban(address) {
+			unbanned = true
+		}
+	}
+	return unbanned
+}
+
+// ClientAddress returns the address of the client of a request. The X-Forwarded-For header is only used when the
+// request comes from a trusted proxy, the header is read from the right to skip the other trusted proxies.
+func (manager *RateLimitManager) ClientAddress(r *http.Request) string {
+	address := remoteIP(r.RemoteAddr)
+
+	manager.mu.RLock()
+	defer manager.mu.RUnlock()
+
+	if !containsIP(manager.trustedProxies, address) {
+		return address
+	}
+
+	forwardedFor := strings.Split(strings.Join(r.Header["X-Forwarded-For"], ","), ",")
+	for idx := len(forwardedFor) - 1; idx >= 0; idx-- {
+		ip := net.ParseIP(strings.TrimSpace(forwardedFor[idx]))
+		if ip == nil {
+			break
+		}
+
+		address = ip.String()
+		if !containsIP(manager.trustedProxies, address) {
+			break
+		}
+	}
+
+	return address
+}
+
+func (manager *RateLimitManager) allowed(address string) bool {
+	manager.mu.RLock()
+	defer manager.mu.RUnlock()
+
+	return containsIP(manager.allowlist, address)
+}
+
+type rateLimitPolicy struct {
+	maxRequests int
+	period      time.Duration
+	banDuration time.Duration
+}
+
+type parsedRateLimitSettings struct {
+	policies       map[string]rateLimitPolicy
+	trustedProxies []*net.IPNet
+	allowlist      []*net.IPNet
+}
+
+func parseRateLimitSettings(settings portainer.RateLimitSettings) (*parsedRateLimitSettings, error) {
+	parsed := &parsedRateLimitSettings{
+		policies: make(map[string]rateLimitPolicy),
+	}
+
+	policies := map[string]portainer.RateLimitPolicy{
+		RateLimitAuthentication: settings.Authentication,
+		RateLimitWebhooks:       settings.Webhooks,
+		RateLimitEdgeCheckin:    settings.EdgeCheckin,
+		RateLimitAPI:            settings.API,
+	}
+
+	for class, policy := range policies {
+		if policy.MaxRequests < 0 {
+			return nil, fmt.Errorf("Invalid %s rate limit. The maximum number of requests must be a positive number, 0 disables the rate limiting", class)
+		}
+		if policy.MaxRequests == 0 {
+			parsed.policies[class] = rateLimitPolicy{}
+			continue
+		}
+
+		period, err := time.ParseDuration(policy.Period)
+		if err != nil || period <= 0 {
+			return nil, fmt.Errorf("Invalid %s rate limit period", class)
+		}
+
+		banDuration, err := time.ParseDuration(policy.BanDuration)
+		if err != nil || banDuration <= 0 {
+			return nil, fmt.Errorf("Invalid %s rate limit ban duration", class)
+		}
+
+		parsed.policies[class] = rateLimitPolicy{maxRequests: policy.MaxRequests, period: period, banDuration: banDuration}
+	}
+
+	var err error
+	parsed.trustedProxies, err = parseIPNets(settings.TrustedProxies)
+	if err != nil {
+		return nil, errors.New("Invalid trusted proxies. Values must be IP addresses or CIDR ranges")
+	}
+
+	parsed.allowlist, err = parseIPNets(settings.Allowlist)
+	if err != nil {
+		return nil, errors.New("Invalid rate limit allowlist. Values must be IP addresses or CIDR ranges")
+	}
+
+	return parsed, nil
+}
+
+// parseIPNets parses a list of IP addresses and CIDR ranges
+func parseIPNets(values []string) ([]*net.IPNet, error) {
+	ipNets := make([]*net.IPNet, 0, len(values))
+	for _, value := range values {
+		value = strings.TrimSpace(value)
+		if !strings.Contains(value, "/") {
+			ip := net.ParseIP(value)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid IP address: %s", value)
+			}
+
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				ip = ip.To4()
+				bits = 8 * net.IPv4len
+			}
+			ipNets = append(ipNets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+
+		_, ipNet, err := net.ParseCIDR(value)
+		if err != nil {
+			return nil, err
+		}
+		ipNets = append(ipNets, ipNet)
+	}
+	return ipNets, nil
+}
+
+func containsIP(ipNets []*net.IPNet, address string) bool {
+	ip := net.ParseIP(address)
+	if ip == nil {
+		return false
+	}
+
+	for _, ipNet := range ipNets {
+		if ipNet.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
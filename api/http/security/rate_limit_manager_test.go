@@ -0,0 +1,87 @@
+package security
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	portainer "github.com/portainer/portainer/api"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRateLimitManager_ClientAddress(t *testing.T) {
+	manager, err := NewRateLimitManager(portainer.RateLimitSettings{TrustedProxies: []string{"10.0.0.0/8", "192.168.1.1"}})
+	assert.NoError(t, err)
+
+	tests := []struct {
+		name          string
+		remoteAddr    string
+		forwardedFor  string
+		clientAddress string
+	}{
+		{"untrusted proxy", "203.0.113.5:1234", "198.51.100.1", "203.0.113.5"},
+		{"trusted proxy", "10.1.2.3:1234", "198.51.100.1", "198.51.100.1"},
+		{"spoofed header", "10.1.2.3:1234", "1.1.1.1, 198.51.100.1", "198.51.100.1"},
+		{"chain of trusted proxies", "10.1.2.3:1234", "198.51.100.1, 192.168.1.1", "198.51.100.1"},
+		{"invalid header", "10.1.2.3:1234", "unknown", "10.1.2.3"},
+		{"IPv6", "[::1]:1234", "", "::1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.RemoteAddr = tt.remoteAddr
+			if tt.forwardedFor != "" {
+				req.Header.Set("X-Forwarded-For", tt.forwardedFor)
+			}
+
+			assert.Equal(t, tt.clientAddress, manager.ClientAddress(req))
+		})
+	}
+}
+
+func TestRateLimitManager_LimitAccess(t *testing.T) {
+	manager, err := NewRateLimitManager(portainer.RateLimitSettings{
+		Authentication: portainer.RateLimitPolicy{MaxRequests: 1, Period: "1h", BanDuration: "1h"},
+		Allowlist:      []string{"192.168.0.0/16"},
+	})
+	assert.NoError(t, err)
+
+	handler := manager.Limiter(RateLimitAuthentication).LimitAccess(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	serve := func(remoteAddr string) int {
+		req := httptest.NewRequest(http.MethodPost, "/", nil)
+		req.RemoteAddr = remoteAddr
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr.Code
+	}
+
+	assert.Equal(t, http.StatusOK, serve("203.0.113.5:1000"))
+	assert.Equal(t, http.StatusForbidden, serve("203.0.113.5:1001"))
+	assert.Equal(t, http.StatusOK, serve("203.0.113.6:1000"), "other addresses should not be banned")
+
+	assert.Equal(t, http.StatusOK, serve("192.168.1.10:1000"))
+	assert.Equal(t, http.StatusOK, serve("192.168.1.10:1000"), "allowlisted addresses should not be limited")
+
+	err = manager.Update(portainer.RateLimitSettings{Authentication: portainer.RateLimitPolicy{MaxRequests: 5, Period: "1h", BanDuration: "1h"}})
+	assert.NoError(t, err)
+
+	bans := manager.Bans()
+	assert.Len(t, bans, 1, "the bans should be kept when the policies are updated")
+	assert.Equal(t, RateLimitBan{Class: RateLimitAuthentication, Address: "203.0.113.5", ExpiresAt: bans[0].ExpiresAt}, bans[0])
+
+	assert.False(t, manager.Unban("203.0.113.5", RateLimitWebhooks))
+	assert.True(t, manager.Unban("203.0.113.5", ""))
+	assert.Empty(t, manager.Bans())
+	assert.Equal(t, http.StatusOK, serve("203.0.113.5:1000"))
+}
+
+func TestValidateRateLimitSettings(t *testing.T) {
+	assert.NoError(t, ValidateRateLimitSettings(portainer.RateLimitSettings{}))
+	assert.Error(t, ValidateRateLimitSettings(portainer.RateLimitSettings{API: portainer.RateLimitPolicy{MaxRequests: 10}}))
+	assert.Error(t, ValidateRateLimitSettings(portainer.RateLimitSettings{Webhooks: portainer.RateLimitPolicy{MaxRequests: -1}}))
+	assert.Error(t, ValidateRateLimitSettings(portainer.RateLimitSettings{TrustedProxies: []string{"proxy.local"}}))
+}
@@ -1,35 +1,81 @@
 package security
 
 import (
+	"net"
 	"net/http"
 	"strings"
+	"sync"
 	"time"
 
-	"github.com/g07cha/defender"
 	httperror "github.com/portainer/libhttp/error"
 	"github.com/portainer/portainer/api/http/errors"
+	"golang.org/x/time/rate"
 )
 
+// cleanupFactor is the number of periods after which the record of a client that is not banned is removed
+const cleanupFactor = 10
+
 // RateLimiter represents an entity that manages request rate limiting
 type RateLimiter struct {
-	*defender.Defender
+	mu          sync.Mutex
+	manager     *RateLimitManager
+	maxRequests int
+	period      time.Duration
+	banDuration time.Duration
+	clients     map[string]*rateLimitClient
+	nextCleanup time.Time
+}
+
+type rateLimitClient struct {
+	limiter     *rate.Limiter
+	lastSeen    time.Time
+	bannedUntil time.Time
 }
 
-// NewRateLimiter initializes a new RateLimiter
+// NewRateLimiter initializes a new RateLimiter accepting maxRequests per duration from each client address.
+// An address exceeding the limit is banned for banDuration.
 func NewRateLimiter(maxRequests int, duration time.Duration, banDuration time.Duration) *RateLimiter {
-	messages := make(chan struct{})
-	limiter := defender.New(maxRequests, duration, banDuration)
-	go limiter.CleanupTask(messages)
+	limiter := newRateLimiter(nil)
+	limiter.setPolicy(maxRequests, duration, banDuration)
+	return limiter
+}
+
+func newRateLimiter(manager *RateLimitManager) *RateLimiter {
 	return &RateLimiter{
-		limiter,
+		manager: manager,
+		clients: make(map[string]*rateLimitClient),
+	}
+}
+
+// setPolicy replaces the limits, the current bans are kept.
+func (limiter *RateLimiter) setPolicy(maxRequests int, period time.Duration, banDuration time.Duration) {
+	limiter.mu.Lock()
+	defer limiter.mu.Unlock()
+
+	if limiter.maxRequests != maxRequests || limiter.period != period {
+		for _, client := range limiter.clients {
+			client.limiter = nil
+		}
 	}
+
+	limiter.maxRequests = maxRequests
+	limiter.period = period
+	limiter.banDuration = banDuration
 }
 
 // LimitAccess wraps current request with check if remote address does not goes above the defined limits
 func (limiter *RateLimiter) LimitAccess(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		ip := StripAddrPort(r.RemoteAddr)
-		if banned := limiter.Inc(ip); banned == true {
+		address := remoteIP(r.RemoteAddr)
+		if limiter.manager != nil {
+			address = limiter.manager.ClientAddress(r)
+			if limiter.manager.allowed(address) {
+				next.ServeHTTP(w, r)
+				return
+			}
+		}
+
+		if banned := limiter.Inc(address); banned {
 			httperror.WriteError(w, http.StatusForbidden, "Access denied", errors.ErrResourceAccessDenied)
 			return
 		}
@@ -37,6 +83,88 @@ func (limiter *RateLimiter) LimitAccess(next http.Handler) http.Handler {
 	})
 }
 
+// Inc records a request from the address and returns true if the address is banned
+func (limiter *RateLimiter) Inc(address string) bool {
+	limiter.mu.Lock()
+	defer limiter.mu.Unlock()
+
+	if limiter.maxRequests <= 0 {
+		return false
+	}
+
+	now := time.Now()
+	limiter.cleanup(now)
+
+	client, ok := limiter.clients[address]
+	if !ok {
+		client = &rateLimitClient{}
+		limiter.clients[address] = client
+	}
+	client.lastSeen = now
+
+	if now.Before(client.bannedUntil) {
+		return true
+	}
+
+	if client.limiter == nil {
+		client.limiter = rate.NewLimiter(rate.Limit(float64(limiter.maxRequests)/limiter.period.Seconds()), limiter.maxRequests)
+	}
+
+	if !client.limiter.AllowN(now, 1) {
+		client.bannedUntil = now.Add(limiter.banDuration)
+		client.limiter = nil
+		return true
+	}
+
+	return false
+}
+
+// bans returns the addresses currently banned and the end of their ban
+func (limiter *RateLimiter) bans() map[string]time.Time {
+	limiter.mu.Lock()
+	defer limiter.mu.Unlock()
+
+	now := time.Now()
+	bans := make(map[string]time.Time)
+	for address, client := range limiter.clients {
+		if now.Before(client.bannedUntil) {
+			bans[address] = client.bannedUntil
+		}
+	}
+	return bans
+}
+
+// unban removes the ban of an address and returns true if the address was banned
+func (limiter *RateLimiter) unban(address string) bool {
+	limiter.mu.Lock()
+	defer limiter.mu.Unlock()
+
+	client, ok := limiter.clients[address]
+	if !ok {
+		return false
+	}
+
+	delete(limiter.clients, address)
+	return time.Now().Before(client.bannedUntil)
+}
+
+// cleanup removes the records of the clients that are not banned and were not seen recently.
+// Must be called with the lock held.
+func (limiter *RateLimiter) cleanup(now time.Time) {
+	if now.Before(limiter.nextCleanup) {
+		return
+	}
+
+	retention := limiter.period * cleanupFactor
+	for address, client := range limiter.clients {
+		if now.After(client.bannedUntil) && now.Sub(client.lastSeen) > retention {
+			delete(limiter.clients, address)
+		}
+	}
+
+	limiter.nextCleanup = now.Add(retention)
+}
+
 // StripAddrPort removes port from IP address
 func StripAddrPort(addr string) string {
 	portIndex := strings.LastIndex(addr, ":")
@@ -45,3 +173,12 @@ func StripAddrPort(addr string) string {
 	}
 	return addr
 }
+
+// remoteIP returns the IP address of a remote address, without the port and the brackets of IPv6 addresses
+func remoteIP(remoteAddr string) string {
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		host = remoteAddr
+	}
+	return strings.Trim(host, "[]")
+}
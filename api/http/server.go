@@ -6,13 +6,16 @@ import (
 	"log"
 	"net/http"
 	"path/filepath"
+	"strings"
 	"time"
 
 	portainer "github.com/portainer/portainer/api"
 	"github.com/portainer/portainer/api/adminmonitor"
+	operations "github.com/portainer/portainer/api/backup"
 	"github.com/portainer/portainer/api/crypto"
 	"github.com/portainer/portainer/api/docker"
 	"github.com/portainer/portainer/api/http/handler"
+	"github.com/portainer/portainer/api/http/handler/auditlogs"
 	"github.com/portainer/portainer/api/http/handler/auth"
 	"github.com/portainer/portainer/api/http/handler/backup"
 	"github.com/portainer/portainer/api/http/handler/customtemplates"
@@ -26,6 +29,7 @@ import (
 	"github.com/portainer/portainer/api/http/handler/endpointproxy"
 	"github.com/portainer/portainer/api/http/handler/endpoints"
 	"github.com/portainer/portainer/api/http/handler/file"
+	"github.com/portainer/portainer/api/http/handler/integrity"
 	"github.com/portainer/portainer/api/http/handler/motd"
 	"github.com/portainer/portainer/api/http/handler/registries"
 	"github.com/portainer/portainer/api/http/handler/resourcecontrols"
@@ -45,8 +49,10 @@ import (
 	"github.com/portainer/portainer/api/http/proxy"
 	"github.com/portainer/portainer/api/http/proxy/factory/kubernetes"
 	"github.com/portainer/portainer/api/http/security"
+	"github.com/portainer/portainer/api/internal/audit"
 	"github.com/portainer/portainer/api/internal/authorization"
 	"github.com/portainer/portainer/api/kubernetes/cli"
+	"github.com/portainer/portainer/api/ldap"
 )
 
 // Server implements the portainer.Server interface
@@ -73,20 +79,34 @@ type Server struct {
 	SSL                         bool
 	SSLCert                     string
 	SSLKey                      string
+	SSLClientCA                 string
+	SSLClientCertRequired       bool
 	DockerClientFactory         *docker.ClientFactory
 	KubernetesClientFactory     *cli.ClientFactory
 	KubernetesDeployer          portainer.KubernetesDeployer
 	ShutdownCtx                 context.Context
 	ShutdownTrigger             context.CancelFunc
+	RestoreS3Endpoints          []string
 }
 
 // Start starts the HTTP server
 func (server *Server) Start() error {
 	kubernetesTokenCacheManager := server.KubernetesTokenCacheManager
 
-	requestBouncer := security.NewRequestBouncer(server.DataStore, server.JWTService)
+	appSettings, err := server.DataStore.Settings().Settings()
+	if err != nil {
+		return err
+	}
+
+	rateLimitManager, err := security.NewRateLimitManager(appSettings.RateLimiting)
+	if err != nil {
+		return err
+	}
+	rateLimiter := rateLimitManager.Limiter(security.RateLimitAuthentication)
+	edgeRateLimiter := rateLimitManager.Limiter(security.RateLimitEdgeCheckin)
+
+	requestBouncer := security.NewRequestBouncer(server.DataStore, server.JWTService, rateLimitManager)
 
-	rateLimiter := security.NewRateLimiter(10, 1*time.Second, 1*time.Hour)
 	offlineGate := offlinegate.NewOfflineGate()
 
 	var authHandler = auth.NewHandler(requestBouncer, rateLimiter)
@@ -98,13 +118,36 @@ func (server *Server) Start() error {
 	authHandler.KubernetesTokenCacheManager = kubernetesTokenCacheManager
 	authHandler.OAuthService = server.OAuthService
 
+	auditRecorder := audit.NewRecorder(server.DataStore)
+	auditRecorder.Start(server.ShutdownCtx)
+
 	adminMonitor := adminmonitor.New(5*time.Minute, server.DataStore, server.ShutdownCtx)
 	adminMonitor.Start()
 
+	backupScheduler := operations.NewScheduler(offlineGate, server.DataStore, server.FileService.GetDatastorePath(), server.ShutdownCtx)
+	err = backupScheduler.Start()
+	if err != nil {
+		log.Printf("[ERROR] [http,server] [message: unable to start the backup scheduler] [error: %s]", err)
+	}
+
+	ldapSyncService := ldap.NewSyncService(server.LDAPService, server.DataStore, server.ShutdownCtx)
+	err = ldapSyncService.Start()
+	if err != nil {
+		log.Printf("[ERROR] [http,server] [message: unable to start the LDAP synchronization] [error: %s]", err)
+	}
+
 	var backupHandler = backup.NewHandler(requestBouncer, server.DataStore, offlineGate, server.FileService.GetDatastorePath(), server.ShutdownTrigger, adminMonitor)
+	backupHandler.RestoreS3Endpoints = server.RestoreS3Endpoints
+
+	var auditLogHandler = auditlogs.NewHandler(requestBouncer)
+	auditLogHandler.DataStore = server.DataStore
+
+	var integrityHandler = integrity.NewHandler(requestBouncer)
+	integrityHandler.DataStore = server.DataStore
 
 	var roleHandler = roles.NewHandler(requestBouncer)
 	roleHandler.DataStore = server.DataStore
+	roleHandler.AuthorizationService = server.AuthorizationService
 
 	var customTemplatesHandler = customtemplates.NewHandler(requestBouncer)
 	customTemplatesHandler.DataStore = server.DataStore
@@ -130,7 +173,7 @@ func (server *Server) Start() error {
 	var edgeTemplatesHandler = edgetemplates.NewHandler(requestBouncer)
 	edgeTemplatesHandler.DataStore = server.DataStore
 
-	var endpointHandler = endpoints.NewHandler(requestBouncer)
+	var endpointHandler = endpoints.NewHandler(requestBouncer, edgeRateLimiter)
 	endpointHandler.DataStore = server.DataStore
 	endpointHandler.FileService = server.FileService
 	endpointHandler.ProxyManager = server.ProxyManager
@@ -139,7 +182,7 @@ func (server *Server) Start() error {
 	endpointHandler.ComposeStackManager = server.ComposeStackManager
 	endpointHandler.AuthorizationService = server.AuthorizationService
 
-	var endpointEdgeHandler = endpointedge.NewHandler(requestBouncer)
+	var endpointEdgeHandler = endpointedge.NewHandler(requestBouncer, edgeRateLimiter)
 	endpointEdgeHandler.DataStore = server.DataStore
 	endpointEdgeHandler.FileService = server.FileService
 	endpointEdgeHandler.ReverseTunnelService = server.ReverseTunnelService
@@ -171,6 +214,9 @@ func (server *Server) Start() error {
 	settingsHandler.JWTService = server.JWTService
 	settingsHandler.LDAPService = server.LDAPService
 	settingsHandler.SnapshotService = server.SnapshotService
+	settingsHandler.BackupScheduler = backupScheduler
+	settingsHandler.LDAPSyncService = ldapSyncService
+	settingsHandler.RateLimitManager = rateLimitManager
 
 	var stackHandler = stacks.NewHandler(requestBouncer)
 	stackHandler.DataStore = server.DataStore
@@ -186,6 +232,7 @@ func (server *Server) Start() error {
 
 	var teamHandler = teams.NewHandler(requestBouncer)
 	teamHandler.DataStore = server.DataStore
+	teamHandler.AuthorizationService = server.AuthorizationService
 
 	var teamMembershipHandler = teammemberships.NewHandler(requestBouncer)
 	teamMembershipHandler.DataStore = server.DataStore
@@ -203,6 +250,8 @@ func (server *Server) Start() error {
 	var userHandler = users.NewHandler(requestBouncer, rateLimiter)
 	userHandler.DataStore = server.DataStore
 	userHandler.CryptoService = server.CryptoService
+	userHandler.JWTService = server.JWTService
+	userHandler.AuthorizationService = server.AuthorizationService
 
 	var websocketHandler = websocket.NewHandler(requestBouncer)
 	websocketHandler.DataStore = server.DataStore
@@ -210,12 +259,13 @@ func (server *Server) Start() error {
 	websocketHandler.ReverseTunnelService = server.ReverseTunnelService
 	websocketHandler.KubernetesClientFactory = server.KubernetesClientFactory
 
-	var webhookHandler = webhooks.NewHandler(requestBouncer)
+	var webhookHandler = webhooks.NewHandler(requestBouncer, rateLimitManager.Limiter(security.RateLimitWebhooks))
 	webhookHandler.DataStore = server.DataStore
 	webhookHandler.DockerClientFactory = server.DockerClientFactory
 
 	server.Handler = &handler.Handler{
 		RoleHandler:            roleHandler,
+		AuditLogHandler:        auditLogHandler,
 		AuthHandler:            authHandler,
 		BackupHandler:          backupHandler,
 		CustomTemplatesHandler: customTemplatesHandler,
@@ -229,6 +279,7 @@ func (server *Server) Start() error {
 		EndpointEdgeHandler:    endpointEdgeHandler,
 		EndpointProxyHandler:   endpointProxyHandler,
 		FileHandler:            fileHandler,
+		IntegrityHandler:       integrityHandler,
 		MOTDHandler:            motdHandler,
 		RegistryHandler:        registryHandler,
 		ResourceControlHandler: resourceControlHandler,
@@ -247,12 +298,18 @@ func (server *Server) Start() error {
 
 	httpServer := &http.Server{
 		Addr:    server.BindAddress,
-		Handler: server.Handler,
+		Handler: limitAPIAccess(rateLimitManager.Limiter(security.RateLimitAPI), audit.Middleware(auditRecorder, requestBouncer.ClientAddress, server.Handler)),
 	}
 	httpServer.Handler = offlineGate.WaitingMiddleware(time.Minute, httpServer.Handler)
 
 	if server.SSL {
 		httpServer.TLSConfig = crypto.CreateServerTLSConfiguration()
+		if server.SSLClientCA != "" {
+			httpServer.TLSConfig, err = crypto.CreateServerMTLSConfiguration(server.SSLClientCA, server.SSLClientCertRequired)
+			if err != nil {
+				return err
+			}
+		}
 		return httpServer.ListenAndServeTLS(server.SSLCert, server.SSLKey)
 	}
 
@@ -261,6 +318,18 @@ func (server *Server) Start() error {
 	return httpServer.ListenAndServe()
 }
 
+// limitAPIAccess applies the rate limiting policy of the API to the API requests, the static files are not limited
+func limitAPIAccess(rateLimiter *security.RateLimiter, next http.Handler) http.Handler {
+	limitedHandler := rateLimiter.LimitAccess(next)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/api/") {
+			limitedHandler.ServeHTTP(w, r)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
 func (server *Server) shutdown(httpServer *http.Server) {
 	<-server.ShutdownCtx.Done()
 
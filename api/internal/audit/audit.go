@@ -0,0 +1,149 @@
+package audit
+
+import (
+	"bufio"
+	"context"
+	"log"
+	"net"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	portainer "github.com/portainer/portainer/api"
+)
+
+type contextKey int
+
+const contextRequestKey contextKey = iota
+
+// proxiedRequestRe matches the API requests proxied to the Docker and Kubernetes endpoints
+var proxiedRequestRe = regexp.MustCompile(`^/api/endpoints/[0-9]+/(docker|kubernetes)/`)
+
+// endpointRequestRe matches the API requests targeting an endpoint
+var endpointRequestRe = regexp.MustCompile(`^/api/endpoints/([0-9]+)`)
+
+// requestIdentity holds the identity of the client of a request and the recorder of its audit logs. It is created
+// by the middleware and completed by the request bouncer once the request is authenticated.
+type requestIdentity struct {
+	recorder  *Recorder
+	clientIP  string
+	tokenData *portainer.TokenData
+}
+
+// IsMutating returns true when the method of a request can change a resource
+func IsMutating(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return false
+	}
+	return true
+}
+
+// SetTokenData records the authenticated user of a request in the audit logs of the request
+func SetTokenData(r *http.Request, tokenData *portainer.TokenData) {
+	identity, ok := r.Context().Value(contextRequestKey).(*requestIdentity)
+	if ok {
+		identity.tokenData = tokenData
+	}
+}
+
+// Middleware records an audit log for every API request which is not a read, once it is served.
+// The requests proxied to the Docker and Kubernetes endpoints are recorded by their transports, which
+// identify the operation.
+func Middleware(recorder *Recorder, clientAddress func(*http.Request) string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.URL.Path, "/api/") {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		identity := &requestIdentity{recorder: recorder, clientIP: clientAddress(r)}
+		r = r.WithContext(context.WithValue(r.Context(), contextRequestKey, identity))
+
+		if !IsMutating(r.Method) || proxiedRequestRe.MatchString(r.URL.Path) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(recorder, r)
+
+		Record(r, &portainer.AuditLog{
+			EndpointID: apiEndpointID(r.URL.Path),
+			Method:     r.Method,
+			Path:       r.URL.Path,
+			Operation:  apiOperation(r.Method, r.URL.Path),
+			ResourceID: apiResourceID(r.URL.Path),
+			Status:     recorder.status,
+		})
+	})
+}
+
+// Record queues the audit log of a request, it is persisted by the recorder of the request. The timestamp,
+// the user and the client address are set from the request, the teams of the user are set when the audit
+// log is persisted. A request which is not served by the middleware is not recorded.
+func Record(r *http.Request, auditLog *portainer.AuditLog) {
+	identity, ok := r.Context().Value(contextRequestKey).(*requestIdentity)
+	if !ok {
+		log.Printf("[WARN] [audit] [message: no audit log recorder for the request] [method: %s] [path: %s]", auditLog.Method, auditLog.Path)
+		return
+	}
+
+	auditLog.Timestamp = time.Now().Unix()
+	auditLog.TeamIDs = make([]portainer.TeamID, 0)
+	auditLog.ClientIP = identity.clientIP
+
+	if identity.tokenData != nil {
+		auditLog.UserID = identity.tokenData.ID
+		auditLog.Username = identity.tokenData.Username
+	}
+
+	identity.recorder.queue(*auditLog)
+}
+
+func apiEndpointID(path string) portainer.EndpointID {
+	match := endpointRequestRe.FindStringSubmatch(path)
+	if match == nil {
+		return 0
+	}
+
+	endpointID, _ := strconv.Atoi(match[1])
+	return portainer.EndpointID(endpointID)
+}
+
+// apiResourceID returns the identifier following the resource collection of an API path, e.g. 3 for /api/stacks/3/stop
+func apiResourceID(path string) string {
+	segments := strings.Split(strings.Trim(strings.TrimPrefix(path, "/api/"), "/"), "/")
+	if len(segments) < 2 {
+		return ""
+	}
+	return segments[1]
+}
+
+// statusRecorder captures the status of a response. The websocket and streaming responses keep working.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (recorder *statusRecorder) WriteHeader(status int) {
+	recorder.status = status
+	recorder.ResponseWriter.WriteHeader(status)
+}
+
+func (recorder *statusRecorder) Flush() {
+	if flusher, ok := recorder.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func (recorder *statusRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := recorder.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, http.ErrNotSupported
+	}
+	recorder.status = http.StatusSwitchingProtocols
+	return hijacker.Hijack()
+}
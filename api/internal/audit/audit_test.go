@@ -0,0 +1,83 @@
+package audit_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/bolt/bolttest"
+	"github.com/portainer/portainer/api/internal/audit"
+	"github.com/stretchr/testify/assert"
+)
+
+func clientAddress(r *http.Request) string {
+	return "203.0.113.10"
+}
+
+func Test_Middleware_RecordsMutatingRequests(t *testing.T) {
+	is := assert.New(t)
+
+	store, teardown := bolttest.MustNewTestStore(true)
+	defer teardown()
+
+	user := &portainer.User{Username: "bob", Role: portainer.StandardUserRole}
+	is.NoError(store.User().CreateUser(user))
+	is.NoError(store.TeamMembership().CreateTeamMembership(&portainer.TeamMembership{UserID: user.ID, TeamID: 3, Role: portainer.TeamMember}))
+
+	recorder := audit.NewRecorder(store)
+	handler := audit.Middleware(recorder, clientAddress, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		audit.SetTokenData(r, &portainer.TokenData{ID: user.ID, Username: user.Username, Role: user.Role})
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	requests := []*http.Request{
+		httptest.NewRequest(http.MethodDelete, "/api/stacks/4", nil),
+		httptest.NewRequest(http.MethodGet, "/api/stacks/4", nil),
+		httptest.NewRequest(http.MethodPost, "/api/endpoints/1/docker/containers/617c5f22bb9b/stop", nil),
+		httptest.NewRequest(http.MethodPost, "/index.html", nil),
+	}
+	for _, req := range requests {
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+	}
+	recorder.Flush()
+
+	auditLogs, err := store.AuditLog().AuditLogs()
+	is.NoError(err)
+	if !is.Len(auditLogs, 1, "only the mutating API request which is not proxied should be recorded") {
+		return
+	}
+
+	auditLog := auditLogs[0]
+	is.Equal(user.ID, auditLog.UserID)
+	is.Equal("bob", auditLog.Username)
+	is.Equal([]portainer.TeamID{3}, auditLog.TeamIDs)
+	is.Equal(portainer.OperationPortainerStackDelete, auditLog.Operation)
+	is.Equal("4", auditLog.ResourceID)
+	is.Equal(http.StatusNoContent, auditLog.Status)
+	is.Equal("203.0.113.10", auditLog.ClientIP)
+	is.NotZero(auditLog.Timestamp)
+}
+
+func Test_CreateAuditLogs_RemovesTheOldestAuditLogs(t *testing.T) {
+	is := assert.New(t)
+
+	store, teardown := bolttest.MustNewTestStore(true)
+	defer teardown()
+
+	is.NoError(store.AuditLog().CreateAuditLogs([]portainer.AuditLog{{Path: "/api/stacks/1"}, {Path: "/api/stacks/2"}}, 3))
+	is.NoError(store.AuditLog().CreateAuditLogs([]portainer.AuditLog{{Path: "/api/stacks/3"}, {Path: "/api/stacks/4"}}, 3))
+
+	auditLogs, err := store.AuditLog().AuditLogs()
+	is.NoError(err)
+	if !is.Len(auditLogs, 3, "only the most recent audit logs should be kept") {
+		return
+	}
+	is.Equal(portainer.AuditLogID(2), auditLogs[0].ID)
+	is.Equal("/api/stacks/4", auditLogs[2].Path)
+
+	page, count, err := store.AuditLog().FilteredAuditLogs(func(portainer.AuditLog) bool { return true }, 1, 1)
+	is.NoError(err)
+	is.Equal(3, count)
+	is.Equal([]portainer.AuditLog{auditLogs[1]}, page, "the page should be counted from the most recent audit log")
+}
@@ -0,0 +1,77 @@
+package audit
+
+import (
+	"net/http"
+	"strings"
+
+	portainer "github.com/portainer/portainer/api"
+)
+
+// collectionOperations are the operations creating, updating and removing the resources of an API collection
+type collectionOperations struct {
+	create portainer.Authorization
+	update portainer.Authorization
+	delete portainer.Authorization
+}
+
+var apiCollectionOperations = map[string]collectionOperations{
+	"dockerhub":         {update: portainer.OperationPortainerDockerHubUpdate},
+	"endpoint_groups":   {portainer.OperationPortainerEndpointGroupCreate, portainer.OperationPortainerEndpointGroupUpdate, portainer.OperationPortainerEndpointGroupDelete},
+	"endpoints":         {portainer.OperationPortainerEndpointCreate, portainer.OperationPortainerEndpointUpdate, portainer.OperationPortainerEndpointDelete},
+	"registries":        {portainer.OperationPortainerRegistryCreate, portainer.OperationPortainerRegistryUpdate, portainer.OperationPortainerRegistryDelete},
+	"resource_controls": {portainer.OperationPortainerResourceControlCreate, portainer.OperationPortainerResourceControlUpdate, portainer.OperationPortainerResourceControlDelete},
+	"roles":             {portainer.OperationPortainerRoleCreate, portainer.OperationPortainerRoleUpdate, portainer.OperationPortainerRoleDelete},
+	"settings":          {update: portainer.OperationPortainerSettingsUpdate},
+	"stacks":            {portainer.OperationPortainerStackCreate, portainer.OperationPortainerStackUpdate, portainer.OperationPortainerStackDelete},
+	"tags":              {create: portainer.OperationPortainerTagCreate, delete: portainer.OperationPortainerTagDelete},
+	"team_memberships":  {portainer.OperationPortainerTeamMembershipCreate, portainer.OperationPortainerTeamMembershipUpdate, portainer.OperationPortainerTeamMembershipDelete},
+	"teams":             {portainer.OperationPortainerTeamCreate, portainer.OperationPortainerTeamUpdate, portainer.OperationPortainerTeamDelete},
+	"users":             {portainer.OperationPortainerUserCreate, portainer.OperationPortainerUserUpdate, portainer.OperationPortainerUserDelete},
+	"webhooks":          {create: portainer.OperationPortainerWebhookCreate, delete: portainer.OperationPortainerWebhookDelete},
+}
+
+// apiActionOperations are the operations of the actions on a resource, keyed by collection and action
+var apiActionOperations = map[string]portainer.Authorization{
+	"endpoints/job":        portainer.OperationPortainerEndpointJob,
+	"endpoints/snapshot":   portainer.OperationPortainerEndpointSnapshot,
+	"registries/configure": portainer.OperationPortainerRegistryConfigure,
+	"roles/clone":          portainer.OperationPortainerRoleCreate,
+	"stacks/migrate":       portainer.OperationPortainerStackMigrate,
+	"users/passwd":         portainer.OperationPortainerUserUpdatePassword,
+}
+
+// apiOperation returns the operation of a Portainer API request, or OperationPortainerUndefined
+// when it cannot be identified
+func apiOperation(method, path string) portainer.Authorization {
+	segments := strings.Split(strings.Trim(strings.TrimPrefix(path, "/api/"), "/"), "/")
+
+	switch {
+	case len(segments) == 2 && segments[0] == "endpoints" && segments[1] == "snapshot" && method == http.MethodPost:
+		return portainer.OperationPortainerEndpointSnapshots
+	case len(segments) == 2 && segments[0] == "upload" && segments[1] == "tls":
+		return portainer.OperationPortainerUploadTLS
+	case len(segments) > 2:
+		operation, ok := apiActionOperations[segments[0]+"/"+segments[2]]
+		if ok {
+			return operation
+		}
+		return portainer.OperationPortainerUndefined
+	}
+
+	operations := apiCollectionOperations[segments[0]]
+
+	var operation portainer.Authorization
+	switch method {
+	case http.MethodPost:
+		operation = operations.create
+	case http.MethodPut, http.MethodPatch:
+		operation = operations.update
+	case http.MethodDelete:
+		operation = operations.delete
+	}
+
+	if operation == "" {
+		return portainer.OperationPortainerUndefined
+	}
+	return operation
+}
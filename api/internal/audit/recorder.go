@@ -0,0 +1,127 @@
+package audit
+
+import (
+	"context"
+	"log"
+	"time"
+
+	portainer "github.com/portainer/portainer/api"
+)
+
+const (
+	// MaxAuditLogs is the number of audit logs kept in the database, the oldest ones are removed first
+	MaxAuditLogs = 100000
+	// recorderQueueSize is the number of audit logs waiting to be persisted before they are persisted synchronously
+	recorderQueueSize = 1024
+	// recorderBatchSize is the maximum number of audit logs persisted in a single transaction
+	recorderBatchSize = 100
+	// recorderFlushInterval is the maximum delay before a queued audit log is persisted
+	recorderFlushInterval = time.Second
+)
+
+// Recorder persists the audit logs in batches, outside of the requests they are recorded for.
+type Recorder struct {
+	dataStore portainer.DataStore
+	auditLogs chan portainer.AuditLog
+}
+
+// NewRecorder creates a recorder of audit logs. The audit logs are only persisted in batches once it is started.
+func NewRecorder(dataStore portainer.DataStore) *Recorder {
+	return &Recorder{
+		dataStore: dataStore,
+		auditLogs: make(chan portainer.AuditLog, recorderQueueSize),
+	}
+}
+
+// Start starts a background routine which persists the queued audit logs every second, or as soon as a batch
+// is complete, until the shutdown context is done. The queued audit logs are persisted before it stops.
+func (recorder *Recorder) Start(shutdownCtx context.Context) {
+	go func() {
+		ticker := time.NewTicker(recorderFlushInterval)
+		defer ticker.Stop()
+
+		batch := make([]portainer.AuditLog, 0, recorderBatchSize)
+		for {
+			select {
+			case auditLog := <-recorder.auditLogs:
+				batch = append(batch, auditLog)
+				if len(batch) < recorderBatchSize {
+					continue
+				}
+			case <-ticker.C:
+			case <-shutdownCtx.Done():
+				recorder.persist(batch)
+				recorder.Flush()
+				log.Println("[DEBUG] [audit] [message: shutting down audit log recorder]")
+				return
+			}
+
+			recorder.persist(batch)
+			batch = batch[:0]
+		}
+	}()
+}
+
+// Flush persists the queued audit logs
+func (recorder *Recorder) Flush() {
+	batch := make([]portainer.AuditLog, 0, recorderBatchSize)
+	for {
+		select {
+		case auditLog := <-recorder.auditLogs:
+			batch = append(batch, auditLog)
+			if len(batch) < recorderBatchSize {
+				continue
+			}
+			recorder.persist(batch)
+			batch = batch[:0]
+		default:
+			recorder.persist(batch)
+			return
+		}
+	}
+}
+
+// queue queues an audit log. It is persisted synchronously when the queue is full, so that none is lost.
+func (recorder *Recorder) queue(auditLog portainer.AuditLog) {
+	select {
+	case recorder.auditLogs <- auditLog:
+	default:
+		recorder.persist([]portainer.AuditLog{auditLog})
+	}
+}
+
+// persist sets the teams of the users of a batch of audit logs and persists it in a single transaction.
+// A failure is logged and the audit logs of the batch are lost.
+func (recorder *Recorder) persist(batch []portainer.AuditLog) {
+	if len(batch) == 0 {
+		return
+	}
+
+	userTeams := make(map[portainer.UserID][]portainer.TeamID)
+	for idx := range batch {
+		auditLog := &batch[idx]
+		if auditLog.Username == "" {
+			continue
+		}
+
+		teamIDs, ok := userTeams[auditLog.UserID]
+		if !ok {
+			teamIDs = make([]portainer.TeamID, 0)
+
+			memberships, err := recorder.dataStore.TeamMembership().TeamMembershipsByUserID(auditLog.UserID)
+			if err != nil {
+				log.Printf("[WARN] [audit] [message: unable to retrieve the teams of the user] [user: %s] [error: %s]", auditLog.Username, err)
+			}
+			for _, membership := range memberships {
+				teamIDs = append(teamIDs, membership.TeamID)
+			}
+			userTeams[auditLog.UserID] = teamIDs
+		}
+		auditLog.TeamIDs = teamIDs
+	}
+
+	err := recorder.dataStore.AuditLog().CreateAuditLogs(batch, MaxAuditLogs)
+	if err != nil {
+		log.Printf("[ERROR] [audit] [message: unable to persist the audit logs] [count: %d] [error: %s]", len(batch), err)
+	}
+}
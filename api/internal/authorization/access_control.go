@@ -2,6 +2,7 @@ package authorization
 
 import (
 	"strconv"
+	"time"
 
 	portainer "github.com/portainer/portainer/api"
 	"github.com/portainer/portainer/api/internal/stackutils"
@@ -174,6 +175,10 @@ func UserCanAccessResource(userID portainer.UserID, userTeamIDs []portainer.Team
 	}
 
 	for _, authorizedTeamAccess := range resourceControl.TeamAccesses {
+		if TeamResourceAccessExpired(authorizedTeamAccess, time.Now()) {
+			continue
+		}
+
 		for _, userTeamID := range userTeamIDs {
 			if userTeamID == authorizedTeamAccess.TeamID {
 				return true
@@ -1,6 +1,8 @@
 package authorization
 
 import (
+	"time"
+
 	"github.com/portainer/portainer/api"
 	"github.com/portainer/portainer/api/kubernetes/cli"
 )
@@ -487,13 +489,31 @@ func (service *Service) getAuthorizations(user *portainer.User) (portainer.Endpo
 		return endpointAuthorizations, err
 	}
 
-	endpointAuthorizations = getUserEndpointAuthorizations(user, endpoints, endpointGroups, roles, userMemberships)
+	endpointAuthorizations, _ = getUserEndpointAuthorizations(user, endpoints, endpointGroups, roles, userMemberships)
 
 	return endpointAuthorizations, nil
 }
 
-func getUserEndpointAuthorizations(user *portainer.User, endpoints []portainer.Endpoint, endpointGroups []portainer.EndpointGroup, roles []portainer.Role, userMemberships []portainer.TeamMembership) portainer.EndpointAuthorizations {
+// endpointRoleDecision is the role granted to a user on an endpoint, along with the access policy it comes from
+type endpointRoleDecision struct {
+	role   *portainer.Role
+	source string
+	teamID portainer.TeamID
+}
+
+// policyGrant is a role granted by an access policy, teamID is only set for the team access policies
+type policyGrant struct {
+	roleID portainer.RoleID
+	teamID portainer.TeamID
+}
+
+// getUserEndpointAuthorizations returns the authorizations of a user on each endpoint, and the decision that
+// produced them. The policies are evaluated in this order: the user policies of the endpoint, then of its group,
+// then the team policies of the endpoint, then of its group. The first source granting a role with authorizations
+// applies, and within a source the role with the highest priority wins.
+func getUserEndpointAuthorizations(user *portainer.User, endpoints []portainer.Endpoint, endpointGroups []portainer.EndpointGroup, roles []portainer.Role, userMemberships []portainer.TeamMembership) (portainer.EndpointAuthorizations, map[portainer.EndpointID]endpointRoleDecision) {
 	endpointAuthorizations := make(portainer.EndpointAuthorizations)
+	decisions := make(map[portainer.EndpointID]endpointRoleDecision)
 
 	groupUserAccessPolicies := map[portainer.EndpointGroupID]portainer.UserAccessPolicies{}
 	groupTeamAccessPolicies := map[portainer.EndpointGroupID]portainer.TeamAccessPolicies{}
@@ -503,101 +523,74 @@ func getUserEndpointAuthorizations(user *portainer.User, endpoints []portainer.E
 	}
 
 	for _, endpoint := range endpoints {
-		authorizations := getAuthorizationsFromUserEndpointPolicy(user, &endpoint, roles)
-		if len(authorizations) > 0 {
-			endpointAuthorizations[endpoint.ID] = authorizations
-			continue
-		}
-
-		authorizations = getAuthorizationsFromUserEndpointGroupPolicy(user, &endpoint, roles, groupUserAccessPolicies)
-		if len(authorizations) > 0 {
-			endpointAuthorizations[endpoint.ID] = authorizations
-			continue
+		sources := []struct {
+			source string
+			grants []policyGrant
+		}{
+			{PolicySourceUserEndpoint, getUserPolicyGrants(user, endpoint.UserAccessPolicies)},
+			{PolicySourceUserEndpointGroup, getUserPolicyGrants(user, groupUserAccessPolicies[endpoint.GroupID])},
+			{PolicySourceTeamEndpoint, getTeamPolicyGrants(userMemberships, endpoint.TeamAccessPolicies)},
+			{PolicySourceTeamEndpointGroup, getTeamPolicyGrants(userMemberships, groupTeamAccessPolicies[endpoint.GroupID])},
 		}
 
-		authorizations = getAuthorizationsFromTeamEndpointPolicies(userMemberships, &endpoint, roles)
-		if len(authorizations) > 0 {
-			endpointAuthorizations[endpoint.ID] = authorizations
-			continue
-		}
-
-		authorizations = getAuthorizationsFromTeamEndpointGroupPolicies(userMemberships, &endpoint, roles, groupTeamAccessPolicies)
-		if len(authorizations) > 0 {
-			endpointAuthorizations[endpoint.ID] = authorizations
+		for _, source := range sources {
+			role, teamID := getHighestPriorityRole(source.grants, roles)
+			if role != nil && len(role.Authorizations) > 0 {
+				endpointAuthorizations[endpoint.ID] = role.Authorizations
+				decisions[endpoint.ID] = endpointRoleDecision{role: role, source: source.source, teamID: teamID}
+				break
+			}
 		}
 	}
 
-	return endpointAuthorizations
-}
-
-func getAuthorizationsFromUserEndpointPolicy(user *portainer.User, endpoint *portainer.Endpoint, roles []portainer.Role) portainer.Authorizations {
-	policyRoles := make([]portainer.RoleID, 0)
-
-	policy, ok := endpoint.UserAccessPolicies[user.ID]
-	if ok {
-		policyRoles = append(policyRoles, policy.RoleID)
-	}
-
-	return getAuthorizationsFromRoles(policyRoles, roles)
-}
-
-func getAuthorizationsFromUserEndpointGroupPolicy(user *portainer.User, endpoint *portainer.Endpoint, roles []portainer.Role, groupAccessPolicies map[portainer.EndpointGroupID]portainer.UserAccessPolicies) portainer.Authorizations {
-	policyRoles := make([]portainer.RoleID, 0)
-
-	policy, ok := groupAccessPolicies[endpoint.GroupID][user.ID]
-	if ok {
-		policyRoles = append(policyRoles, policy.RoleID)
-	}
-
-	return getAuthorizationsFromRoles(policyRoles, roles)
+	return endpointAuthorizations, decisions
 }
 
-func getAuthorizationsFromTeamEndpointPolicies(memberships []portainer.TeamMembership, endpoint *portainer.Endpoint, roles []portainer.Role) portainer.Authorizations {
-	policyRoles := make([]portainer.RoleID, 0)
+func getUserPolicyGrants(user *portainer.User, accessPolicies portainer.UserAccessPolicies) []policyGrant {
+	grants := make([]policyGrant, 0)
 
-	for _, membership := range memberships {
-		policy, ok := endpoint.TeamAccessPolicies[membership.TeamID]
-		if ok {
-			policyRoles = append(policyRoles, policy.RoleID)
-		}
+	policy, ok := accessPolicies[user.ID]
+	if ok && !AccessPolicyExpired(policy, time.Now()) {
+		grants = append(grants, policyGrant{roleID: policy.RoleID})
 	}
 
-	return getAuthorizationsFromRoles(policyRoles, roles)
+	return grants
 }
 
-func getAuthorizationsFromTeamEndpointGroupPolicies(memberships []portainer.TeamMembership, endpoint *portainer.Endpoint, roles []portainer.Role, groupAccessPolicies map[portainer.EndpointGroupID]portainer.TeamAccessPolicies) portainer.Authorizations {
-	policyRoles := make([]portainer.RoleID, 0)
+func getTeamPolicyGrants(memberships []portainer.TeamMembership, accessPolicies portainer.TeamAccessPolicies) []policyGrant {
+	grants := make([]policyGrant, 0)
 
 	for _, membership := range memberships {
-		policy, ok := groupAccessPolicies[endpoint.GroupID][membership.TeamID]
-		if ok {
-			policyRoles = append(policyRoles, policy.RoleID)
+		policy, ok := accessPolicies[membership.TeamID]
+		if ok && !AccessPolicyExpired(policy, time.Now()) {
+			grants = append(grants, policyGrant{roleID: policy.RoleID, teamID: membership.TeamID})
 		}
 	}
 
-	return getAuthorizationsFromRoles(policyRoles, roles)
+	return grants
 }
 
-func getAuthorizationsFromRoles(roleIdentifiers []portainer.RoleID, roles []portainer.Role) portainer.Authorizations {
-	var associatedRoles []portainer.Role
+// getHighestPriorityRole returns the existing role with the highest priority among the grants, and the team
+// of the grant. The first grant wins a tie.
+func getHighestPriorityRole(grants []policyGrant, roles []portainer.Role) (*portainer.Role, portainer.TeamID) {
+	var highestRole *portainer.Role
+	var teamID portainer.TeamID
 
-	for _, id := range roleIdentifiers {
-		for _, role := range roles {
-			if role.ID == id {
-				associatedRoles = append(associatedRoles, role)
-				break
+	highestPriority := 0
+	for _, grant := range grants {
+		for idx := range roles {
+			if roles[idx].ID != grant.roleID {
+				continue
 			}
-		}
-	}
 
-	var authorizations portainer.Authorizations
-	highestPriority := 0
-	for _, role := range associatedRoles {
-		if role.Priority > highestPriority {
-			highestPriority = role.Priority
-			authorizations = role.Authorizations
+			if roles[idx].Priority > highestPriority {
+				highestPriority = roles[idx].Priority
+				highestRole = &roles[idx]
+				teamID = grant.teamID
+			}
+			break
 		}
 	}
 
-	return authorizations
+	return highestRole, teamID
 }
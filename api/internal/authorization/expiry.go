@@ -0,0 +1,124 @@
+package authorization
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	portainer "github.com/portainer/portainer/api"
+)
+
+// grantExpiryInterval is the interval between two removals of the expired access grants
+const grantExpiryInterval = time.Minute
+
+var errInvalidAccessPolicyExpiry = errors.New("Invalid access policy expiry. Value must be a Unix timestamp in the future, or 0 when the policy does not expire")
+
+// AccessPolicyExpired returns true if the access policy has an expiry date which is passed
+func AccessPolicyExpired(policy portainer.AccessPolicy, now time.Time) bool {
+	return policy.ExpiresAt != 0 && policy.ExpiresAt <= now.Unix()
+}
+
+// TeamResourceAccessExpired returns true if the team access has an expiry date which is passed
+func TeamResourceAccessExpired(access portainer.TeamResourceAccess, now time.Time) bool {
+	return access.ExpiresAt != 0 && access.ExpiresAt <= now.Unix()
+}
+
+// ValidateAccessPolicies ensures that the access policies either do not expire or expire in the future
+func ValidateAccessPolicies(userPolicies portainer.UserAccessPolicies, teamPolicies portainer.TeamAccessPolicies) error {
+	now := time.Now()
+
+	for _, policy := range userPolicies {
+		if policy.ExpiresAt < 0 || AccessPolicyExpired(policy, now) {
+			return errInvalidAccessPolicyExpiry
+		}
+	}
+
+	for _, policy := range teamPolicies {
+		if policy.ExpiresAt < 0 || AccessPolicyExpired(policy, now) {
+			return errInvalidAccessPolicyExpiry
+		}
+	}
+
+	return nil
+}
+
+// StartGrantExpiryJob starts a background routine which removes the expired access grants every minute,
+// until the shutdown context is done. The expired grants are ignored as soon as they expire, the routine
+// only cleans them up.
+func (service *Service) StartGrantExpiryJob(shutdownCtx context.Context) {
+	go func() {
+		ticker := time.NewTicker(grantExpiryInterval)
+		defer ticker.Stop()
+
+		for {
+			_, err := service.RemoveExpiredGrants(time.Now())
+			if err != nil {
+				log.Printf("[ERROR] [authorization,expiry] [message: background schedule error (access grant expiry)] [error: %s]", err)
+			}
+
+			select {
+			case <-ticker.C:
+			case <-shutdownCtx.Done():
+				log.Println("[DEBUG] [authorization,expiry] [message: shutting down access grant expiry]")
+				return
+			}
+		}
+	}()
+}
+
+// RemoveExpiredGrants removes the expired access policies of the endpoints and endpoint groups, and the expired
+// team accesses of the resource controls in a single transaction. The authorizations of the users are computed
+// again when a grant is removed. It returns the number of grants removed.
+func (service *Service) RemoveExpiredGrants(now time.Time) (int, error) {
+	removed, err := service.dataStore.RemoveExpiredAccessGrants(now)
+	if err != nil || removed == 0 {
+		return removed, err
+	}
+
+	return removed, service.UpdateUsersAuthorizations()
+}
+
+// RemoveExpiredTeamAccesses removes the expired team accesses of a resource control and returns the number
+// of accesses removed.
+func RemoveExpiredTeamAccesses(resourceControl *portainer.ResourceControl, now time.Time) int {
+	teamAccesses := make([]portainer.TeamResourceAccess, 0, len(resourceControl.TeamAccesses))
+	for _, access := range resourceControl.TeamAccesses {
+		if TeamResourceAccessExpired(access, now) {
+			log.Printf("[INFO] [authorization,expiry] [audit] [message: expired access grant removed] [resource_control: %d] [team: %d] [reason: %s]", resourceControl.ID, access.TeamID, access.Reason)
+			continue
+		}
+		teamAccesses = append(teamAccesses, access)
+	}
+
+	removed := len(resourceControl.TeamAccesses) - len(teamAccesses)
+	if removed > 0 {
+		resourceControl.TeamAccesses = teamAccesses
+	}
+
+	return removed
+}
+
+// RemoveExpiredPolicies removes the expired policies from the maps and returns the number of policies removed.
+// The kind and ID identify the owner of the policies in the logs.
+func RemoveExpiredPolicies(userPolicies portainer.UserAccessPolicies, teamPolicies portainer.TeamAccessPolicies, now time.Time, kind string, ID int) int {
+	removed := 0
+
+	for userID, policy := range userPolicies {
+		if AccessPolicyExpired(policy, now) {
+			delete(userPolicies, userID)
+			removed++
+			log.Printf("[INFO] [authorization,expiry] [audit] [message: expired access grant removed] [%s: %d] [user: %d] [reason: %s]", kind, ID, userID, policy.Reason)
+		}
+	}
+
+	for teamID, policy := range teamPolicies {
+		if AccessPolicyExpired(policy, now) {
+			delete(teamPolicies, teamID)
+			removed++
+			log.Printf("[INFO] [authorization,expiry] [audit] [message: expired access grant removed] [%s: %d] [team: %d] [reason: %s]", kind, ID, teamID, policy.Reason)
+		}
+	}
+
+	return removed
+}
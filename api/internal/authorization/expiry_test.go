@@ -0,0 +1,92 @@
+package authorization_test
+
+import (
+	"testing"
+	"time"
+
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/bolt/bolttest"
+	"github.com/portainer/portainer/api/internal/authorization"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestService_RemoveExpiredGrants(t *testing.T) {
+	store, teardown := bolttest.MustNewTestStore(true)
+	defer teardown()
+
+	now := time.Now()
+	expired := now.Add(-time.Minute).Unix()
+	active := now.Add(time.Hour).Unix()
+
+	role := &portainer.Role{Name: "on-call", Priority: 1, Authorizations: portainer.Authorizations{portainer.OperationDockerContainerList: true}}
+	assert.NoError(t, store.Role().CreateRole(role))
+
+	user := &portainer.User{Username: "bob", Role: portainer.StandardUserRole}
+	assert.NoError(t, store.User().CreateUser(user))
+
+	endpoint := &portainer.Endpoint{
+		ID:                 1,
+		Name:               "endpoint",
+		GroupID:            portainer.EndpointGroupID(1),
+		UserAccessPolicies: portainer.UserAccessPolicies{user.ID: {RoleID: role.ID, ExpiresAt: expired, Reason: "incident"}},
+		TeamAccessPolicies: portainer.TeamAccessPolicies{1: {RoleID: role.ID, ExpiresAt: active}, 2: {RoleID: role.ID}},
+	}
+	assert.NoError(t, store.Endpoint().CreateEndpoint(endpoint))
+
+	resourceControl := &portainer.ResourceControl{
+		ResourceID:   "container",
+		Type:         portainer.ContainerResourceControl,
+		TeamAccesses: []portainer.TeamResourceAccess{{TeamID: 1, ExpiresAt: expired}, {TeamID: 2}},
+	}
+	assert.NoError(t, store.ResourceControl().CreateResourceControl(resourceControl))
+
+	service := authorization.NewService(store)
+
+	t.Run("the expired grants are ignored before they are removed", func(t *testing.T) {
+		assert.NoError(t, service.UpdateUsersAuthorizations())
+
+		user, err := store.User().User(user.ID)
+		assert.NoError(t, err)
+		assert.Empty(t, user.EndpointAuthorizations[endpoint.ID])
+
+		assert.False(t, authorization.UserCanAccessResource(user.ID, []portainer.TeamID{1}, resourceControl))
+	})
+
+	t.Run("the expired grants are removed", func(t *testing.T) {
+		removed, err := service.RemoveExpiredGrants(now)
+		assert.NoError(t, err)
+		assert.Equal(t, 2, removed)
+
+		endpoint, err := store.Endpoint().Endpoint(endpoint.ID)
+		assert.NoError(t, err)
+		assert.Empty(t, endpoint.UserAccessPolicies)
+		assert.Len(t, endpoint.TeamAccessPolicies, 2)
+
+		resourceControl, err := store.ResourceControl().ResourceControl(resourceControl.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, []portainer.TeamResourceAccess{{TeamID: 2}}, resourceControl.TeamAccesses)
+
+		removed, err = service.RemoveExpiredGrants(now)
+		assert.NoError(t, err)
+		assert.Equal(t, 0, removed)
+	})
+}
+
+func TestValidateAccessPolicies(t *testing.T) {
+	now := time.Now()
+
+	assert.NoError(t, authorization.ValidateAccessPolicies(
+		portainer.UserAccessPolicies{1: {RoleID: 1}, 2: {RoleID: 1, ExpiresAt: now.Add(time.Hour).Unix()}},
+		portainer.TeamAccessPolicies{1: {RoleID: 1}},
+	))
+
+	assert.Error(t, authorization.ValidateAccessPolicies(
+		portainer.UserAccessPolicies{1: {RoleID: 1, ExpiresAt: now.Add(-time.Minute).Unix()}},
+		portainer.TeamAccessPolicies{},
+	))
+
+	assert.Error(t, authorization.ValidateAccessPolicies(
+		portainer.UserAccessPolicies{},
+		portainer.TeamAccessPolicies{1: {RoleID: 1, ExpiresAt: -1}},
+	))
+}
@@ -0,0 +1,284 @@
+package authorization
+
+import (
+	"time"
+
+	portainer "github.com/portainer/portainer/api"
+)
+
+// Sources of the access policy granting a role to a user on an endpoint, in evaluation order
+const (
+	// PolicySourceUserEndpoint is a user access policy of the endpoint
+	PolicySourceUserEndpoint = "user_endpoint_policy"
+	// PolicySourceUserEndpointGroup is a user access policy of the endpoint group
+	PolicySourceUserEndpointGroup = "user_endpoint_group_policy"
+	// PolicySourceTeamEndpoint is an access policy of the endpoint for a team of the user
+	PolicySourceTeamEndpoint = "team_endpoint_policy"
+	// PolicySourceTeamEndpointGroup is an access policy of the endpoint group for a team of the user
+	PolicySourceTeamEndpointGroup = "team_endpoint_group_policy"
+)
+
+// Rules producing a resource control decision
+const (
+	// ResourceControlRuleAdministrator grants the access to the administrators
+	ResourceControlRuleAdministrator = "administrator"
+	// ResourceControlRuleNoResourceControl denies the access to a resource without resource control
+	ResourceControlRuleNoResourceControl = "no_resource_control"
+	// ResourceControlRuleUserAccess grants the access to a user listed in the resource control
+	ResourceControlRuleUserAccess = "user_access"
+	// ResourceControlRuleTeamAccess grants the access to a member of a team listed in the resource control
+	ResourceControlRuleTeamAccess = "team_access"
+	// ResourceControlRulePublic grants the access to every user
+	ResourceControlRulePublic = "public"
+	// ResourceControlRuleAdministratorsOnly denies the access to the users who are not administrators
+	ResourceControlRuleAdministratorsOnly = "administrators_only"
+	// ResourceControlRuleNotListed denies the access to a user who is not listed in the resource control
+	ResourceControlRuleNotListed = "not_listed"
+)
+
+type (
+	// AccessPolicyExplanation describes an access policy applying to a user on an endpoint
+	AccessPolicyExplanation struct {
+		// Source of the policy
+		Source string `json:"Source" example:"team_endpoint_policy"`
+		// Team of the user the policy is defined for, only set for the team policies
+		TeamID portainer.TeamID `json:"TeamId,omitempty" example:"1"`
+		// Endpoint group the policy is defined on, only set for the endpoint group policies
+		EndpointGroupID portainer.EndpointGroupID `json:"EndpointGroupId,omitempty" example:"1"`
+		// Role referenced by the policy
+		RoleID portainer.RoleID `json:"RoleId" example:"1"`
+		// Name of the role, empty when the role does not exist
+		RoleName string `json:"RoleName" example:"Endpoint administrator"`
+		// Priority of the role
+		Priority int `json:"Priority" example:"1"`
+		// Unix timestamp at which the policy expires, 0 when the policy does not expire
+		ExpiresAt int64 `json:"ExpiresAt,omitempty" example:"1587399600"`
+		// Reason the policy was granted
+		Reason string `json:"Reason,omitempty" example:"On-call incident #42"`
+		// Whether the policy is expired, an expired policy is ignored until it is removed
+		Expired bool `json:"Expired" example:"false"`
+		// Whether the policy produced the effective role
+		Applied bool `json:"Applied" example:"true"`
+	}
+
+	// ResourceControlDecision describes whether a user can access a resource and the rule that decided it
+	ResourceControlDecision struct {
+		// Identifier of the resource
+		ResourceID string `json:"ResourceId" example:"617c5f22bb9b023d6daab7cba43a57576f83492867bc767d1c59416b065e5f08"`
+		// Resource control of the resource, nil when the resource has no resource control
+		ResourceControl *portainer.ResourceControl `json:"ResourceControl"`
+		// Whether the user can access the resource
+		Allowed bool `json:"Allowed" example:"true"`
+		// Rule that produced the decision
+		Rule string `json:"Rule" example:"team_access"`
+		// Team of the user granting the access, only set for the team_access rule
+		TeamID portainer.TeamID `json:"TeamId,omitempty" example:"1"`
+	}
+
+	// EndpointAccessExplanation describes the effective access of a user to an endpoint and how it was computed
+	EndpointAccessExplanation struct {
+		UserID     portainer.UserID     `json:"UserId" example:"2"`
+		EndpointID portainer.EndpointID `json:"EndpointId" example:"1"`
+		// Whether the user is an administrator, in which case every operation is authorized
+		Administrator bool `json:"Administrator" example:"false"`
+		// Effective role of the user on the endpoint, nil when no policy applies
+		Role *portainer.Role `json:"Role"`
+		// Source of the policy granting the effective role
+		Source string `json:"Source,omitempty" example:"team_endpoint_policy"`
+		// Team granting the effective role, only set when the role comes from a team policy
+		TeamID portainer.TeamID `json:"TeamId,omitempty" example:"1"`
+		// Every policy applying to the user on the endpoint, in evaluation order
+		Policies []AccessPolicyExplanation `json:"Policies"`
+		// Operations authorized on the endpoint
+		Authorizations portainer.Authorizations `json:"Authorizations"`
+		// Operation checked by the request
+		Operation portainer.Authorization `json:"Operation,omitempty" example:"DockerContainerList"`
+		// Whether the operation is authorized, only set when an operation is checked
+		OperationAllowed *bool `json:"OperationAllowed,omitempty" example:"true"`
+		// Resource control decision, only set when a resource is checked
+		ResourceControlDecision *ResourceControlDecision `json:"ResourceControlDecision,omitempty"`
+	}
+)
+
+// ExplainEndpointAccess returns the effective role and authorizations of a user on an endpoint, along with the
+// policies they come from. The effective role is the one decided when the authorizations of the users are updated.
+func (service *Service) ExplainEndpointAccess(user *portainer.User, endpoint *portainer.Endpoint) (*EndpointAccessExplanation, error) {
+	explanation := &EndpointAccessExplanation{
+		UserID:         user.ID,
+		EndpointID:     endpoint.ID,
+		Administrator:  user.Role == portainer.AdministratorRole,
+		Policies:       make([]AccessPolicyExplanation, 0),
+		Authorizations: portainer.Authorizations{},
+	}
+
+	userMemberships, err := service.dataStore.TeamMembership().TeamMembershipsByUserID(user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	roles, err := service.dataStore.Role().Roles()
+	if err != nil {
+		return nil, err
+	}
+
+	var endpointGroup *portainer.EndpointGroup
+	if endpoint.GroupID != 0 {
+		endpointGroup, err = service.dataStore.EndpointGroup().EndpointGroup(endpoint.GroupID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	explainEndpointPolicies(explanation, user, endpoint, endpointGroup, roles, userMemberships)
+
+	return explanation, nil
+}
+
+// ExplainOperation records whether the operation is authorized by the explained access
+func (explanation *EndpointAccessExplanation) ExplainOperation(operation portainer.Authorization) {
+	allowed := explanation.Administrator || explanation.Authorizations[operation]
+	explanation.Operation = operation
+	explanation.OperationAllowed = &allowed
+}
+
+// explainEndpointPolicies lists the policies applying to the user on the endpoint and marks the one producing the
+// role decided by getUserEndpointAuthorizations, so that the explanation cannot diverge from the real authorizations
+func explainEndpointPolicies(explanation *EndpointAccessExplanation, user *portainer.User, endpoint *portainer.Endpoint, endpointGroup *portainer.EndpointGroup, roles []portainer.Role, memberships []portainer.TeamMembership) {
+	endpointGroups := make([]portainer.EndpointGroup, 0)
+	sources := [][]AccessPolicyExplanation{
+		userPolicies(PolicySourceUserEndpoint, 0, endpoint.UserAccessPolicies, user.ID, roles),
+		nil,
+		teamPolicies(PolicySourceTeamEndpoint, 0, endpoint.TeamAccessPolicies, memberships, roles),
+		nil,
+	}
+	if endpointGroup != nil {
+		endpointGroups = append(endpointGroups, *endpointGroup)
+		sources[1] = userPolicies(PolicySourceUserEndpointGroup, endpointGroup.ID, endpointGroup.UserAccessPolicies, user.ID, roles)
+		sources[3] = teamPolicies(PolicySourceTeamEndpointGroup, endpointGroup.ID, endpointGroup.TeamAccessPolicies, memberships, roles)
+	}
+
+	_, decisions := getUserEndpointAuthorizations(user, []portainer.Endpoint{*endpoint}, endpointGroups, roles, memberships)
+	decision, decided := decisions[endpoint.ID]
+	if decided {
+		explanation.Role = decision.role
+		explanation.Source = decision.source
+		explanation.TeamID = decision.teamID
+		explanation.Authorizations = decision.role.Authorizations
+	}
+
+	for _, policies := range sources {
+		for idx := range policies {
+			policies[idx].Applied = decided && policies[idx].Source == decision.source && policies[idx].TeamID == decision.teamID
+		}
+
+		explanation.Policies = append(explanation.Policies, policies...)
+	}
+}
+
+func userPolicies(source string, endpointGroupID portainer.EndpointGroupID, accessPolicies portainer.UserAccessPolicies, userID portainer.UserID, roles []portainer.Role) []AccessPolicyExplanation {
+	policies := make([]AccessPolicyExplanation, 0)
+
+	policy, ok := accessPolicies[userID]
+	if ok {
+		policies = append(policies, newAccessPolicyExplanation(source, 0, endpointGroupID, policy, roles))
+	}
+
+	return policies
+}
+
+func teamPolicies(source string, endpointGroupID portainer.EndpointGroupID, accessPolicies portainer.TeamAccessPolicies, memberships []portainer.TeamMembership, roles []portainer.Role) []AccessPolicyExplanation {
+	policies := make([]AccessPolicyExplanation, 0)
+
+	for _, membership := range memberships {
+		policy, ok := accessPolicies[membership.TeamID]
+		if ok {
+			policies = append(policies, newAccessPolicyExplanation(source, membership.TeamID, endpointGroupID, policy, roles))
+		}
+	}
+
+	return policies
+}
+
+func newAccessPolicyExplanation(source string, teamID portainer.TeamID, endpointGroupID portainer.EndpointGroupID, accessPolicy portainer.AccessPolicy, roles []portainer.Role) AccessPolicyExplanation {
+	policy := AccessPolicyExplanation{
+		Source:          source,
+		TeamID:          teamID,
+		EndpointGroupID: endpointGroupID,
+		RoleID:          accessPolicy.RoleID,
+		ExpiresAt:       accessPolicy.ExpiresAt,
+		Reason:          accessPolicy.Reason,
+		Expired:         AccessPolicyExpired(accessPolicy, time.Now()),
+	}
+
+	role := findRole(accessPolicy.RoleID, roles)
+	if role != nil {
+		policy.RoleName = role.Name
+		policy.Priority = role.Priority
+	}
+
+	return policy
+}
+
+func findRole(roleID portainer.RoleID, roles []portainer.Role) *portainer.Role {
+	for idx := range roles {
+		if roles[idx].ID == roleID {
+			return &roles[idx]
+		}
+	}
+	return nil
+}
+
+// ExplainResourceControl returns whether a user can access a resource protected by a resource control, using
+// the same rules as UserCanAccessResource. A nil resource control denies the access to the users who are not
+// administrators.
+func ExplainResourceControl(resourceID string, resourceControl *portainer.ResourceControl, user *portainer.User, userTeamIDs []portainer.TeamID) *ResourceControlDecision {
+	decision := &ResourceControlDecision{
+		ResourceID:      resourceID,
+		ResourceControl: resourceControl,
+	}
+
+	switch {
+	case user.Role == portainer.AdministratorRole:
+		decision.Allowed = true
+		decision.Rule = ResourceControlRuleAdministrator
+		return decision
+	case resourceControl == nil:
+		decision.Rule = ResourceControlRuleNoResourceControl
+		return decision
+	}
+
+	for _, access := range resourceControl.UserAccesses {
+		if access.UserID == user.ID {
+			decision.Allowed = true
+			decision.Rule = ResourceControlRuleUserAccess
+			return decision
+		}
+	}
+
+	for _, access := range resourceControl.TeamAccesses {
+		if TeamResourceAccessExpired(access, time.Now()) {
+			continue
+		}
+
+		for _, teamID := range userTeamIDs {
+			if access.TeamID == teamID {
+				decision.Allowed = true
+				decision.Rule = ResourceControlRuleTeamAccess
+				decision.TeamID = teamID
+				return decision
+			}
+		}
+	}
+
+	switch {
+	case resourceControl.Public:
+		decision.Allowed = true
+		decision.Rule = ResourceControlRulePublic
+	case resourceControl.AdministratorsOnly:
+		decision.Rule = ResourceControlRuleAdministratorsOnly
+	default:
+		decision.Rule = ResourceControlRuleNotListed
+	}
+
+	return decision
+}
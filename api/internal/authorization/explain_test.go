@@ -0,0 +1,134 @@
+package authorization
+
+import (
+	"testing"
+
+	portainer "github.com/portainer/portainer/api"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_explainEndpointPolicies(t *testing.T) {
+	roles := []portainer.Role{
+		{ID: 1, Name: "Endpoint administrator", Priority: 1, Authorizations: portainer.Authorizations{portainer.OperationDockerContainerDelete: true}},
+		{ID: 2, Name: "Helpdesk", Priority: 2, Authorizations: portainer.Authorizations{portainer.OperationDockerContainerInspect: true}},
+		{ID: 3, Name: "Standard user", Priority: 3, Authorizations: portainer.Authorizations{portainer.OperationDockerContainerList: true}},
+	}
+	user := &portainer.User{ID: 2, Role: portainer.StandardUserRole}
+	memberships := []portainer.TeamMembership{{UserID: 2, TeamID: 1}, {UserID: 2, TeamID: 2}}
+
+	endpointGroup := &portainer.EndpointGroup{
+		ID:                 5,
+		UserAccessPolicies: portainer.UserAccessPolicies{},
+		TeamAccessPolicies: portainer.TeamAccessPolicies{1: {RoleID: 1}},
+	}
+
+	t.Run("the team policy with the highest priority applies", func(t *testing.T) {
+		endpoint := &portainer.Endpoint{
+			ID:                 1,
+			GroupID:            5,
+			UserAccessPolicies: portainer.UserAccessPolicies{},
+			TeamAccessPolicies: portainer.TeamAccessPolicies{1: {RoleID: 2}, 2: {RoleID: 3}},
+		}
+		explanation := &EndpointAccessExplanation{Authorizations: portainer.Authorizations{}}
+
+		explainEndpointPolicies(explanation, user, endpoint, endpointGroup, roles, memberships)
+
+		assert.Equal(t, portainer.RoleID(3), explanation.Role.ID)
+		assert.Equal(t, PolicySourceTeamEndpoint, explanation.Source)
+		assert.Equal(t, portainer.TeamID(2), explanation.TeamID)
+		assert.Len(t, explanation.Policies, 3)
+		assert.False(t, explanation.Policies[0].Applied)
+		assert.True(t, explanation.Policies[1].Applied)
+		assert.Equal(t, PolicySourceTeamEndpointGroup, explanation.Policies[2].Source)
+
+		explanation.ExplainOperation(portainer.OperationDockerContainerList)
+		assert.True(t, *explanation.OperationAllowed)
+		explanation.ExplainOperation(portainer.OperationDockerContainerDelete)
+		assert.False(t, *explanation.OperationAllowed)
+	})
+
+	t.Run("a user policy prevails over the team policies", func(t *testing.T) {
+		endpoint := &portainer.Endpoint{
+			ID:                 1,
+			GroupID:            5,
+			UserAccessPolicies: portainer.UserAccessPolicies{},
+			TeamAccessPolicies: portainer.TeamAccessPolicies{2: {RoleID: 3}},
+		}
+		group := &portainer.EndpointGroup{
+			ID:                 5,
+			UserAccessPolicies: portainer.UserAccessPolicies{2: {RoleID: 1}},
+			TeamAccessPolicies: portainer.TeamAccessPolicies{},
+		}
+		explanation := &EndpointAccessExplanation{Authorizations: portainer.Authorizations{}}
+
+		explainEndpointPolicies(explanation, user, endpoint, group, roles, memberships)
+
+		assert.Equal(t, portainer.RoleID(1), explanation.Role.ID)
+		assert.Equal(t, PolicySourceUserEndpointGroup, explanation.Source)
+		assert.Equal(t, portainer.TeamID(0), explanation.TeamID)
+	})
+
+	t.Run("a role without authorizations does not apply", func(t *testing.T) {
+		emptyRoles := append([]portainer.Role{{ID: 4, Name: "Empty", Priority: 4, Authorizations: portainer.Authorizations{}}}, roles...)
+		endpoint := &portainer.Endpoint{
+			ID:                 1,
+			GroupID:            5,
+			UserAccessPolicies: portainer.UserAccessPolicies{2: {RoleID: 4}},
+			TeamAccessPolicies: portainer.TeamAccessPolicies{},
+		}
+		explanation := &EndpointAccessExplanation{Authorizations: portainer.Authorizations{}}
+
+		explainEndpointPolicies(explanation, user, endpoint, endpointGroup, emptyRoles, memberships)
+
+		assert.Equal(t, portainer.RoleID(1), explanation.Role.ID)
+		assert.Equal(t, PolicySourceTeamEndpointGroup, explanation.Source)
+		assert.False(t, explanation.Policies[0].Applied)
+		assert.True(t, explanation.Policies[1].Applied)
+
+		endpointAuthorizations, _ := getUserEndpointAuthorizations(user, []portainer.Endpoint{*endpoint}, []portainer.EndpointGroup{*endpointGroup}, emptyRoles, memberships)
+		assert.Equal(t, explanation.Authorizations, endpointAuthorizations[endpoint.ID])
+	})
+
+	t.Run("no role applies without policy", func(t *testing.T) {
+		endpoint := &portainer.Endpoint{ID: 1, GroupID: 1}
+		explanation := &EndpointAccessExplanation{Authorizations: portainer.Authorizations{}}
+
+		explainEndpointPolicies(explanation, user, endpoint, nil, roles, memberships)
+
+		assert.Nil(t, explanation.Role)
+		assert.Empty(t, explanation.Policies)
+	})
+}
+
+func TestExplainResourceControl(t *testing.T) {
+	user := &portainer.User{ID: 2, Role: portainer.StandardUserRole}
+	admin := &portainer.User{ID: 1, Role: portainer.AdministratorRole}
+
+	tests := []struct {
+		name            string
+		user            *portainer.User
+		resourceControl *portainer.ResourceControl
+		allowed         bool
+		rule            string
+	}{
+		{"administrator", admin, nil, true, ResourceControlRuleAdministrator},
+		{"no resource control", user, nil, false, ResourceControlRuleNoResourceControl},
+		{"user access", user, &portainer.ResourceControl{UserAccesses: []portainer.UserResourceAccess{{UserID: 2}}}, true, ResourceControlRuleUserAccess},
+		{"team access", user, &portainer.ResourceControl{TeamAccesses: []portainer.TeamResourceAccess{{TeamID: 3}}}, true, ResourceControlRuleTeamAccess},
+		{"public", user, &portainer.ResourceControl{Public: true}, true, ResourceControlRulePublic},
+		{"administrators only", user, &portainer.ResourceControl{AdministratorsOnly: true}, false, ResourceControlRuleAdministratorsOnly},
+		{"not listed", user, &portainer.ResourceControl{UserAccesses: []portainer.UserResourceAccess{{UserID: 4}}}, false, ResourceControlRuleNotListed},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			decision := ExplainResourceControl("resource", test.resourceControl, test.user, []portainer.TeamID{3})
+
+			assert.Equal(t, test.allowed, decision.Allowed)
+			assert.Equal(t, test.rule, decision.Rule)
+			if test.resourceControl != nil && test.user != admin {
+				assert.Equal(t, UserCanAccessResource(test.user.ID, []portainer.TeamID{3}, test.resourceControl), decision.Allowed)
+			}
+		})
+	}
+}
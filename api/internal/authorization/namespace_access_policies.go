@@ -0,0 +1,89 @@
+package authorization
+
+import (
+	"fmt"
+
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/internal/endpointutils"
+)
+
+// RemoveUserNamespaceAccessPolicies removes a user from the namespace access policies of the Kubernetes endpoints.
+// The namespace accesses of the user are given to the user transferTo when it is set.
+func (service *Service) RemoveUserNamespaceAccessPolicies(userID, transferTo portainer.UserID) error {
+	return service.updateNamespaceAccessPolicies(func(policy portainer.K8sNamespaceAccessPolicy) bool {
+		accessPolicy, ok := policy.UserAccessPolicies[userID]
+		if !ok {
+			return false
+		}
+		delete(policy.UserAccessPolicies, userID)
+
+		if _, exists := policy.UserAccessPolicies[transferTo]; transferTo != 0 && !exists {
+			policy.UserAccessPolicies[transferTo] = accessPolicy
+		}
+		return true
+	})
+}
+
+// RemoveTeamNamespaceAccessPolicies removes a team from the namespace access policies of the Kubernetes endpoints.
+func (service *Service) RemoveTeamNamespaceAccessPolicies(teamID portainer.TeamID) error {
+	return service.updateNamespaceAccessPolicies(func(policy portainer.K8sNamespaceAccessPolicy) bool {
+		if _, ok := policy.TeamAccessPolicies[teamID]; !ok {
+			return false
+		}
+		delete(policy.TeamAccessPolicies, teamID)
+		return true
+	})
+}
+
+// updateNamespaceAccessPolicies applies an update to the namespace access policies of every Kubernetes endpoint,
+// the update returns whether the policy changed. The policies are stored in the clusters, an endpoint that
+// cannot be reached fails the update.
+func (service *Service) updateNamespaceAccessPolicies(update func(policy portainer.K8sNamespaceAccessPolicy) bool) error {
+	if service.K8sClientFactory == nil {
+		return nil
+	}
+
+	endpoints, err := service.dataStore.Endpoint().Endpoints()
+	if err != nil {
+		return err
+	}
+
+	for idx := range endpoints {
+		endpoint := &endpoints[idx]
+		if !endpointutils.IsKubernetesEndpoint(endpoint) {
+			continue
+		}
+
+		err := service.updateEndpointNamespaceAccessPolicies(endpoint, update)
+		if err != nil {
+			return fmt.Errorf("unable to update the namespace access policies of the endpoint %d: %w", endpoint.ID, err)
+		}
+	}
+
+	return nil
+}
+
+func (service *Service) updateEndpointNamespaceAccessPolicies(endpoint *portainer.Endpoint, update func(policy portainer.K8sNamespaceAccessPolicy) bool) error {
+	kubecli, err := service.K8sClientFactory.GetKubeClient(endpoint)
+	if err != nil {
+		return err
+	}
+
+	accessPolicies, err := kubecli.GetNamespaceAccessPolicies()
+	if err != nil {
+		return err
+	}
+
+	hasChange := false
+	for _, policy := range accessPolicies {
+		if update(policy) {
+			hasChange = true
+		}
+	}
+
+	if !hasChange {
+		return nil
+	}
+
+	return kubecli.UpdateNamespaceAccessPolicies(accessPolicies)
+}
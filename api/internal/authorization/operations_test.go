@@ -0,0 +1,48 @@
+package authorization
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"strconv"
+	"testing"
+
+	portainer "github.com/portainer/portainer/api"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOperations_shouldContainEveryAuthorizationConstant(t *testing.T) {
+	file, err := parser.ParseFile(token.NewFileSet(), "../../portainer.go", nil, 0)
+	assert.NoError(t, err)
+
+	count := 0
+	ast.Inspect(file, func(node ast.Node) bool {
+		spec, ok := node.(*ast.ValueSpec)
+		if !ok {
+			return true
+		}
+
+		if ident, ok := spec.Type.(*ast.Ident); !ok || ident.Name != "Authorization" {
+			return false
+		}
+
+		for _, value := range spec.Values {
+			literal, ok := value.(*ast.BasicLit)
+			if !ok {
+				continue
+			}
+
+			operation, _ := strconv.Unquote(literal.Value)
+			assert.True(t, IsKnownOperation(portainer.Authorization(operation)), "%s should be a known operation", operation)
+			count++
+		}
+		return false
+	})
+
+	assert.Equal(t, len(operations), count)
+}
+
+func TestValidateAuthorizations(t *testing.T) {
+	assert.NoError(t, ValidateAuthorizations([]portainer.Authorization{portainer.OperationDockerContainerList, portainer.EndpointResourcesAccess}))
+	assert.Error(t, ValidateAuthorizations([]portainer.Authorization{portainer.OperationDockerContainerList, "DockerContainerDestroyEverything"}))
+}
@@ -0,0 +1,145 @@
+package passwordpolicy
+
+import (
+	"errors"
+	"fmt"
+	"time"
+	"unicode"
+
+	portainer "github.com/portainer/portainer/api"
+)
+
+const (
+	// MaxLength is the maximum length of a password, bcrypt ignores the bytes after the 72nd
+	MaxLength = 72
+	// MaxHistorySize is the maximum number of previous passwords that can be remembered
+	MaxHistorySize = 24
+)
+
+var (
+	errMissingUppercase        = errors.New("Invalid password. Must contain at least one uppercase letter")
+	errMissingLowercase        = errors.New("Invalid password. Must contain at least one lowercase letter")
+	errMissingDigit            = errors.New("Invalid password. Must contain at least one digit")
+	errMissingSpecialCharacter = errors.New("Invalid password. Must contain at least one special character")
+	errPasswordReused          = errors.New("Invalid password. Must be different from the previous passwords")
+)
+
+// ValidatePolicy ensures that a policy can be enforced.
+func ValidatePolicy(policy portainer.PasswordPolicy) error {
+	if policy.MinLength < 0 || policy.MinLength > MaxLength {
+		return fmt.Errorf("Invalid password minimum length. Value must be between 0 and %d", MaxLength)
+	}
+	if policy.HistorySize < 0 || policy.HistorySize > MaxHistorySize {
+		return fmt.Errorf("Invalid password history size. Value must be between 0 and %d", MaxHistorySize)
+	}
+	if policy.ExpiryDays < 0 {
+		return errors.New("Invalid password expiry. Value must be a positive number of days")
+	}
+	return nil
+}
+
+// Check ensures that a password complies with the policy and that it is not one of the previous
+// passwords of the user. The returned errors can be displayed to the user.
+func Check(cryptoService portainer.CryptoService, user *portainer.User, policy portainer.PasswordPolicy, password string) error {
+	if len(password) > MaxLength {
+		return fmt.Errorf("Invalid password. Must not be longer than %d characters", MaxLength)
+	}
+	if len([]rune(password)) < policy.MinLength {
+		return fmt.Errorf("Invalid password. Must be at least %d characters long", policy.MinLength)
+	}
+
+	var hasUppercase, hasLowercase, hasDigit, hasSpecialCharacter bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			hasUppercase = true
+		case unicode.IsLower(r):
+			hasLowercase = true
+		case unicode.IsDigit(r):
+			hasDigit = true
+		case !unicode.IsLetter(r):
+			hasSpecialCharacter = true
+		}
+	}
+
+	switch {
+	case policy.RequireUppercase && !hasUppercase:
+		return errMissingUppercase
+	case policy.RequireLowercase && !hasLowercase:
+		return errMissingLowercase
+	case policy.RequireDigit && !hasDigit:
+		return errMissingDigit
+	case policy.RequireSpecialCharacter && !hasSpecialCharacter:
+		return errMissingSpecialCharacter
+	}
+
+	for _, hash := range previousPasswords(user, policy.HistorySize) {
+		if cryptoService.CompareHashAndData(hash, password) == nil {
+			return errPasswordReused
+		}
+	}
+
+	return nil
+}
+
+// SetPassword hashes the password of the user and remembers the previous one according to the history
+// size of the policy. The password must have been checked with Check, the caller must persist the user.
+func SetPassword(cryptoService portainer.CryptoService, user *portainer.User, policy portainer.PasswordPolicy, password string, now time.Time) error {
+	hash, err := cryptoService.Hash(password)
+	if err != nil {
+		return err
+	}
+	if hash == "" {
+		return errors.New("Unable to hash data")
+	}
+
+	history := make([]string, 0)
+	if user.Password != "" && policy.HistorySize > 1 {
+		history = append(history, user.Password)
+		history = append(history, user.PasswordHistory...)
+		if len(history) > policy.HistorySize-1 {
+			history = history[:policy.HistorySize-1]
+		}
+	}
+
+	user.Password = hash
+	user.PasswordHistory = history
+	user.PasswordChangeDate = now.Unix()
+
+	return nil
+}
+
+// Expired returns whether the password of the user is older than the expiry of the policy.
+// Passwords without a change date never expire, see Track.
+func Expired(user *portainer.User, policy portainer.PasswordPolicy, now time.Time) bool {
+	if policy.ExpiryDays == 0 || user.Password == "" || user.PasswordChangeDate == 0 {
+		return false
+	}
+
+	expiryDate := time.Unix(user.PasswordChangeDate, 0).AddDate(0, 0, policy.ExpiryDays)
+	return now.After(expiryDate)
+}
+
+// Track sets the change date of a password set before the expiry was introduced, so that it
+// expires after the policy duration. It returns true when the user was updated.
+func Track(user *portainer.User, now time.Time) bool {
+	if user.Password == "" || user.PasswordChangeDate != 0 {
+		return false
+	}
+
+	user.PasswordChangeDate = now.Unix()
+	return true
+}
+
+// previousPasswords returns the hashes that cannot be reused, including the current password.
+func previousPasswords(user *portainer.User, historySize int) []string {
+	if historySize == 0 || user.Password == "" {
+		return nil
+	}
+
+	hashes := append([]string{user.Password}, user.PasswordHistory...)
+	if len(hashes) > historySize {
+		hashes = hashes[:historySize]
+	}
+	return hashes
+}
@@ -0,0 +1,74 @@
+package passwordpolicy
+
+import (
+	"testing"
+	"time"
+
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/crypto"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheck(t *testing.T) {
+	policy := portainer.PasswordPolicy{
+		MinLength:               10,
+		RequireUppercase:        true,
+		RequireLowercase:        true,
+		RequireDigit:            true,
+		RequireSpecialCharacter: true,
+	}
+	user := &portainer.User{}
+
+	err := Check(&crypto.Service{}, user, policy, "Sh0rt!")
+	assert.Error(t, err, "a short password should be rejected")
+
+	tests := []struct {
+		password string
+		err      error
+	}{
+		{"nouppercase1!", errMissingUppercase},
+		{"NOLOWERCASE1!", errMissingLowercase},
+		{"NoDigitHere!", errMissingDigit},
+		{"NoSpecial123", errMissingSpecialCharacter},
+		{"Compl1ant pass", nil},
+	}
+
+	for _, tt := range tests {
+		err := Check(&crypto.Service{}, user, policy, tt.password)
+		assert.Equal(t, tt.err, err, tt.password)
+	}
+}
+
+func TestSetPassword_shouldPreventReuse(t *testing.T) {
+	cryptoService := &crypto.Service{}
+	policy := portainer.PasswordPolicy{HistorySize: 3}
+	user := &portainer.User{}
+	now := time.Now()
+
+	for _, password := range []string{"first", "second", "third", "fourth"} {
+		assert.NoError(t, Check(cryptoService, user, policy, password))
+		assert.NoError(t, SetPassword(cryptoService, user, policy, password, now))
+	}
+	assert.Len(t, user.PasswordHistory, 2)
+	assert.Equal(t, now.Unix(), user.PasswordChangeDate)
+
+	assert.Equal(t, errPasswordReused, Check(cryptoService, user, policy, "fourth"))
+	assert.Equal(t, errPasswordReused, Check(cryptoService, user, policy, "second"))
+	assert.NoError(t, Check(cryptoService, user, policy, "first"), "passwords older than the history should be accepted")
+
+	policy.HistorySize = 0
+	assert.NoError(t, Check(cryptoService, user, policy, "fourth"))
+}
+
+func TestExpired(t *testing.T) {
+	policy := portainer.PasswordPolicy{ExpiryDays: 30}
+	now := time.Now()
+	user := &portainer.User{Password: "hash"}
+
+	assert.False(t, Expired(user, policy, now), "passwords without a change date should not expire")
+	assert.True(t, Track(user, now.AddDate(0, 0, -31)))
+	assert.False(t, Track(user, now), "the change date should only be initialized once")
+
+	assert.True(t, Expired(user, policy, now))
+	assert.False(t, Expired(user, portainer.PasswordPolicy{}, now))
+}
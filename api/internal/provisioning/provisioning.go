@@ -0,0 +1,116 @@
+package provisioning
+
+import (
+	"log"
+	"strings"
+
+	portainer "github.com/portainer/portainer/api"
+)
+
+// AddUserIntoTeams adds a user to the teams whose name matches one of its groups.
+// The existing memberships of the user are kept.
+func AddUserIntoTeams(dataStore portainer.DataStore, user *portainer.User, userGroups []string) error {
+	teams, err := dataStore.Team().Teams()
+	if err != nil {
+		return err
+	}
+
+	userMemberships, err := dataStore.TeamMembership().TeamMembershipsByUserID(user.ID)
+	if err != nil {
+		return err
+	}
+
+	for _, team := range teams {
+		if !containsGroup(userGroups, team.Name) || membershipExists(team.ID, userMemberships) {
+			continue
+		}
+
+		membership := &portainer.TeamMembership{
+			UserID: user.ID,
+			TeamID: team.ID,
+			Role:   portainer.TeamMember,
+		}
+
+		err := dataStore.TeamMembership().CreateTeamMembership(membership)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// UpdateUserRoleFromGroups grants or removes the administrator role of a user according to its
+// membership of the admin groups. The tokens previously issued to the user are revoked when the role changes.
+// The source identifies the authentication method in the logs.
+func UpdateUserRoleFromGroups(dataStore portainer.DataStore, jwtService portainer.JWTService, user *portainer.User, userGroups []string, adminGroups []string, source string) error {
+	role, managed := UserRoleFromGroups(userGroups, adminGroups)
+	if !managed || user.Role == role {
+		return nil
+	}
+
+	if user.Role == portainer.AdministratorRole {
+		users, err := dataStore.User().UsersByRole(portainer.AdministratorRole)
+		if err != nil {
+			return err
+		}
+
+		if len(users) < 2 {
+			log.Printf("[WARN] [provisioning] [message: the last administrator cannot be demoted] [user: %s] [source: %s]", user.Username, source)
+			return nil
+		}
+	}
+
+	previousRole := user.Role
+	user.Role = role
+
+	err := dataStore.User().UpdateUser(user.ID, user)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("[INFO] [provisioning] [audit] [message: user role updated from group membership] [user: %s] [previous_role: %s] [role: %s] [source: %s]", user.Username, roleName(previousRole), roleName(role), source)
+
+	return jwtService.RevokeUserTokens(user.ID, "")
+}
+
+// UserRoleFromGroups returns the role matching the groups of a user. The second value is false
+// when no admin group is configured, in which case the role of the user is not managed.
+func UserRoleFromGroups(userGroups []string, adminGroups []string) (portainer.UserRole, bool) {
+	if len(adminGroups) == 0 {
+		return portainer.StandardUserRole, false
+	}
+
+	for _, adminGroup := range adminGroups {
+		if containsGroup(userGroups, adminGroup) {
+			return portainer.AdministratorRole, true
+		}
+	}
+
+	return portainer.StandardUserRole, true
+}
+
+func roleName(role portainer.UserRole) string {
+	if role == portainer.AdministratorRole {
+		return "administrator"
+	}
+	return "standard"
+}
+
+func containsGroup(groups []string, name string) bool {
+	for _, group := range groups {
+		if strings.EqualFold(group, name) {
+			return true
+		}
+	}
+	return false
+}
+
+func membershipExists(teamID portainer.TeamID, memberships []portainer.TeamMembership) bool {
+	for _, membership := range memberships {
+		if membership.TeamID == teamID {
+			return true
+		}
+	}
+	return false
+}
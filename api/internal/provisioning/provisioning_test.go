@@ -0,0 +1,50 @@
+package provisioning
+
+import (
+	"testing"
+
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/bolt/bolttest"
+	"github.com/portainer/portainer/api/jwt"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUpdateUserRoleFromGroups(t *testing.T) {
+	store, teardown := bolttest.MustNewTestStore(true)
+	defer teardown()
+
+	jwtService, err := jwt.NewService("24h", store, []byte("secret"))
+	assert.NoError(t, err)
+
+	admin := &portainer.User{Username: "admin", Role: portainer.AdministratorRole}
+	store.User().CreateUser(admin)
+	user := &portainer.User{Username: "alice", Role: portainer.StandardUserRole}
+	store.User().CreateUser(user)
+
+	err = UpdateUserRoleFromGroups(store, jwtService, user, []string{"Admins"}, nil, "ldap")
+	assert.NoError(t, err)
+	assert.Equal(t, portainer.StandardUserRole, user.Role, "the role should not be managed without admin groups")
+
+	err = UpdateUserRoleFromGroups(store, jwtService, user, []string{"devs", "Admins"}, []string{"admins"}, "ldap")
+	assert.NoError(t, err)
+	user, _ = store.User().User(user.ID)
+	assert.Equal(t, portainer.AdministratorRole, user.Role)
+
+	jwtService.GenerateToken(&portainer.TokenData{ID: user.ID, Username: user.Username, Role: user.Role})
+
+	err = UpdateUserRoleFromGroups(store, jwtService, user, []string{"devs"}, []string{"admins"}, "ldap")
+	assert.NoError(t, err)
+	user, _ = store.User().User(user.ID)
+	assert.Equal(t, portainer.StandardUserRole, user.Role)
+
+	tokens, _ := store.IssuedToken().IssuedTokensByUserID(user.ID)
+	assert.True(t, tokens[0].Revoked, "the tokens issued with the previous role should be revoked")
+
+	store.User().DeleteUser(admin.ID)
+	user.Role = portainer.AdministratorRole
+	store.User().UpdateUser(user.ID, user)
+
+	err = UpdateUserRoleFromGroups(store, jwtService, user, []string{}, []string{"admins"}, "oauth")
+	assert.NoError(t, err)
+	assert.Equal(t, portainer.AdministratorRole, user.Role, "the last administrator should not be demoted")
+}
@@ -82,7 +82,7 @@ func SupportDirectSnapshot(endpoint *portainer.Endpoint) bool {
 }
 
 // SnapshotEndpoint will create a snapshot of the endpoint based on the endpoint type.
-// If the snapshot is a success, it will be associated to the endpoint.
+// If the snapshot is a success, it will be stored and the summary of a Docker snapshot will be associated to the endpoint.
 func (service *Service) SnapshotEndpoint(endpoint *portainer.Endpoint) error {
 	switch endpoint.Type {
 	case portainer.AzureEnvironment:
@@ -101,7 +101,7 @@ func (service *Service) snapshotKubernetesEndpoint(endpoint *portainer.Endpoint)
 	}
 
 	if snapshot != nil {
-		endpoint.Kubernetes.Snapshots = []portainer.KubernetesSnapshot{*snapshot}
+		return service.dataStore.Snapshot().UpdateSnapshot(&portainer.Snapshot{EndpointID: endpoint.ID, Kubernetes: snapshot})
 	}
 
 	return nil
@@ -114,7 +114,15 @@ func (service *Service) snapshotDockerEndpoint(endpoint *portainer.Endpoint) err
 	}
 
 	if snapshot != nil {
-		endpoint.Snapshots = []portainer.DockerSnapshot{*snapshot}
+		err := service.dataStore.Snapshot().UpdateSnapshot(&portainer.Snapshot{EndpointID: endpoint.ID, Docker: snapshot})
+		if err != nil {
+			return err
+		}
+
+		// the raw data is only kept in the snapshot, the endpoint keeps the summary
+		summary := *snapshot
+		summary.SnapshotRaw = portainer.DockerSnapshotRaw{}
+		endpoint.Snapshots = []portainer.DockerSnapshot{summary}
 	}
 
 	return nil
@@ -176,7 +184,6 @@ func (service *Service) snapshotEndpoints() error {
 		}
 
 		latestEndpointReference.Snapshots = endpoint.Snapshots
-		latestEndpointReference.Kubernetes.Snapshots = endpoint.Kubernetes.Snapshots
 
 		err = service.dataStore.Endpoint().UpdateEndpoint(latestEndpointReference.ID, latestEndpointReference)
 		if err != nil {
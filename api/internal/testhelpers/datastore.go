@@ -2,11 +2,14 @@ package testhelpers
 
 import (
 	"io"
+	"time"
 
 	portainer "github.com/portainer/portainer/api"
 )
 
 type datastore struct {
+	apiKey           portainer.APIKeyService
+	auditLog         portainer.AuditLogService
 	dockerHub        portainer.DockerHubService
 	customTemplate   portainer.CustomTemplateService
 	edgeGroup        portainer.EdgeGroupService
@@ -15,10 +18,13 @@ type datastore struct {
 	endpoint         portainer.EndpointService
 	endpointGroup    portainer.EndpointGroupService
 	endpointRelation portainer.EndpointRelationService
+	issuedToken      portainer.IssuedTokenService
+	jwtSigningKey    portainer.JWTSigningKeyService
 	registry         portainer.RegistryService
 	resourceControl  portainer.ResourceControlService
 	role             portainer.RoleService
 	settings         portainer.SettingsService
+	snapshot         portainer.EndpointSnapshotService
 	stack            portainer.StackService
 	tag              portainer.TagService
 	teamMembership   portainer.TeamMembershipService
@@ -37,6 +43,8 @@ func (d *datastore) CheckCurrentEdition() error                          { retur
 func (d *datastore) IsNew() bool                                         { return false }
 func (d *datastore) MigrateData(force bool) error                        { return nil }
 func (d *datastore) RollbackToCE() error                                 { return nil }
+func (d *datastore) APIKey() portainer.APIKeyService                     { return d.apiKey }
+func (d *datastore) AuditLog() portainer.AuditLogService                 { return d.auditLog }
 func (d *datastore) DockerHub() portainer.DockerHubService               { return d.dockerHub }
 func (d *datastore) CustomTemplate() portainer.CustomTemplateService     { return d.customTemplate }
 func (d *datastore) EdgeGroup() portainer.EdgeGroupService               { return d.edgeGroup }
@@ -45,10 +53,13 @@ func (d *datastore) EdgeStack() portainer.EdgeStackService               { retur
 func (d *datastore) Endpoint() portainer.EndpointService                 { return d.endpoint }
 func (d *datastore) EndpointGroup() portainer.EndpointGroupService       { return d.endpointGroup }
 func (d *datastore) EndpointRelation() portainer.EndpointRelationService { return d.endpointRelation }
+func (d *datastore) IssuedToken() portainer.IssuedTokenService           { return d.issuedToken }
+func (d *datastore) JWTSigningKey() portainer.JWTSigningKeyService       { return d.jwtSigningKey }
 func (d *datastore) Registry() portainer.RegistryService                 { return d.registry }
 func (d *datastore) ResourceControl() portainer.ResourceControlService   { return d.resourceControl }
 func (d *datastore) Role() portainer.RoleService                         { return d.role }
 func (d *datastore) Settings() portainer.SettingsService                 { return d.settings }
+func (d *datastore) Snapshot() portainer.EndpointSnapshotService         { return d.snapshot }
 func (d *datastore) Stack() portainer.StackService                       { return d.stack }
 func (d *datastore) Tag() portainer.TagService                           { return d.tag }
 func (d *datastore) TeamMembership() portainer.TeamMembershipService     { return d.teamMembership }
@@ -58,6 +69,30 @@ func (d *datastore) User() portainer.UserService                         { retur
 func (d *datastore) Version() portainer.VersionService                   { return d.version }
 func (d *datastore) Webhook() portainer.WebhookService                   { return d.webhook }
 
+func (d *datastore) CheckIntegrity(repair bool) (*portainer.IntegrityReport, error) {
+	return &portainer.IntegrityReport{}, nil
+}
+
+func (d *datastore) CreateEntities(batch *portainer.EntityBatch) error {
+	return nil
+}
+
+func (d *datastore) DeleteEndpointCascade(ID portainer.EndpointID) ([]portainer.Stack, error) {
+	return nil, nil
+}
+
+func (d *datastore) DeleteTeamCascade(ID portainer.TeamID) error {
+	return nil
+}
+
+func (d *datastore) DeleteUserCascade(ID portainer.UserID, transferTo portainer.UserID) error {
+	return nil
+}
+
+func (d *datastore) RemoveExpiredAccessGrants(now time.Time) (int, error) {
+	return 0, nil
+}
+
 type datastoreOption = func(d *datastore)
 
 // NewDatastore creates new instance of datastore.
@@ -83,6 +118,7 @@ func (s *stubUserService) UsersByRole(role portainer.UserRole) ([]portainer.User
 func (s *stubUserService) CreateUser(user *portainer.User) error                      { return nil }
 func (s *stubUserService) UpdateUser(ID portainer.UserID, user *portainer.User) error { return nil }
 func (s *stubUserService) DeleteUser(ID portainer.UserID) error                       { return nil }
+func (s *stubUserService) GetNextIdentifier() int                                     { return 0 }
 
 // WithUsers datastore option that will instruct datastore to return provided users
 func WithUsers(us []portainer.User) datastoreOption {
@@ -112,3 +148,22 @@ func WithEdgeJobs(js []portainer.EdgeJob) datastoreOption {
 		d.edgeJob = &stubEdgeJobService{jobs: js}
 	}
 }
+
+type stubAuditLogService struct {
+	auditLogs []portainer.AuditLog
+}
+
+func (s *stubAuditLogService) AuditLogs() ([]portainer.AuditLog, error) { return s.auditLogs, nil }
+func (s *stubAuditLogService) FilteredAuditLogs(match func(portainer.AuditLog) bool, start, limit int) ([]portainer.AuditLog, int, error) {
+	return s.auditLogs, len(s.auditLogs), nil
+}
+func (s *stubAuditLogService) CreateAuditLogs(auditLogs []portainer.AuditLog, maxAuditLogs int) error {
+	return nil
+}
+
+// WithAuditLogs option will instruct datastore to return provided audit logs
+func WithAuditLogs(auditLogs []portainer.AuditLog) datastoreOption {
+	return func(d *datastore) {
+		d.auditLog = &stubAuditLogService{auditLogs: auditLogs}
+	}
+}
@@ -1,21 +1,41 @@
 package jwt
 
 import (
+	"bytes"
+	"context"
 	"errors"
+	"io/ioutil"
+	"log"
+	"strconv"
+	"sync"
 
 	portainer "github.com/portainer/portainer/api"
+	bolterrors "github.com/portainer/portainer/api/bolt/errors"
+	"github.com/portainer/portainer/api/crypto"
 
 	"fmt"
 	"time"
 
 	"github.com/dgrijalva/jwt-go"
+	"github.com/gofrs/uuid"
 	"github.com/gorilla/securecookie"
 )
 
 // Service represents a service for managing JWT tokens.
+// The signing keys are persisted encrypted in the datastore so that tokens survive a restart
+// and the issued tokens are recorded so that they can be revoked.
 type Service struct {
-	secret             []byte
+	mu                 sync.RWMutex
+	keys               map[portainer.JWTSigningKeyID]*signingKey
+	activeKeyID        portainer.JWTSigningKeyID
 	userSessionTimeout time.Duration
+	dataStore          portainer.DataStore
+	encryptionSecret   []byte
+}
+
+type signingKey struct {
+	secret         []byte
+	retirementDate int64
 }
 
 type claims struct {
@@ -25,49 +45,82 @@ type claims struct {
 	jwt.StandardClaims
 }
 
+// issuedTokenCleanupInterval is the interval between two removals of the expired issued tokens
+const issuedTokenCleanupInterval = time.Hour
+
 var (
 	errSecretGeneration = errors.New("Unable to generate secret key")
 	errInvalidJWTToken  = errors.New("Invalid JWT token")
 )
 
-// NewService initializes a new service. It will generate a random key that will be used to sign JWT tokens.
-func NewService(userSessionDuration string) (*Service, error) {
+// NewService initializes a new service. It loads the signing keys stored in the datastore
+// and decrypts them with the encryption secret. A new key is generated when there is no usable key.
+func NewService(userSessionDuration string, dataStore portainer.DataStore, encryptionSecret []byte) (*Service, error) {
 	userSessionTimeout, err := time.ParseDuration(userSessionDuration)
 	if err != nil {
 		return nil, err
 	}
 
-	secret := securecookie.GenerateRandomKey(32)
-	if secret == nil {
-		return nil, errSecretGeneration
+	service := &Service{
+		keys:               make(map[portainer.JWTSigningKeyID]*signingKey),
+		userSessionTimeout: userSessionTimeout,
+		dataStore:          dataStore,
+		encryptionSecret:   encryptionSecret,
 	}
 
-	service := &Service{
-		secret,
-		userSessionTimeout,
+	err = service.loadSigningKeys()
+	if err != nil {
+		return nil, err
 	}
+
 	return service, nil
 }
 
-// GenerateToken generates a new JWT token.
+// StartIssuedTokenCleanupJob starts a background routine which removes the expired issued tokens every hour,
+// until the shutdown context is done. The expired tokens are rejected as soon as they expire, the routine
+// only cleans them up.
+func (service *Service) StartIssuedTokenCleanupJob(shutdownCtx context.Context) {
+	go func() {
+		ticker := time.NewTicker(issuedTokenCleanupInterval)
+		defer ticker.Stop()
+
+		for {
+			err := service.dataStore.IssuedToken().DeleteExpiredIssuedTokens(time.Now().Unix())
+			if err != nil {
+				log.Printf("[ERROR] [jwt] [message: background schedule error (issued token cleanup)] [error: %s]", err)
+			}
+
+			select {
+			case <-ticker.C:
+			case <-shutdownCtx.Done():
+				log.Println("[DEBUG] [jwt] [message: shutting down issued token cleanup]")
+				return
+			}
+		}
+	}()
+}
+
+// GenerateToken generates a new JWT token. The token ID of data is set to the ID of the new token.
 func (service *Service) GenerateToken(data *portainer.TokenData) (string, error) {
 	return service.generateSignedToken(data, nil)
 }
 
 // GenerateTokenForOAuth generates a new JWT for OAuth login
-// token expiry time from the OAuth provider is considered
+// token expiry time from the OAuth provider is considered.
+// The token ID of data is set to the ID of the new token.
 func (service *Service) GenerateTokenForOAuth(data *portainer.TokenData, expiryTime *time.Time) (string, error) {
 	return service.generateSignedToken(data, expiryTime)
 }
 
 // ParseAndVerifyToken parses a JWT token and verify its validity. It returns an error if token is invalid.
+// The revocation of the token is not checked, see the IssuedToken data management layer.
 func (service *Service) ParseAndVerifyToken(token string) (*portainer.TokenData, error) {
 	parsedToken, err := jwt.ParseWithClaims(token, &claims{}, func(token *jwt.Token) (interface{}, error) {
 		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
 			msg := fmt.Errorf("Unexpected signing method: %v", token.Header["alg"])
 			return nil, msg
 		}
-		return service.secret, nil
+		return service.verificationKey(token)
 	})
 	if err == nil && parsedToken != nil {
 		if cl, ok := parsedToken.Claims.(*claims); ok && parsedToken.Valid {
@@ -75,6 +128,7 @@ func (service *Service) ParseAndVerifyToken(token string) (*portainer.TokenData,
 				ID:       portainer.UserID(cl.UserID),
 				Username: cl.Username,
 				Role:     portainer.UserRole(cl.Role),
+				TokenID:  cl.Id,
 			}
 			return tokenData, nil
 		}
@@ -88,25 +142,253 @@ func (service *Service) SetUserSessionDuration(userSessionDuration time.Duration
 	service.userSessionTimeout = userSessionDuration
 }
 
+// RevokeToken revokes the token with the specified token ID (jti).
+func (service *Service) RevokeToken(tokenID string) error {
+	token, err := service.dataStore.IssuedToken().IssuedToken(tokenID)
+	if err == bolterrors.ErrObjectNotFound {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	return service.revoke(token)
+}
+
+// RevokeUserTokens revokes all the tokens issued to a user, except the one with the specified token ID.
+// An empty exceptTokenID revokes all the tokens.
+func (service *Service) RevokeUserTokens(userID portainer.UserID, exceptTokenID string) error {
+	tokens, err := service.dataStore.IssuedToken().IssuedTokensByUserID(userID)
+	if err != nil {
+		return err
+	}
+
+	for idx := range tokens {
+		if tokens[idx].Revoked || tokens[idx].ID == exceptTokenID {
+			continue
+		}
+
+		err = service.revoke(&tokens[idx])
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// RotateSigningKey replaces the active signing key with a new one. Tokens signed with the previous keys
+// remain valid until the end of their session, unless revokeTokens is set in which case the previous keys
+// are removed and every token is invalidated.
+func (service *Service) RotateSigningKey(revokeTokens bool) error {
+	service.mu.Lock()
+	defer service.mu.Unlock()
+
+	now := time.Now().Unix()
+
+	keys, err := service.dataStore.JWTSigningKey().SigningKeys()
+	if err != nil {
+		return err
+	}
+
+	for idx := range keys {
+		key := &keys[idx]
+
+		if revokeTokens {
+			err = service.dataStore.JWTSigningKey().DeleteSigningKey(key.ID)
+			if err != nil {
+				return err
+			}
+			delete(service.keys, key.ID)
+			continue
+		}
+
+		if key.RetirementDate != 0 {
+			continue
+		}
+
+		key.RetirementDate = now
+		err = service.dataStore.JWTSigningKey().UpdateSigningKey(key.ID, key)
+		if err != nil {
+			return err
+		}
+		if loaded, ok := service.keys[key.ID]; ok {
+			loaded.retirementDate = now
+		}
+	}
+
+	return service.createSigningKey()
+}
+
+func (service *Service) revoke(token *portainer.IssuedToken) error {
+	token.Revoked = true
+	token.RevocationDate = time.Now().Unix()
+	return service.dataStore.IssuedToken().UpdateIssuedToken(token.ID, token)
+}
+
 func (service *Service) generateSignedToken(data *portainer.TokenData, expiryTime *time.Time) (string, error) {
-	expireToken := time.Now().Add(service.userSessionTimeout).Unix()
+	now := time.Now()
+	expireToken := now.Add(service.userSessionTimeout).Unix()
 	if expiryTime != nil && !expiryTime.IsZero() {
 		expireToken = expiryTime.Unix()
 	}
+
+	tokenID, err := uuid.NewV4()
+	if err != nil {
+		return "", err
+	}
+
 	cl := claims{
 		UserID:   int(data.ID),
 		Username: data.Username,
 		Role:     int(data.Role),
 		StandardClaims: jwt.StandardClaims{
+			Id:        tokenID.String(),
+			IssuedAt:  now.Unix(),
 			ExpiresAt: expireToken,
 		},
 	}
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, cl)
 
-	signedToken, err := token.SignedString(service.secret)
+	service.mu.RLock()
+	keyID := service.activeKeyID
+	key := service.keys[keyID]
+	service.mu.RUnlock()
+
+	token.Header["kid"] = strconv.Itoa(int(keyID))
+
+	signedToken, err := token.SignedString(key.secret)
 	if err != nil {
 		return "", err
 	}
 
+	err = service.dataStore.IssuedToken().CreateIssuedToken(&portainer.IssuedToken{
+		ID:        cl.Id,
+		UserID:    data.ID,
+		IssuedAt:  cl.IssuedAt,
+		ExpiresAt: cl.ExpiresAt,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	data.TokenID = cl.Id
+
 	return signedToken, nil
 }
+
+// verificationKey returns the key that signed the token, identified by the kid header.
+// Keys retired for longer than a user session are no longer accepted.
+func (service *Service) verificationKey(token *jwt.Token) ([]byte, error) {
+	kid, _ := token.Header["kid"].(string)
+	keyID, err := strconv.Atoi(kid)
+	if err != nil {
+		return nil, errInvalidJWTToken
+	}
+
+	service.mu.RLock()
+	defer service.mu.RUnlock()
+
+	key, ok := service.keys[portainer.JWTSigningKeyID(keyID)]
+	if !ok || service.expired(key.retirementDate, time.Now()) {
+		return nil, errInvalidJWTToken
+	}
+
+	return key.secret, nil
+}
+
+func (service *Service) expired(retirementDate int64, now time.Time) bool {
+	return retirementDate != 0 && time.Unix(retirementDate, 0).Add(service.userSessionTimeout).Before(now)
+}
+
+// loadSigningKeys decrypts the stored signing keys. Expired keys and keys that cannot be decrypted
+// with the encryption secret are removed.
+func (service *Service) loadSigningKeys() error {
+	service.mu.Lock()
+	defer service.mu.Unlock()
+
+	keys, err := service.dataStore.JWTSigningKey().SigningKeys()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for _, key := range keys {
+		if service.expired(key.RetirementDate, now) {
+			err = service.dataStore.JWTSigningKey().DeleteSigningKey(key.ID)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		secret, err := service.decrypt(key.EncryptedKey)
+		if err != nil {
+			log.Printf("[WARN] [jwt] [message: unable to decrypt signing key, removing it] [key: %d] [error: %s]", key.ID, err)
+
+			err = service.dataStore.JWTSigningKey().DeleteSigningKey(key.ID)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		service.keys[key.ID] = &signingKey{secret: secret, retirementDate: key.RetirementDate}
+		if key.RetirementDate == 0 && key.ID > service.activeKeyID {
+			service.activeKeyID = key.ID
+		}
+	}
+
+	if service.activeKeyID == 0 {
+		return service.createSigningKey()
+	}
+
+	return nil
+}
+
+// createSigningKey generates and persists a new active signing key. Must be called with the lock held.
+func (service *Service) createSigningKey() error {
+	secret := securecookie.GenerateRandomKey(32)
+	if secret == nil {
+		return errSecretGeneration
+	}
+
+	encryptedKey, err := service.encrypt(secret)
+	if err != nil {
+		return err
+	}
+
+	key := &portainer.JWTSigningKey{
+		EncryptedKey: encryptedKey,
+		CreationDate: time.Now().Unix(),
+	}
+
+	err = service.dataStore.JWTSigningKey().CreateSigningKey(key)
+	if err != nil {
+		return err
+	}
+
+	service.keys[key.ID] = &signingKey{secret: secret}
+	service.activeKeyID = key.ID
+
+	return nil
+}
+
+func (service *Service) encrypt(secret []byte) ([]byte, error) {
+	var encrypted bytes.Buffer
+
+	err := crypto.AesEncrypt(bytes.NewReader(secret), &encrypted, service.encryptionSecret)
+	if err != nil {
+		return nil, err
+	}
+
+	return encrypted.Bytes(), nil
+}
+
+func (service *Service) decrypt(encryptedKey []byte) ([]byte, error) {
+	reader, err := crypto.AesDecrypt(bytes.NewReader(encryptedKey), service.encryptionSecret)
+	if err != nil {
+		return nil, err
+	}
+
+	return ioutil.ReadAll(reader)
+}
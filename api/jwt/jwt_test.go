@@ -6,11 +6,17 @@ import (
 
 	"github.com/dgrijalva/jwt-go"
 	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/bolt/bolttest"
 	"github.com/stretchr/testify/assert"
 )
 
+var testEncryptionSecret = []byte("encryption-secret")
+
 func TestGenerateSignedToken(t *testing.T) {
-	svc, err := NewService("24h")
+	store, teardown := bolttest.MustNewTestStore(true)
+	defer teardown()
+
+	svc, err := NewService("24h", store, testEncryptionSecret)
 	assert.NoError(t, err, "failed to create a copy of service")
 
 	token := &portainer.TokenData{
@@ -24,7 +30,7 @@ func TestGenerateSignedToken(t *testing.T) {
 	assert.NoError(t, err, "failed to generate a signed token")
 
 	parsedToken, err := jwt.ParseWithClaims(generatedToken, &claims{}, func(token *jwt.Token) (interface{}, error) {
-		return svc.secret, nil
+		return svc.keys[svc.activeKeyID].secret, nil
 	})
 	assert.NoError(t, err, "failed to parse generated token")
 
@@ -35,4 +41,137 @@ func TestGenerateSignedToken(t *testing.T) {
 	assert.Equal(t, int(token.ID), tokenClaims.UserID)
 	assert.Equal(t, int(token.Role), tokenClaims.Role)
 	assert.Equal(t, expirtationTime.Unix(), tokenClaims.ExpiresAt)
+
+	issuedToken, err := store.IssuedToken().IssuedToken(tokenClaims.Id)
+	assert.NoError(t, err, "the token should be recorded")
+	assert.Equal(t, token.ID, issuedToken.UserID)
+	assert.Equal(t, expirtationTime.Unix(), issuedToken.ExpiresAt)
+}
+
+func TestNewService_shouldReuseStoredSigningKey(t *testing.T) {
+	store, teardown := bolttest.MustNewTestStore(true)
+	defer teardown()
+
+	svc, err := NewService("24h", store, testEncryptionSecret)
+	assert.NoError(t, err)
+
+	token, err := svc.GenerateToken(&portainer.TokenData{ID: 1, Username: "admin", Role: portainer.AdministratorRole})
+	assert.NoError(t, err)
+
+	keys, _ := store.JWTSigningKey().SigningKeys()
+	assert.Len(t, keys, 1)
+	assert.NotContains(t, string(keys[0].EncryptedKey), string(svc.keys[svc.activeKeyID].secret), "the key should be stored encrypted")
+
+	restarted, err := NewService("24h", store, testEncryptionSecret)
+	assert.NoError(t, err)
+
+	tokenData, err := restarted.ParseAndVerifyToken(token)
+	assert.NoError(t, err, "the token should remain valid after a restart")
+	assert.Equal(t, "admin", tokenData.Username)
+	assert.NotEmpty(t, tokenData.TokenID)
+}
+
+func TestNewService_shouldReplaceKeysEncryptedWithAnotherSecret(t *testing.T) {
+	store, teardown := bolttest.MustNewTestStore(true)
+	defer teardown()
+
+	svc, err := NewService("24h", store, testEncryptionSecret)
+	assert.NoError(t, err)
+
+	token, err := svc.GenerateToken(&portainer.TokenData{ID: 1, Username: "admin"})
+	assert.NoError(t, err)
+
+	other, err := NewService("24h", store, []byte("another-secret"))
+	assert.NoError(t, err)
+
+	_, err = other.ParseAndVerifyToken(token)
+	assert.Error(t, err)
+
+	keys, _ := store.JWTSigningKey().SigningKeys()
+	assert.Len(t, keys, 1)
+}
+
+func TestRotateSigningKey(t *testing.T) {
+	store, teardown := bolttest.MustNewTestStore(true)
+	defer teardown()
+
+	svc, err := NewService("24h", store, testEncryptionSecret)
+	assert.NoError(t, err)
+
+	token, _ := svc.GenerateToken(&portainer.TokenData{ID: 1, Username: "admin"})
+
+	err = svc.RotateSigningKey(false)
+	assert.NoError(t, err)
+
+	_, err = svc.ParseAndVerifyToken(token)
+	assert.NoError(t, err, "tokens signed with the previous key should remain valid")
+
+	rotatedToken, _ := svc.GenerateToken(&portainer.TokenData{ID: 1, Username: "admin"})
+	_, err = svc.ParseAndVerifyToken(rotatedToken)
+	assert.NoError(t, err)
+
+	err = svc.RotateSigningKey(true)
+	assert.NoError(t, err)
+
+	_, err = svc.ParseAndVerifyToken(token)
+	assert.Error(t, err)
+	_, err = svc.ParseAndVerifyToken(rotatedToken)
+	assert.Error(t, err)
+
+	keys, _ := store.JWTSigningKey().SigningKeys()
+	assert.Len(t, keys, 1)
+}
+
+func TestParseAndVerifyToken_shouldRejectKeysRetiredForLongerThanASession(t *testing.T) {
+	store, teardown := bolttest.MustNewTestStore(true)
+	defer teardown()
+
+	svc, err := NewService("24h", store, testEncryptionSecret)
+	assert.NoError(t, err)
+
+	expiryTime := time.Now().Add(72 * time.Hour)
+	token, _ := svc.GenerateTokenForOAuth(&portainer.TokenData{ID: 1, Username: "admin"}, &expiryTime)
+
+	svc.RotateSigningKey(false)
+	for _, key := range svc.keys {
+		if key.retirementDate != 0 {
+			key.retirementDate = time.Now().Add(-25 * time.Hour).Unix()
+		}
+	}
+
+	_, err = svc.ParseAndVerifyToken(token)
+	assert.Error(t, err)
+}
+
+func TestRevokeUserTokens(t *testing.T) {
+	store, teardown := bolttest.MustNewTestStore(true)
+	defer teardown()
+
+	svc, err := NewService("24h", store, testEncryptionSecret)
+	assert.NoError(t, err)
+
+	current, _ := svc.GenerateToken(&portainer.TokenData{ID: 1, Username: "bob"})
+	svc.GenerateToken(&portainer.TokenData{ID: 1, Username: "bob"})
+	svc.GenerateToken(&portainer.TokenData{ID: 2, Username: "alice"})
+
+	currentData, _ := svc.ParseAndVerifyToken(current)
+
+	err = svc.RevokeUserTokens(1, currentData.TokenID)
+	assert.NoError(t, err)
+
+	tokens, _ := store.IssuedToken().IssuedTokensByUserID(1)
+	assert.Len(t, tokens, 2)
+	for _, token := range tokens {
+		assert.Equal(t, token.ID != currentData.TokenID, token.Revoked)
+	}
+
+	tokens, _ = store.IssuedToken().IssuedTokensByUserID(2)
+	assert.False(t, tokens[0].Revoked)
+
+	err = svc.RevokeToken(currentData.TokenID)
+	assert.NoError(t, err)
+
+	issuedToken, _ := store.IssuedToken().IssuedToken(currentData.TokenID)
+	assert.True(t, issuedToken.Revoked)
+	assert.NotZero(t, issuedToken.RevocationDate)
 }
@@ -169,6 +169,106 @@ func getGroups(userDN string, conn *ldap.Conn, settings []portainer.LDAPGroupSea
 	return groups
 }
 
+// SearchGroupMembers is used to retrieve the members of the groups found with the group search settings.
+// It returns the usernames of the members indexed by group name, members that cannot be found with the
+// user search settings are ignored.
+func (*Service) SearchGroupMembers(settings *portainer.LDAPSettings) (map[string][]string, error) {
+	connection, err := createConnection(settings)
+	if err != nil {
+		return nil, err
+	}
+	defer connection.Close()
+
+	if !settings.AnonymousMode {
+		err = connection.Bind(settings.ReaderDN, settings.Password)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	members := make(map[string][]string)
+	usernames := make(map[string]string)
+
+	for _, searchSettings := range settings.GroupSearchSettings {
+		searchRequest := ldap.NewSearchRequest(
+			searchSettings.GroupBaseDN,
+			ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 0, 0, false,
+			fmt.Sprintf("(&%s(%s=*))", searchSettings.GroupFilter, searchSettings.GroupAttribute),
+			[]string{"cn", searchSettings.GroupAttribute},
+			nil,
+		)
+
+		// Unlike the other searches, errors are not skipped: an incomplete result would remove
+		// members from their teams during a synchronization.
+		sr, err := connection.Search(searchRequest)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, entry := range sr.Entries {
+			groupName := entry.GetAttributeValue("cn")
+			if groupName == "" {
+				continue
+			}
+
+			if _, ok := members[groupName]; !ok {
+				members[groupName] = make([]string, 0)
+			}
+
+			for _, memberDN := range entry.GetAttributeValues(searchSettings.GroupAttribute) {
+				username, ok := usernames[memberDN]
+				if !ok {
+					username, err = searchUsername(memberDN, connection, settings.SearchSettings)
+					if err != nil {
+						return nil, err
+					}
+					usernames[memberDN] = username
+				}
+
+				if username != "" {
+					members[groupName] = append(members[groupName], username)
+				}
+			}
+		}
+	}
+
+	return members, nil
+}
+
+// searchUsername returns the username of the user identified by the DN, or an empty string
+// when the entry does not exist or does not match any of the user search settings.
+func searchUsername(userDN string, conn *ldap.Conn, settings []portainer.LDAPSearchSettings) (string, error) {
+	for _, searchSettings := range settings {
+		searchRequest := ldap.NewSearchRequest(
+			userDN,
+			ldap.ScopeBaseObject, ldap.NeverDerefAliases, 0, 0, false,
+			fmt.Sprintf("(&%s(%s=*))", searchSettings.Filter, searchSettings.UserNameAttribute),
+			[]string{searchSettings.UserNameAttribute},
+			nil,
+		)
+
+		// An entry outside of the base DN of the search settings is not a user, any other error would
+		// make the member look like it left the group.
+		sr, err := conn.Search(searchRequest)
+		if ldap.IsErrorWithCode(err, ldap.LDAPResultNoSuchObject) {
+			continue
+		} else if err != nil {
+			return "", err
+		}
+
+		if len(sr.Entries) != 1 {
+			continue
+		}
+
+		username := sr.Entries[0].GetAttributeValue(searchSettings.UserNameAttribute)
+		if username != "" {
+			return username, nil
+		}
+	}
+
+	return "", nil
+}
+
 // TestConnectivity is used to test a connection against the LDAP server using the credentials
 // specified in the LDAPSettings.
 func (*Service) TestConnectivity(settings *portainer.LDAPSettings) error {
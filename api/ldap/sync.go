@@ -0,0 +1,316 @@
+package ldap
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	portainer "github.com/portainer/portainer/api"
+)
+
+// minSyncInterval prevents the synchronization from flooding the LDAP server
+const minSyncInterval = time.Minute
+
+var errLDAPNotEnabled = errors.New("LDAP authentication is not enabled")
+
+// SyncService periodically reconciles the team memberships of the LDAP users with the LDAP groups.
+// Only the teams whose name matches a group are managed, the memberships of the other teams are kept.
+type SyncService struct {
+	mu            sync.Mutex
+	syncMu        sync.Mutex
+	ldapService   portainer.LDAPService
+	dataStore     portainer.DataStore
+	refreshSignal chan struct{}
+	shutdownCtx   context.Context
+}
+
+// SyncReport describes the changes made, or that would be made, by a synchronization
+type SyncReport struct {
+	// Whether the changes were only computed and not applied
+	DryRun bool `json:"DryRun" example:"false"`
+	// Users created because they are members of a group matching a team
+	CreatedUsers []string `json:"CreatedUsers"`
+	// Memberships added to match the LDAP groups
+	AddedMemberships []SyncMembershipChange `json:"AddedMemberships"`
+	// Memberships removed because the user is no longer a member of the LDAP group
+	RemovedMemberships []SyncMembershipChange `json:"RemovedMemberships"`
+}
+
+// SyncMembershipChange represents a team membership added or removed by a synchronization
+type SyncMembershipChange struct {
+	Username string `json:"Username" example:"bob"`
+	Team     string `json:"Team" example:"developers"`
+}
+
+// ValidateSyncInterval ensures that an interval can be used to schedule synchronizations.
+// An empty interval disables the synchronization.
+func ValidateSyncInterval(interval string) error {
+	if interval == "" {
+		return nil
+	}
+
+	duration, err := time.ParseDuration(interval)
+	if err != nil || duration < minSyncInterval {
+		return errors.New("Invalid LDAP synchronization interval. Value must be a duration of at least 1m")
+	}
+
+	return nil
+}
+
+// NewSyncService creates a new instance of a service
+func NewSyncService(ldapService portainer.LDAPService, dataStore portainer.DataStore, shutdownCtx context.Context) *SyncService {
+	return &SyncService{
+		ldapService: ldapService,
+		dataStore:   dataStore,
+		shutdownCtx: shutdownCtx,
+	}
+}
+
+// Start schedules the synchronization according to the settings stored in the database.
+func (service *SyncService) Start() error {
+	settings, err := service.dataStore.Settings().Settings()
+	if err != nil {
+		return err
+	}
+
+	return service.Update(settings)
+}
+
+// Update replaces the current schedule with the one defined in the settings. The synchronization
+// only runs when LDAP is the active authentication method.
+func (service *SyncService) Update(settings *portainer.Settings) error {
+	service.Stop()
+
+	if settings.AuthenticationMethod != portainer.AuthenticationLDAP || settings.LDAPSettings.SyncInterval == "" {
+		return nil
+	}
+
+	interval, err := time.ParseDuration(settings.LDAPSettings.SyncInterval)
+	if err != nil {
+		return err
+	}
+
+	service.mu.Lock()
+	defer service.mu.Unlock()
+
+	service.refreshSignal = make(chan struct{})
+	go service.syncLoop(interval, service.refreshSignal)
+
+	return nil
+}
+
+// Stop stops the schedule. Safe to call even if the service wasn't started.
+func (service *SyncService) Stop() {
+	service.mu.Lock()
+	defer service.mu.Unlock()
+
+	if service.refreshSignal == nil {
+		return
+	}
+
+	close(service.refreshSignal)
+	service.refreshSignal = nil
+}
+
+func (service *SyncService) syncLoop(interval time.Duration, refreshSignal chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			report, err := service.Sync(false)
+			if err != nil {
+				log.Printf("[ERROR] [ldap,sync] [message: background schedule error (LDAP synchronization)] [error: %s]", err)
+				continue
+			}
+
+			if len(report.CreatedUsers)+len(report.AddedMemberships)+len(report.RemovedMemberships) > 0 {
+				log.Printf("[INFO] [ldap,sync] [message: team memberships synchronized] [created_users: %d] [added_memberships: %d] [removed_memberships: %d]", len(report.CreatedUsers), len(report.AddedMemberships), len(report.RemovedMemberships))
+			}
+		case <-service.shutdownCtx.Done():
+			log.Println("[DEBUG] [ldap,sync] [message: shutting down LDAP synchronization]")
+			return
+		case <-refreshSignal:
+			return
+		}
+	}
+}
+
+// Sync reconciles the team memberships with the LDAP groups and returns the changes.
+// When dryRun is set, the changes are computed but not applied.
+func (service *SyncService) Sync(dryRun bool) (*SyncReport, error) {
+	settings, err := service.dataStore.Settings().Settings()
+	if err != nil {
+		return nil, err
+	}
+
+	if settings.AuthenticationMethod != portainer.AuthenticationLDAP {
+		return nil, errLDAPNotEnabled
+	}
+
+	groupMembers, err := service.ldapService.SearchGroupMembers(&settings.LDAPSettings)
+	if err != nil {
+		return nil, err
+	}
+
+	teams, err := service.dataStore.Team().Teams()
+	if err != nil {
+		return nil, err
+	}
+
+	// members of the managed teams, indexed by team and lowercase username
+	teamMembers := make(map[portainer.TeamID]map[string]bool)
+	for _, team := range teams {
+		for groupName, usernames := range groupMembers {
+			if !strings.EqualFold(groupName, team.Name) {
+				continue
+			}
+
+			if _, ok := teamMembers[team.ID]; !ok {
+				teamMembers[team.ID] = make(map[string]bool)
+			}
+			for _, username := range usernames {
+				teamMembers[team.ID][strings.ToLower(username)] = true
+			}
+		}
+	}
+
+	// synchronizations are serialized so that a manual synchronization does not race with the schedule
+	service.syncMu.Lock()
+	defer service.syncMu.Unlock()
+
+	report := &SyncReport{
+		DryRun:             dryRun,
+		CreatedUsers:       make([]string, 0),
+		AddedMemberships:   make([]SyncMembershipChange, 0),
+		RemovedMemberships: make([]SyncMembershipChange, 0),
+	}
+
+	users, err := service.dataStore.User().Users()
+	if err != nil {
+		return nil, err
+	}
+
+	if settings.LDAPSettings.SyncCreateUsers {
+		users, err = service.createMissingUsers(users, groupMembers, teams, teamMembers, report)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	memberships, err := service.dataStore.TeamMembership().TeamMemberships()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, user := range users {
+		// only the accounts created for the LDAP authentication are managed by the directory
+		if user.AuthenticationMethod != portainer.AuthenticationLDAP {
+			continue
+		}
+
+		for _, team := range teams {
+			members, managed := teamMembers[team.ID]
+			if !managed {
+				continue
+			}
+
+			membership := findMembership(memberships, user.ID, team.ID)
+			isMember := members[strings.ToLower(user.Username)]
+
+			switch {
+			case isMember && membership == nil:
+				report.AddedMemberships = append(report.AddedMemberships, SyncMembershipChange{Username: user.Username, Team: team.Name})
+				if dryRun {
+					continue
+				}
+
+				err = service.dataStore.TeamMembership().CreateTeamMembership(&portainer.TeamMembership{
+					UserID: user.ID,
+					TeamID: team.ID,
+					Role:   portainer.TeamMember,
+				})
+				if err != nil {
+					return nil, err
+				}
+			case !isMember && membership != nil:
+				report.RemovedMemberships = append(report.RemovedMemberships, SyncMembershipChange{Username: user.Username, Team: team.Name})
+				if dryRun {
+					continue
+				}
+
+				err = service.dataStore.TeamMembership().DeleteTeamMembership(membership.ID)
+				if err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+
+	return report, nil
+}
+
+// createMissingUsers creates the users that are members of a group matching a team. In dry-run mode,
+// the users are only added to the report and to the returned list with a zero identifier.
+func (service *SyncService) createMissingUsers(users []portainer.User, groupMembers map[string][]string, teams []portainer.Team, teamMembers map[portainer.TeamID]map[string]bool, report *SyncReport) ([]portainer.User, error) {
+	existing := make(map[string]bool)
+	for _, user := range users {
+		existing[strings.ToLower(user.Username)] = true
+	}
+
+	usernames := make([]string, 0)
+	for _, team := range teams {
+		if _, managed := teamMembers[team.ID]; !managed {
+			continue
+		}
+
+		for groupName, members := range groupMembers {
+			if !strings.EqualFold(groupName, team.Name) {
+				continue
+			}
+
+			for _, username := range members {
+				if existing[strings.ToLower(username)] {
+					continue
+				}
+				existing[strings.ToLower(username)] = true
+				usernames = append(usernames, username)
+			}
+		}
+	}
+	sort.Strings(usernames)
+
+	for _, username := range usernames {
+		user := portainer.User{
+			Username:             username,
+			Role:                 portainer.StandardUserRole,
+			AuthenticationMethod: portainer.AuthenticationLDAP,
+		}
+
+		if !report.DryRun {
+			err := service.dataStore.User().CreateUser(&user)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		report.CreatedUsers = append(report.CreatedUsers, username)
+		users = append(users, user)
+	}
+
+	return users, nil
+}
+
+func findMembership(memberships []portainer.TeamMembership, userID portainer.UserID, teamID portainer.TeamID) *portainer.TeamMembership {
+	for idx := range memberships {
+		if memberships[idx].UserID == userID && memberships[idx].TeamID == teamID {
+			return &memberships[idx]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,133 @@
+package ldap
+
+import (
+	"errors"
+	"testing"
+
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/bolt/bolttest"
+	"github.com/stretchr/testify/assert"
+)
+
+type testLDAPService struct {
+	portainer.LDAPService
+	groupMembers map[string][]string
+	err          error
+}
+
+func (service *testLDAPService) SearchGroupMembers(settings *portainer.LDAPSettings) (map[string][]string, error) {
+	return service.groupMembers, service.err
+}
+
+func Test_Sync(t *testing.T) {
+	store, teardown := bolttest.MustNewTestStore(true)
+	defer teardown()
+
+	settings, _ := store.Settings().Settings()
+	settings.AuthenticationMethod = portainer.AuthenticationLDAP
+	settings.LDAPSettings.SyncCreateUsers = true
+	store.Settings().UpdateSettings(settings)
+
+	devs := &portainer.Team{Name: "Developers"}
+	store.Team().CreateTeam(devs)
+	manual := &portainer.Team{Name: "manual"}
+	store.Team().CreateTeam(manual)
+
+	alice := &portainer.User{Username: "alice", AuthenticationMethod: portainer.AuthenticationLDAP}
+	store.User().CreateUser(alice)
+	bob := &portainer.User{Username: "bob", AuthenticationMethod: portainer.AuthenticationLDAP}
+	store.User().CreateUser(bob)
+	local := &portainer.User{Username: "local", Password: "hash", AuthenticationMethod: portainer.AuthenticationInternal}
+	store.User().CreateUser(local)
+	oauth := &portainer.User{Username: "oauth", AuthenticationMethod: portainer.AuthenticationOAuth}
+	store.User().CreateUser(oauth)
+
+	store.TeamMembership().CreateTeamMembership(&portainer.TeamMembership{UserID: bob.ID, TeamID: devs.ID, Role: portainer.TeamMember})
+	store.TeamMembership().CreateTeamMembership(&portainer.TeamMembership{UserID: bob.ID, TeamID: manual.ID, Role: portainer.TeamMember})
+	store.TeamMembership().CreateTeamMembership(&portainer.TeamMembership{UserID: local.ID, TeamID: devs.ID, Role: portainer.TeamMember})
+	store.TeamMembership().CreateTeamMembership(&portainer.TeamMembership{UserID: oauth.ID, TeamID: devs.ID, Role: portainer.TeamMember})
+
+	ldapService := &testLDAPService{groupMembers: map[string][]string{
+		"developers": {"Alice", "carol"},
+		"unmatched":  {"dave"},
+	}}
+	service := NewSyncService(ldapService, store, nil)
+
+	report, err := service.Sync(true)
+	assert.NoError(t, err)
+	assert.True(t, report.DryRun)
+	assert.Equal(t, []string{"carol"}, report.CreatedUsers)
+	assert.ElementsMatch(t, []SyncMembershipChange{{Username: "alice", Team: "Developers"}, {Username: "carol", Team: "Developers"}}, report.AddedMemberships)
+	assert.Equal(t, []SyncMembershipChange{{Username: "bob", Team: "Developers"}}, report.RemovedMemberships)
+
+	users, _ := store.User().Users()
+	assert.Len(t, users, 4, "a dry run should not create users")
+	memberships, _ := store.TeamMembership().TeamMemberships()
+	assert.Len(t, memberships, 4, "a dry run should not change memberships")
+
+	applied, err := service.Sync(false)
+	assert.NoError(t, err)
+	assert.Equal(t, report.CreatedUsers, applied.CreatedUsers)
+	assert.ElementsMatch(t, report.AddedMemberships, applied.AddedMemberships)
+	assert.Equal(t, report.RemovedMemberships, applied.RemovedMemberships)
+
+	carol, err := store.User().UserByUsername("carol")
+	assert.NoError(t, err)
+
+	teamIDsByUser := make(map[portainer.UserID][]portainer.TeamID)
+	memberships, _ = store.TeamMembership().TeamMemberships()
+	for _, membership := range memberships {
+		teamIDsByUser[membership.UserID] = append(teamIDsByUser[membership.UserID], membership.TeamID)
+	}
+	assert.Equal(t, []portainer.TeamID{devs.ID}, teamIDsByUser[alice.ID])
+	assert.Equal(t, []portainer.TeamID{manual.ID}, teamIDsByUser[bob.ID], "the memberships of unmanaged teams should be kept")
+	assert.Equal(t, []portainer.TeamID{devs.ID}, teamIDsByUser[carol.ID])
+	assert.Equal(t, []portainer.TeamID{devs.ID}, teamIDsByUser[local.ID], "internal users should not be managed")
+	assert.Equal(t, []portainer.TeamID{devs.ID}, teamIDsByUser[oauth.ID], "OAuth users should not be managed")
+
+	report, err = service.Sync(false)
+	assert.NoError(t, err)
+	assert.Empty(t, report.CreatedUsers)
+	assert.Empty(t, report.AddedMemberships)
+	assert.Empty(t, report.RemovedMemberships)
+}
+
+func Test_Sync_shouldKeepTheMemberships_whenTheGroupsCannotBeRetrieved(t *testing.T) {
+	store, teardown := bolttest.MustNewTestStore(true)
+	defer teardown()
+
+	settings, _ := store.Settings().Settings()
+	settings.AuthenticationMethod = portainer.AuthenticationLDAP
+	store.Settings().UpdateSettings(settings)
+
+	devs := &portainer.Team{Name: "Developers"}
+	store.Team().CreateTeam(devs)
+	bob := &portainer.User{Username: "bob", AuthenticationMethod: portainer.AuthenticationLDAP}
+	store.User().CreateUser(bob)
+	store.TeamMembership().CreateTeamMembership(&portainer.TeamMembership{UserID: bob.ID, TeamID: devs.ID, Role: portainer.TeamMember})
+
+	service := NewSyncService(&testLDAPService{err: errors.New("connection reset")}, store, nil)
+
+	_, err := service.Sync(false)
+	assert.Error(t, err)
+
+	memberships, _ := store.TeamMembership().TeamMembershipsByUserID(bob.ID)
+	assert.Len(t, memberships, 1)
+}
+
+func Test_Sync_shouldRequireLDAPAuthentication(t *testing.T) {
+	store, teardown := bolttest.MustNewTestStore(true)
+	defer teardown()
+
+	service := NewSyncService(&testLDAPService{}, store, nil)
+
+	_, err := service.Sync(false)
+	assert.Equal(t, errLDAPNotEnabled, err)
+}
+
+func Test_ValidateSyncInterval(t *testing.T) {
+	assert.NoError(t, ValidateSyncInterval(""))
+	assert.NoError(t, ValidateSyncInterval("1h"))
+	assert.Error(t, ValidateSyncInterval("10s"))
+	assert.Error(t, ValidateSyncInterval("often"))
+}
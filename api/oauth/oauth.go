@@ -9,6 +9,7 @@ import (
 	"mime"
 	"net/http"
 	"net/url"
+	"sync"
 	"time"
 
 	"golang.org/x/oauth2"
@@ -17,38 +18,52 @@ import (
 )
 
 // Service represents a service used to authenticate users against an authorization server
-type Service struct{}
+type Service struct {
+	mu            sync.Mutex
+	pendingLogins map[string]*pendingLogin
+	providers     map[string]*provider
+	httpClient    *http.Client
+}
 
 // NewService returns a pointer to a new instance of this service
 func NewService() *Service {
-	return &Service{}
+	return &Service{
+		pendingLogins: make(map[string]*pendingLogin),
+		providers:     make(map[string]*provider),
+		httpClient:    &http.Client{Timeout: 10 * time.Second},
+	}
 }
 
 // Authenticate takes an access code and exchanges it for an access token from portainer OAuthSettings token endpoint.
 // On success, it will then return the username and token expiry time associated to authenticated user by fetching this information
 // from the resource server and matching it with the user identifier setting.
-func (*Service) Authenticate(code string, configuration *portainer.OAuthSettings) (string, *time.Time, error) {
-	token, err := getOAuthToken(code, configuration)
+// When OpenID Connect is enabled, the state must match a login started with AuthorizationURL and the username
+// and groups are extracted from the verified ID token.
+func (service *Service) Authenticate(code, state string, configuration *portainer.OAuthSettings) (*portainer.OAuthInfo, error) {
+	if configuration.OIDC {
+		return service.authenticateOIDC(code, state, configuration)
+	}
+
+	token, err := getOAuthToken(code, buildConfig(configuration))
 	if err != nil {
 		log.Printf("[DEBUG] - Failed retrieving access token: %v", err)
-		return "", nil, err
+		return nil, err
 	}
 	username, err := getUsername(token.AccessToken, configuration)
 	if err != nil {
 		log.Printf("[DEBUG] - Failed retrieving oauth user name: %v", err)
-		return "", nil, err
+		return nil, err
 	}
-	return username, &token.Expiry, nil
+	return &portainer.OAuthInfo{Username: username, ExpiryTime: &token.Expiry}, nil
 }
 
-func getOAuthToken(code string, configuration *portainer.OAuthSettings) (*oauth2.Token, error) {
+func getOAuthToken(code string, config *oauth2.Config, opts ...oauth2.AuthCodeOption) (*oauth2.Token, error) {
 	unescapedCode, err := url.QueryUnescape(code)
 	if err != nil {
 		return nil, err
 	}
 
-	config := buildConfig(configuration)
-	token, err := config.Exchange(context.Background(), unescapedCode)
+	token, err := config.Exchange(context.Background(), unescapedCode, opts...)
 	if err != nil {
 		return nil, err
 	}
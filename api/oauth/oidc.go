@@ -0,0 +1,507 @@
+package oauth
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"golang.org/x/oauth2"
+
+	portainer "github.com/portainer/portainer/api"
+)
+
+const (
+	pendingLoginTTL     = 10 * time.Minute
+	maxPendingLogins    = 1000
+	providerCacheTTL    = time.Hour
+	jwksRefreshInterval = time.Minute
+)
+
+var (
+	errOIDCNotEnabled    = errors.New("OpenID Connect is not enabled")
+	errInvalidState      = errors.New("Invalid or expired OAuth state")
+	errMissingIDToken    = errors.New("The authorization server did not return an ID token")
+	errInvalidIDToken    = errors.New("Invalid ID token")
+	errUnknownSigningKey = errors.New("Unknown ID token signing key")
+	errMissingUsername   = errors.New("Unable to find the user identifier in the ID token")
+)
+
+// pendingLogin holds the secrets of a login started with AuthorizationURL, until the authorization
+// code is returned with the matching state.
+type pendingLogin struct {
+	nonce        string
+	codeVerifier string
+	expiresAt    time.Time
+}
+
+// provider holds the discovered configuration and the signing keys of an OpenID Connect issuer.
+type provider struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	UserinfoEndpoint      string `json:"userinfo_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+
+	discoveryDate time.Time
+	keys          map[string]interface{}
+	keysDate      time.Time
+}
+
+type jsonWebKeySet struct {
+	Keys []jsonWebKey `json:"keys"`
+}
+
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// AuthorizationURL starts an OpenID Connect login. It returns the URL of the authorization endpoint
+// including the state, a nonce and a PKCE code challenge. The nonce and the code verifier are kept
+// until the authorization code is exchanged with Authenticate. The state is generated by Portainer,
+// it is prefixed with the client state so that the client can check that the login was started by itself.
+func (service *Service) AuthorizationURL(clientState string, configuration *portainer.OAuthSettings) (string, error) {
+	if !configuration.OIDC {
+		return "", errOIDCNotEnabled
+	}
+
+	if clientState == "" {
+		return "", errInvalidState
+	}
+
+	serverState, err := randomString()
+	if err != nil {
+		return "", err
+	}
+	state := clientState + "." + serverState
+
+	provider, err := service.provider(configuration.IssuerURL)
+	if err != nil {
+		return "", err
+	}
+
+	nonce, err := randomString()
+	if err != nil {
+		return "", err
+	}
+
+	codeVerifier, err := randomString()
+	if err != nil {
+		return "", err
+	}
+
+	service.storePendingLogin(state, &pendingLogin{
+		nonce:        nonce,
+		codeVerifier: codeVerifier,
+		expiresAt:    time.Now().Add(pendingLoginTTL),
+	})
+
+	opts := []oauth2.AuthCodeOption{
+		oauth2.SetAuthURLParam("nonce", nonce),
+		oauth2.SetAuthURLParam("code_challenge", codeChallenge(codeVerifier)),
+		oauth2.SetAuthURLParam("code_challenge_method", "S256"),
+	}
+	if !configuration.SSO {
+		opts = append(opts, oauth2.SetAuthURLParam("prompt", "login"))
+	}
+
+	return buildOIDCConfig(configuration, provider).AuthCodeURL(state, opts...), nil
+}
+
+func (service *Service) authenticateOIDC(code, state string, configuration *portainer.OAuthSettings) (*portainer.OAuthInfo, error) {
+	login, err := service.consumePendingLogin(state)
+	if err != nil {
+		return nil, err
+	}
+
+	provider, err := service.provider(configuration.IssuerURL)
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := getOAuthToken(code, buildOIDCConfig(configuration, provider), oauth2.SetAuthURLParam("code_verifier", login.codeVerifier))
+	if err != nil {
+		log.Printf("[DEBUG] - Failed retrieving access token: %v", err)
+		return nil, err
+	}
+
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok || rawIDToken == "" {
+		return nil, errMissingIDToken
+	}
+
+	claims, err := service.verifyIDToken(rawIDToken, provider, configuration.ClientID)
+	if err != nil {
+		return nil, err
+	}
+
+	if nonce, _ := claims["nonce"].(string); nonce != login.nonce {
+		return nil, errInvalidIDToken
+	}
+
+	username := claimString(claims, configuration.UserIdentifier)
+	if username == "" && provider.UserinfoEndpoint != "" {
+		userinfoConfiguration := *configuration
+		userinfoConfiguration.ResourceURI = provider.UserinfoEndpoint
+
+		username, err = getUsername(token.AccessToken, &userinfoConfiguration)
+		if err != nil {
+			log.Printf("[DEBUG] - Failed retrieving oauth user name: %v", err)
+			return nil, err
+		}
+	}
+	if username == "" {
+		return nil, errMissingUsername
+	}
+
+	info := &portainer.OAuthInfo{
+		Username:   username,
+		ExpiryTime: &token.Expiry,
+	}
+	if _, ok := claims[configuration.GroupsClaim]; ok && configuration.GroupsClaim != "" {
+		info.Groups = claimStrings(claims, configuration.GroupsClaim)
+		info.GroupsProvided = true
+	}
+
+	return info, nil
+}
+
+// verifyIDToken verifies the signature of the ID token against the keys published by the issuer
+// as well as its issuer, audience and expiry.
+func (service *Service) verifyIDToken(rawIDToken string, provider *provider, clientID string) (jwt.MapClaims, error) {
+	parser := &jwt.Parser{ValidMethods: []string{"RS256", "RS384", "RS512", "ES256", "ES384", "ES512"}}
+
+	claims := jwt.MapClaims{}
+	_, err := parser.ParseWithClaims(rawIDToken, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		return service.signingKey(provider, kid)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if !claims.VerifyIssuer(provider.Issuer, true) || !claims.VerifyExpiresAt(time.Now().Unix(), true) || !audienceContains(claims["aud"], clientID) {
+		return nil, errInvalidIDToken
+	}
+
+	return claims, nil
+}
+
+// provider returns the discovered configuration of an issuer. The configuration is cached for an hour.
+func (service *Service) provider(issuerURL string) (*provider, error) {
+	issuerURL = strings.TrimSuffix(issuerURL, "/")
+
+	service.mu.Lock()
+	cached, ok := service.providers[issuerURL]
+	service.mu.Unlock()
+
+	if ok && time.Since(cached.discoveryDate) < providerCacheTTL {
+		return cached, nil
+	}
+
+	var discovered provider
+	err := service.getJSON(issuerURL+"/.well-known/openid-configuration", &discovered)
+	if err != nil {
+		return nil, err
+	}
+
+	if strings.TrimSuffix(discovered.Issuer, "/") != issuerURL {
+		return nil, fmt.Errorf("The discovered issuer (%s) does not match the issuer URL (%s)", discovered.Issuer, issuerURL)
+	}
+
+	if discovered.AuthorizationEndpoint == "" || discovered.TokenEndpoint == "" || discovered.JWKSURI == "" {
+		return nil, errors.New("The discovery document of the issuer is incomplete")
+	}
+
+	keys, err := service.fetchSigningKeys(discovered.JWKSURI)
+	if err != nil {
+		return nil, err
+	}
+
+	discovered.discoveryDate = time.Now()
+	discovered.keys = keys
+	discovered.keysDate = discovered.discoveryDate
+
+	service.mu.Lock()
+	service.providers[issuerURL] = &discovered
+	service.mu.Unlock()
+
+	return &discovered, nil
+}
+
+// signingKey returns the key identified by kid. The key set is fetched again when the key is unknown,
+// to support key rotation by the issuer.
+func (service *Service) signingKey(provider *provider, kid string) (interface{}, error) {
+	service.mu.Lock()
+	key, ok := lookupKey(provider.keys, kid)
+	refresh := !ok && time.Since(provider.keysDate) >= jwksRefreshInterval
+	service.mu.Unlock()
+
+	if ok {
+		return key, nil
+	}
+
+	if !refresh {
+		return nil, errUnknownSigningKey
+	}
+
+	keys, err := service.fetchSigningKeys(provider.JWKSURI)
+	if err != nil {
+		return nil, err
+	}
+
+	service.mu.Lock()
+	defer service.mu.Unlock()
+
+	provider.keys = keys
+	provider.keysDate = time.Now()
+
+	key, ok = lookupKey(keys, kid)
+	if !ok {
+		return nil, errUnknownSigningKey
+	}
+
+	return key, nil
+}
+
+func lookupKey(keys map[string]interface{}, kid string) (interface{}, bool) {
+	if kid == "" && len(keys) == 1 {
+		for _, key := range keys {
+			return key, true
+		}
+	}
+
+	key, ok := keys[kid]
+	return key, ok
+}
+
+func (service *Service) fetchSigningKeys(jwksURI string) (map[string]interface{}, error) {
+	var keySet jsonWebKeySet
+	err := service.getJSON(jwksURI, &keySet)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make(map[string]interface{})
+	for _, jwk := range keySet.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+
+		key, err := jwk.publicKey()
+		if err != nil {
+			log.Printf("[WARN] [oauth] [message: ignoring unsupported signing key] [kid: %s] [error: %s]", jwk.Kid, err)
+			continue
+		}
+
+		keys[jwk.Kid] = key
+	}
+
+	return keys, nil
+}
+
+func (service *Service) getJSON(url string, v interface{}) error {
+	resp, err := service.httpClient.Get(url)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("Unexpected status code %d when requesting %s", resp.StatusCode, url)
+	}
+
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// storePendingLogin keeps a login until its state is used or expires. The expired logins are removed
+// and the oldest login is evicted when too many logins are pending.
+func (service *Service) storePendingLogin(state string, login *pendingLogin) {
+	service.mu.Lock()
+	defer service.mu.Unlock()
+
+	now := time.Now()
+	oldestState := ""
+	for key, pending := range service.pendingLogins {
+		if now.After(pending.expiresAt) {
+			delete(service.pendingLogins, key)
+			continue
+		}
+
+		if oldestState == "" || pending.expiresAt.Before(service.pendingLogins[oldestState].expiresAt) {
+			oldestState = key
+		}
+	}
+
+	if len(service.pendingLogins) >= maxPendingLogins {
+		delete(service.pendingLogins, oldestState)
+	}
+
+	service.pendingLogins[state] = login
+}
+
+// consumePendingLogin returns and removes the login matching the state, a state can only be used once.
+func (service *Service) consumePendingLogin(state string) (*pendingLogin, error) {
+	service.mu.Lock()
+	defer service.mu.Unlock()
+
+	login, ok := service.pendingLogins[state]
+	delete(service.pendingLogins, state)
+
+	if !ok || time.Now().After(login.expiresAt) {
+		return nil, errInvalidState
+	}
+
+	return login, nil
+}
+
+func (jwk *jsonWebKey) publicKey() (interface{}, error) {
+	switch jwk.Kty {
+	case "RSA":
+		n, err := decodeBase64URL(jwk.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBase64URL(jwk.E)
+		if err != nil {
+			return nil, err
+		}
+
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch jwk.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %s", jwk.Crv)
+		}
+
+		x, err := decodeBase64URL(jwk.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBase64URL(jwk.Y)
+		if err != nil {
+			return nil, err
+		}
+
+		return &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}, nil
+	}
+
+	return nil, fmt.Errorf("unsupported key type %s", jwk.Kty)
+}
+
+func decodeBase64URL(value string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(strings.TrimRight(value, "="))
+}
+
+func buildOIDCConfig(configuration *portainer.OAuthSettings, provider *provider) *oauth2.Config {
+	scopes := strings.Fields(configuration.Scopes)
+	if !contains(scopes, "openid") {
+		scopes = append([]string{"openid"}, scopes...)
+	}
+
+	return &oauth2.Config{
+		ClientID:     configuration.ClientID,
+		ClientSecret: configuration.ClientSecret,
+		Endpoint: oauth2.Endpoint{
+			AuthURL:  provider.AuthorizationEndpoint,
+			TokenURL: provider.TokenEndpoint,
+		},
+		RedirectURL: configuration.RedirectURI,
+		Scopes:      scopes,
+	}
+}
+
+func randomString() (string, error) {
+	randomBytes := make([]byte, 32)
+	_, err := rand.Read(randomBytes)
+	if err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(randomBytes), nil
+}
+
+func codeChallenge(codeVerifier string) string {
+	hash := sha256.Sum256([]byte(codeVerifier))
+	return base64.RawURLEncoding.EncodeToString(hash[:])
+}
+
+func audienceContains(audience interface{}, clientID string) bool {
+	switch aud := audience.(type) {
+	case string:
+		return aud == clientID
+	case []interface{}:
+		for _, value := range aud {
+			if value == clientID {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func claimString(claims jwt.MapClaims, name string) string {
+	switch value := claims[name].(type) {
+	case string:
+		return value
+	case float64:
+		if value != 0 {
+			return fmt.Sprint(int(value))
+		}
+	}
+	return ""
+}
+
+// claimStrings returns the values of a claim that is either a string or an array of strings.
+func claimStrings(claims jwt.MapClaims, name string) []string {
+	values := make([]string, 0)
+
+	switch claim := claims[name].(type) {
+	case string:
+		if claim != "" {
+			values = append(values, claim)
+		}
+	case []interface{}:
+		for _, value := range claim {
+			if str, ok := value.(string); ok && str != "" {
+				values = append(values, str)
+			}
+		}
+	}
+
+	return values
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,238 @@
+package oauth
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	portainer "github.com/portainer/portainer/api"
+	"github.com/stretchr/testify/assert"
+)
+
+const testClientID = "portainer"
+
+// testProvider is a minimal OpenID Connect provider issuing ID tokens for the last authorization request
+type testProvider struct {
+	server        *httptest.Server
+	key           *rsa.PrivateKey
+	nonce         string
+	codeChallenge string
+	claims        jwt.MapClaims
+}
+
+func newTestProvider(t *testing.T) *testProvider {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+
+	provider := &testProvider{key: key}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 provider.server.URL,
+			"authorization_endpoint": provider.server.URL + "/authorize",
+			"token_endpoint":         provider.server.URL + "/token",
+			"jwks_uri":               provider.server.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(jsonWebKeySet{Keys: []jsonWebKey{{
+			Kty: "RSA",
+			Kid: "test",
+			Use: "sig",
+			N:   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}}})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		if codeChallenge(r.PostForm.Get("code_verifier")) != provider.codeChallenge {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"access_token": "access-token",
+			"token_type":   "Bearer",
+			"expires_in":   3600,
+			"id_token":     provider.signIDToken(t, provider.key),
+		})
+	})
+	provider.server = httptest.NewServer(mux)
+
+	provider.claims = jwt.MapClaims{
+		"iss":                provider.server.URL,
+		"aud":                testClientID,
+		"sub":                "42",
+		"preferred_username": "alice",
+		"groups":             []string{"devs", "ops"},
+		"exp":                time.Now().Add(time.Hour).Unix(),
+	}
+
+	return provider
+}
+
+func (provider *testProvider) signIDToken(t *testing.T, key *rsa.PrivateKey) string {
+	claims := jwt.MapClaims{"nonce": provider.nonce}
+	for name, value := range provider.claims {
+		claims[name] = value
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = "test"
+
+	signedToken, err := token.SignedString(key)
+	assert.NoError(t, err)
+	return signedToken
+}
+
+func (provider *testProvider) settings() *portainer.OAuthSettings {
+	return &portainer.OAuthSettings{
+		OIDC:           true,
+		IssuerURL:      provider.server.URL,
+		ClientID:       testClientID,
+		RedirectURI:    "https://portainer.local",
+		UserIdentifier: "preferred_username",
+		GroupsClaim:    "groups",
+	}
+}
+
+// authorize starts a login and records the nonce and code challenge sent to the provider, it returns the state of the login
+func (provider *testProvider) authorize(t *testing.T, service *Service, clientState string) string {
+	authorizationURL, err := service.AuthorizationURL(clientState, provider.settings())
+	assert.NoError(t, err)
+
+	parsedURL, err := url.Parse(authorizationURL)
+	assert.NoError(t, err)
+
+	query := parsedURL.Query()
+	state := query.Get("state")
+	assert.True(t, strings.HasPrefix(state, clientState+"."), "the state should be prefixed with the client state")
+	assert.NotEqual(t, clientState+".", state, "the state should be generated by the server")
+	assert.Equal(t, "S256", query.Get("code_challenge_method"))
+	assert.Contains(t, query.Get("scope"), "openid")
+
+	provider.nonce = query.Get("nonce")
+	provider.codeChallenge = query.Get("code_challenge")
+
+	return state
+}
+
+func TestAuthenticateOIDC(t *testing.T) {
+	provider := newTestProvider(t)
+	defer provider.server.Close()
+
+	service := NewService()
+	state := provider.authorize(t, service, "state")
+
+	_, err := service.Authenticate("code", "state", provider.settings())
+	assert.Equal(t, errInvalidState, err, "the client state alone should be rejected")
+
+	info, err := service.Authenticate("code", state, provider.settings())
+	assert.NoError(t, err)
+	assert.Equal(t, "alice", info.Username)
+	assert.Equal(t, []string{"devs", "ops"}, info.Groups)
+	assert.True(t, info.GroupsProvided)
+	assert.NotNil(t, info.ExpiryTime)
+
+	_, err = service.Authenticate("code", state, provider.settings())
+	assert.Equal(t, errInvalidState, err, "a state should only be used once")
+}
+
+func TestAuthenticateOIDC_shouldNotProvideGroups_whenTheClaimIsMissing(t *testing.T) {
+	provider := newTestProvider(t)
+	defer provider.server.Close()
+	delete(provider.claims, "groups")
+
+	service := NewService()
+	state := provider.authorize(t, service, "state")
+
+	info, err := service.Authenticate("code", state, provider.settings())
+	assert.NoError(t, err)
+	assert.Empty(t, info.Groups)
+	assert.False(t, info.GroupsProvided, "the memberships should not be synchronized without groups")
+}
+
+func TestAuthenticateOIDC_shouldRejectInvalidIDTokens(t *testing.T) {
+	provider := newTestProvider(t)
+	defer provider.server.Close()
+
+	otherKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+
+	tests := []struct {
+		name   string
+		tamper func(provider *testProvider)
+	}{
+		{"wrong audience", func(provider *testProvider) { provider.claims["aud"] = "another-client" }},
+		{"wrong issuer", func(provider *testProvider) { provider.claims["iss"] = "https://evil.example.com" }},
+		{"expired", func(provider *testProvider) { provider.claims["exp"] = time.Now().Add(-time.Minute).Unix() }},
+		{"wrong nonce", func(provider *testProvider) { provider.nonce = "replayed" }},
+		{"wrong signature", func(provider *testProvider) { provider.key = otherKey }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key, claims := provider.key, provider.claims
+			defer func() { provider.key, provider.claims = key, claims }()
+
+			provider.claims = jwt.MapClaims{}
+			for name, value := range claims {
+				provider.claims[name] = value
+			}
+
+			service := NewService()
+			state := provider.authorize(t, service, "state")
+			tt.tamper(provider)
+
+			_, err := service.Authenticate("code", state, provider.settings())
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestAuthenticateOIDC_shouldRequireTheCodeVerifierOfTheState(t *testing.T) {
+	provider := newTestProvider(t)
+	defer provider.server.Close()
+
+	service := NewService()
+	provider.authorize(t, service, "first")
+	firstChallenge := provider.codeChallenge
+	secondState := provider.authorize(t, service, "second")
+	provider.codeChallenge = firstChallenge
+
+	_, err := service.Authenticate("code", secondState, provider.settings())
+	assert.Error(t, err)
+
+	_, err = service.Authenticate("code", "unknown", provider.settings())
+	assert.Equal(t, errInvalidState, err)
+}
+
+func TestAuthorizationURL_shouldEvictTheOldestPendingLogin(t *testing.T) {
+	provider := newTestProvider(t)
+	defer provider.server.Close()
+
+	service := NewService()
+	oldestState := provider.authorize(t, service, "oldest")
+	for i := 0; i < maxPendingLogins; i++ {
+		service.storePendingLogin(fmt.Sprintf("flood.%d", i), &pendingLogin{expiresAt: time.Now().Add(pendingLoginTTL)})
+	}
+	state := provider.authorize(t, service, "state")
+
+	assert.Len(t, service.pendingLogins, maxPendingLogins)
+	assert.NotContains(t, service.pendingLogins, oldestState)
+
+	_, err := service.Authenticate("code", state, provider.settings())
+	assert.NoError(t, err, "a new login should not be refused when too many logins are pending")
+}
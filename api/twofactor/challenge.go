@@ -0,0 +1,131 @@
+package twofactor
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"sync"
+	"time"
+
+	portainer "github.com/portainer/portainer/api"
+)
+
+const (
+	// challengeTTL is the time given to a user to provide a code after the password was verified
+	challengeTTL = 5 * time.Minute
+	// maxChallengeAttempts is the number of invalid codes accepted before a challenge is invalidated
+	maxChallengeAttempts = 5
+)
+
+// ErrInvalidChallenge is returned when a challenge token is unknown, expired or exhausted
+var ErrInvalidChallenge = errors.New("Invalid or expired two-factor authentication challenge")
+
+// ChallengeStore keeps the challenges issued to the users whose password was verified and who still have
+// to provide a second factor. The challenge tokens are opaque and cannot be used to access the API.
+type ChallengeStore struct {
+	mu         sync.Mutex
+	challenges map[string]*challenge
+}
+
+type challenge struct {
+	userID         portainer.UserID
+	passwordUpdate *PasswordUpdate
+	attempts       int
+	expiresAt      time.Time
+}
+
+// PasswordUpdate is the replacement of an expired password, it is only applied once the second factor is verified
+type PasswordUpdate struct {
+	Password           string
+	PasswordHistory    []string
+	PasswordChangeDate int64
+}
+
+// Apply replaces the password of the user, the caller must persist the user.
+func (update *PasswordUpdate) Apply(user *portainer.User) {
+	user.Password = update.Password
+	user.PasswordHistory = update.PasswordHistory
+	user.PasswordChangeDate = update.PasswordChangeDate
+}
+
+// NewChallengeStore creates a new instance of a challenge store
+func NewChallengeStore() *ChallengeStore {
+	return &ChallengeStore{
+		challenges: make(map[string]*challenge),
+	}
+}
+
+// Create issues a new challenge token for a user. The password update is optional.
+func (store *ChallengeStore) Create(userID portainer.UserID, passwordUpdate *PasswordUpdate) (string, error) {
+	randomBytes := make([]byte, 32)
+	_, err := rand.Read(randomBytes)
+	if err != nil {
+		return "", err
+	}
+	token := base64.RawURLEncoding.EncodeToString(randomBytes)
+
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	now := time.Now()
+	for key, c := range store.challenges {
+		if now.After(c.expiresAt) {
+			delete(store.challenges, key)
+		}
+	}
+
+	store.challenges[token] = &challenge{userID: userID, passwordUpdate: passwordUpdate, expiresAt: now.Add(challengeTTL)}
+
+	return token, nil
+}
+
+// UserID returns the identifier of the user the challenge was issued to.
+func (store *ChallengeStore) UserID(token string) (portainer.UserID, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	c, ok := store.challenges[token]
+	if !ok || time.Now().After(c.expiresAt) {
+		delete(store.challenges, token)
+		return 0, ErrInvalidChallenge
+	}
+
+	return c.userID, nil
+}
+
+// PasswordUpdate returns the password update of the challenge, nil when there is none.
+func (store *ChallengeStore) PasswordUpdate(token string) *PasswordUpdate {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	c, ok := store.challenges[token]
+	if !ok {
+		return nil
+	}
+
+	return c.passwordUpdate
+}
+
+// Fail records an invalid code, the challenge is invalidated after too many attempts.
+func (store *ChallengeStore) Fail(token string) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	c, ok := store.challenges[token]
+	if !ok {
+		return
+	}
+
+	c.attempts++
+	if c.attempts >= maxChallengeAttempts {
+		delete(store.challenges, token)
+	}
+}
+
+// Complete invalidates a challenge once the second factor was verified.
+func (store *ChallengeStore) Complete(token string) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	delete(store.challenges, token)
+}
@@ -0,0 +1,40 @@
+package twofactor
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChallengeStore(t *testing.T) {
+	store := NewChallengeStore()
+
+	token, err := store.Create(2, nil)
+	assert.NoError(t, err)
+
+	userID, err := store.UserID(token)
+	assert.NoError(t, err)
+	assert.EqualValues(t, 2, userID)
+
+	_, err = store.UserID("unknown")
+	assert.Equal(t, ErrInvalidChallenge, err)
+
+	store.Complete(token)
+	_, err = store.UserID(token)
+	assert.Equal(t, ErrInvalidChallenge, err, "a challenge should only be completed once")
+}
+
+func TestChallengeStore_shouldInvalidateAfterTooManyAttempts(t *testing.T) {
+	store := NewChallengeStore()
+	token, _ := store.Create(1, nil)
+
+	for i := 0; i < maxChallengeAttempts-1; i++ {
+		store.Fail(token)
+	}
+	_, err := store.UserID(token)
+	assert.NoError(t, err)
+
+	store.Fail(token)
+	_, err = store.UserID(token)
+	assert.Equal(t, ErrInvalidChallenge, err)
+}
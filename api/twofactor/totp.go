@@ -0,0 +1,107 @@
+package twofactor
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pquerna/otp"
+	"github.com/pquerna/otp/totp"
+)
+
+const (
+	issuer = "Portainer"
+	// period is the validity of a code in seconds, as recommended by RFC 6238
+	period = 30
+	// recoveryCodeCount is the number of recovery codes generated during an enrolment
+	recoveryCodeCount = 10
+)
+
+var validateOpts = totp.ValidateOpts{
+	Period:    period,
+	Skew:      0,
+	Digits:    otp.DigitsSix,
+	Algorithm: otp.AlgorithmSHA1,
+}
+
+// GenerateSecret generates a new TOTP secret for a user. It returns the base32 encoded secret and
+// the otpauth:// provisioning URI that can be displayed as a QR code by authenticator applications.
+func GenerateSecret(username string) (secret, provisioningURI string, err error) {
+	key, err := totp.Generate(totp.GenerateOpts{
+		Issuer:      issuer,
+		AccountName: username,
+		Period:      period,
+		Digits:      otp.DigitsSix,
+		Algorithm:   otp.AlgorithmSHA1,
+	})
+	if err != nil {
+		return "", "", err
+	}
+
+	return key.Secret(), key.URL(), nil
+}
+
+// ValidateCode validates a code against the secret, accepting the codes of the previous and next periods
+// to tolerate clock drift. It returns the time step of the code so that callers can reject a code that was
+// already used, a code is only valid if its step is greater than lastUsedStep.
+func ValidateCode(secret, code string, now time.Time, lastUsedStep int64) (int64, bool) {
+	code = strings.TrimSpace(code)
+	currentStep := now.Unix() / period
+
+	for _, step := range []int64{currentStep - 1, currentStep, currentStep + 1} {
+		if step <= lastUsedStep {
+			continue
+		}
+
+		valid, err := totp.ValidateCustom(code, secret, time.Unix(step*period, 0).UTC(), validateOpts)
+		if err == nil && valid {
+			return step, true
+		}
+	}
+
+	return 0, false
+}
+
+// GenerateRecoveryCodes generates the one-time recovery codes of a user. It returns the codes, which are
+// only displayed once, and their digests to store.
+func GenerateRecoveryCodes() (codes []string, digests []string, err error) {
+	for i := 0; i < recoveryCodeCount; i++ {
+		randomBytes := make([]byte, 5)
+		_, err := rand.Read(randomBytes)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		code := hex.EncodeToString(randomBytes)
+		code = fmt.Sprintf("%s-%s", code[:5], code[5:])
+
+		codes = append(codes, code)
+		digests = append(digests, RecoveryCodeDigest(code))
+	}
+
+	return codes, digests, nil
+}
+
+// RecoveryCodeDigest returns the SHA-256 digest of a recovery code, hex encoded.
+func RecoveryCodeDigest(code string) string {
+	hash := sha256.Sum256([]byte(strings.ToLower(strings.TrimSpace(code))))
+	return hex.EncodeToString(hash[:])
+}
+
+// ConsumeRecoveryCode returns the digests without the digest of the code. The second value is false
+// when the code does not match any digest.
+func ConsumeRecoveryCode(digests []string, code string) ([]string, bool) {
+	digest := RecoveryCodeDigest(code)
+
+	for idx, d := range digests {
+		if d == digest {
+			remaining := append([]string{}, digests[:idx]...)
+			return append(remaining, digests[idx+1:]...), true
+		}
+	}
+
+	return digests, false
+}
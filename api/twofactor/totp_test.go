@@ -0,0 +1,92 @@
+package twofactor
+
+import (
+	"testing"
+	"time"
+
+	portainer "github.com/portainer/portainer/api"
+	"github.com/pquerna/otp/totp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateCode(t *testing.T) {
+	secret, provisioningURI, err := GenerateSecret("admin")
+	assert.NoError(t, err)
+	assert.Contains(t, provisioningURI, "otpauth://totp/Portainer:admin")
+
+	now := time.Unix(1600000000, 0)
+	code, _ := totp.GenerateCode(secret, now)
+
+	step, ok := ValidateCode(secret, code, now, 0)
+	assert.True(t, ok)
+	assert.Equal(t, now.Unix()/period, step)
+
+	_, ok = ValidateCode(secret, code, now.Add(period*time.Second), 0)
+	assert.True(t, ok, "the code of the previous period should be accepted")
+
+	_, ok = ValidateCode(secret, code, now.Add(3*period*time.Second), 0)
+	assert.False(t, ok, "an old code should be rejected")
+
+	_, ok = ValidateCode(secret, code, now, step)
+	assert.False(t, ok, "a code should only be used once")
+
+	_, ok = ValidateCode(secret, "000000", now, 0)
+	assert.False(t, ok)
+}
+
+func TestConsumeRecoveryCode(t *testing.T) {
+	codes, digests, err := GenerateRecoveryCodes()
+	assert.NoError(t, err)
+	assert.Len(t, codes, recoveryCodeCount)
+	assert.NotContains(t, digests, codes[0], "the codes should not be stored in clear")
+
+	remaining, ok := ConsumeRecoveryCode(digests, " "+codes[3]+" ")
+	assert.True(t, ok)
+	assert.Len(t, remaining, recoveryCodeCount-1)
+
+	_, ok = ConsumeRecoveryCode(remaining, codes[3])
+	assert.False(t, ok, "a recovery code should only be used once")
+}
+
+func TestEnrolment(t *testing.T) {
+	user := &portainer.User{Username: "alice"}
+	now := time.Unix(1600000000, 0)
+
+	_, err := CompleteEnrolment(user, "123456", now)
+	assert.Equal(t, ErrNoPendingEnrolment, err)
+
+	secret, _, err := StartEnrolment(user)
+	assert.NoError(t, err)
+	assert.False(t, user.TOTP.Enabled)
+
+	_, err = CompleteEnrolment(user, "000000", now)
+	assert.Equal(t, ErrInvalidCode, err)
+
+	code, _ := totp.GenerateCode(secret, now)
+	recoveryCodes, err := CompleteEnrolment(user, code, now)
+	assert.NoError(t, err)
+	assert.True(t, user.TOTP.Enabled)
+	assert.Equal(t, secret, user.TOTP.Secret)
+	assert.Empty(t, user.TOTP.PendingSecret)
+
+	err = Verify(user, code, "", now)
+	assert.Equal(t, ErrInvalidCode, err, "the code used to enrol should not be accepted again")
+
+	err = Verify(user, "", recoveryCodes[0], now)
+	assert.NoError(t, err)
+	assert.Len(t, user.TOTP.RecoveryCodeDigests, recoveryCodeCount-1)
+
+	nextCode, _ := totp.GenerateCode(secret, now.Add(period*time.Second))
+	err = Verify(user, nextCode, "", now.Add(period*time.Second))
+	assert.NoError(t, err)
+}
+
+func TestRequired(t *testing.T) {
+	admin := &portainer.User{Role: portainer.AdministratorRole}
+	user := &portainer.User{Role: portainer.StandardUserRole}
+
+	assert.False(t, Required(admin, 0))
+	assert.True(t, Required(admin, portainer.TwoFactorRequiredForAdministrators))
+	assert.False(t, Required(user, portainer.TwoFactorRequiredForAdministrators))
+	assert.True(t, Required(user, portainer.TwoFactorRequiredForEveryone))
+}
@@ -0,0 +1,88 @@
+package twofactor
+
+import (
+	"errors"
+	"time"
+
+	portainer "github.com/portainer/portainer/api"
+)
+
+var (
+	// ErrInvalidCode is returned when a TOTP or recovery code is invalid
+	ErrInvalidCode = errors.New("Invalid two-factor authentication code")
+	// ErrNoPendingEnrolment is returned when a code is verified before the enrolment was started
+	ErrNoPendingEnrolment = errors.New("Two-factor authentication enrolment was not started")
+)
+
+// Required returns whether the user must use two-factor authentication according to the requirement
+// defined in the settings.
+func Required(user *portainer.User, requirement portainer.TwoFactorRequirement) bool {
+	switch requirement {
+	case portainer.TwoFactorRequiredForEveryone:
+		return true
+	case portainer.TwoFactorRequiredForAdministrators:
+		return user.Role == portainer.AdministratorRole
+	}
+	return false
+}
+
+// StartEnrolment generates a pending secret for the user. Two-factor authentication is only
+// enabled once a code generated with this secret is verified with CompleteEnrolment.
+func StartEnrolment(user *portainer.User) (secret, provisioningURI string, err error) {
+	secret, provisioningURI, err = GenerateSecret(user.Username)
+	if err != nil {
+		return "", "", err
+	}
+
+	user.TOTP.PendingSecret = secret
+	return secret, provisioningURI, nil
+}
+
+// CompleteEnrolment verifies a code against the pending secret of the user and enables two-factor
+// authentication. It returns the recovery codes of the user, the caller must persist the user.
+func CompleteEnrolment(user *portainer.User, code string, now time.Time) ([]string, error) {
+	if user.TOTP.PendingSecret == "" {
+		return nil, ErrNoPendingEnrolment
+	}
+
+	step, ok := ValidateCode(user.TOTP.PendingSecret, code, now, 0)
+	if !ok {
+		return nil, ErrInvalidCode
+	}
+
+	recoveryCodes, digests, err := GenerateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+
+	user.TOTP = portainer.TOTPSettings{
+		Enabled:             true,
+		Secret:              user.TOTP.PendingSecret,
+		RecoveryCodeDigests: digests,
+		LastUsedStep:        step,
+	}
+
+	return recoveryCodes, nil
+}
+
+// Verify verifies a TOTP code, or a recovery code when code is empty. The used code is recorded
+// in the settings of the user so that it cannot be used again, the caller must persist the user.
+func Verify(user *portainer.User, code, recoveryCode string, now time.Time) error {
+	if code == "" {
+		digests, ok := ConsumeRecoveryCode(user.TOTP.RecoveryCodeDigests, recoveryCode)
+		if recoveryCode == "" || !ok {
+			return ErrInvalidCode
+		}
+
+		user.TOTP.RecoveryCodeDigests = digests
+		return nil
+	}
+
+	step, ok := ValidateCode(user.TOTP.Secret, code, now, user.TOTP.LastUsedStep)
+	if !ok {
+		return ErrInvalidCode
+	}
+
+	user.TOTP.LastUsedStep = step
+	return nil
+}
@@ -96,16 +96,18 @@ class AuthenticationController {
   generateState() {
     const uuid = uuidv4();
     this.LocalStorage.storeLoginStateUUID(uuid);
-    return '&state=' + uuid;
+    const separator = (this.state.OAuthLoginURI || '').indexOf('?') === -1 ? '?' : '&';
+    return separator + 'state=' + uuid;
   }
 
   generateOAuthLoginURI() {
     this.OAuthLoginURI = this.state.OAuthLoginURI + this.generateState();
   }
 
+  // the OpenID Connect logins are started by Portainer, which prefixes the state it generates with the one of the UI
   hasValidState(state) {
     const savedUUID = this.LocalStorage.getLoginStateUUID();
-    return savedUUID && state && savedUUID === state;
+    return savedUUID && state && (savedUUID === state || state.startsWith(savedUUID + '.'));
   }
 
   /**
@@ -161,9 +163,9 @@ class AuthenticationController {
    * LOGIN METHODS SECTION
    */
 
-  async oAuthLoginAsync(code) {
+  async oAuthLoginAsync(code, state) {
     try {
-      await this.Authentication.OAuthLogin(code);
+      await this.Authentication.OAuthLogin(code, state);
       this.URLHelper.cleanParameters();
     } catch (err) {
       this.error(err, 'Unable to login via OAuth');
@@ -207,7 +209,7 @@ class AuthenticationController {
    */
   async manageOauthCodeReturn(code, state) {
     if (this.hasValidState(state)) {
-      await this.oAuthLoginAsync(code);
+      await this.oAuthLoginAsync(code, state);
     } else {
       this.error(null, 'Invalid OAuth state, try again.');
     }